@@ -18,6 +18,7 @@ import (
 	"github.com/greenplum-db/gpbackup/report"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
@@ -30,36 +31,56 @@ import (
 func initializeFlags(cmd *cobra.Command) {
 	SetFlagDefaults(cmd.Flags())
 
-	_ = cmd.MarkFlagRequired(options.DBNAME)
-
 	cmdFlags = cmd.Flags()
 }
 
 func SetFlagDefaults(flagSet *pflag.FlagSet) {
+	flagSet.Bool(options.ALL_DATABASES, false, "Back up every database that allows connections, other than template databases, with the same flags")
 	flagSet.String(options.BACKUP_DIR, "", "The absolute path of the directory to which all backup files will be written")
 	flagSet.Int(options.COMPRESSION_LEVEL, 1, "Level of compression to use during data backup. Valid values are between 1 and 9.")
+	flagSet.String(options.CONFIG, "", "A YAML file setting the value of any flag that is not given on the command line or in a GPBACKUP_<FLAG> environment variable, with optional per-database profiles")
 	flagSet.Bool(options.DATA_ONLY, false, "Only back up data, do not back up metadata")
-	flagSet.String(options.DBNAME, "", "The database to be backed up")
+	flagSet.String(options.CLUSTER_TIMESTAMP, "", "The timestamp of the backup of several databases that this backup is part of")
+	_ = flagSet.MarkHidden(options.CLUSTER_TIMESTAMP)
+	flagSet.StringArray(options.DBNAME, []string{}, "The database to be backed up. --dbname can be specified multiple times.")
 	flagSet.Bool(options.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(options.ENCRYPTION_KEY_COMMAND, "", "A command whose output is the key with which to encrypt the backup files")
+	flagSet.String(options.ENCRYPTION_KEY_FILE, "", "A file containing the key, 64 hexadecimal characters, with which to encrypt the backup files")
 	flagSet.StringArray(options.EXCLUDE_SCHEMA, []string{}, "Back up all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.String(options.EXCLUDE_SCHEMA_FILE, "", "A file containing a list of schemas to be excluded from the backup")
+	flagSet.StringArray(options.EXCLUDE_SCHEMA_PATTERN, []string{}, "Back up all metadata except objects in schemas matching the specified glob or /regular expression/ pattern(s). --exclude-schema-pattern can be specified multiple times.")
+	flagSet.StringArray(options.EXCLUDE_OBJECT_TYPE, []string{}, "Back up all metadata except objects of the specified type(s), such as ROLE, TRIGGER, or COMMENT. --exclude-object-type can be specified multiple times.")
 	flagSet.StringArray(options.EXCLUDE_RELATION, []string{}, "Back up all metadata except the specified table(s). --exclude-table can be specified multiple times.")
 	flagSet.String(options.EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be excluded from the backup")
+	flagSet.StringArray(options.EXCLUDE_RELATION_PATTERN, []string{}, "Back up all metadata except tables matching the specified glob or /regular expression/ pattern(s), such as 'etl.stage_*'. --exclude-table-pattern can be specified multiple times.")
 	flagSet.String(options.FROM_TIMESTAMP, "", "A timestamp to use to base the current incremental backup off")
 	flagSet.Bool("help", false, "Help for gpbackup")
 	flagSet.StringArray(options.INCLUDE_SCHEMA, []string{}, "Back up only the specified schema(s). --include-schema can be specified multiple times.")
 	flagSet.String(options.INCLUDE_SCHEMA_FILE, "", "A file containing a list of schema(s) to be included in the backup")
+	flagSet.StringArray(options.INCLUDE_SCHEMA_PATTERN, []string{}, "Back up only schemas matching the specified glob or /regular expression/ pattern(s). --include-schema-pattern can be specified multiple times.")
+	flagSet.Bool(options.INCLUDE_DEPENDENCIES, false, "With --include-table, also back up the schemas, types, functions, sequences, and other objects that the included tables depend on")
+	flagSet.StringArray(options.INCLUDE_OBJECT_TYPE, []string{}, "Back up only metadata for objects of the specified type(s), such as SCHEMA, TABLE, or VIEW. --include-object-type can be specified multiple times.")
 	flagSet.StringArray(options.INCLUDE_RELATION, []string{}, "Back up only the specified table(s). --include-table can be specified multiple times.")
 	flagSet.String(options.INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be included in the backup")
-	flagSet.Bool(options.INCREMENTAL, false, "Only back up data for AO tables that have been modified since the last backup")
+	flagSet.StringArray(options.INCLUDE_RELATION_PATTERN, []string{}, "Back up only tables matching the specified glob or /regular expression/ pattern(s), such as 'etl.stage_*'. --include-table-pattern can be specified multiple times.")
+	flagSet.Bool(options.INCREMENTAL, false, "Only back up data for tables that have been modified since the last backup")
 	flagSet.Int(options.JOBS, 1, "The number of parallel connections to use when backing up data")
 	flagSet.Bool(options.LEAF_PARTITION_DATA, false, "For partition tables, create one data file per leaf partition instead of one data file for the whole table")
+	flagSet.Bool(options.LOCK_NOWAIT, false, "Fail to lock a batch of tables at once, instead of waiting, if another session holds a conflicting lock on any of them")
+	flagSet.Int(options.LOCK_RETRIES, 0, "The number of times to retry locking a batch of tables that could not be locked because of --lock-wait-timeout or --lock-nowait")
+	flagSet.Int(options.LOCK_RETRY_BACKOFF, 10, "The number of seconds to wait before the first retry of --lock-retries, doubled for each retry after it")
+	flagSet.Int(options.LOCK_WAIT_TIMEOUT, 0, "The maximum number of seconds to wait for the locks on each batch of tables. 0 waits indefinitely.")
 	flagSet.Bool(options.METADATA_ONLY, false, "Only back up metadata, do not back up data")
+	flagSet.Bool(options.NO_CLUSTER_GLOBALS, false, "Do not back up the global objects shared by all databases, such as roles and tablespaces")
+	_ = flagSet.MarkHidden(options.NO_CLUSTER_GLOBALS)
 	flagSet.Bool(options.NO_COMPRESSION, false, "Disable compression of data files")
+	flagSet.String(options.ON_BROKEN_CHAIN, "fail", "What to do when a backup that an incremental backup would depend on is missing or incomplete: fail, or take a full backup instead")
 	flagSet.String(options.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(options.QUIET, false, "Suppress non-warning, non-error log messages")
 	flagSet.Bool(options.SINGLE_DATA_FILE, false, "Back up all data to a single file instead of one per table")
+	flagSet.Bool(options.SKIP_LOCKED_TABLES, false, "Leave tables that cannot be locked because of --lock-wait-timeout or --lock-nowait out of the backup instead of failing it")
+	flagSet.Bool(options.STRUCTURED_METADATA, false, "Also write the owner, privileges, comment, and definition of each object to a structured YAML metadata file, which gprestore uses to change them reliably")
 	flagSet.Bool(options.VERBOSE, false, "Print verbose log messages")
 	flagSet.Bool(options.WITH_STATS, false, "Back up query plan statistics")
 }
@@ -74,7 +95,17 @@ func DoInit(cmd *cobra.Command) {
 	objectCounts = make(map[string]int)
 }
 
+/*
+ * Flags not given on the command line are set from the environment and the
+ * configuration file before the flags are validated, so that a --dbname from
+ * either of them satisfies the check that --dbname or --all-databases is given.
+ */
+func ApplyConfig(cmd *cobra.Command) error {
+	return options.ApplyConfig(cmd.Flags(), "gpbackup", options.DBNAME)
+}
+
 func DoFlagValidation(cmd *cobra.Command) {
+	effectiveConfig = options.GetEffectiveConfig(cmd.Flags())
 	ValidateFlagCombinations(cmd.Flags())
 	ValidateFlagValues()
 }
@@ -84,16 +115,23 @@ func DoSetup() {
 	SetLoggerVerbosity()
 	gplog.Verbose("Backup Command: %s", os.Args)
 
-	utils.CheckGpexpandRunning(utils.BackupPreventedByGpexpandMessage)
+	gpexpandPhase := utils.GetGpexpandPhase()
+	if gpexpandPhase == utils.GpexpandAddingSegments {
+		gplog.Fatal(errors.New(string(utils.BackupPreventedByGpexpandMessage)), "")
+	} else if gpexpandPhase == utils.GpexpandRedistributingTables {
+		gplog.Info("Greenplum expansion is redistributing tables; tables that have not been redistributed yet will be backed up in their pre-expansion layout")
+	}
 	timestamp := history.CurrentTimestamp()
 	CreateBackupLockFile(timestamp)
 	InitializeConnectionPool()
 
-	gplog.Info("Starting backup of database %s", MustGetFlagString(options.DBNAME))
+	gplog.Info("Starting backup of database %s", connectionPool.DBName)
+	ResolveFilterPatterns()
 	opts, err := options.NewOptions(cmdFlags)
 	gplog.FatalOnError(err)
 
 	validateFilterLists(opts)
+	ResolveIncludedDependencies(opts)
 
 	err = opts.ExpandIncludesForPartitions(connectionPool, cmdFlags)
 	gplog.FatalOnError(err)
@@ -110,6 +148,9 @@ func DoSetup() {
 	}
 	globalTOC = &toc.TOC{}
 	globalTOC.InitializeMetadataEntryMap()
+	if MustGetFlagBool(options.STRUCTURED_METADATA) {
+		globalTOC.ObjectDefinitions = toc.NewObjectDefinitions()
+	}
 	utils.InitializePipeThroughParameters(!MustGetFlagBool(options.NO_COMPRESSION), MustGetFlagInt(options.COMPRESSION_LEVEL))
 	GetQuotedRoleNames(connectionPool)
 
@@ -125,7 +166,11 @@ func DoSetup() {
 		gplog.Info("Plugin config path: %s", pluginConfig.ConfigPath)
 	}
 
+	InitializeEncryption()
 	InitializeBackupReport(*opts)
+	if encryptionKey != nil {
+		backupReport.EncryptionKeyID = encryptionKey.ID
+	}
 
 	if pluginConfigFlag != "" {
 		backupReport.PluginVersion = pluginConfig.CheckPluginExistsOnAllHosts(globalCluster)
@@ -144,10 +189,13 @@ func DoBackup() {
 	var targetBackupFPInfo filepath.FilePathInfo
 	if MustGetFlagBool(options.INCREMENTAL) {
 		targetBackupTimestamp = GetTargetBackupTimestamp()
+		if chainProblems := CheckIncrementalChain(targetBackupTimestamp); len(chainProblems) > 0 {
+			targetBackupTimestamp = HandleBrokenChain(targetBackupTimestamp, chainProblems)
+		}
 		targetBackupFPInfo = filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
 			targetBackupTimestamp, globalFPInfo.UserSpecifiedSegPrefix)
 
-		if pluginConfigFlag != "" {
+		if pluginConfigFlag != "" && targetBackupTimestamp != "" {
 			// These files need to be downloaded from the remote system into the local filesystem
 			pluginConfig.MustRestoreFile(targetBackupFPInfo.GetConfigFilePath())
 			pluginConfig.MustRestoreFile(targetBackupFPInfo.GetTOCFilePath())
@@ -163,7 +211,7 @@ func DoBackup() {
 	CheckTablesContainData(dataTables)
 	metadataFilename := globalFPInfo.GetMetadataFilePath()
 	gplog.Info("Metadata will be written to %s", metadataFilename)
-	metadataFile := utils.NewFileWithByteCountFromFile(metadataFilename)
+	metadataFile := utils.NewEncryptedFileWithByteCountFromFile(metadataFilename, encryptionKey)
 
 	BackupSessionGUCs(metadataFile)
 	if !MustGetFlagBool(options.DATA_ONLY) {
@@ -172,7 +220,7 @@ func DoBackup() {
 			tableOnlyBackup = false
 			backupGlobal(metadataFile)
 		}
-		backupPredata(metadataFile, metadataTables, tableOnlyBackup)
+		backupPredata(metadataFile, metadataTables, tableOnlyBackup && !MustGetFlagBool(options.INCLUDE_DEPENDENCIES))
 		backupPostdata(metadataFile)
 	}
 
@@ -188,7 +236,7 @@ func DoBackup() {
 		if targetBackupTimestamp != "" {
 			gplog.Info("Basing incremental backup off of backup with timestamp = %s", targetBackupTimestamp)
 
-			targetBackupTOC := toc.NewTOC(targetBackupFPInfo.GetTOCFilePath())
+			targetBackupTOC := toc.NewTOC(decryptedFiles.MustGetPath(targetBackupFPInfo.GetTOCFilePath()))
 			targetBackupRestorePlan = history.ReadConfigFile(targetBackupFPInfo.GetConfigFilePath()).RestorePlan
 			backupSetTables = FilterTablesForIncremental(targetBackupTOC, globalTOC, dataTables)
 		}
@@ -198,11 +246,22 @@ func DoBackup() {
 		backupData(backupSetTables)
 	}
 
+	// Large object contents are only backed up along with the pre-data statements that create the large objects
+	if !MustGetFlagBool(options.METADATA_ONLY) && !MustGetFlagBool(options.DATA_ONLY) && shouldBackupLargeObjects() {
+		backupLargeObjectData()
+	}
+
 	if MustGetFlagBool(options.WITH_STATS) {
 		backupStatistics(metadataTables)
 	}
 
-	globalTOC.WriteToFileAndMakeReadOnly(globalFPInfo.GetTOCFilePath())
+	globalTOC.WriteToEncryptedFileAndMakeReadOnly(globalFPInfo.GetTOCFilePath(), encryptionKey)
+	if len(backupReport.SkippedTables) > 0 {
+		WriteSkippedTablesFile()
+	}
+	if MustGetFlagBool(options.STRUCTURED_METADATA) {
+		globalTOC.ObjectDefinitions.WriteToEncryptedFileAndMakeReadOnly(globalFPInfo.GetStructuredMetadataFilePath(), encryptionKey)
+	}
 	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
 		connectionPool.MustCommit(connNum)
 	}
@@ -210,9 +269,18 @@ func DoBackup() {
 	if pluginConfigFlag != "" {
 		pluginConfig.MustBackupFile(metadataFilename)
 		pluginConfig.MustBackupFile(globalFPInfo.GetTOCFilePath())
+		if MustGetFlagBool(options.STRUCTURED_METADATA) {
+			pluginConfig.MustBackupFile(globalFPInfo.GetStructuredMetadataFilePath())
+		}
 		if MustGetFlagBool(options.WITH_STATS) {
 			pluginConfig.MustBackupFile(globalFPInfo.GetStatisticsFilePath())
 		}
+		if len(backupReport.SkippedTables) > 0 {
+			pluginConfig.MustBackupFile(globalFPInfo.GetSkippedTablesFilePath())
+		}
+		if len(globalTOC.LargeObjectEntries) > 0 {
+			pluginConfig.MustBackupFile(globalFPInfo.GetLargeObjectDataFilePath())
+		}
 		_ = utils.CopyFile(pluginConfigFlag, globalFPInfo.GetPluginConfigPath())
 		pluginConfig.MustBackupFile(globalFPInfo.GetPluginConfigPath())
 	}
@@ -224,16 +292,35 @@ func DoBackup() {
 func backupGlobal(metadataFile *utils.FileWithByteCount) {
 	gplog.Info("Writing global database metadata")
 
-	BackupResourceQueues(metadataFile)
-	if connectionPool.Version.AtLeast("5") {
+	/*
+	 * In a backup of several databases, the objects shared by all databases
+	 * are only backed up with the first database.
+	 */
+	backupClusterGlobals := !MustGetFlagBool(options.NO_CLUSTER_GLOBALS)
+	if backupClusterGlobals && shouldBackupObjectType("RESOURCE QUEUE") {
+		BackupResourceQueues(metadataFile)
+	}
+	if backupClusterGlobals && connectionPool.Version.AtLeast("5") && shouldBackupObjectType("RESOURCE GROUP") {
 		BackupResourceGroups(metadataFile)
 	}
-	BackupRoles(metadataFile)
-	BackupRoleGrants(metadataFile)
-	BackupTablespaces(metadataFile)
-	BackupCreateDatabase(metadataFile)
-	BackupDatabaseGUCs(metadataFile)
-	BackupRoleGUCs(metadataFile)
+	if backupClusterGlobals && shouldBackupObjectType("ROLE") {
+		BackupRoles(metadataFile)
+	}
+	if backupClusterGlobals && shouldBackupObjectType("ROLE GRANT") {
+		BackupRoleGrants(metadataFile)
+	}
+	if backupClusterGlobals && shouldBackupObjectType("TABLESPACE") {
+		BackupTablespaces(metadataFile)
+	}
+	if shouldBackupObjectType("DATABASE") {
+		BackupCreateDatabase(metadataFile)
+	}
+	if shouldBackupObjectType("DATABASE GUC") {
+		BackupDatabaseGUCs(metadataFile)
+	}
+	if backupClusterGlobals && shouldBackupObjectType("ROLE GUCS") {
+		BackupRoleGUCs(metadataFile)
+	}
 
 	if wasTerminated {
 		gplog.Info("Global database metadata backup incomplete")
@@ -258,58 +345,109 @@ func backupPredata(metadataFile *utils.FileWithByteCount, tables []Table, tableO
 	funcInfoMap := GetFunctionOidToInfoMap(connectionPool)
 
 	if !tableOnly {
-		BackupSchemas(metadataFile)
-		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 && connectionPool.Version.AtLeast("5") {
+		if shouldBackupObjectType("SCHEMA") {
+			BackupSchemas(metadataFile)
+		}
+		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 && connectionPool.Version.AtLeast("5") && shouldBackupObjectType("EXTENSION") {
 			BackupExtensions(metadataFile)
 		}
 
-		if connectionPool.Version.AtLeast("6") {
+		if connectionPool.Version.AtLeast("6") && shouldBackupObjectType("COLLATION") {
 			BackupCollations(metadataFile)
 		}
 
-		procLangs := GetProceduralLanguages(connectionPool)
-		langFuncs, functionMetadata := RetrieveFunctions(&sortables, metadataMap, procLangs)
+		// Functions that are handlers for procedural languages are written with the languages
+		if shouldBackupObjectType("FUNCTION") || shouldBackupObjectType("LANGUAGE") {
+			procLangs := FilterIncludedDependencies(GetProceduralLanguages(connectionPool)).([]ProceduralLanguage)
+			langFuncs, functionMetadata := RetrieveFunctions(&sortables, metadataMap, procLangs)
 
-		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 {
-			BackupProceduralLanguages(metadataFile, procLangs, langFuncs, functionMetadata, funcInfoMap)
+			if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 && shouldBackupObjectType("LANGUAGE") {
+				BackupProceduralLanguages(metadataFile, procLangs, langFuncs, functionMetadata, funcInfoMap)
+			}
+		}
+		if shouldBackupObjectType("TYPE") || shouldBackupObjectType("DOMAIN") {
+			RetrieveAndBackupTypes(metadataFile, &sortables, metadataMap)
 		}
-		RetrieveAndBackupTypes(metadataFile, &sortables, metadataMap)
 
 		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 &&
 			connectionPool.Version.AtLeast("6") {
-			RetrieveForeignDataWrappers(&sortables, metadataMap)
-			RetrieveForeignServers(&sortables, metadataMap)
-			RetrieveUserMappings(&sortables)
+			if shouldBackupObjectType("FOREIGN DATA WRAPPER") {
+				RetrieveForeignDataWrappers(&sortables, metadataMap)
+			}
+			if shouldBackupObjectType("FOREIGN SERVER") {
+				RetrieveForeignServers(&sortables, metadataMap)
+			}
+			if shouldBackupObjectType("USER MAPPING") {
+				RetrieveUserMappings(&sortables)
+			}
+		}
+		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 &&
+			connectionPool.Version.AtLeast("7") && shouldBackupObjectType("ACCESS METHOD") {
+			RetrieveAccessMethods(&sortables, metadataMap)
 		}
 
-		protocols = RetrieveProtocols(&sortables, metadataMap)
+		if shouldBackupObjectType("PROTOCOL") {
+			protocols = RetrieveProtocols(&sortables, metadataMap)
+		}
 
 		if connectionPool.Version.AtLeast("5") {
-			RetrieveTSParsers(&sortables, metadataMap)
-			RetrieveTSConfigurations(&sortables, metadataMap)
-			RetrieveTSTemplates(&sortables, metadataMap)
-			RetrieveTSDictionaries(&sortables, metadataMap)
+			if shouldBackupObjectType("TEXT SEARCH PARSER") {
+				RetrieveTSParsers(&sortables, metadataMap)
+			}
+			if shouldBackupObjectType("TEXT SEARCH CONFIGURATION") {
+				RetrieveTSConfigurations(&sortables, metadataMap)
+			}
+			if shouldBackupObjectType("TEXT SEARCH TEMPLATE") {
+				RetrieveTSTemplates(&sortables, metadataMap)
+			}
+			if shouldBackupObjectType("TEXT SEARCH DICTIONARY") {
+				RetrieveTSDictionaries(&sortables, metadataMap)
+			}
 
-			BackupOperatorFamilies(metadataFile)
+			if shouldBackupObjectType("OPERATOR FAMILY") {
+				BackupOperatorFamilies(metadataFile)
+			}
 		}
 
-		RetrieveOperators(&sortables, metadataMap)
-		RetrieveOperatorClasses(&sortables, metadataMap)
-		RetrieveAggregates(&sortables, metadataMap)
-		RetrieveCasts(&sortables, metadataMap)
+		if shouldBackupObjectType("OPERATOR") {
+			RetrieveOperators(&sortables, metadataMap)
+		}
+		if shouldBackupObjectType("OPERATOR CLASS") {
+			RetrieveOperatorClasses(&sortables, metadataMap)
+		}
+		if shouldBackupObjectType("AGGREGATE") {
+			RetrieveAggregates(&sortables, metadataMap)
+		}
+		if shouldBackupObjectType("CAST") {
+			RetrieveCasts(&sortables, metadataMap)
+		}
 	}
 
-	RetrieveViews(&sortables)
+	if shouldBackupObjectType("VIEW") || shouldBackupObjectType("MATERIALIZED VIEW") {
+		RetrieveViews(&sortables)
+	}
 	sequences, sequenceOwnerColumns := RetrieveSequences()
-	BackupCreateSequences(metadataFile, sequences, relationMetadata)
+	if shouldBackupObjectType("SEQUENCE") {
+		BackupCreateSequences(metadataFile, sequences, relationMetadata)
+	}
 	constraints, conMetadata := RetrieveConstraints()
 
+	sortables = FilterSortablesByObjectType(sortables)
 	BackupDependentObjects(metadataFile, tables, protocols, metadataMap, constraints, sortables, funcInfoMap, tableOnly)
 
-	PrintAlterSequenceStatements(metadataFile, globalTOC, sequences, sequenceOwnerColumns)
+	if shouldBackupObjectType("SEQUENCE OWNER") {
+		PrintAlterSequenceStatements(metadataFile, globalTOC, sequences, sequenceOwnerColumns)
+	}
 
-	BackupConversions(metadataFile)
-	BackupConstraints(metadataFile, constraints, conMetadata)
+	if shouldBackupObjectType("CONVERSION") {
+		BackupConversions(metadataFile)
+	}
+	if !tableOnly && shouldBackupLargeObjects() {
+		BackupLargeObjects(metadataFile)
+	}
+	if shouldBackupObjectType("CONSTRAINT") {
+		BackupConstraints(metadataFile, constraints, conMetadata)
+	}
 	if wasTerminated {
 		gplog.Info("Pre-data metadata backup incomplete")
 	} else {
@@ -325,6 +463,14 @@ func backupData(tables []Table) {
 		return
 	}
 
+	tableSegmentCounts := GetTableSegmentCounts(connectionPool, tables, len(globalCluster.ContentIDs)-1)
+	if len(tableSegmentCounts) > 0 {
+		if MustGetFlagBool(options.SINGLE_DATA_FILE) {
+			gplog.Fatal(errors.Errorf("%d tables have not been redistributed onto all segments and cannot be backed up with --single-data-file. Back them up without --single-data-file or after they have been redistributed.", len(tableSegmentCounts)), "")
+		}
+		gplog.Info("%d tables have not been redistributed onto all segments; their data will be backed up from the segments they span", len(tableSegmentCounts))
+	}
+
 	if MustGetFlagBool(options.SINGLE_DATA_FILE) {
 		gplog.Verbose("Initializing pipes and gpbackup_helper on segments for single data file backup")
 		utils.VerifyHelperVersionOnSegments(version, globalCluster)
@@ -342,11 +488,12 @@ func backupData(tables []Table) {
 		}
 		// Do not pass through the --on-error-continue flag because it does not apply to gpbackup
 		utils.StartGpbackupHelpers(globalCluster, globalFPInfo, "--backup-agent",
-			MustGetFlagString(options.PLUGIN_CONFIG), compressStr, false)
+			MustGetFlagString(options.PLUGIN_CONFIG), compressStr, false, encryptionKey != nil)
 	}
 	gplog.Info("Writing data to file")
-	rowsCopiedMaps := BackupDataForAllTables(tables)
-	AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+	tableSizes := GetTableSizes(connectionPool, tables)
+	rowsCopiedMaps := BackupDataForAllTables(tables, tableSizes)
+	AddTableDataEntriesToTOC(tables, rowsCopiedMaps, tableSizes, tableSegmentCounts)
 	if MustGetFlagBool(options.SINGLE_DATA_FILE) && MustGetFlagString(options.PLUGIN_CONFIG) != "" {
 		pluginConfig.BackupSegmentTOCs(globalCluster, globalFPInfo)
 	}
@@ -363,15 +510,39 @@ func backupPostdata(metadataFile *utils.FileWithByteCount) {
 	}
 	gplog.Info("Writing post-data metadata")
 
-	BackupIndexes(metadataFile)
-	BackupRules(metadataFile)
-	BackupTriggers(metadataFile)
+	if shouldBackupObjectType("INDEX") {
+		BackupIndexes(metadataFile)
+	}
+	if shouldBackupObjectType("RULE") {
+		BackupRules(metadataFile)
+	}
+	if shouldBackupObjectType("TRIGGER") {
+		BackupTriggers(metadataFile)
+	}
 	if connectionPool.Version.AtLeast("6") {
-		BackupDefaultPrivileges(metadataFile)
-		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 {
+		if shouldBackupObjectType("DEFAULT PRIVILEGES") {
+			BackupDefaultPrivileges(metadataFile)
+		}
+		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 && shouldBackupObjectType("EVENT TRIGGER") {
 			BackupEventTriggers(metadataFile)
 		}
 	}
+	if connectionPool.Version.AtLeast("7") {
+		if shouldBackupObjectType("POLICY") {
+			BackupPolicies(metadataFile)
+		}
+		if shouldBackupObjectType("EXTENDED STATISTICS") {
+			BackupExtendedStatistics(metadataFile)
+		}
+		if len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 && len(MustGetFlagStringArray(options.INCLUDE_RELATION)) == 0 {
+			if shouldBackupObjectType("PUBLICATION") {
+				BackupPublications(metadataFile)
+			}
+			if shouldBackupObjectType("SUBSCRIPTION") {
+				BackupSubscriptions(metadataFile)
+			}
+		}
+	}
 	if wasTerminated {
 		gplog.Info("Post-data metadata backup incomplete")
 	} else {
@@ -379,13 +550,26 @@ func backupPostdata(metadataFile *utils.FileWithByteCount) {
 	}
 }
 
+func backupLargeObjectData() {
+	if wasTerminated {
+		return
+	}
+	gplog.Info("Writing large object data to file")
+	BackupLargeObjectData()
+	if wasTerminated {
+		gplog.Info("Large object data backup incomplete")
+	} else {
+		gplog.Info("Large object data backup complete")
+	}
+}
+
 func backupStatistics(tables []Table) {
 	if wasTerminated {
 		return
 	}
 	statisticsFilename := globalFPInfo.GetStatisticsFilePath()
 	gplog.Info("Writing query planner statistics to %s", statisticsFilename)
-	statisticsFile := utils.NewFileWithByteCountFromFile(statisticsFilename)
+	statisticsFile := utils.NewEncryptedFileWithByteCountFromFile(statisticsFilename, encryptionKey)
 	defer statisticsFile.Close()
 	BackupStatistics(statisticsFile, tables)
 	if wasTerminated {
@@ -495,8 +679,11 @@ func DoCleanup(backupFailed bool) {
 				utils.TerminateHangingCopySessions(connectionPool, globalFPInfo, "gpbackup")
 			}
 			utils.CleanUpHelperFilesOnAllHosts(globalCluster, globalFPInfo)
+		} else if encryptionKey != nil {
+			utils.CleanUpHelperFilesOnAllHosts(globalCluster, globalFPInfo)
 		}
 	}
+	decryptedFiles.Cleanup()
 	err := backupLockFile.Unlock()
 	if err != nil && backupLockFile != "" {
 		gplog.Warn("Failed to remove lock file %s.", backupLockFile)
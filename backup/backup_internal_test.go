@@ -1,6 +1,9 @@
 package backup
 
 import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 
 	. "github.com/onsi/ginkgo"
@@ -22,4 +25,41 @@ var _ = Describe("backup internal tests", func() {
 			Expect(string(log.Contents())).To(ContainSubstring("Data backup complete"))
 		})
 	})
+	Describe("SynchronizeSnapshots", func() {
+		var mock sqlmock.Sqlmock
+		BeforeEach(func() {
+			connectionPool, mock = testhelper.CreateAndConnectMockDB(2)
+			backupSnapshotID = ""
+		})
+		It("imports the snapshot of the first connection on every other connection", func() {
+			testhelper.SetDBVersion(connectionPool, "6.21.0")
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.pg_export_snapshot()")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("00000005-00000002-1"))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.txid_current_snapshot()")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("700:700:"))
+			mock.ExpectExec(regexp.QuoteMeta("SET TRANSACTION SNAPSHOT '00000005-00000002-1'")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.txid_current_snapshot()")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("700:700:"))
+
+			SynchronizeSnapshots()
+
+			Expect(backupSnapshotID).To(Equal("00000005-00000002-1"))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("panics if a connection does not use the exported snapshot", func() {
+			testhelper.SetDBVersion(connectionPool, "7.0.0")
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.pg_export_snapshot()")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("00000005-00000002-1"))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.txid_current_snapshot()")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("700:700:"))
+			mock.ExpectExec(regexp.QuoteMeta("SET TRANSACTION SNAPSHOT '00000005-00000002-1'")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.txid_current_snapshot()")).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("702:702:"))
+
+			defer testhelper.ShouldPanicWithMessage("Connection 1 is using snapshot 702:702: instead of exported snapshot 00000005-00000002-1 (700:700:)")
+			SynchronizeSnapshots()
+		})
+		It("does not export a snapshot on versions that do not support it", func() {
+			testhelper.SetDBVersion(connectionPool, "6.20.0")
+
+			SynchronizeSnapshots()
+
+			Expect(backupSnapshotID).To(Equal(""))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
 })
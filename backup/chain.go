@@ -0,0 +1,444 @@
+package backup
+
+/*
+ * This file contains functions that check the chain of an incremental backup,
+ * which is the set of backups in its restore plan that gprestore reads data
+ * from, and the gpbackup chain-health command, which checks the chain of
+ * every backup in the backup history.
+ */
+
+import (
+	"fmt"
+	"os"
+	path "path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var validBrokenChainActions = []string{"fail", "full"}
+
+/*
+ * A backup in a chain, and the problems found with its files. The config and
+ * TOC are nil if they could not be read. MissingDataFiles maps the oid of a
+ * table to the segments on which its data file is missing, and is only used
+ * for backups with one data file per table.
+ */
+type ChainBackup struct {
+	Timestamp        string
+	FPInfo           filepath.FilePathInfo
+	UsesPlugin       bool
+	Config           *history.BackupConfig
+	TOC              *toc.TOC
+	Problems         []string
+	MissingDataFiles map[uint32][]int
+}
+
+// A file on a segment that is needed to restore data from a backup in a chain
+type SegmentFile struct {
+	Timestamp  string
+	Oid        uint32
+	Path       string
+	UsesPlugin bool
+}
+
+/*
+ * Backups are checked once even if they are in the chains of several backups.
+ * The history is nil if there is no backup history file.
+ */
+type ChainChecker struct {
+	SegPrefix    string
+	History      *history.History
+	HelperFPInfo filepath.FilePathInfo
+	Backups      map[string]*ChainBackup
+}
+
+func NewChainChecker(segPrefix string, backupHistory *history.History, helperFPInfo filepath.FilePathInfo) *ChainChecker {
+	return &ChainChecker{
+		SegPrefix:    segPrefix,
+		History:      backupHistory,
+		HelperFPInfo: helperFPInfo,
+		Backups:      make(map[string]*ChainBackup),
+	}
+}
+
+/*
+ * Reads the config file and TOC of each backup, downloading them with the
+ * plugin first if the backups were taken with one.
+ */
+func (checker *ChainChecker) AddBackups(backupDir string, usesPlugin bool, timestamps ...string) {
+	for _, timestamp := range timestamps {
+		if _, ok := checker.Backups[timestamp]; ok {
+			continue
+		}
+		chainBackup := &ChainBackup{
+			Timestamp:        timestamp,
+			FPInfo:           filepath.NewFilePathInfo(globalCluster, backupDir, timestamp, checker.SegPrefix),
+			UsesPlugin:       usesPlugin,
+			MissingDataFiles: make(map[uint32][]int),
+		}
+		checker.Backups[timestamp] = chainBackup
+
+		configFilename := chainBackup.FPInfo.GetConfigFilePath()
+		if !isBackupFileAvailable(configFilename, usesPlugin) {
+			chainBackup.Problems = append(chainBackup.Problems, fmt.Sprintf("Config file %s of backup %s is missing", configFilename, timestamp))
+			continue
+		}
+		chainBackup.Config = history.ReadConfigFile(configFilename)
+		if chainBackup.Config.EndTime == "" {
+			chainBackup.Problems = append(chainBackup.Problems, fmt.Sprintf("Backup %s did not complete successfully", timestamp))
+		}
+		tocFilename := chainBackup.FPInfo.GetTOCFilePath()
+		if !isBackupFileAvailable(tocFilename, usesPlugin) {
+			chainBackup.Problems = append(chainBackup.Problems, fmt.Sprintf("TOC file %s of backup %s is missing", tocFilename, timestamp))
+			continue
+		}
+		if chainBackup.Config.EncryptionKeyID != "" {
+			if decryptedFiles == nil || decryptedFiles.Key.ID != chainBackup.Config.EncryptionKeyID {
+				chainBackup.Problems = append(chainBackup.Problems, fmt.Sprintf("Backup %s is encrypted with key %s, which was not given", timestamp, chainBackup.Config.EncryptionKeyID))
+				continue
+			}
+			var err error
+			tocFilename, err = decryptedFiles.GetPath(tocFilename)
+			if err != nil {
+				chainBackup.Problems = append(chainBackup.Problems, fmt.Sprintf("TOC file of backup %s cannot be decrypted: %v", timestamp, err))
+				continue
+			}
+		}
+		chainBackup.TOC = toc.NewTOC(tocFilename)
+	}
+}
+
+func isBackupFileAvailable(filename string, usesPlugin bool) bool {
+	if usesPlugin {
+		err := pluginConfig.RestoreFile(filename)
+		if err != nil {
+			gplog.Verbose(err.Error())
+			return false
+		}
+	}
+	return iohelper.FileExistsAndIsReadable(filename)
+}
+
+/*
+ * The plugin API has no way to check whether a data file exists without
+ * reading all of it, so for backups taken with a plugin only the segment TOC
+ * files of single data file backups are checked.
+ */
+func GetSegmentFilesForBackup(contentID int, chainBackup *ChainBackup) []SegmentFile {
+	files := make([]SegmentFile, 0)
+	if chainBackup.Config == nil || chainBackup.TOC == nil || len(chainBackup.TOC.DataEntries) == 0 {
+		return files
+	}
+	extension := ""
+	if chainBackup.Config.Compressed {
+		extension = ".gz"
+	}
+	newSegmentFile := func(oid uint32, filename string) SegmentFile {
+		return SegmentFile{Timestamp: chainBackup.Timestamp, Oid: oid, Path: filename, UsesPlugin: chainBackup.UsesPlugin}
+	}
+	if chainBackup.Config.SingleDataFile {
+		if !chainBackup.UsesPlugin {
+			files = append(files, newSegmentFile(0, chainBackup.FPInfo.GetTableBackupFilePath(contentID, 0, extension, true)))
+		}
+		files = append(files, newSegmentFile(0, chainBackup.FPInfo.GetSegmentTOCFilePath(contentID)))
+	} else if !chainBackup.UsesPlugin {
+		for _, entry := range chainBackup.TOC.DataEntries {
+			files = append(files, newSegmentFile(entry.Oid, chainBackup.FPInfo.GetTableBackupFilePath(contentID, entry.Oid, extension, false)))
+		}
+	}
+	return files
+}
+
+// Each line of the script prints the path of a file if it is missing
+func GetSegmentFileCheckScript(files []SegmentFile, plugin *utils.PluginConfig) string {
+	var script strings.Builder
+	sourcedGreenplumPath := false
+	for _, file := range files {
+		if !file.UsesPlugin {
+			script.WriteString(fmt.Sprintf("[ -f %[1]s ] || echo %[1]s\n", file.Path))
+			continue
+		}
+		if !sourcedGreenplumPath {
+			script.WriteString(fmt.Sprintf("source %s/greenplum_path.sh\n", operating.System.Getenv("GPHOME")))
+			sourcedGreenplumPath = true
+		}
+		script.WriteString(fmt.Sprintf("(mkdir -p %[1]s && %[2]s restore_file %[3]s %[4]s) >/dev/null 2>&1 || echo %[4]s\n", path.Dir(file.Path), plugin.ExecutablePath, plugin.ConfigPath, file.Path))
+	}
+	return script.String()
+}
+
+/*
+ * The segment files of all of the backups that were added are checked with
+ * one script per segment, as checking each backup separately would connect
+ * to every segment host once per backup.
+ */
+func (checker *ChainChecker) CheckSegmentFiles() {
+	filesForContent := make(map[int][]SegmentFile)
+	numFiles := 0
+	timestamps := make([]string, 0, len(checker.Backups))
+	for timestamp := range checker.Backups {
+		timestamps = append(timestamps, timestamp)
+	}
+	sort.Strings(timestamps)
+	for _, contentID := range globalCluster.ContentIDs {
+		if contentID == -1 {
+			continue
+		}
+		for _, timestamp := range timestamps {
+			filesForContent[contentID] = append(filesForContent[contentID], GetSegmentFilesForBackup(contentID, checker.Backups[timestamp])...)
+		}
+		numFiles += len(filesForContent[contentID])
+	}
+	if numFiles == 0 {
+		return
+	}
+
+	writeSegmentHelperFiles(checker.HelperFPInfo, "script", func(contentID int) string {
+		return GetSegmentFileCheckScript(filesForContent[contentID], pluginConfig)
+	})
+	remoteOutput := globalCluster.GenerateAndExecuteCommand("Checking segment files of backups", func(contentID int) string {
+		return fmt.Sprintf("bash %s", checker.HelperFPInfo.GetSegmentHelperFilePath(contentID, "script"))
+	}, cluster.ON_SEGMENTS)
+	globalCluster.CheckClusterError(remoteOutput, "Unable to check segment files of backups", func(contentID int) string {
+		return fmt.Sprintf("Unable to check segment files for segment %d on host %s", contentID, globalCluster.GetHostForContent(contentID))
+	})
+	utils.CleanUpHelperFilesOnAllHosts(globalCluster, checker.HelperFPInfo)
+
+	for contentID, stdout := range remoteOutput.Stdouts {
+		checker.RecordMissingSegmentFiles(contentID, stdout, filesForContent[contentID])
+	}
+}
+
+func (checker *ChainChecker) RecordMissingSegmentFiles(contentID int, missingPaths string, files []SegmentFile) {
+	fileForPath := make(map[string]SegmentFile, len(files))
+	for _, file := range files {
+		fileForPath[file.Path] = file
+	}
+	for _, missingPath := range strings.Split(strings.TrimSpace(missingPaths), "\n") {
+		file, ok := fileForPath[missingPath]
+		if !ok {
+			continue
+		}
+		chainBackup := checker.Backups[file.Timestamp]
+		if file.Oid == 0 {
+			chainBackup.Problems = append(chainBackup.Problems, fmt.Sprintf("File %s of backup %s is missing on segment %d", file.Path, file.Timestamp, contentID))
+		} else {
+			chainBackup.MissingDataFiles[file.Oid] = append(chainBackup.MissingDataFiles[file.Oid], contentID)
+		}
+	}
+}
+
+/*
+ * Returns the problems that would prevent gprestore from restoring a backup
+ * with the given restore plan. Every backup in the restore plan must have
+ * been added to the checker.
+ */
+func (checker *ChainChecker) GetChainProblems(restorePlan []history.RestorePlanEntry) []string {
+	problems := make([]string, 0)
+	for _, entry := range restorePlan {
+		if checker.History != nil {
+			historyConfig := getBackupConfigFromHistory(checker.History, entry.Timestamp)
+			if historyConfig == nil {
+				problems = append(problems, fmt.Sprintf("Backup %s is not in the backup history", entry.Timestamp))
+			} else if historyConfig.DateDeleted != "" {
+				problems = append(problems, fmt.Sprintf("Backup %s is marked as deleted in the backup history", entry.Timestamp))
+			}
+		}
+		chainBackup := checker.Backups[entry.Timestamp]
+		problems = append(problems, chainBackup.Problems...)
+		if chainBackup.TOC == nil || len(chainBackup.MissingDataFiles) == 0 {
+			continue
+		}
+		for _, dataEntry := range chainBackup.TOC.GetDataEntriesMatching([]string{}, []string{}, []string{}, []string{}, entry.TableFQNs) {
+			contentIDs, ok := chainBackup.MissingDataFiles[dataEntry.Oid]
+			if !ok {
+				continue
+			}
+			sort.Ints(contentIDs)
+			segments := make([]string, len(contentIDs))
+			for i, contentID := range contentIDs {
+				segments[i] = fmt.Sprintf("%d", contentID)
+			}
+			problems = append(problems, fmt.Sprintf("Data file of table %s in backup %s is missing on segment(s) %s",
+				utils.MakeFQN(dataEntry.Schema, dataEntry.Name), entry.Timestamp, strings.Join(segments, ", ")))
+		}
+	}
+	return problems
+}
+
+func getBackupConfigFromHistory(backupHistory *history.History, timestamp string) *history.BackupConfig {
+	for i := range backupHistory.BackupConfigs {
+		if backupHistory.BackupConfigs[i].Timestamp == timestamp {
+			return &backupHistory.BackupConfigs[i]
+		}
+	}
+	return nil
+}
+
+func readBackupHistoryIfExists(historyFilename string) *history.History {
+	if !iohelper.FileExistsAndIsReadable(historyFilename) {
+		return nil
+	}
+	backupHistory, err := history.NewHistory(historyFilename)
+	gplog.FatalOnError(err)
+	return backupHistory
+}
+
+/*
+ * Checks the chain of the backup that the current incremental backup would be
+ * based on, which is that backup's restore plan, before any data is backed up.
+ */
+func CheckIncrementalChain(targetTimestamp string) []string {
+	gplog.Info("Checking the backups that incremental backup %s depends on", targetTimestamp)
+	usesPlugin := MustGetFlagString(options.PLUGIN_CONFIG) != ""
+	checker := NewChainChecker(globalFPInfo.UserSpecifiedSegPrefix, readBackupHistoryIfExists(globalFPInfo.GetBackupHistoryFilePath()), globalFPInfo)
+	checker.AddBackups(globalFPInfo.UserSpecifiedBackupDir, usesPlugin, targetTimestamp)
+	targetBackup := checker.Backups[targetTimestamp]
+	if targetBackup.Config == nil {
+		return targetBackup.Problems
+	}
+	for _, entry := range targetBackup.Config.RestorePlan {
+		checker.AddBackups(globalFPInfo.UserSpecifiedBackupDir, usesPlugin, entry.Timestamp)
+	}
+	checker.CheckSegmentFiles()
+	return checker.GetChainProblems(targetBackup.Config.RestorePlan)
+}
+
+/*
+ * Returns the timestamp to base the incremental backup on, which is empty if
+ * a full backup is taken instead.
+ */
+func HandleBrokenChain(targetTimestamp string, problems []string) string {
+	if MustGetFlagString(options.ON_BROKEN_CHAIN) == "full" {
+		for _, problem := range problems {
+			gplog.Warn(problem)
+		}
+		gplog.Warn("Backup %s cannot be restored, so a full backup will be taken instead of an incremental backup", targetTimestamp)
+		backupReport.Incremental = false
+		return ""
+	}
+	gplog.Fatal(errors.Errorf("Backup %s cannot be restored, so an incremental backup cannot be based on it:\n%s\n"+
+		"Use --on-broken-chain=full to take a full backup instead.", targetTimestamp, strings.Join(problems, "\n")), "")
+	return targetTimestamp
+}
+
+func ValidateBrokenChainAction(action string) {
+	for _, validAction := range validBrokenChainActions {
+		if action == validAction {
+			return
+		}
+	}
+	gplog.Fatal(errors.Errorf(`Invalid --on-broken-chain value "%s". Valid values are: %s.`, action, strings.Join(validBrokenChainActions, ", ")), "")
+}
+
+/*
+ * Functions for the chain-health command
+ */
+
+func SetChainHealthFlagDefaults(flagSet *pflag.FlagSet) {
+	flagSet.Bool(options.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.Bool("help", false, "Help for chain-health")
+	flagSet.String(options.PLUGIN_CONFIG, "", "The configuration file to use to check backups taken with a plugin")
+	flagSet.Bool(options.QUIET, false, "Suppress non-warning, non-error log messages")
+	flagSet.Bool(options.VERBOSE, false, "Print verbose log messages")
+}
+
+func InitializeChainHealthFlags(cmd *cobra.Command) {
+	SetChainHealthFlagDefaults(cmd.Flags())
+}
+
+func DoChainHealthValidation(cmd *cobra.Command) {
+	cmdFlags = cmd.Flags()
+	options.CheckExclusiveFlags(cmdFlags, options.DEBUG, options.QUIET, options.VERBOSE)
+	err := utils.ValidateFullPath(MustGetFlagString(options.PLUGIN_CONFIG))
+	gplog.FatalOnError(err)
+}
+
+/*
+ * Reports whether each backup in the backup history that has not been deleted
+ * can be restored. The exit code is 1 if any of them cannot be restored.
+ */
+func DoChainHealth() {
+	SetLoggerVerbosity()
+	gplog.Verbose("Chain Health Command: %s", os.Args)
+
+	conn := dbconn.NewDBConnFromEnvironment("postgres")
+	conn.MustConnect(1)
+	segPrefix := filepath.GetSegPrefix(conn)
+	globalCluster = cluster.NewCluster(cluster.MustGetSegmentConfiguration(conn))
+	conn.Close()
+
+	helperFPInfo := filepath.NewFilePathInfo(globalCluster, "", history.CurrentTimestamp(), segPrefix)
+	backupHistory := readBackupHistoryIfExists(helperFPInfo.GetBackupHistoryFilePath())
+	if backupHistory == nil {
+		gplog.Info("No backup history found at %s", helperFPInfo.GetBackupHistoryFilePath())
+		return
+	}
+	if pluginConfigFlag := MustGetFlagString(options.PLUGIN_CONFIG); pluginConfigFlag != "" {
+		var err error
+		pluginConfig, err = utils.ReadPluginConfig(pluginConfigFlag)
+		gplog.FatalOnError(err)
+		pluginConfig.CopyPluginConfigToAllHosts(globalCluster)
+	}
+
+	checker := NewChainChecker(segPrefix, backupHistory, helperFPInfo)
+	backupsToCheck := make([]history.BackupConfig, 0)
+	for _, config := range backupHistory.BackupConfigs {
+		if config.DateDeleted != "" {
+			continue
+		}
+		usesPlugin := config.Plugin != ""
+		if usesPlugin && (pluginConfig == nil || config.Plugin != pluginConfig.ExecutablePath) {
+			gplog.Warn("Backup %s was taken with plugin %s and is not checked. Use --plugin-config with a config file for that plugin to check it.", config.Timestamp, config.Plugin)
+			continue
+		}
+		if config.EncryptionKeyID != "" {
+			gplog.Warn("Backup %s is encrypted with key %s and is not checked.", config.Timestamp, config.EncryptionKeyID)
+			continue
+		}
+		for _, entry := range config.RestorePlan {
+			checker.AddBackups(config.BackupDir, usesPlugin, entry.Timestamp)
+		}
+		backupsToCheck = append(backupsToCheck, config)
+	}
+	checker.CheckSegmentFiles()
+
+	numBroken := 0
+	for _, config := range backupsToCheck {
+		backupType := "full"
+		if config.Incremental {
+			backupType = "incremental"
+		}
+		problems := checker.GetChainProblems(config.RestorePlan)
+		if len(problems) == 0 {
+			gplog.Info("Backup %s (%s, database %s): OK", config.Timestamp, backupType, config.DatabaseName)
+			continue
+		}
+		numBroken++
+		gplog.Warn("Backup %s (%s, database %s): BROKEN", config.Timestamp, backupType, config.DatabaseName)
+		for _, problem := range problems {
+			gplog.Warn("    %s", problem)
+		}
+	}
+	if numBroken > 0 {
+		gplog.Error("%d of %d backups cannot be restored", numBroken, len(backupsToCheck))
+	}
+}
+
+func DoChainHealthTeardown() {
+	utils.TeardownAndExit(recover(), DoCleanup, CleanupGroup, &wasTerminated, "Chain health check completed successfully")
+}
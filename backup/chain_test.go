@@ -0,0 +1,155 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/report"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/chain tests", func() {
+	segDirMap := map[int]string{-1: "/data/gpseg-1", 0: "/data/gpseg0"}
+	fullFPInfo := filepath.FilePathInfo{SegDirMap: segDirMap, Timestamp: "20170101010101"}
+	incrementalFPInfo := filepath.FilePathInfo{SegDirMap: segDirMap, Timestamp: "20170102010101"}
+	fullTOC := &toc.TOC{DataEntries: []toc.MasterDataEntry{{Schema: "public", Name: "foo", Oid: 1}, {Schema: "public", Name: "bar", Oid: 2}}}
+	incrementalTOC := &toc.TOC{DataEntries: []toc.MasterDataEntry{{Schema: "public", Name: "bar", Oid: 2}}}
+	restorePlan := []history.RestorePlanEntry{
+		{Timestamp: "20170101010101", TableFQNs: []string{"public.foo"}},
+		{Timestamp: "20170102010101", TableFQNs: []string{"public.bar"}},
+	}
+	var fullBackup, incrementalBackup *backup.ChainBackup
+	var checker *backup.ChainChecker
+	BeforeEach(func() {
+		fullBackup = &backup.ChainBackup{
+			Timestamp:        "20170101010101",
+			FPInfo:           fullFPInfo,
+			Config:           &history.BackupConfig{Timestamp: "20170101010101", Compressed: true},
+			TOC:              fullTOC,
+			MissingDataFiles: map[uint32][]int{},
+		}
+		incrementalBackup = &backup.ChainBackup{
+			Timestamp:        "20170102010101",
+			FPInfo:           incrementalFPInfo,
+			Config:           &history.BackupConfig{Timestamp: "20170102010101", Compressed: true, Incremental: true},
+			TOC:              incrementalTOC,
+			MissingDataFiles: map[uint32][]int{},
+		}
+		checker = backup.NewChainChecker("gpseg", &history.History{BackupConfigs: []history.BackupConfig{{Timestamp: "20170102010101"}, {Timestamp: "20170101010101"}}}, incrementalFPInfo)
+		checker.Backups["20170101010101"] = fullBackup
+		checker.Backups["20170102010101"] = incrementalBackup
+	})
+	Describe("GetSegmentFilesForBackup", func() {
+		It("returns the data file of each table in a backup with one data file per table", func() {
+			Expect(backup.GetSegmentFilesForBackup(0, fullBackup)).To(Equal([]backup.SegmentFile{
+				{Timestamp: "20170101010101", Oid: 1, Path: "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz"},
+				{Timestamp: "20170101010101", Oid: 2, Path: "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_2.gz"},
+			}))
+		})
+		It("returns the data file and segment TOC of a single data file backup", func() {
+			fullBackup.Config.SingleDataFile = true
+			fullBackup.Config.Compressed = false
+
+			Expect(backup.GetSegmentFilesForBackup(0, fullBackup)).To(Equal([]backup.SegmentFile{
+				{Timestamp: "20170101010101", Path: "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101"},
+				{Timestamp: "20170101010101", Path: "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_toc.yaml"},
+			}))
+		})
+		It("returns only the segment TOC of a single data file backup taken with a plugin", func() {
+			fullBackup.Config.SingleDataFile = true
+			fullBackup.UsesPlugin = true
+
+			Expect(backup.GetSegmentFilesForBackup(0, fullBackup)).To(Equal([]backup.SegmentFile{
+				{Timestamp: "20170101010101", Path: "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_toc.yaml", UsesPlugin: true},
+			}))
+		})
+		It("returns no files for a backup with no data or whose TOC could not be read", func() {
+			fullBackup.UsesPlugin = true
+			incrementalBackup.TOC = nil
+
+			Expect(backup.GetSegmentFilesForBackup(0, fullBackup)).To(BeEmpty())
+			Expect(backup.GetSegmentFilesForBackup(0, incrementalBackup)).To(BeEmpty())
+			Expect(backup.GetSegmentFilesForBackup(0, &backup.ChainBackup{Config: &history.BackupConfig{}, TOC: &toc.TOC{}})).To(BeEmpty())
+		})
+	})
+	Describe("GetSegmentFileCheckScript", func() {
+		AfterEach(func() {
+			operating.InitializeSystemFunctions()
+		})
+		It("checks that local files exist and restores plugin files", func() {
+			operating.System.Getenv = func(key string) string {
+				return "/usr/local/greenplum-db"
+			}
+			plugin := &utils.PluginConfig{ExecutablePath: "/tmp/plugin.sh", ConfigPath: "/tmp/plugin_config.yaml"}
+			files := []backup.SegmentFile{
+				{Path: "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz"},
+				{Path: "/data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_toc.yaml", UsesPlugin: true},
+			}
+
+			Expect(backup.GetSegmentFileCheckScript(files, plugin)).To(Equal(`[ -f /data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz ] || echo /data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz
+source /usr/local/greenplum-db/greenplum_path.sh
+(mkdir -p /data/gpseg0/backups/20170102/20170102010101 && /tmp/plugin.sh restore_file /tmp/plugin_config.yaml /data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_toc.yaml) >/dev/null 2>&1 || echo /data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_toc.yaml
+`))
+		})
+	})
+	Describe("GetChainProblems", func() {
+		It("returns no problems for an intact chain", func() {
+			Expect(checker.GetChainProblems(restorePlan)).To(BeEmpty())
+		})
+		It("returns the missing data files of tables in the restore plan", func() {
+			files := backup.GetSegmentFilesForBackup(0, fullBackup)
+			checker.RecordMissingSegmentFiles(0, "/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz\n/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_2.gz\n", files)
+
+			Expect(checker.GetChainProblems(restorePlan)).To(Equal([]string{"Data file of table public.foo in backup 20170101010101 is missing on segment(s) 0"}))
+		})
+		It("returns the missing files of single data file backups", func() {
+			incrementalBackup.Config.SingleDataFile = true
+			files := backup.GetSegmentFilesForBackup(0, incrementalBackup)
+			checker.RecordMissingSegmentFiles(0, "/data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_toc.yaml\n", files)
+
+			Expect(checker.GetChainProblems(restorePlan)).To(Equal([]string{"File /data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_toc.yaml of backup 20170102010101 is missing on segment 0"}))
+		})
+		It("returns the problems with the master files of backups in the restore plan", func() {
+			fullBackup.Config = nil
+			fullBackup.TOC = nil
+			fullBackup.Problems = []string{"Config file /data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_config.yaml of backup 20170101010101 is missing"}
+
+			Expect(checker.GetChainProblems(restorePlan)).To(Equal(fullBackup.Problems))
+		})
+		It("returns backups in the restore plan that are deleted or not in the backup history", func() {
+			checker.History = &history.History{BackupConfigs: []history.BackupConfig{{Timestamp: "20170102010101", DateDeleted: "20170103010101"}}}
+
+			Expect(checker.GetChainProblems(restorePlan)).To(Equal([]string{
+				"Backup 20170101010101 is not in the backup history",
+				"Backup 20170102010101 is marked as deleted in the backup history",
+			}))
+		})
+	})
+	Describe("HandleBrokenChain", func() {
+		It("panics by default", func() {
+			defer testhelper.ShouldPanicWithMessage("Backup 20170102010101 cannot be restored, so an incremental backup cannot be based on it")
+			backup.HandleBrokenChain("20170102010101", []string{"Backup 20170101010101 is not in the backup history"})
+		})
+		It("takes a full backup instead when --on-broken-chain=full is set", func() {
+			_ = cmdFlags.Set(options.ON_BROKEN_CHAIN, "full")
+			backupReport := &report.Report{BackupConfig: history.BackupConfig{Incremental: true}}
+			backup.SetReport(backupReport)
+
+			Expect(backup.HandleBrokenChain("20170102010101", []string{"Backup 20170101010101 is not in the backup history"})).To(Equal(""))
+			Expect(backupReport.Incremental).To(BeFalse())
+		})
+	})
+	Describe("ValidateBrokenChainAction", func() {
+		It("panics on an invalid value", func() {
+			defer testhelper.ShouldPanicWithMessage(`Invalid --on-broken-chain value "ignore". Valid values are: fail, full.`)
+			backup.ValidateBrokenChainAction("ignore")
+		})
+	})
+})
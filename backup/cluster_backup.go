@@ -0,0 +1,104 @@
+package backup
+
+/*
+ * This file contains functions related to backing up several databases in one
+ * gpbackup invocation. Each database is backed up by a separate gpbackup
+ * process with the same flags, and every backup records the timestamp of the
+ * cluster backup in the backup history, so that gprestore can find the backups
+ * of all of the databases with --cluster-timestamp.
+ */
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+func IsClusterBackup() bool {
+	return MustGetFlagBool(options.ALL_DATABASES) || len(MustGetFlagStringArray(options.DBNAME)) > 1
+}
+
+func GetAllDatabaseNames(connectionPool *dbconn.DBConn) []string {
+	query := `
+	SELECT datname
+	FROM pg_database
+	WHERE datallowconn AND NOT datistemplate
+	ORDER BY datname`
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
+
+/*
+ * The arguments of the gpbackup process that backs up one database are those
+ * of this process with that database in place of the databases to back up.
+ * The flags are given on the command line so that they take precedence over
+ * any environment variables and configuration file, which the process also
+ * reads.
+ */
+func GetDatabaseBackupArgs(flags *pflag.FlagSet, args []string, dbName string, clusterTimestamp string, backupClusterGlobals bool) []string {
+	dbArgs := options.RemoveFlagsFromArgs(flags, options.HandleSingleDashes(args), options.ALL_DATABASES, options.DBNAME, options.CLUSTER_TIMESTAMP, options.NO_CLUSTER_GLOBALS)
+	dbArgs = append(dbArgs,
+		fmt.Sprintf("--%s", options.DBNAME), dbName,
+		fmt.Sprintf("--%s=false", options.ALL_DATABASES),
+		fmt.Sprintf("--%s", options.CLUSTER_TIMESTAMP), clusterTimestamp)
+	if !backupClusterGlobals {
+		dbArgs = append(dbArgs, fmt.Sprintf("--%s", options.NO_CLUSTER_GLOBALS))
+	}
+	return dbArgs
+}
+
+func DoClusterBackup() {
+	SetLoggerVerbosity()
+	gplog.Verbose("Cluster Backup Command: %s", os.Args)
+
+	dbNames := MustGetFlagStringArray(options.DBNAME)
+	if MustGetFlagBool(options.ALL_DATABASES) {
+		conn := dbconn.NewDBConnFromEnvironment("postgres")
+		conn.MustConnect(1)
+		dbNames = GetAllDatabaseNames(conn)
+		conn.Close()
+	}
+	executable, err := os.Executable()
+	gplog.FatalOnError(err)
+
+	clusterTimestamp := history.CurrentTimestamp()
+	gplog.Info("Cluster Backup Timestamp = %s", clusterTimestamp)
+	gplog.Info("Backing up %d databases: %s", len(dbNames), strings.Join(dbNames, ", "))
+
+	// The global objects are backed up with the first database whose backup succeeds
+	clusterGlobalsBackedUp := false
+	failedDBNames := make([]string, 0)
+	for i, dbName := range dbNames {
+		if wasTerminated {
+			return
+		}
+		gplog.Info("Backing up database %s (%d of %d)", dbName, i+1, len(dbNames))
+		cmd := exec.Command(executable, GetDatabaseBackupArgs(cmdFlags, os.Args[1:], dbName, clusterTimestamp, !clusterGlobalsBackedUp)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			gplog.Error("Backup of database %s completed with errors", dbName)
+		} else if err != nil {
+			gplog.Error("Backup of database %s failed: %v", dbName, err)
+			failedDBNames = append(failedDBNames, dbName)
+			continue
+		}
+		clusterGlobalsBackedUp = true
+	}
+	if len(failedDBNames) > 0 {
+		gplog.Fatal(errors.Errorf("Backups of %d of %d databases failed: %s", len(failedDBNames), len(dbNames), strings.Join(failedDBNames, ", ")), "")
+	}
+}
+
+func DoClusterBackupTeardown() {
+	utils.TeardownAndExit(recover(), DoCleanup, CleanupGroup, &wasTerminated, "Cluster backup completed successfully")
+}
@@ -0,0 +1,52 @@
+package backup_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/options"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/cluster_backup tests", func() {
+	Describe("IsClusterBackup", func() {
+		It("returns false when one database is backed up", func() {
+			_ = cmdFlags.Set(options.DBNAME, "testdb")
+			Expect(backup.IsClusterBackup()).To(BeFalse())
+		})
+		It("returns true when more than one database is backed up", func() {
+			_ = cmdFlags.Set(options.DBNAME, "testdb")
+			_ = cmdFlags.Set(options.DBNAME, "otherdb")
+			Expect(backup.IsClusterBackup()).To(BeTrue())
+		})
+		It("returns true when all databases are backed up", func() {
+			_ = cmdFlags.Set(options.ALL_DATABASES, "true")
+			Expect(backup.IsClusterBackup()).To(BeTrue())
+		})
+	})
+	Describe("GetAllDatabaseNames", func() {
+		It("returns the databases that allow connections and are not templates", func() {
+			mock.ExpectQuery("WHERE datallowconn AND NOT datistemplate").WillReturnRows(sqlmock.NewRows([]string{"datname"}).AddRow("postgres").AddRow("testdb"))
+
+			Expect(backup.GetAllDatabaseNames(connectionPool)).To(Equal([]string{"postgres", "testdb"}))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+	Describe("GetDatabaseBackupArgs", func() {
+		It("replaces the databases to back up with one database and the cluster timestamp", func() {
+			args := []string{"--dbname", "testdb", "--dbname=otherdb", "-jobs", "4", "--backup-dir", "/tmp/backups"}
+
+			dbArgs := backup.GetDatabaseBackupArgs(cmdFlags, args, "otherdb", "20170101010101", true)
+
+			Expect(dbArgs).To(Equal([]string{"--jobs", "4", "--backup-dir", "/tmp/backups", "--dbname", "otherdb", "--all-databases=false", "--cluster-timestamp", "20170101010101"}))
+		})
+		It("leaves out the global objects shared by all databases once they have been backed up", func() {
+			args := []string{"--all-databases", "--with-stats"}
+
+			dbArgs := backup.GetDatabaseBackupArgs(cmdFlags, args, "testdb", "20170101010101", false)
+
+			Expect(dbArgs).To(Equal([]string{"--with-stats", "--dbname", "testdb", "--all-databases=false", "--cluster-timestamp", "20170101010101", "--no-cluster-globals"}))
+		})
+	})
+})
@@ -0,0 +1,301 @@
+package backup
+
+/*
+ * This file contains functions related to gpbackup consolidate, which combines
+ * an incremental backup and the backups in its restore plan into a new full
+ * backup, so that the earlier backups are no longer needed to restore it.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	path "path/filepath"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/report"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The data entry of a table, and the timestamp of the backup its data file is in
+type ConsolidatedDataEntry struct {
+	Timestamp string
+	toc.MasterDataEntry
+}
+
+func SetConsolidateFlagDefaults(flagSet *pflag.FlagSet) {
+	flagSet.String(options.BACKUP_DIR, "", "The absolute path of the directory to which the incremental backup set was written")
+	flagSet.Bool(options.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.Bool("help", false, "Help for consolidate")
+	flagSet.Bool(options.QUIET, false, "Suppress non-warning, non-error log messages")
+	flagSet.String(options.TIMESTAMP, "", "The timestamp of the incremental backup to consolidate, in the format YYYYMMDDHHMMSS")
+	flagSet.Bool(options.VERBOSE, false, "Print verbose log messages")
+}
+
+func InitializeConsolidateFlags(cmd *cobra.Command) {
+	SetConsolidateFlagDefaults(cmd.Flags())
+
+	_ = cmd.MarkFlagRequired(options.TIMESTAMP)
+}
+
+func DoConsolidateValidation(cmd *cobra.Command) {
+	cmdFlags = cmd.Flags()
+	err := utils.ValidateFullPath(MustGetFlagString(options.BACKUP_DIR))
+	gplog.FatalOnError(err)
+	if !filepath.IsValidTimestamp(MustGetFlagString(options.TIMESTAMP)) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", MustGetFlagString(options.TIMESTAMP)), "")
+	}
+}
+
+/*
+ * Consolidation only reads and writes backup files; the database is queried
+ * for the segment configuration, to find the backup directories on each host,
+ * and is otherwise not used.
+ */
+func DoConsolidate() {
+	SetLoggerVerbosity()
+	gplog.Verbose("Consolidate Command: %s", os.Args)
+
+	incrementalTimestamp := MustGetFlagString(options.TIMESTAMP)
+	backupDir := MustGetFlagString(options.BACKUP_DIR)
+	timestamp := history.CurrentTimestamp()
+	CreateBackupLockFile(timestamp)
+	initializeClusterForConsolidate()
+
+	segPrefix := filepath.ParseSegPrefix(backupDir, incrementalTimestamp)
+	incrementalFPInfo := filepath.NewFilePathInfo(globalCluster, backupDir, incrementalTimestamp, segPrefix)
+	incrementalConfig := history.ReadConfigFile(incrementalFPInfo.GetConfigFilePath())
+	ValidateConsolidateConfig(incrementalConfig)
+	utils.InitializePipeThroughParameters(incrementalConfig.Compressed, 0)
+	gplog.Info("Consolidating incremental backup %s into full backup %s", incrementalTimestamp, timestamp)
+
+	sourceFPInfos := make(map[string]filepath.FilePathInfo)
+	sourceTOCs := make(map[string]*toc.TOC)
+	for _, entry := range incrementalConfig.RestorePlan {
+		fpInfo := filepath.NewFilePathInfo(globalCluster, backupDir, entry.Timestamp, filepath.ParseSegPrefix(backupDir, entry.Timestamp))
+		sourceFPInfos[entry.Timestamp] = fpInfo
+		sourceTOCs[entry.Timestamp] = toc.NewTOC(fpInfo.GetTOCFilePath())
+	}
+	dataEntries := GetConsolidatedDataEntries(incrementalConfig.RestorePlan, sourceTOCs)
+
+	consolidatedFPInfo := filepath.NewFilePathInfo(globalCluster, backupDir, timestamp, segPrefix)
+	remoteOutput := globalCluster.GenerateAndExecuteCommand("Creating backup directories", func(contentID int) string {
+		return fmt.Sprintf("mkdir -p %s", consolidatedFPInfo.GetDirForContent(contentID))
+	}, cluster.ON_SEGMENTS_AND_MASTER)
+	globalCluster.CheckClusterError(remoteOutput, "Unable to create backup directories", func(contentID int) string {
+		return fmt.Sprintf("Unable to create backup directory %s", consolidatedFPInfo.GetDirForContent(contentID))
+	})
+
+	consolidateSegmentDataFiles(incrementalConfig, dataEntries, sourceFPInfos, consolidatedFPInfo)
+	consolidateMasterFiles(incrementalFPInfo, sourceTOCs[incrementalTimestamp], incrementalConfig, dataEntries, consolidatedFPInfo)
+	gplog.Info("Backup %s no longer depends on the backups in the restore plan of %s", timestamp, incrementalTimestamp)
+}
+
+func initializeClusterForConsolidate() {
+	conn := dbconn.NewDBConnFromEnvironment("postgres")
+	conn.MustConnect(1)
+	defer conn.Close()
+	segConfig := cluster.MustGetSegmentConfiguration(conn)
+	globalCluster = cluster.NewCluster(segConfig)
+}
+
+func ValidateConsolidateConfig(config *history.BackupConfig) {
+	if !config.Incremental {
+		gplog.Fatal(errors.Errorf("Backup %s is not an incremental backup and cannot be consolidated", config.Timestamp), "")
+	}
+	if config.Plugin != "" {
+		gplog.Fatal(errors.Errorf("Backup %s was taken with a plugin and cannot be consolidated. Only backups on local storage can be consolidated.", config.Timestamp), "")
+	}
+	if config.EncryptionKeyID != "" {
+		gplog.Fatal(errors.Errorf("Backup %s is encrypted and cannot be consolidated", config.Timestamp), "")
+	}
+}
+
+/*
+ * Each entry in the restore plan lists the tables whose data is in the backup
+ * with that timestamp. The data entries of those tables are taken from that
+ * backup's TOC, as gprestore would when restoring the incremental backup.
+ */
+func GetConsolidatedDataEntries(restorePlan []history.RestorePlanEntry, tocs map[string]*toc.TOC) []ConsolidatedDataEntry {
+	dataEntries := make([]ConsolidatedDataEntry, 0)
+	timestampForOid := make(map[uint32]string)
+	for _, restorePlanEntry := range restorePlan {
+		matchingEntries := tocs[restorePlanEntry.Timestamp].GetDataEntriesMatching([]string{}, []string{}, []string{}, []string{}, restorePlanEntry.TableFQNs)
+		for _, entry := range matchingEntries {
+			if otherTimestamp, ok := timestampForOid[entry.Oid]; ok {
+				gplog.Fatal(errors.Errorf("Table %s in backup %s has the same oid as a table in backup %s, so their data cannot be consolidated",
+					utils.MakeFQN(entry.Schema, entry.Name), restorePlanEntry.Timestamp, otherTimestamp), "")
+			}
+			timestampForOid[entry.Oid] = restorePlanEntry.Timestamp
+			dataEntries = append(dataEntries, ConsolidatedDataEntry{Timestamp: restorePlanEntry.Timestamp, MasterDataEntry: entry})
+		}
+	}
+	return dataEntries
+}
+
+/*
+ * The metadata of the consolidated backup is that of the incremental backup,
+ * including its incremental metadata, so that later incremental backups can
+ * be based on the consolidated backup.
+ */
+func NewConsolidatedTOC(incrementalTOC *toc.TOC, dataEntries []ConsolidatedDataEntry) *toc.TOC {
+	consolidatedTOC := *incrementalTOC
+	consolidatedTOC.DataEntries = make([]toc.MasterDataEntry, len(dataEntries))
+	for i, entry := range dataEntries {
+		consolidatedTOC.DataEntries[i] = entry.MasterDataEntry
+	}
+	return &consolidatedTOC
+}
+
+func NewConsolidatedConfig(incrementalConfig *history.BackupConfig, timestamp string) *history.BackupConfig {
+	consolidatedConfig := *incrementalConfig
+	tableFQNs := make([]string, 0)
+	for _, restorePlanEntry := range incrementalConfig.RestorePlan {
+		tableFQNs = append(tableFQNs, restorePlanEntry.TableFQNs...)
+	}
+	consolidatedConfig.ConsolidatedFrom = incrementalConfig.Timestamp
+	consolidatedConfig.DateDeleted = ""
+	consolidatedConfig.EndTime = ""
+	consolidatedConfig.Incremental = false
+	consolidatedConfig.RestorePlan = []history.RestorePlanEntry{{Timestamp: timestamp, TableFQNs: tableFQNs}}
+	consolidatedConfig.Timestamp = timestamp
+	return &consolidatedConfig
+}
+
+/*
+ * With one data file per table, each data file is hard-linked into the new
+ * backup directory, or copied if it is on a different filesystem. With a
+ * single data file per segment, gpbackup_helper copies the data of each
+ * table from the data files of the earlier backups into a new data file.
+ */
+func consolidateSegmentDataFiles(config *history.BackupConfig, dataEntries []ConsolidatedDataEntry, sourceFPInfos map[string]filepath.FilePathInfo, consolidatedFPInfo filepath.FilePathInfo) {
+	extension := utils.GetPipeThroughProgram().Extension
+	if config.SingleDataFile {
+		utils.VerifyHelperVersionOnSegments(version, globalCluster)
+		writeSegmentHelperFiles(consolidatedFPInfo, "oid", func(contentID int) string {
+			return GetConsolidateOidFileContents(contentID, dataEntries, sourceFPInfos, extension)
+		})
+		compressionLevel := 0
+		if config.Compressed {
+			compressionLevel = 1
+		}
+		gphomePath := operating.System.Getenv("GPHOME")
+		writeSegmentHelperFiles(consolidatedFPInfo, "script", func(contentID int) string {
+			return fmt.Sprintf(`source %[1]s/greenplum_path.sh
+%[1]s/bin/gpbackup_helper --consolidate-agent --content %[2]d --oid-file %[3]s --data-file %[4]s --toc-file %[5]s --compression-level %[6]d
+`, gphomePath, contentID, consolidatedFPInfo.GetSegmentHelperFilePath(contentID, "oid"),
+				consolidatedFPInfo.GetTableBackupFilePath(contentID, 0, extension, true), consolidatedFPInfo.GetSegmentTOCFilePath(contentID), compressionLevel)
+		})
+	} else {
+		writeSegmentHelperFiles(consolidatedFPInfo, "script", func(contentID int) string {
+			return GetLinkDataFilesScript(contentID, dataEntries, sourceFPInfos, consolidatedFPInfo, extension)
+		})
+	}
+
+	remoteOutput := globalCluster.GenerateAndExecuteCommand("Consolidating segment data files", func(contentID int) string {
+		return fmt.Sprintf("bash %s", consolidatedFPInfo.GetSegmentHelperFilePath(contentID, "script"))
+	}, cluster.ON_SEGMENTS)
+	globalCluster.CheckClusterError(remoteOutput, "Unable to consolidate segment data files", func(contentID int) string {
+		return fmt.Sprintf("Unable to consolidate data files for segment %d on host %s", contentID, globalCluster.GetHostForContent(contentID))
+	})
+	utils.CleanUpHelperFilesOnAllHosts(globalCluster, consolidatedFPInfo)
+}
+
+func GetLinkDataFilesScript(contentID int, dataEntries []ConsolidatedDataEntry, sourceFPInfos map[string]filepath.FilePathInfo, consolidatedFPInfo filepath.FilePathInfo, extension string) string {
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	for _, entry := range dataEntries {
+		sourceFPInfo := sourceFPInfos[entry.Timestamp]
+		sourceFile := sourceFPInfo.GetTableBackupFilePath(contentID, entry.Oid, extension, false)
+		destFile := consolidatedFPInfo.GetTableBackupFilePath(contentID, entry.Oid, extension, false)
+		script.WriteString(fmt.Sprintf("ln -f %[1]s %[2]s 2>/dev/null || cp -p %[1]s %[2]s\n", sourceFile, destFile))
+	}
+	return script.String()
+}
+
+// The format of this file is described in gpbackup_helper's consolidate agent
+func GetConsolidateOidFileContents(contentID int, dataEntries []ConsolidatedDataEntry, sourceFPInfos map[string]filepath.FilePathInfo, extension string) string {
+	var contents strings.Builder
+	for _, entry := range dataEntries {
+		sourceFPInfo := sourceFPInfos[entry.Timestamp]
+		contents.WriteString(fmt.Sprintf("%d\t%s\t%s\n", entry.Oid, sourceFPInfo.GetTableBackupFilePath(contentID, 0, extension, true), sourceFPInfo.GetSegmentTOCFilePath(contentID)))
+	}
+	return contents.String()
+}
+
+func writeSegmentHelperFiles(fpInfo filepath.FilePathInfo, suffix string, generateContents func(contentID int) string) {
+	localDir, err := ioutil.TempDir("", "gpbackup-consolidate")
+	gplog.FatalOnError(err, "Cannot create temporary directory to write segment files")
+	defer func() {
+		err = os.RemoveAll(localDir)
+		if err != nil {
+			gplog.Warn("Cannot remove temporary directory: %s, Err: %s", localDir, err.Error())
+		}
+	}()
+	localFileForContent := func(contentID int) string {
+		return path.Join(localDir, fmt.Sprintf("%s_%d", suffix, contentID))
+	}
+	for _, contentID := range globalCluster.ContentIDs {
+		if contentID == -1 {
+			continue
+		}
+		err = ioutil.WriteFile(localFileForContent(contentID), []byte(generateContents(contentID)), 0644)
+		gplog.FatalOnError(err, localFileForContent(contentID))
+	}
+
+	remoteOutput := globalCluster.GenerateAndExecuteCommand(fmt.Sprintf("Scp %s files to segments", suffix), func(contentID int) string {
+		return fmt.Sprintf("scp %s %s:%s", localFileForContent(contentID), globalCluster.GetHostForContent(contentID), fpInfo.GetSegmentHelperFilePath(contentID, suffix))
+	}, cluster.ON_MASTER_TO_SEGMENTS)
+	globalCluster.CheckClusterError(remoteOutput, fmt.Sprintf("Failed to scp %s files", suffix), func(contentID int) string {
+		return "Failed to run scp"
+	})
+}
+
+/*
+ * The files of the incremental backup on the master that do not change when
+ * it is consolidated are copied as they are. Files that a backup does not
+ * always write, such as the list of skipped tables, are copied if present.
+ */
+func CopyConsolidatedMasterFiles(incrementalFPInfo filepath.FilePathInfo, consolidatedFPInfo filepath.FilePathInfo) {
+	for _, filetype := range []string{"metadata", "statistics", "structured metadata", "large_objects", "skipped_tables"} {
+		sourceFile := incrementalFPInfo.GetBackupFilePath(filetype)
+		if !iohelper.FileExistsAndIsReadable(sourceFile) {
+			continue
+		}
+		err := utils.CopyFile(sourceFile, consolidatedFPInfo.GetBackupFilePath(filetype))
+		gplog.FatalOnError(err)
+	}
+}
+
+func consolidateMasterFiles(incrementalFPInfo filepath.FilePathInfo, incrementalTOC *toc.TOC, incrementalConfig *history.BackupConfig, dataEntries []ConsolidatedDataEntry, consolidatedFPInfo filepath.FilePathInfo) {
+	gplog.Verbose("Writing metadata files for backup %s", consolidatedFPInfo.Timestamp)
+	CopyConsolidatedMasterFiles(incrementalFPInfo, consolidatedFPInfo)
+	NewConsolidatedTOC(incrementalTOC, dataEntries).WriteToFileAndMakeReadOnly(consolidatedFPInfo.GetTOCFilePath())
+
+	consolidatedConfig := NewConsolidatedConfig(incrementalConfig, consolidatedFPInfo.Timestamp)
+	err := history.WriteBackupHistory(consolidatedFPInfo.GetBackupHistoryFilePath(), consolidatedConfig)
+	gplog.FatalOnError(err)
+	history.WriteConfigFile(consolidatedConfig, consolidatedFPInfo.GetConfigFilePath())
+
+	consolidatedReport := &report.Report{BackupConfig: *consolidatedConfig}
+	consolidatedReport.ConstructBackupParamsString()
+	endtime, _ := time.ParseInLocation("20060102150405", consolidatedConfig.EndTime, operating.System.Local)
+	consolidatedReport.WriteBackupReportFile(consolidatedFPInfo.GetBackupReportFilePath(), consolidatedFPInfo.Timestamp, endtime, map[string]int{}, "")
+}
+
+func DoConsolidateTeardown() {
+	utils.TeardownAndExit(recover(), DoCleanup, CleanupGroup, &wasTerminated, "Consolidation completed successfully")
+}
@@ -0,0 +1,138 @@
+package backup_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/consolidate tests", func() {
+	fooEntry := toc.MasterDataEntry{Schema: "public", Name: "foo", Oid: 1, AttributeString: "(i)"}
+	barEntry := toc.MasterDataEntry{Schema: "public", Name: "bar", Oid: 2, AttributeString: "(j)"}
+	droppedEntry := toc.MasterDataEntry{Schema: "public", Name: "dropped", Oid: 3, AttributeString: "(k)"}
+	fullTOC := &toc.TOC{DataEntries: []toc.MasterDataEntry{fooEntry, barEntry, droppedEntry}}
+	incrementalTOC := &toc.TOC{
+		DataEntries:         []toc.MasterDataEntry{{Schema: "public", Name: "foo", Oid: 1, AttributeString: "(i)"}, {Schema: "public", Name: "bar", Oid: 4, AttributeString: "(j)"}},
+		IncrementalMetadata: toc.IncrementalEntries{AO: map[string]toc.AOEntry{"public.bar": {Modcount: 2}}},
+	}
+	tocs := map[string]*toc.TOC{"20170101010101": fullTOC, "20170102010101": incrementalTOC}
+	restorePlan := []history.RestorePlanEntry{
+		{Timestamp: "20170101010101", TableFQNs: []string{"public.foo"}},
+		{Timestamp: "20170102010101", TableFQNs: []string{"public.bar"}},
+	}
+	consolidatedEntries := []backup.ConsolidatedDataEntry{
+		{Timestamp: "20170101010101", MasterDataEntry: fooEntry},
+		{Timestamp: "20170102010101", MasterDataEntry: toc.MasterDataEntry{Schema: "public", Name: "bar", Oid: 4, AttributeString: "(j)"}},
+	}
+	segDirMap := map[int]string{-1: "/data/gpseg-1", 0: "/data/gpseg0"}
+	sourceFPInfos := map[string]filepath.FilePathInfo{
+		"20170101010101": {SegDirMap: segDirMap, Timestamp: "20170101010101"},
+		"20170102010101": {SegDirMap: segDirMap, Timestamp: "20170102010101"},
+	}
+	consolidatedFPInfo := filepath.FilePathInfo{SegDirMap: segDirMap, Timestamp: "20170103010101"}
+
+	Describe("ValidateConsolidateConfig", func() {
+		It("panics if the backup is not incremental", func() {
+			defer testhelper.ShouldPanicWithMessage("Backup 20170101010101 is not an incremental backup and cannot be consolidated")
+			backup.ValidateConsolidateConfig(&history.BackupConfig{Timestamp: "20170101010101"})
+		})
+		It("panics if the backup was taken with a plugin", func() {
+			defer testhelper.ShouldPanicWithMessage("Backup 20170102010101 was taken with a plugin and cannot be consolidated")
+			backup.ValidateConsolidateConfig(&history.BackupConfig{Timestamp: "20170102010101", Incremental: true, Plugin: "/tmp/plugin.sh"})
+		})
+		It("panics if the backup is encrypted", func() {
+			defer testhelper.ShouldPanicWithMessage("Backup 20170102010101 is encrypted and cannot be consolidated")
+			backup.ValidateConsolidateConfig(&history.BackupConfig{Timestamp: "20170102010101", Incremental: true, EncryptionKeyID: "0123456789abcdef"})
+		})
+	})
+	Describe("GetConsolidatedDataEntries", func() {
+		It("takes the data entry of each table from the backup listed in the restore plan", func() {
+			Expect(backup.GetConsolidatedDataEntries(restorePlan, tocs)).To(Equal(consolidatedEntries))
+		})
+		It("panics if tables in different backups have the same oid", func() {
+			otherTOC := &toc.TOC{DataEntries: []toc.MasterDataEntry{{Schema: "public", Name: "baz", Oid: 1}}}
+			otherPlan := append(restorePlan, history.RestorePlanEntry{Timestamp: "20170103010101", TableFQNs: []string{"public.baz"}})
+
+			defer testhelper.ShouldPanicWithMessage("Table public.baz in backup 20170103010101 has the same oid as a table in backup 20170101010101")
+			backup.GetConsolidatedDataEntries(otherPlan, map[string]*toc.TOC{"20170101010101": fullTOC, "20170102010101": incrementalTOC, "20170103010101": otherTOC})
+		})
+	})
+	Describe("NewConsolidatedTOC", func() {
+		It("replaces the data entries of the incremental backup and keeps its incremental metadata", func() {
+			consolidatedTOC := backup.NewConsolidatedTOC(incrementalTOC, consolidatedEntries)
+
+			Expect(consolidatedTOC.DataEntries).To(Equal([]toc.MasterDataEntry{fooEntry, {Schema: "public", Name: "bar", Oid: 4, AttributeString: "(j)"}}))
+			Expect(consolidatedTOC.IncrementalMetadata).To(Equal(incrementalTOC.IncrementalMetadata))
+			Expect(incrementalTOC.DataEntries[0]).To(Equal(fooEntry))
+		})
+	})
+	Describe("NewConsolidatedConfig", func() {
+		It("marks the backup as a full backup whose data is all in the new timestamp", func() {
+			incrementalConfig := &history.BackupConfig{DatabaseName: "testdb", Incremental: true, SingleDataFile: true, RestorePlan: restorePlan, Timestamp: "20170102010101", EndTime: "20170102010102"}
+
+			consolidatedConfig := backup.NewConsolidatedConfig(incrementalConfig, "20170103010101")
+
+			Expect(*consolidatedConfig).To(Equal(history.BackupConfig{
+				ConsolidatedFrom: "20170102010101",
+				DatabaseName:     "testdb",
+				SingleDataFile:   true,
+				RestorePlan:      []history.RestorePlanEntry{{Timestamp: "20170103010101", TableFQNs: []string{"public.foo", "public.bar"}}},
+				Timestamp:        "20170103010101",
+			}))
+			Expect(incrementalConfig.RestorePlan).To(Equal(restorePlan))
+		})
+	})
+	Describe("GetLinkDataFilesScript", func() {
+		It("links the data file of each table into the new backup directory", func() {
+			script := backup.GetLinkDataFilesScript(0, consolidatedEntries, sourceFPInfos, consolidatedFPInfo, ".gz")
+
+			Expect(script).To(Equal(`set -e
+ln -f /data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz /data/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101_1.gz 2>/dev/null || cp -p /data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz /data/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101_1.gz
+ln -f /data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_4.gz /data/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101_4.gz 2>/dev/null || cp -p /data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_4.gz /data/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101_4.gz
+`))
+		})
+	})
+	Describe("GetConsolidateOidFileContents", func() {
+		It("lists the single data file and segment TOC that the data of each table is in", func() {
+			contents := backup.GetConsolidateOidFileContents(0, consolidatedEntries, sourceFPInfos, "")
+
+			Expect(contents).To(Equal(`1	/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101	/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_toc.yaml
+4	/data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101	/data/gpseg0/backups/20170102/20170102010101/gpbackup_0_20170102010101_toc.yaml
+`))
+		})
+	})
+	Describe("CopyConsolidatedMasterFiles", func() {
+		var masterDir string
+		BeforeEach(func() {
+			var err error
+			masterDir, err = ioutil.TempDir("", "gpbackup-consolidate-test")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(masterDir)
+		})
+		It("copies the metadata files and the list of skipped tables, and ignores files that are not present", func() {
+			incrementalFPInfo := filepath.FilePathInfo{SegDirMap: map[int]string{-1: masterDir}, Timestamp: "20170102010101"}
+			masterFPInfo := filepath.FilePathInfo{SegDirMap: map[int]string{-1: masterDir}, Timestamp: "20170103010101"}
+			Expect(os.MkdirAll(incrementalFPInfo.GetDirForContent(-1), 0755)).To(Succeed())
+			Expect(os.MkdirAll(masterFPInfo.GetDirForContent(-1), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(incrementalFPInfo.GetMetadataFilePath(), []byte("CREATE TABLE public.foo(i int);\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(incrementalFPInfo.GetSkippedTablesFilePath(), []byte("public.bar\n"), 0644)).To(Succeed())
+
+			backup.CopyConsolidatedMasterFiles(incrementalFPInfo, masterFPInfo)
+
+			Expect(ioutil.ReadFile(masterFPInfo.GetMetadataFilePath())).To(Equal([]byte("CREATE TABLE public.foo(i int);\n")))
+			Expect(ioutil.ReadFile(masterFPInfo.GetSkippedTablesFilePath())).To(Equal([]byte("public.bar\n")))
+			_, err := os.Stat(masterFPInfo.GetStatisticsFilePath())
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
@@ -6,6 +6,7 @@ package backup
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -32,7 +33,7 @@ func ConstructTableAttributesList(columnDefs []ColumnDefinition) string {
 	return ""
 }
 
-func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64) {
+func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64, tableSizes map[uint32]int64, tableSegmentCounts map[uint32]int) {
 	for _, table := range tables {
 		if !table.SkipDataBackup() {
 			var rowsCopied int64
@@ -43,7 +44,7 @@ func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64)
 				}
 			}
 			attributes := ConstructTableAttributesList(table.ColumnDefs)
-			globalTOC.AddMasterDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName)
+			globalTOC.AddMasterDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName, tableSizes[table.Oid], tableSegmentCounts[table.Oid])
 		}
 	}
 }
@@ -113,7 +114,21 @@ func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters
 	return nil
 }
 
-func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
+/*
+ * Sorting tables largest first means the connections finish at roughly the
+ * same time, instead of a large table started last running on its own after
+ * every other table has been backed up.
+ */
+func SortTablesBySize(tables []Table, tableSizes map[uint32]int64) []Table {
+	sortedTables := make([]Table, len(tables))
+	copy(sortedTables, tables)
+	sort.SliceStable(sortedTables, func(i, j int) bool {
+		return tableSizes[sortedTables[i].Oid] > tableSizes[sortedTables[j].Oid]
+	})
+	return sortedTables
+}
+
+func BackupDataForAllTables(tables []Table, tableSizes map[uint32]int64) []map[uint32]int64 {
 	var numExtOrForeignTables int64
 	for _, table := range tables {
 		if table.SkipDataBackup() {
@@ -149,7 +164,12 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 			}
 		}(connNum)
 	}
-	for _, table := range tables {
+	// Single data file backups use one connection and write tables in the order of the oid list
+	tablesToBackUp := tables
+	if connectionPool.NumConns > 1 {
+		tablesToBackUp = SortTablesBySize(tables, tableSizes)
+	}
+	for _, table := range tablesToBackUp {
 		tasks <- table
 	}
 	close(tasks)
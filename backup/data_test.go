@@ -53,24 +53,43 @@ var _ = Describe("backup/data tests", func() {
 		})
 		It("adds an entry for a regular table to the TOC", func() {
 			tables := []backup.Table{table}
-			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
-			expectedDataEntries := []toc.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)"}}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, map[uint32]int64{1: 4096}, map[uint32]int{})
+			expectedDataEntries := []toc.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", SegmentSize: 4096}}
+			Expect(tocfile.DataEntries).To(Equal(expectedDataEntries))
+		})
+		It("records the segment count of a table that has not been redistributed onto all segments", func() {
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, map[uint32]int64{}, map[uint32]int{1: 3})
+			expectedDataEntries := []toc.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", SegmentCount: 3}}
 			Expect(tocfile.DataEntries).To(Equal(expectedDataEntries))
 		})
 		It("does not add an entry for an external table to the TOC", func() {
 			table.IsExternal = true
 			tables := []backup.Table{table}
-			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, map[uint32]int64{}, map[uint32]int{})
 			Expect(tocfile.DataEntries).To(BeNil())
 		})
 		It("does not add an entry for a foreign table to the TOC", func() {
 			foreignDef := backup.ForeignTableDefinition{Oid: 23, Options: "", Server: "fs"}
 			table.ForeignDef = foreignDef
 			tables := []backup.Table{table}
-			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, map[uint32]int64{}, map[uint32]int{})
 			Expect(tocfile.DataEntries).To(BeNil())
 		})
 	})
+	Describe("SortTablesBySize", func() {
+		small := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "small"}}
+		large := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "large"}}
+		unknown := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "unknown"}}
+		It("sorts tables largest first without modifying the original list", func() {
+			tables := []backup.Table{small, unknown, large}
+
+			sortedTables := backup.SortTablesBySize(tables, map[uint32]int64{1: 10, 2: 1000})
+
+			Expect(sortedTables).To(Equal([]backup.Table{large, small, unknown}))
+			Expect(tables).To(Equal([]backup.Table{small, unknown, large}))
+		})
+	})
 	Describe("CopyTableOut", func() {
 		testTable := backup.Table{Relation: backup.Relation{SchemaOid: 2345, Oid: 3456, Schema: "public", Name: "foo"}}
 		It("will back up a table to its own file with compression", func() {
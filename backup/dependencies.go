@@ -2,10 +2,13 @@ package backup
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
@@ -42,6 +45,7 @@ func AddProtocolDependenciesForGPDB4(depMap DependencyMap, tables []Table, proto
 
 var (
 	PG_AGGREGATE_OID            uint32 = 1255
+	PG_AM_OID                   uint32 = 2601
 	PG_AUTHID_OID               uint32 = 1260
 	PG_CAST_OID                 uint32 = 2605
 	PG_CLASS_OID                uint32 = 1259
@@ -56,14 +60,19 @@ var (
 	PG_FOREIGN_SERVER_OID       uint32 = 1417
 	PG_INDEX_OID                uint32 = 2610
 	PG_LANGUAGE_OID             uint32 = 2612
+	PG_LARGEOBJECT_OID          uint32 = 2613
 	PG_NAMESPACE_OID            uint32 = 2615
 	PG_OPCLASS_OID              uint32 = 2616
 	PG_OPERATOR_OID             uint32 = 2617
 	PG_OPFAMILY_OID             uint32 = 2753
+	PG_POLICY_OID               uint32 = 3256
 	PG_PROC_OID                 uint32 = 1255
+	PG_PUBLICATION_OID          uint32 = 6104
 	PG_RESGROUP_OID             uint32 = 6436
 	PG_RESQUEUE_OID             uint32 = 6026
 	PG_REWRITE_OID              uint32 = 2618
+	PG_STATISTIC_EXT_OID        uint32 = 3381
+	PG_SUBSCRIPTION_OID         uint32 = 6100
 	PG_TABLESPACE_OID           uint32 = 1213
 	PG_TRIGGER_OID              uint32 = 2620
 	PG_TS_CONFIG_OID            uint32 = 3602
@@ -254,13 +263,166 @@ func breakCircularDependencies(depMap DependencyMap) {
 	}
 }
 
-func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, objects []Sortable, metadataMap MetadataMap, constraints []Constraint, funcInfoMap map[uint32]FunctionInfo) {
+/*
+ * Unlike GetDependencies, this function returns dependencies between all
+ * objects in the database. Column defaults, constraints, and triggers are
+ * attributed to the table or domain they belong to, so that a table depends
+ * on the sequences and functions its defaults and triggers call.
+ */
+func GetAllDependencies(connectionPool *dbconn.DBConn) DependencyMap {
+	query := `SELECT
+	CASE
+		WHEN ad.oid IS NOT NULL OR tg.oid IS NOT NULL OR co.conrelid != 0 THEN 'pg_class'::regclass::oid
+		WHEN co.contypid != 0 THEN 'pg_type'::regclass::oid
+		ELSE coalesce(id1.refclassid, d.classid)
+	END AS classid,
+	CASE
+		WHEN ad.oid IS NOT NULL THEN ad.adrelid
+		WHEN tg.oid IS NOT NULL THEN tg.tgrelid
+		WHEN co.conrelid != 0 THEN co.conrelid
+		WHEN co.contypid != 0 THEN co.contypid
+		ELSE coalesce(id1.refobjid, d.objid)
+	END AS objid,
+	coalesce(id2.refclassid, d.refclassid) AS refclassid,
+	coalesce(id2.refobjid, d.refobjid) AS refobjid
+FROM pg_depend d
+-- link implicit objects, using objid and refobjid, to the objects that created them
+LEFT JOIN pg_depend id1 ON (d.objid = id1.objid and d.classid = id1.classid and id1.deptype='i')
+LEFT JOIN pg_depend id2 ON (d.refobjid = id2.objid and d.refclassid = id2.classid and id2.deptype='i')
+LEFT JOIN pg_attrdef ad ON (coalesce(id1.refclassid, d.classid) = 'pg_attrdef'::regclass::oid AND coalesce(id1.refobjid, d.objid) = ad.oid)
+LEFT JOIN pg_trigger tg ON (coalesce(id1.refclassid, d.classid) = 'pg_trigger'::regclass::oid AND coalesce(id1.refobjid, d.objid) = tg.oid)
+LEFT JOIN pg_constraint co ON (coalesce(id1.refclassid, d.classid) = 'pg_constraint'::regclass::oid AND coalesce(id1.refobjid, d.objid) = co.oid)
+WHERE d.classid != 0
+AND d.deptype != 'i'
+UNION
+-- this converts dependencies on array types to the underlying type, as in GetDependencies
+SELECT
+	d.classid,
+	d.objid,
+	d.refclassid,
+	t.typelem AS refobjid
+FROM pg_depend d
+JOIN pg_type t ON d.refobjid = t.oid
+WHERE d.refclassid = 'pg_type'::regclass::oid
+AND typelem != 0`
+
+	pgDependDeps := make([]struct {
+		ClassID    uint32
+		ObjID      uint32
+		RefClassID uint32
+		RefObjID   uint32
+	}, 0)
+
+	err := connectionPool.Select(&pgDependDeps, query)
+	gplog.FatalOnError(err)
+
+	dependencyMap := make(DependencyMap)
+	for _, dep := range pgDependDeps {
+		object := UniqueID{ClassID: dep.ClassID, Oid: dep.ObjID}
+		referenceObject := UniqueID{ClassID: dep.RefClassID, Oid: dep.RefObjID}
+		if object == referenceObject {
+			continue
+		}
+		if _, ok := dependencyMap[object]; !ok {
+			dependencyMap[object] = make(map[UniqueID]bool)
+		}
+		dependencyMap[object][referenceObject] = true
+	}
+	return dependencyMap
+}
+
+/*
+ * Returns the given objects and every object they depend on, directly or
+ * indirectly.
+ */
+func GetDependencyClosure(dependencies DependencyMap, objects []UniqueID) map[UniqueID]bool {
+	closure := make(map[UniqueID]bool, len(objects))
+	queue := make([]UniqueID, 0, len(objects))
+	for _, object := range objects {
+		if !closure[object] {
+			closure[object] = true
+			queue = append(queue, object)
+		}
+	}
+	for len(queue) > 0 {
+		object := queue[0]
+		queue = queue[1:]
+		for dependency := range dependencies[object] {
+			if !closure[dependency] {
+				closure[dependency] = true
+				queue = append(queue, dependency)
+			}
+		}
+	}
+	return closure
+}
+
+/*
+ * With --include-dependencies, the tables and sequences that the included
+ * tables depend on are added to the included tables, and the other objects
+ * they depend on are recorded so that they are backed up alongside them.
+ */
+func ResolveIncludedDependencies(opts *options.Options) {
+	if !MustGetFlagBool(options.INCLUDE_DEPENDENCIES) {
+		return
+	}
+	gplog.Verbose("Resolving dependencies of included tables")
+	quotedIncludeRelations, err := options.QuoteTableNames(connectionPool, opts.GetIncludedTables())
+	gplog.FatalOnError(err)
+	includeOids := GetOidsFromRelationList(connectionPool, quotedIncludeRelations)
+	objects := make([]UniqueID, 0, len(includeOids))
+	for _, oidStr := range includeOids {
+		oid, err := strconv.ParseUint(oidStr, 10, 32)
+		gplog.FatalOnError(err)
+		objects = append(objects, UniqueID{ClassID: PG_CLASS_OID, Oid: uint32(oid)})
+	}
+	includedDependencies = GetDependencyClosure(GetAllDependencies(connectionPool), objects)
+
+	relationOids := make([]string, 0)
+	for object := range includedDependencies {
+		if object.ClassID == PG_CLASS_OID {
+			relationOids = append(relationOids, fmt.Sprintf("%d", object.Oid))
+		}
+	}
+	for _, relation := range GetTableAndSequenceNamesFromOids(connectionPool, relationOids) {
+		fqn := fmt.Sprintf("%s.%s", relation.SchemaName, relation.TableName)
+		if utils.Exists(opts.GetIncludedTables(), fqn) {
+			continue
+		}
+		gplog.Info("Including %s, which included tables depend on", fqn)
+		err = cmdFlags.Set(options.INCLUDE_RELATION, fqn)
+		gplog.FatalOnError(err)
+		opts.AddIncludedRelation(fqn)
+	}
+}
+
+/*
+ * Records which object a TOC entry belongs to and which objects it depends on
+ * in a form the toc package understands. Dependencies are sorted so that the
+ * TOC contents are stable across backups of the same database.
+ */
+func getTOCDependencyInfo(uniqueID UniqueID, dependencies DependencyMap) (toc.UniqueID, []toc.UniqueID) {
+	tocDependencies := make([]toc.UniqueID, 0, len(dependencies[uniqueID]))
+	for dependency := range dependencies[uniqueID] {
+		tocDependencies = append(tocDependencies, toc.UniqueID(dependency))
+	}
+	sort.Slice(tocDependencies, func(i, j int) bool {
+		if tocDependencies[i].ClassID != tocDependencies[j].ClassID {
+			return tocDependencies[i].ClassID < tocDependencies[j].ClassID
+		}
+		return tocDependencies[i].Oid < tocDependencies[j].Oid
+	})
+	return toc.UniqueID(uniqueID), tocDependencies
+}
+
+func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, objects []Sortable, metadataMap MetadataMap, constraints []Constraint, funcInfoMap map[uint32]FunctionInfo, dependencies DependencyMap) {
 	conMap := make(map[string][]Constraint)
 	for _, constraint := range constraints {
 		conMap[constraint.OwningObject] = append(conMap[constraint.OwningObject], constraint)
 	}
 	for _, object := range objects {
 		objMetadata := metadataMap[object.GetUniqueID()]
+		start := len(toc.PredataEntries)
 		switch obj := object.(type) {
 		case BaseType:
 			PrintCreateBaseTypeStatement(metadataFile, toc, obj, objMetadata)
@@ -302,6 +464,10 @@ func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *
 			PrintCreateUserMappingStatement(metadataFile, toc, obj)
 		case MaterializedView:
 			PrintCreateMaterializedViewStatement(metadataFile, toc, obj, objMetadata)
+		case AccessMethod:
+			PrintCreateAccessMethodStatement(metadataFile, toc, obj, objMetadata)
 		}
+		objectID, objectDependencies := getTOCDependencyInfo(object.GetUniqueID(), dependencies)
+		toc.AddDependencyInfo("predata", start, objectID, objectDependencies)
 	}
 }
@@ -1,9 +1,12 @@
 package backup_test
 
 import (
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/toc"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -94,6 +97,110 @@ var _ = Describe("backup/dependencies tests", func() {
 			sortable = backup.TopologicalSort(sortable, depMap)
 		})
 	})
+	Describe("FilterSortablesByObjectType", func() {
+		view := backup.View{Oid: 1, Schema: "public", Name: "view1"}
+		function := backup.Function{Oid: 2, Schema: "public", Name: "function1"}
+		It("returns all objects when no object types are filtered", func() {
+			Expect(backup.FilterSortablesByObjectType([]backup.Sortable{view, function})).To(Equal([]backup.Sortable{view, function}))
+		})
+		It("removes objects whose types are excluded", func() {
+			_ = cmdFlags.Set(options.EXCLUDE_OBJECT_TYPE, "VIEW")
+
+			Expect(backup.FilterSortablesByObjectType([]backup.Sortable{view, function})).To(Equal([]backup.Sortable{function}))
+		})
+		It("keeps only objects whose types are included", func() {
+			_ = cmdFlags.Set(options.INCLUDE_OBJECT_TYPE, "VIEW")
+
+			Expect(backup.FilterSortablesByObjectType([]backup.Sortable{view, function})).To(Equal([]backup.Sortable{view}))
+		})
+		It("filters foreign tables by their own object type", func() {
+			table := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "table1"}}
+			foreignTable := backup.Table{
+				Relation:        backup.Relation{Oid: 4, Schema: "public", Name: "foreigntable1"},
+				TableDefinition: backup.TableDefinition{ForeignDef: backup.ForeignTableDefinition{Oid: 4, Server: "fs"}},
+			}
+			_ = cmdFlags.Set(options.INCLUDE_OBJECT_TYPE, "FOREIGN TABLE")
+
+			Expect(backup.FilterSortablesByObjectType([]backup.Sortable{table, foreignTable, function})).To(Equal([]backup.Sortable{foreignTable}))
+		})
+	})
+	Describe("GetDependencyClosure", func() {
+		table := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 1}
+		sequence := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 2}
+		function := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 3}
+		baseType := backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 4}
+		schema := backup.UniqueID{ClassID: backup.PG_NAMESPACE_OID, Oid: 5}
+		otherFunction := backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 6}
+		It("returns the given objects when they have no dependencies", func() {
+			Expect(backup.GetDependencyClosure(depMap, []backup.UniqueID{table})).To(Equal(map[backup.UniqueID]bool{table: true}))
+		})
+		It("returns the objects that the given objects depend on, directly or indirectly", func() {
+			depMap[table] = map[backup.UniqueID]bool{sequence: true, baseType: true, schema: true}
+			depMap[baseType] = map[backup.UniqueID]bool{function: true, schema: true}
+			depMap[function] = map[backup.UniqueID]bool{baseType: true, schema: true}
+			depMap[otherFunction] = map[backup.UniqueID]bool{schema: true}
+
+			closure := backup.GetDependencyClosure(depMap, []backup.UniqueID{table})
+
+			Expect(closure).To(Equal(map[backup.UniqueID]bool{table: true, sequence: true, function: true, baseType: true, schema: true}))
+		})
+	})
+	Describe("FilterIncludedDependencies", func() {
+		function1 := backup.Function{Oid: 1, Schema: "public", Name: "function1"}
+		function2 := backup.Function{Oid: 2, Schema: "public", Name: "function2"}
+		view := backup.View{Oid: 3, Schema: "public", Name: "view1"}
+		AfterEach(func() {
+			backup.SetIncludedDependencies(nil)
+		})
+		It("returns all objects when dependencies are not being resolved", func() {
+			Expect(backup.FilterIncludedDependencies([]backup.Function{function1, function2})).To(Equal([]backup.Function{function1, function2}))
+		})
+		It("keeps only objects that are included dependencies", func() {
+			backup.SetIncludedDependencies(map[backup.UniqueID]bool{function2.GetUniqueID(): true})
+
+			Expect(backup.FilterIncludedDependencies([]backup.Function{function1, function2})).To(Equal([]backup.Function{function2}))
+		})
+		It("keeps relations, which are already filtered by the relation filters", func() {
+			backup.SetIncludedDependencies(map[backup.UniqueID]bool{})
+
+			Expect(backup.FilterIncludedDependencies([]backup.Sortable{function1, view})).To(Equal([]backup.Sortable{view}))
+		})
+	})
+	Describe("ResolveIncludedDependencies", func() {
+		AfterEach(func() {
+			backup.SetIncludedDependencies(nil)
+		})
+		It("does nothing when --include-dependencies is not set", func() {
+			_ = cmdFlags.Set(options.INCLUDE_RELATION, "public.foo")
+			opts, err := options.NewOptions(cmdFlags)
+			Expect(err).ToNot(HaveOccurred())
+
+			backup.ResolveIncludedDependencies(opts)
+
+			Expect(opts.GetIncludedTables()).To(Equal([]string{"public.foo"}))
+			Expect(backup.FilterIncludedDependencies([]backup.Function{{Oid: 3}})).To(HaveLen(1))
+		})
+		It("includes the tables and sequences that the included tables depend on and records their other dependencies", func() {
+			_ = cmdFlags.Set(options.INCLUDE_RELATION, "public.foo")
+			_ = cmdFlags.Set(options.INCLUDE_DEPENDENCIES, "true")
+			opts, err := options.NewOptions(cmdFlags)
+			Expect(err).ToNot(HaveOccurred())
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"schemaname", "tablename"}).AddRow("public", "foo"))
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("1"))
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"classid", "objid", "refclassid", "refobjid"}).
+				AddRow(backup.PG_CLASS_OID, 1, backup.PG_CLASS_OID, 2).
+				AddRow(backup.PG_CLASS_OID, 1, backup.PG_PROC_OID, 3).
+				AddRow(backup.PG_PROC_OID, 4, backup.PG_NAMESPACE_OID, 5))
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"schemaname", "tablename"}).
+				AddRow("public", "foo").AddRow("public", "foo_seq"))
+
+			backup.ResolveIncludedDependencies(opts)
+
+			Expect(opts.GetIncludedTables()).To(Equal([]string{"public.foo", "public.foo_seq"}))
+			Expect(backup.MustGetFlagStringArray(options.INCLUDE_RELATION)).To(Equal([]string{"public.foo", "public.foo_seq"}))
+			Expect(backup.FilterIncludedDependencies([]backup.Function{{Oid: 3}, {Oid: 4}})).To(Equal([]backup.Function{{Oid: 3}}))
+		})
+	})
 	Describe("ConstructDependentObjectMetadataMap", func() {
 		It("composes metadata maps for functions, types, and tables into one map", func() {
 			funcMap := backup.MetadataMap{backup.UniqueID{Oid: 1}: backup.ObjectMetadata{Comment: "function"}}
@@ -156,7 +263,7 @@ var _ = Describe("backup/dependencies tests", func() {
 			constraints := []backup.Constraint{
 				{Name: "check_constraint", ConDef: "CHECK (VALUE > 2)", OwningObject: "public.domain"},
 			}
-			backup.PrintDependentObjectStatements(backupfile, tocfile, objects, metadataMap, constraints, funcInfoMap)
+			backup.PrintDependentObjectStatements(backupfile, tocfile, objects, metadataMap, constraints, funcInfoMap, backup.DependencyMap{})
 			testhelper.ExpectRegexp(buffer, `
 CREATE FUNCTION public.function(integer, integer) RETURNS integer AS
 $_$SELECT $1 + $2$_$
@@ -203,7 +310,7 @@ COMMENT ON PROTOCOL ext_protocol IS 'protocol';
 		})
 		It("prints create statements for dependent types, functions, protocols, and tables (no domain constraint)", func() {
 			constraints := make([]backup.Constraint, 0)
-			backup.PrintDependentObjectStatements(backupfile, tocfile, objects, metadataMap, constraints, funcInfoMap)
+			backup.PrintDependentObjectStatements(backupfile, tocfile, objects, metadataMap, constraints, funcInfoMap, backup.DependencyMap{})
 			testhelper.ExpectRegexp(buffer, `
 CREATE FUNCTION public.function(integer, integer) RETURNS integer AS
 $_$SELECT $1 + $2$_$
@@ -247,5 +354,29 @@ CREATE TRUSTED PROTOCOL ext_protocol (readfunc = public.read_from_s3, writefunc
 COMMENT ON PROTOCOL ext_protocol IS 'protocol';
 `)
 		})
+		It("records each object and its sorted dependencies in the TOC entries for that object", func() {
+			tableID := backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 5}
+			dependencies := backup.DependencyMap{
+				tableID: {
+					backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 4}: true,
+					backup.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 1}: true,
+					backup.UniqueID{ClassID: backup.PG_TYPE_OID, Oid: 2}: true,
+				},
+			}
+			backup.PrintDependentObjectStatements(backupfile, tocfile, objects[:5], metadataMap, []backup.Constraint{}, funcInfoMap, dependencies)
+
+			Expect(tocfile.PredataEntries).To(HaveLen(10))
+			Expect(tocfile.PredataEntries[0].ObjectID).To(Equal(toc.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 1}))
+			Expect(tocfile.PredataEntries[1].ObjectID).To(Equal(toc.UniqueID{ClassID: backup.PG_PROC_OID, Oid: 1}))
+			Expect(tocfile.PredataEntries[1].Dependencies).To(BeEmpty())
+			for _, entry := range tocfile.PredataEntries[8:] {
+				Expect(entry.ObjectID).To(Equal(toc.UniqueID(tableID)))
+				Expect(entry.Dependencies).To(Equal([]toc.UniqueID{
+					{ClassID: backup.PG_TYPE_OID, Oid: 2},
+					{ClassID: backup.PG_TYPE_OID, Oid: 4},
+					{ClassID: backup.PG_PROC_OID, Oid: 1},
+				}))
+			}
+		})
 	})
 })
@@ -37,7 +37,12 @@ var (
 	wasTerminated        bool
 	backupLockFile       lockfile.Lockfile
 	filterRelationClause string
+	includedDependencies map[UniqueID]bool
 	quotedRoleNames      map[string]string
+	backupSnapshotID     string
+	encryptionKey        *utils.EncryptionKey
+	decryptedFiles       *utils.DecryptedFiles
+	effectiveConfig      map[string]interface{}
 	/*
 	 * Used for synchronizing DoCleanup.  In DoInit() we increment the group
 	 * and then wait for at least one DoCleanup to finish, either in DoTeardown
@@ -95,6 +100,10 @@ func SetFilterRelationClause(filterClause string) {
 	filterRelationClause = filterClause
 }
 
+func SetIncludedDependencies(dependencies map[UniqueID]bool) {
+	includedDependencies = dependencies
+}
+
 func SetQuotedRoleNames(quotedRoles map[string]string) {
 	quotedRoleNames = quotedRoles
 }
@@ -12,15 +12,16 @@ import (
 
 func FilterTablesForIncremental(lastBackupTOC, currentTOC *toc.TOC, tables []Table) []Table {
 	var filteredTables []Table
+	currentLeafFQNs := getLeafPartitionFQNsByRoot(currentTOC)
+	previousLeafFQNs := getLeafPartitionFQNsByRoot(lastBackupTOC)
 	for _, table := range tables {
-		currentAOEntry, isAOTable := currentTOC.IncrementalMetadata.AO[table.FQN()]
-		if !isAOTable {
-			filteredTables = append(filteredTables, table)
+		if table.PartitionLevelInfo.Level == "p" && currentTOC.IncrementalMetadata.PartitionRoots != nil {
+			if isChangedPartitionTable(lastBackupTOC, currentTOC, table.FQN(), currentLeafFQNs[table.FQN()], previousLeafFQNs[table.FQN()]) {
+				filteredTables = append(filteredTables, table)
+			}
 			continue
 		}
-		previousAOEntry := lastBackupTOC.IncrementalMetadata.AO[table.FQN()]
-
-		if previousAOEntry.Modcount != currentAOEntry.Modcount || previousAOEntry.LastDDLTimestamp != currentAOEntry.LastDDLTimestamp {
+		if isChangedTable(lastBackupTOC, currentTOC, table.FQN()) {
 			filteredTables = append(filteredTables, table)
 		}
 	}
@@ -28,6 +29,63 @@ func FilterTablesForIncremental(lastBackupTOC, currentTOC *toc.TOC, tables []Tab
 	return filteredTables
 }
 
+func isChangedTable(lastBackupTOC, currentTOC *toc.TOC, tableFQN string) bool {
+	currentAOEntry, isAOTable := currentTOC.IncrementalMetadata.AO[tableFQN]
+	if !isAOTable {
+		return !isUnchangedHeapTable(lastBackupTOC, currentTOC, tableFQN)
+	}
+	previousAOEntry := lastBackupTOC.IncrementalMetadata.AO[tableFQN]
+
+	return previousAOEntry.Modcount != currentAOEntry.Modcount || previousAOEntry.LastDDLTimestamp != currentAOEntry.LastDDLTimestamp
+}
+
+/*
+ * A heap table is only skipped if both backups recorded change information
+ * for it, as heap change information is not collected on every version.
+ */
+func isUnchangedHeapTable(lastBackupTOC, currentTOC *toc.TOC, tableFQN string) bool {
+	currentHeapEntry, isHeapTable := currentTOC.IncrementalMetadata.Heap[tableFQN]
+	previousHeapEntry, wasHeapTable := lastBackupTOC.IncrementalMetadata.Heap[tableFQN]
+	return isHeapTable && wasHeapTable && previousHeapEntry == currentHeapEntry
+}
+
+/*
+ * Without --leaf-partition-data, the data of a partition table is backed up
+ * as a whole, so it is backed up again if any of its leaf partitions changed
+ * or if partitions were added or dropped. The root partition table itself
+ * holds no data, so only DDL changes recorded for it are considered.
+ */
+func isChangedPartitionTable(lastBackupTOC, currentTOC *toc.TOC, rootFQN string, currentLeafFQNs map[string]bool, previousLeafFQNs map[string]bool) bool {
+	if len(currentLeafFQNs) != len(previousLeafFQNs) {
+		return true
+	}
+	for leafFQN := range currentLeafFQNs {
+		if !previousLeafFQNs[leafFQN] || isChangedTable(lastBackupTOC, currentTOC, leafFQN) {
+			return true
+		}
+	}
+	currentAOEntry, isAOTable := currentTOC.IncrementalMetadata.AO[rootFQN]
+	if isAOTable {
+		return lastBackupTOC.IncrementalMetadata.AO[rootFQN].LastDDLTimestamp != currentAOEntry.LastDDLTimestamp
+	}
+	currentHeapEntry, isHeapTable := currentTOC.IncrementalMetadata.Heap[rootFQN]
+	if isHeapTable {
+		return lastBackupTOC.IncrementalMetadata.Heap[rootFQN].LastDDLTimestamp != currentHeapEntry.LastDDLTimestamp
+	}
+	return false
+}
+
+func getLeafPartitionFQNsByRoot(tocfile *toc.TOC) map[string]map[string]bool {
+	leafFQNsByRoot := make(map[string]map[string]bool)
+	for leafFQN, rootFQN := range tocfile.IncrementalMetadata.PartitionRoots {
+		if leafFQNsByRoot[rootFQN] == nil {
+			leafFQNsByRoot[rootFQN] = make(map[string]bool)
+		}
+		leafFQNsByRoot[rootFQN][leafFQN] = true
+	}
+	return leafFQNsByRoot
+}
+
 func GetTargetBackupTimestamp() string {
 	targetTimestamp := ""
 	if fromTimestamp := MustGetFlagString(options.FROM_TIMESTAMP); fromTimestamp != "" {
@@ -77,6 +135,7 @@ func MatchesIncrementalFlags(backupConfig *history.BackupConfig, currentBackupCo
 		backupConfig.Plugin == currentBackupConfig.Plugin &&
 		backupConfig.SingleDataFile == MustGetFlagBool(options.SINGLE_DATA_FILE) &&
 		backupConfig.Compressed == currentBackupConfig.Compressed &&
+		backupConfig.EncryptionKeyID == currentBackupConfig.EncryptionKeyID &&
 		// Expanding of the include list happens before this now so we must compare again current backup config
 		utils.NewIncludeSet(backupConfig.IncludeRelations).Equals(utils.NewIncludeSet(currentBackupConfig.IncludeRelations)) &&
 		utils.NewIncludeSet(backupConfig.IncludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringArray(options.INCLUDE_SCHEMA))) &&
@@ -76,6 +76,139 @@ var _ = Describe("backup/incremental tests", func() {
 		It("Should NOT include the unmodified AO table", func() {
 			Expect(filteredTables).To(Not(ContainElement(tblAOUnchanged)))
 		})
+		Context("Heap tables with change information", func() {
+			defaultHeapEntry := toc.HeapEntry{Modcount: 5, LastDDLTimestamp: "00000", StatsResetTimestamp: "2020-01-01 00:00:00+00", Relfilenode: 16384, Size: 32768}
+			prevHeapTOC := toc.TOC{
+				IncrementalMetadata: toc.IncrementalEntries{
+					Heap: map[string]toc.HeapEntry{
+						"public.heap_changed_modcount":    defaultHeapEntry,
+						"public.heap_changed_timestamp":   defaultHeapEntry,
+						"public.heap_stats_reset":         defaultHeapEntry,
+						"public.heap_changed_relfilenode": defaultHeapEntry,
+						"public.heap_changed_size":        defaultHeapEntry,
+						"public.heap_unchanged":           defaultHeapEntry,
+					},
+				},
+			}
+			currHeapTOC := toc.TOC{
+				IncrementalMetadata: toc.IncrementalEntries{
+					Heap: map[string]toc.HeapEntry{
+						"public.heap_changed_modcount":    {Modcount: 6, LastDDLTimestamp: "00000", StatsResetTimestamp: "2020-01-01 00:00:00+00", Relfilenode: 16384, Size: 32768},
+						"public.heap_changed_timestamp":   {Modcount: 5, LastDDLTimestamp: "00001", StatsResetTimestamp: "2020-01-01 00:00:00+00", Relfilenode: 16384, Size: 32768},
+						"public.heap_stats_reset":         {Modcount: 5, LastDDLTimestamp: "00000", StatsResetTimestamp: "2020-01-02 00:00:00+00", Relfilenode: 16384, Size: 32768},
+						"public.heap_changed_relfilenode": {Modcount: 5, LastDDLTimestamp: "00000", StatsResetTimestamp: "2020-01-01 00:00:00+00", Relfilenode: 16390, Size: 32768},
+						"public.heap_changed_size":        {Modcount: 5, LastDDLTimestamp: "00000", StatsResetTimestamp: "2020-01-01 00:00:00+00", Relfilenode: 16384, Size: 65536},
+						"public.heap_unchanged":           defaultHeapEntry,
+						"public.heap_new":                 defaultHeapEntry,
+					},
+				},
+			}
+			tblHeapChangedModcount := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_modcount"}}
+			tblHeapChangedTS := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_timestamp"}}
+			tblHeapStatsReset := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_stats_reset"}}
+			tblHeapChangedRelfilenode := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_relfilenode"}}
+			tblHeapChangedSize := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_size"}}
+			tblHeapUnchanged := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_unchanged"}}
+			tblHeapNew := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_new"}}
+			heapTables := []backup.Table{tblHeapChangedModcount, tblHeapChangedTS, tblHeapStatsReset, tblHeapChangedRelfilenode, tblHeapChangedSize, tblHeapUnchanged, tblHeapNew}
+
+			filteredHeapTables := backup.FilterTablesForIncremental(&prevHeapTOC, &currHeapTOC, heapTables)
+
+			It("Should include the heap tables having a modified modcount, last DDL timestamp, or statistics reset timestamp", func() {
+				Expect(filteredHeapTables).To(ContainElement(tblHeapChangedModcount))
+				Expect(filteredHeapTables).To(ContainElement(tblHeapChangedTS))
+				Expect(filteredHeapTables).To(ContainElement(tblHeapStatsReset))
+			})
+			It("Should include the heap tables having a different relfilenode or size, even with the same modcount", func() {
+				Expect(filteredHeapTables).To(ContainElement(tblHeapChangedRelfilenode))
+				Expect(filteredHeapTables).To(ContainElement(tblHeapChangedSize))
+			})
+			It("Should include the heap table without change information in the previous backup", func() {
+				Expect(filteredHeapTables).To(ContainElement(tblHeapNew))
+			})
+			It("Should NOT include the unmodified heap table", func() {
+				Expect(filteredHeapTables).To(Not(ContainElement(tblHeapUnchanged)))
+			})
+		})
+		Context("Partition tables without leaf partition data", func() {
+			unchangedLeaf := toc.AOEntry{Modcount: 1, LastDDLTimestamp: "00000"}
+			prevPartTOC := toc.TOC{
+				IncrementalMetadata: toc.IncrementalEntries{
+					AO: map[string]toc.AOEntry{
+						"public.part_changed":           unchangedLeaf,
+						"public.part_changed_1_prt_1":   unchangedLeaf,
+						"public.part_changed_1_prt_2":   unchangedLeaf,
+						"public.part_added":             unchangedLeaf,
+						"public.part_added_1_prt_1":     unchangedLeaf,
+						"public.part_root_ddl":          unchangedLeaf,
+						"public.part_root_ddl_1_prt_1":  unchangedLeaf,
+						"public.part_unchanged":         unchangedLeaf,
+						"public.part_unchanged_1_prt_1": unchangedLeaf,
+						"public.part_unchanged_1_prt_2": unchangedLeaf,
+					},
+					PartitionRoots: map[string]string{
+						"public.part_changed_1_prt_1":   "public.part_changed",
+						"public.part_changed_1_prt_2":   "public.part_changed",
+						"public.part_added_1_prt_1":     "public.part_added",
+						"public.part_root_ddl_1_prt_1":  "public.part_root_ddl",
+						"public.part_unchanged_1_prt_1": "public.part_unchanged",
+						"public.part_unchanged_1_prt_2": "public.part_unchanged",
+					},
+				},
+			}
+			currPartTOC := toc.TOC{
+				IncrementalMetadata: toc.IncrementalEntries{
+					AO: map[string]toc.AOEntry{
+						"public.part_changed":           unchangedLeaf,
+						"public.part_changed_1_prt_1":   unchangedLeaf,
+						"public.part_changed_1_prt_2":   {Modcount: 2, LastDDLTimestamp: "00000"},
+						"public.part_added":             unchangedLeaf,
+						"public.part_added_1_prt_1":     unchangedLeaf,
+						"public.part_added_1_prt_2":     unchangedLeaf,
+						"public.part_root_ddl":          {Modcount: 1, LastDDLTimestamp: "00001"},
+						"public.part_root_ddl_1_prt_1":  unchangedLeaf,
+						"public.part_unchanged":         unchangedLeaf,
+						"public.part_unchanged_1_prt_1": unchangedLeaf,
+						"public.part_unchanged_1_prt_2": unchangedLeaf,
+					},
+					PartitionRoots: map[string]string{
+						"public.part_changed_1_prt_1":   "public.part_changed",
+						"public.part_changed_1_prt_2":   "public.part_changed",
+						"public.part_added_1_prt_1":     "public.part_added",
+						"public.part_added_1_prt_2":     "public.part_added",
+						"public.part_root_ddl_1_prt_1":  "public.part_root_ddl",
+						"public.part_unchanged_1_prt_1": "public.part_unchanged",
+						"public.part_unchanged_1_prt_2": "public.part_unchanged",
+					},
+				},
+			}
+			rootPartitionInfo := backup.PartitionLevelInfo{Level: "p"}
+			tblPartChanged := backup.Table{Relation: backup.Relation{Schema: "public", Name: "part_changed"}, TableDefinition: backup.TableDefinition{PartitionLevelInfo: rootPartitionInfo}}
+			tblPartAdded := backup.Table{Relation: backup.Relation{Schema: "public", Name: "part_added"}, TableDefinition: backup.TableDefinition{PartitionLevelInfo: rootPartitionInfo}}
+			tblPartRootDDL := backup.Table{Relation: backup.Relation{Schema: "public", Name: "part_root_ddl"}, TableDefinition: backup.TableDefinition{PartitionLevelInfo: rootPartitionInfo}}
+			tblPartUnchanged := backup.Table{Relation: backup.Relation{Schema: "public", Name: "part_unchanged"}, TableDefinition: backup.TableDefinition{PartitionLevelInfo: rootPartitionInfo}}
+			partTables := []backup.Table{tblPartChanged, tblPartAdded, tblPartRootDDL, tblPartUnchanged}
+
+			filteredPartTables := backup.FilterTablesForIncremental(&prevPartTOC, &currPartTOC, partTables)
+
+			It("Should include the partition table having a modified leaf partition", func() {
+				Expect(filteredPartTables).To(ContainElement(tblPartChanged))
+			})
+			It("Should include the partition table having an added leaf partition", func() {
+				Expect(filteredPartTables).To(ContainElement(tblPartAdded))
+			})
+			It("Should include the partition table having a modified last DDL timestamp", func() {
+				Expect(filteredPartTables).To(ContainElement(tblPartRootDDL))
+			})
+			It("Should NOT include the partition table with unmodified leaf partitions", func() {
+				Expect(filteredPartTables).To(Not(ContainElement(tblPartUnchanged)))
+			})
+			It("Should include every partition table if the previous backup did not record leaf partitions", func() {
+				prevLeafTOC := toc.TOC{IncrementalMetadata: toc.IncrementalEntries{AO: prevPartTOC.IncrementalMetadata.AO}}
+
+				Expect(backup.FilterTablesForIncremental(&prevLeafTOC, &currPartTOC, partTables)).To(Equal(partTables))
+			})
+		})
 	})
 
 	Describe("GetLatestMatchingBackupConfig", func() {
@@ -99,6 +232,13 @@ var _ = Describe("backup/incremental tests", func() {
 
 			Expect(latestBackupHistoryEntry).To(BeNil())
 		})
+		It("should return nil when the backups were encrypted with a different key", func() {
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test1", EncryptionKeyID: "0123456789abcdef"}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(&contents, &currentBackupConfig)
+
+			Expect(latestBackupHistoryEntry).To(BeNil())
+		})
 		It("should return nil with an empty history", func() {
 			currentBackupConfig := history.BackupConfig{}
 
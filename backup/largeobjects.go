@@ -0,0 +1,57 @@
+package backup
+
+/*
+ * This file contains structs and functions related to backing up large
+ * objects, which are stored in pg_largeobject rather than in user tables.
+ */
+
+import (
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * lo_get and lo_put, which are used to read and write the contents of large
+ * objects, were added in GPDB 6. Large objects are not backed up for filtered
+ * backups that only include specific schemas or tables, as they do not belong
+ * to any schema.
+ */
+func shouldBackupLargeObjects() bool {
+	return connectionPool.Version.AtLeast("6") &&
+		len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 &&
+		len(MustGetFlagStringArray(options.INCLUDE_RELATION)) == 0 &&
+		shouldBackupObjectType("LARGE OBJECT")
+}
+
+/*
+ * Large objects are created with their original OIDs, as applications refer
+ * to them by OID.
+ */
+func PrintCreateLargeObjectStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, largeObjects []LargeObject, largeObjectMetadata MetadataMap) {
+	for _, largeObject := range largeObjects {
+		start := metadataFile.ByteCount
+		metadataFile.MustPrintf("\n\nSELECT pg_catalog.lo_create(%d);\n", largeObject.Oid)
+
+		section, entry := largeObject.GetMetadataEntry()
+		toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+		PrintObjectMetadata(metadataFile, toc, largeObjectMetadata[largeObject.GetUniqueID()], largeObject, "")
+	}
+}
+
+/*
+ * The contents of each large object are copied to the large object data file
+ * one chunk at a time, so that large objects of any size can be backed up
+ * without holding them in memory, and recorded as a data entry in the TOC.
+ */
+func WriteLargeObjectContents(dataFile *utils.FileWithByteCount, toc *toc.TOC, largeObject LargeObject, getChunk func(oid uint32, offset int64, length int) []byte) {
+	start := dataFile.ByteCount
+	for {
+		chunk := getChunk(largeObject.Oid, int64(dataFile.ByteCount-start), utils.LargeObjectChunkSize)
+		dataFile.MustWrite(chunk)
+		if len(chunk) < utils.LargeObjectChunkSize {
+			break
+		}
+	}
+	toc.AddLargeObjectDataEntry(largeObject.Oid, start, dataFile.ByteCount)
+}
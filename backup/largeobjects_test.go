@@ -0,0 +1,88 @@
+package backup_test
+
+import (
+	"bytes"
+
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/largeobjects tests", func() {
+	largeObject := backup.LargeObject{Oid: 16384}
+
+	Describe("PrintCreateLargeObjectStatements", func() {
+		BeforeEach(func() {
+			tocfile, backupfile = testutils.InitializeTestTOC(buffer, "predata")
+		})
+		It("prints a large object with no metadata", func() {
+			backup.PrintCreateLargeObjectStatements(backupfile, tocfile, []backup.LargeObject{largeObject}, backup.MetadataMap{})
+			testutils.ExpectEntry(tocfile.PredataEntries, 0, "", "", "16384", "LARGE OBJECT")
+			testutils.AssertBufferContents(tocfile.PredataEntries, buffer, `SELECT pg_catalog.lo_create(16384);`)
+		})
+		It("prints a large object with an owner, privileges, and a comment", func() {
+			loMetadata := backup.MetadataMap{largeObject.GetUniqueID(): testutils.DefaultMetadata("LARGE OBJECT", true, true, true, false)}
+			backup.PrintCreateLargeObjectStatements(backupfile, tocfile, []backup.LargeObject{largeObject}, loMetadata)
+			testutils.AssertBufferContents(tocfile.PredataEntries, buffer, `SELECT pg_catalog.lo_create(16384);`,
+				`COMMENT ON LARGE OBJECT 16384 IS 'This is a large object comment.';`,
+				`ALTER LARGE OBJECT 16384 OWNER TO testrole;`,
+				`REVOKE ALL ON LARGE OBJECT 16384 FROM PUBLIC;
+REVOKE ALL ON LARGE OBJECT 16384 FROM testrole;
+GRANT ALL ON LARGE OBJECT 16384 TO testrole;`)
+		})
+	})
+	Describe("WriteLargeObjectContents", func() {
+		var offsets []int64
+
+		BeforeEach(func() {
+			tocfile, backupfile = testutils.InitializeTestTOC(buffer, "large_objects")
+			offsets = make([]int64, 0)
+		})
+		It("records an empty data entry for a large object with no contents", func() {
+			getChunk := func(oid uint32, offset int64, length int) []byte {
+				offsets = append(offsets, offset)
+				return []byte{}
+			}
+			backup.WriteLargeObjectContents(backupfile, tocfile, largeObject, getChunk)
+			Expect(tocfile.LargeObjectEntries).To(Equal([]toc.LargeObjectDataEntry{{Oid: 16384, StartByte: 0, EndByte: 0}}))
+			Expect(buffer.Contents()).To(BeEmpty())
+			Expect(offsets).To(Equal([]int64{0}))
+		})
+		It("writes a single chunk for a large object smaller than the chunk size", func() {
+			getChunk := func(oid uint32, offset int64, length int) []byte {
+				offsets = append(offsets, offset)
+				return []byte{0xde, 0xad, 0xbe, 0xef}
+			}
+			backup.WriteLargeObjectContents(backupfile, tocfile, largeObject, getChunk)
+			Expect(tocfile.LargeObjectEntries).To(Equal([]toc.LargeObjectDataEntry{{Oid: 16384, StartByte: 0, EndByte: 4}}))
+			Expect(buffer.Contents()).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+			Expect(offsets).To(Equal([]int64{0}))
+		})
+		It("writes multiple chunks for a large object larger than the chunk size", func() {
+			fullChunk := bytes.Repeat([]byte{0xab}, utils.LargeObjectChunkSize)
+			getChunk := func(oid uint32, offset int64, length int) []byte {
+				offsets = append(offsets, offset)
+				if offset == 0 {
+					return fullChunk
+				}
+				return []byte{0xcd}
+			}
+			backup.WriteLargeObjectContents(backupfile, tocfile, largeObject, getChunk)
+			Expect(tocfile.LargeObjectEntries).To(Equal([]toc.LargeObjectDataEntry{{Oid: 16384, StartByte: 0, EndByte: uint64(utils.LargeObjectChunkSize + 1)}}))
+			Expect(buffer.Contents()).To(Equal(append(fullChunk, 0xcd)))
+			Expect(offsets).To(Equal([]int64{0, int64(utils.LargeObjectChunkSize)}))
+		})
+		It("records where each large object starts in the data file", func() {
+			getChunk := func(oid uint32, offset int64, length int) []byte {
+				return []byte{byte(oid)}
+			}
+			backup.WriteLargeObjectContents(backupfile, tocfile, backup.LargeObject{Oid: 1}, getChunk)
+			backup.WriteLargeObjectContents(backupfile, tocfile, backup.LargeObject{Oid: 2}, getChunk)
+			Expect(tocfile.LargeObjectEntries).To(Equal([]toc.LargeObjectDataEntry{{Oid: 1, StartByte: 0, EndByte: 1}, {Oid: 2, StartByte: 1, EndByte: 2}}))
+		})
+	})
+})
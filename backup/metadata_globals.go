@@ -45,9 +45,31 @@ func PrintCreateDatabaseStatement(metadataFile *utils.FileWithByteCount, tocfile
 
 	entry := toc.MetadataEntry{Name: db.Name, ObjectType: "DATABASE"}
 	tocfile.AddMetadataEntry("global", entry, start, metadataFile.ByteCount)
+	addDatabaseDefinition(tocfile, entry, defaultDB, db)
 	PrintObjectMetadata(metadataFile, tocfile, dbMetadata[db.GetUniqueID()], db, "")
 }
 
+func addDatabaseDefinition(tocfile *toc.TOC, entry toc.MetadataEntry, defaultDB Database, db Database) {
+	if tocfile.ObjectDefinitions == nil {
+		return
+	}
+	definition := tocfile.ObjectDefinitions.Get(entry)
+	definition.Identifier = db.Name
+	definition.Database = &toc.DatabaseDefinition{}
+	if db.Tablespace != "pg_default" {
+		definition.Database.Tablespace = db.Tablespace
+	}
+	if db.Encoding != defaultDB.Encoding {
+		definition.Database.Encoding = db.Encoding
+	}
+	if db.Collate != defaultDB.Collate {
+		definition.Database.Collate = db.Collate
+	}
+	if db.CType != defaultDB.CType {
+		definition.Database.CType = db.CType
+	}
+}
+
 func PrintDatabaseGUCs(metadataFile *utils.FileWithByteCount, tocfile *toc.TOC, gucs []string, dbname string) {
 	for _, guc := range gucs {
 		start := metadataFile.ByteCount
@@ -55,6 +77,14 @@ func PrintDatabaseGUCs(metadataFile *utils.FileWithByteCount, tocfile *toc.TOC,
 
 		entry := toc.MetadataEntry{Name: dbname, ObjectType: "DATABASE GUC"}
 		tocfile.AddMetadataEntry("global", entry, start, metadataFile.ByteCount)
+		if tocfile.ObjectDefinitions != nil {
+			definition := tocfile.ObjectDefinitions.Get(entry)
+			definition.Identifier = dbname
+			if definition.Database == nil {
+				definition.Database = &toc.DatabaseDefinition{}
+			}
+			definition.Database.GUCs = append(definition.Database.GUCs, guc)
+		}
 	}
 }
 
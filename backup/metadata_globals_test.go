@@ -1,11 +1,15 @@
 package backup_test
 
 import (
+	"strings"
+
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/toc"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("backup/metadata_globals tests", func() {
@@ -68,6 +72,17 @@ GRANT TEMPORARY,CONNECT ON DATABASE testdb TO testrole;`,
 			backup.PrintCreateDatabaseStatement(backupfile, tocfile, defaultDB, db, emptyMetadataMap)
 			testutils.AssertBufferContents(tocfile.GlobalEntries, buffer, `CREATE DATABASE testdb TEMPLATE template0 TABLESPACE test_tablespace;`)
 		})
+		It("records the attributes of the printed statement when writing structured metadata", func() {
+			tocfile.ObjectDefinitions = toc.NewObjectDefinitions()
+			defaultDB := backup.Database{Oid: 0, Name: "", Tablespace: "", Encoding: "UTF8", Collate: "en_US.utf-8", CType: "en_US.utf-8"}
+			db := backup.Database{Oid: 1, Name: "testdb", Tablespace: "test_tablespace", Encoding: "UTF8", Collate: "C", CType: "en_US.utf-8"}
+			backup.PrintCreateDatabaseStatement(backupfile, tocfile, defaultDB, db, backup.MetadataMap{})
+
+			hunks, _ := testutils.SliceBufferByEntries(tocfile.GlobalEntries, buffer)
+			definition, ok := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE"})
+			Expect(ok).To(BeTrue())
+			Expect(definition.RenderCreateDatabase()).To(Equal(strings.TrimSpace(hunks[0])))
+		})
 	})
 	Describe("PrintDatabaseGUCs", func() {
 		dbname := "testdb"
@@ -91,6 +106,18 @@ GRANT TEMPORARY,CONNECT ON DATABASE testdb TO testrole;`,
 				`ALTER DATABASE testdb SET search_path TO pg_catalog, public;`,
 				`ALTER DATABASE testdb SET gp_default_storage_options TO 'appendonly=true,blocksize=32768';`)
 		})
+		It("records the printed database GUCs when writing structured metadata", func() {
+			tocfile.ObjectDefinitions = toc.NewObjectDefinitions()
+			gucs := []string{defaultOidGUC, searchPathGUC}
+
+			backup.PrintDatabaseGUCs(backupfile, tocfile, gucs, dbname)
+
+			hunks, _ := testutils.SliceBufferByEntries(tocfile.GlobalEntries, buffer)
+			definition, ok := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE GUC"})
+			Expect(ok).To(BeTrue())
+			Expect(definition.RenderDatabaseGUC(0)).To(Equal(strings.TrimSpace(hunks[0])))
+			Expect(definition.RenderDatabaseGUC(1)).To(Equal(strings.TrimSpace(hunks[1])))
+		})
 	})
 	Describe("PrintCreateResourceQueueStatements", func() {
 		var emptyResQueueMetadata = backup.MetadataMap{}
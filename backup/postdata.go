@@ -7,6 +7,9 @@ package backup
  */
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 )
@@ -97,3 +100,111 @@ func PrintCreateEventTriggerStatements(metadataFile *utils.FileWithByteCount, to
 		PrintObjectMetadata(metadataFile, toc, eventTriggerMetadata[eventTrigger.GetUniqueID()], eventTrigger, "")
 	}
 }
+
+func PrintCreatePolicyStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, policies []RLSPolicy, policyMetadata MetadataMap) {
+	for _, policy := range policies {
+		start := metadataFile.ByteCount
+		tableFQN := utils.MakeFQN(policy.OwningSchema, policy.OwningTable)
+		metadataFile.MustPrintf("\n\nCREATE POLICY %s\nON %s", policy.Name, tableFQN)
+		if !policy.IsPermissive {
+			metadataFile.MustPrintf("\nAS RESTRICTIVE")
+		}
+		switch policy.Command {
+		case "r":
+			metadataFile.MustPrintf("\nFOR SELECT")
+		case "a":
+			metadataFile.MustPrintf("\nFOR INSERT")
+		case "w":
+			metadataFile.MustPrintf("\nFOR UPDATE")
+		case "d":
+			metadataFile.MustPrintf("\nFOR DELETE")
+		case "*": // Default case, don't print anything else
+		}
+		if policy.Roles != "" {
+			metadataFile.MustPrintf("\nTO %s", policy.Roles)
+		}
+		if policy.Qualifier != "" {
+			metadataFile.MustPrintf("\nUSING (%s)", policy.Qualifier)
+		}
+		if policy.WithCheck != "" {
+			metadataFile.MustPrintf("\nWITH CHECK (%s)", policy.WithCheck)
+		}
+		metadataFile.MustPrintf(";")
+
+		section, entry := policy.GetMetadataEntry()
+		toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+		PrintObjectMetadata(metadataFile, toc, policyMetadata[policy.GetUniqueID()], policy, tableFQN)
+	}
+}
+
+func PrintCreateExtendedStatisticsStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, statistics []ExtendedStatistic, statisticsMetadata MetadataMap) {
+	for _, statistic := range statistics {
+		start := metadataFile.ByteCount
+		metadataFile.MustPrintf("\n\n%s;", statistic.Def)
+
+		section, entry := statistic.GetMetadataEntry()
+		toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+		PrintObjectMetadata(metadataFile, toc, statisticsMetadata[statistic.GetUniqueID()], statistic, "")
+	}
+}
+
+/*
+ * The tables in a publication are added in the same statement that creates
+ * it, so that they cannot be restored out of order in a parallel restore.
+ */
+func PrintCreatePublicationStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, publications []Publication, publicationMetadata MetadataMap) {
+	for _, publication := range publications {
+		start := metadataFile.ByteCount
+		metadataFile.MustPrintf("\n\nCREATE PUBLICATION %s", publication.Name)
+		if publication.AllTables {
+			metadataFile.MustPrintf(" FOR ALL TABLES")
+		} else if publication.Tables != "" {
+			metadataFile.MustPrintf(" FOR TABLE %s", publication.Tables)
+		}
+		actions := make([]string, 0)
+		if publication.Insert {
+			actions = append(actions, "insert")
+		}
+		if publication.Update {
+			actions = append(actions, "update")
+		}
+		if publication.Delete {
+			actions = append(actions, "delete")
+		}
+		if publication.Truncate {
+			actions = append(actions, "truncate")
+		}
+		metadataFile.MustPrintf("\nWITH (publish = '%s');", strings.Join(actions, ", "))
+
+		section, entry := publication.GetMetadataEntry()
+		toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+		PrintObjectMetadata(metadataFile, toc, publicationMetadata[publication.GetUniqueID()], publication, "")
+	}
+}
+
+/*
+ * As in pg_dump, subscriptions are restored without connecting to the
+ * publisher, so they do not create replication slots or start replicating
+ * until they are enabled and refreshed after the restore.
+ */
+func PrintCreateSubscriptionStatements(metadataFile *utils.FileWithByteCount, toc *toc.TOC, subscriptions []Subscription, subscriptionMetadata MetadataMap) {
+	for _, subscription := range subscriptions {
+		start := metadataFile.ByteCount
+		metadataFile.MustPrintf("\n\nCREATE SUBSCRIPTION %s\nCONNECTION '%s'\nPUBLICATION %s",
+			subscription.Name, utils.EscapeSingleQuotes(subscription.ConnectionInfo), subscription.Publications)
+		options := []string{"connect = false"}
+		if subscription.SlotName != "" {
+			options = append(options, fmt.Sprintf("slot_name = '%s'", utils.EscapeSingleQuotes(subscription.SlotName)))
+		} else {
+			options = append(options, "slot_name = NONE")
+		}
+		if subscription.SynchronousCommit != "" && subscription.SynchronousCommit != "off" {
+			options = append(options, fmt.Sprintf("synchronous_commit = '%s'", subscription.SynchronousCommit))
+		}
+		metadataFile.MustPrintf("\nWITH (%s);", strings.Join(options, ", "))
+
+		section, entry := subscription.GetMetadataEntry()
+		toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+		PrintObjectMetadata(metadataFile, toc, subscriptionMetadata[subscription.GetUniqueID()], subscription, "")
+	}
+}
@@ -183,4 +183,79 @@ ON ddl_command_start
 EXECUTE PROCEDURE abort_any_command();`, `ALTER EVENT TRIGGER testeventtrigger ENABLE ALWAYS;`)
 		})
 	})
+	Context("PrintCreatePolicyStatements", func() {
+		It("can print a basic policy", func() {
+			policy := backup.RLSPolicy{Oid: 1, Name: "testpolicy", OwningSchema: "public", OwningTable: "testtable", Command: "*", IsPermissive: true, Qualifier: "(a > 1)"}
+			backup.PrintCreatePolicyStatements(backupfile, tocfile, []backup.RLSPolicy{policy}, backup.MetadataMap{})
+			testutils.ExpectEntry(tocfile.PostdataEntries, 0, "public", "public.testtable", "testpolicy", "POLICY")
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE POLICY testpolicy
+ON public.testtable
+USING ((a > 1));`)
+		})
+		It("can print a restrictive policy for specific roles and commands with a comment", func() {
+			policy := backup.RLSPolicy{Oid: 1, Name: "testpolicy", OwningSchema: "public", OwningTable: "testtable", Command: "w", IsPermissive: false, Roles: "role1, role2", Qualifier: "(a > 1)", WithCheck: "(a < 10)"}
+			policyMetadataMap := testutils.DefaultMetadataMap("POLICY", false, false, true, false)
+			backup.PrintCreatePolicyStatements(backupfile, tocfile, []backup.RLSPolicy{policy}, policyMetadataMap)
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE POLICY testpolicy
+ON public.testtable
+AS RESTRICTIVE
+FOR UPDATE
+TO role1, role2
+USING ((a > 1))
+WITH CHECK ((a < 10));`, `COMMENT ON POLICY testpolicy ON public.testtable IS 'This is a policy comment.';`)
+		})
+	})
+	Context("PrintCreateExtendedStatisticsStatements", func() {
+		It("can print extended statistics with an owner and a comment", func() {
+			statistic := backup.ExtendedStatistic{Oid: 1, Schema: "public", Name: "teststats", OwningSchema: "public", OwningTable: "testtable", Def: "CREATE STATISTICS public.teststats (dependencies) ON a, b FROM public.testtable"}
+			statisticsMetadataMap := testutils.DefaultMetadataMap("EXTENDED STATISTICS", false, true, true, false)
+			backup.PrintCreateExtendedStatisticsStatements(backupfile, tocfile, []backup.ExtendedStatistic{statistic}, statisticsMetadataMap)
+			testutils.ExpectEntry(tocfile.PostdataEntries, 0, "public", "public.testtable", "teststats", "EXTENDED STATISTICS")
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE STATISTICS public.teststats (dependencies) ON a, b FROM public.testtable;`,
+				`COMMENT ON STATISTICS public.teststats IS 'This is an extended statistics comment.';`,
+				`ALTER STATISTICS public.teststats OWNER TO testrole;`)
+		})
+	})
+	Context("PrintCreatePublicationStatements", func() {
+		It("can print a publication for all tables", func() {
+			publication := backup.Publication{Oid: 1, Name: "testpub", AllTables: true, Insert: true, Update: true, Delete: true, Truncate: true}
+			backup.PrintCreatePublicationStatements(backupfile, tocfile, []backup.Publication{publication}, backup.MetadataMap{})
+			testutils.ExpectEntry(tocfile.PostdataEntries, 0, "", "", "testpub", "PUBLICATION")
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE PUBLICATION testpub FOR ALL TABLES
+WITH (publish = 'insert, update, delete, truncate');`)
+		})
+		It("can print a publication for specific tables and actions with an owner", func() {
+			publication := backup.Publication{Oid: 1, Name: "testpub", Insert: true, Delete: true, Tables: "public.table1, public.table2"}
+			publicationMetadataMap := testutils.DefaultMetadataMap("PUBLICATION", false, true, false, false)
+			backup.PrintCreatePublicationStatements(backupfile, tocfile, []backup.Publication{publication}, publicationMetadataMap)
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE PUBLICATION testpub FOR TABLE public.table1, public.table2
+WITH (publish = 'insert, delete');`, `ALTER PUBLICATION testpub OWNER TO testrole;`)
+		})
+		It("can print a publication with no tables", func() {
+			publication := backup.Publication{Oid: 1, Name: "testpub", Insert: true}
+			backup.PrintCreatePublicationStatements(backupfile, tocfile, []backup.Publication{publication}, backup.MetadataMap{})
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE PUBLICATION testpub
+WITH (publish = 'insert');`)
+		})
+	})
+	Context("PrintCreateSubscriptionStatements", func() {
+		It("can print a subscription with a replication slot", func() {
+			subscription := backup.Subscription{Oid: 1, Name: "testsub", ConnectionInfo: "host=otherhost dbname=db password='secret'", SlotName: "testslot", SynchronousCommit: "off", Publications: "pub1, pub2"}
+			backup.PrintCreateSubscriptionStatements(backupfile, tocfile, []backup.Subscription{subscription}, backup.MetadataMap{})
+			testutils.ExpectEntry(tocfile.PostdataEntries, 0, "", "", "testsub", "SUBSCRIPTION")
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE SUBSCRIPTION testsub
+CONNECTION 'host=otherhost dbname=db password=''secret'''
+PUBLICATION pub1, pub2
+WITH (connect = false, slot_name = 'testslot');`)
+		})
+		It("can print a subscription without a replication slot and with an owner", func() {
+			subscription := backup.Subscription{Oid: 1, Name: "testsub", ConnectionInfo: "host=otherhost", SynchronousCommit: "remote_apply", Publications: "pub1"}
+			subscriptionMetadataMap := testutils.DefaultMetadataMap("SUBSCRIPTION", false, true, false, false)
+			backup.PrintCreateSubscriptionStatements(backupfile, tocfile, []backup.Subscription{subscription}, subscriptionMetadataMap)
+			testutils.AssertBufferContents(tocfile.PostdataEntries, buffer, `CREATE SUBSCRIPTION testsub
+CONNECTION 'host=otherhost'
+PUBLICATION pub1
+WITH (connect = false, slot_name = NONE, synchronous_commit = 'remote_apply');`, `ALTER SUBSCRIPTION testsub OWNER TO testrole;`)
+		})
+	})
 })
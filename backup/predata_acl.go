@@ -67,9 +67,11 @@ func PrintObjectMetadata(file *utils.FileWithByteCount, toc *toc.TOC, metadata O
 	_, entry := obj.GetMetadataEntry()
 	if entry.ObjectType == "DATABASE METADATA" {
 		entry.ObjectType = "DATABASE"
+	} else if entry.ObjectType == "EXTENDED STATISTICS" {
+		entry.ObjectType = "STATISTICS"
 	}
 	statements := make([]string, 0)
-	if comment := metadata.GetCommentStatement(obj.FQN(), entry.ObjectType, owningTable); comment != "" {
+	if comment := metadata.GetCommentStatement(obj.FQN(), entry.ObjectType, owningTable); comment != "" && shouldBackupObjectType("COMMENT") {
 		statements = append(statements, strings.TrimSpace(comment))
 	}
 	if owner := metadata.GetOwnerStatement(obj.FQN(), entry.ObjectType); owner != "" {
@@ -78,13 +80,58 @@ func PrintObjectMetadata(file *utils.FileWithByteCount, toc *toc.TOC, metadata O
 			statements = append(statements, strings.TrimSpace(owner))
 		}
 	}
-	if privileges := metadata.GetPrivilegesStatements(obj.FQN(), entry.ObjectType); privileges != "" {
+	if privileges := metadata.GetPrivilegesStatements(obj.FQN(), entry.ObjectType); privileges != "" && shouldBackupObjectType("PRIVILEGES") {
 		statements = append(statements, strings.TrimSpace(privileges))
 	}
-	if securityLabel := metadata.GetSecurityLabelStatement(obj.FQN(), entry.ObjectType); securityLabel != "" {
+	if securityLabel := metadata.GetSecurityLabelStatement(obj.FQN(), entry.ObjectType); securityLabel != "" && shouldBackupObjectType("SECURITY LABEL") {
 		statements = append(statements, strings.TrimSpace(securityLabel))
 	}
 	PrintStatements(file, toc, obj, statements)
+	addObjectMetadataDefinition(toc, metadata, obj, entry.ObjectType, owningTable)
+}
+
+/*
+ * Records the attributes printed by PrintObjectMetadata in the structured
+ * metadata file, if one is being written.
+ */
+func addObjectMetadataDefinition(tocfile *toc.TOC, metadata ObjectMetadata, obj toc.TOCObjectWithMetadata, statementType string, owningTable string) {
+	if tocfile.ObjectDefinitions == nil {
+		return
+	}
+	_, entry := obj.GetMetadataEntry()
+	definition := tocfile.ObjectDefinitions.Get(entry)
+	definition.StatementType = statementType
+	definition.Identifier = obj.FQN()
+	if shouldBackupObjectType("COMMENT") {
+		definition.Comment = metadata.Comment
+		definition.CommentTarget = owningTable
+	}
+	definition.Owner = metadata.Owner
+	if !(connectionPool.Version.Before("5") && statementType == "LANGUAGE") {
+		definition.OwnerType = getOwnerStatementType(statementType)
+	}
+	if len(metadata.Privileges) != 0 && shouldBackupObjectType("PRIVILEGES") {
+		definition.HasPrivileges = true
+		definition.PrivilegeType = getPrivilegesStatementType(statementType)
+		definition.Grants = make([]toc.Grant, 0)
+		for _, acl := range metadata.Privileges {
+			grantee := acl.Grantee
+			if grantee == "" {
+				grantee = "PUBLIC"
+			}
+			privStr, privWithGrantStr := createPrivilegeStrings(acl, statementType)
+			if privStr != "" {
+				definition.Grants = append(definition.Grants, toc.Grant{Grantee: grantee, Privileges: privStr})
+			}
+			if privWithGrantStr != "" {
+				definition.Grants = append(definition.Grants, toc.Grant{Grantee: grantee, Privileges: privWithGrantStr, WithGrantOption: true})
+			}
+		}
+	}
+	if shouldBackupObjectType("SECURITY LABEL") {
+		definition.SecurityLabelProvider = metadata.SecurityLabelProvider
+		definition.SecurityLabel = metadata.SecurityLabel
+	}
 }
 
 func ConstructMetadataMap(results []MetadataQueryStruct) MetadataMap {
@@ -231,14 +278,18 @@ func ParseACL(aclStr string) *ACL {
 	return nil
 }
 
-func (obj ObjectMetadata) GetPrivilegesStatements(objectName string, objectType string, columnName ...string) string {
-	statements := make([]string, 0)
-	typeStr := fmt.Sprintf("%s ", objectType)
+func getPrivilegesStatementType(objectType string) string {
 	if objectType == "VIEW" || objectType == "FOREIGN TABLE" || objectType == "MATERIALIZED VIEW" {
-		typeStr = ""
+		return ""
 	} else if objectType == "COLUMN" {
-		typeStr = "TABLE "
+		return "TABLE "
 	}
+	return fmt.Sprintf("%s ", objectType)
+}
+
+func (obj ObjectMetadata) GetPrivilegesStatements(objectName string, objectType string, columnName ...string) string {
+	statements := make([]string, 0)
+	typeStr := getPrivilegesStatementType(objectType)
 	columnStr := ""
 	if len(columnName) == 1 {
 		columnStr = fmt.Sprintf("(%s) ", columnName[0])
@@ -297,12 +348,15 @@ func createPrivilegeStrings(acl ACL, objectType string) (string, string) {
 		hasAllPrivileges = acl.Select && acl.Insert && acl.Update && acl.Delete && acl.References && acl.Trigger
 		hasAllPrivilegesWithGrant = acl.SelectWithGrant && acl.InsertWithGrant && acl.UpdateWithGrant && acl.DeleteWithGrant &&
 			acl.ReferencesWithGrant && acl.TriggerWithGrant
-	case "FUNCTION":
+	case "FUNCTION", "PROCEDURE":
 		hasAllPrivileges = acl.Execute
 		hasAllPrivilegesWithGrant = acl.ExecuteWithGrant
 	case "LANGUAGE":
 		hasAllPrivileges = acl.Usage
 		hasAllPrivilegesWithGrant = acl.UsageWithGrant
+	case "LARGE OBJECT":
+		hasAllPrivileges = acl.Select && acl.Update
+		hasAllPrivilegesWithGrant = acl.SelectWithGrant && acl.UpdateWithGrant
 	case "PROTOCOL":
 		hasAllPrivileges = acl.Select && acl.Insert
 		hasAllPrivilegesWithGrant = acl.SelectWithGrant && acl.InsertWithGrant
@@ -410,13 +464,17 @@ func createPrivilegeStrings(acl ACL, objectType string) (string, string) {
 	return privStr, privWithGrantStr
 
 }
-func (obj ObjectMetadata) GetOwnerStatement(objectName string, objectType string) string {
-	typeStr := objectType
+func getOwnerStatementType(objectType string) string {
 	if connectionPool.Version.Before("6") && (objectType == "SEQUENCE" || objectType == "VIEW") {
-		typeStr = "TABLE"
+		return "TABLE"
 	} else if objectType == "FOREIGN SERVER" {
-		typeStr = "SERVER"
+		return "SERVER"
 	}
+	return objectType
+}
+
+func (obj ObjectMetadata) GetOwnerStatement(objectName string, objectType string) string {
+	typeStr := getOwnerStatementType(objectType)
 	ownerStr := ""
 	if obj.Owner != "" {
 		ownerStr = fmt.Sprintf("ALTER %s %s OWNER TO %s;", typeStr, objectName, obj.Owner)
@@ -2,12 +2,15 @@ package backup_test
 
 import (
 	"database/sql"
+	"strings"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gp-common-go-libs/structmatcher"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/toc"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -132,6 +135,16 @@ REVOKE ALL ON TABLE public.tablename FROM testrole;
 GRANT ALL ON TABLE public.tablename TO anothertestrole;
 GRANT SELECT,INSERT,UPDATE,DELETE,TRUNCATE,REFERENCES ON TABLE public.tablename TO testrole;
 GRANT TRIGGER ON TABLE public.tablename TO PUBLIC;`)
+		})
+		It("does not print comments or privileges when their object types are excluded", func() {
+			_ = cmdFlags.Set(options.EXCLUDE_OBJECT_TYPE, "comment")
+			_ = cmdFlags.Set(options.EXCLUDE_OBJECT_TYPE, "PRIVILEGES")
+			tableMetadata := backup.ObjectMetadata{Privileges: privileges, Owner: "testrole", Comment: "This is a table comment."}
+			backup.PrintObjectMetadata(backupfile, tocfile, tableMetadata, table, "")
+			Expect(string(buffer.Contents())).To(Equal(`
+
+ALTER TABLE public.tablename OWNER TO testrole;
+`))
 		})
 		It("prints SERVER for ALTER and FOREIGN SERVER for GRANT/REVOKE for a foreign server", func() {
 			server := backup.ForeignServer{Name: "foreignserver"}
@@ -147,6 +160,20 @@ REVOKE ALL ON FOREIGN SERVER foreignserver FROM PUBLIC;
 REVOKE ALL ON FOREIGN SERVER foreignserver FROM testrole;
 GRANT ALL ON FOREIGN SERVER foreignserver TO testrole;`)
 		})
+		It("records the attributes of the printed statements when writing structured metadata", func() {
+			tocfile.ObjectDefinitions = toc.NewObjectDefinitions()
+			tableMetadata := backup.ObjectMetadata{Privileges: privilegesWithGrant, Owner: "testrole", Comment: "This is a ta'ble comment.", SecurityLabelProvider: "dummy", SecurityLabel: "unclassified"}
+			backup.PrintObjectMetadata(backupfile, tocfile, tableMetadata, table, "")
+
+			hunks, _ := testutils.SliceBufferByEntries(tocfile.PredataEntries, buffer)
+			definition, ok := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Schema: "public", Name: "tablename", ObjectType: "TABLE"})
+			Expect(ok).To(BeTrue())
+			Expect(hunks).To(HaveLen(4))
+			Expect(definition.RenderComment()).To(Equal(strings.TrimSpace(hunks[0])))
+			Expect(definition.RenderOwner()).To(Equal(strings.TrimSpace(hunks[1])))
+			Expect(definition.RenderPrivileges()).To(Equal(strings.TrimSpace(hunks[2])))
+			Expect(definition.RenderSecurityLabel()).To(Equal(strings.TrimSpace(hunks[3])))
+		})
 		Context("Views and sequences have owners", func() {
 			view := backup.View{Schema: "public", Name: "viewname"}
 			sequence := backup.Sequence{Relation: backup.Relation{Schema: "public", Name: "sequencename"}}
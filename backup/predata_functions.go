@@ -16,11 +16,18 @@ import (
 func PrintCreateFunctionStatement(metadataFile *utils.FileWithByteCount, toc *toc.TOC, funcDef Function, funcMetadata ObjectMetadata) {
 	start := metadataFile.ByteCount
 	funcFQN := utils.MakeFQN(funcDef.Schema, funcDef.Name)
-	metadataFile.MustPrintf("\n\nCREATE FUNCTION %s(%s) RETURNS ", funcFQN, funcDef.Arguments)
-	metadataFile.MustPrintf("%s AS", funcDef.ResultType)
-	PrintFunctionBodyOrPath(metadataFile, funcDef)
-	metadataFile.MustPrintf("LANGUAGE %s", funcDef.Language)
-	PrintFunctionModifiers(metadataFile, funcDef)
+	if funcDef.IsProcedure {
+		metadataFile.MustPrintf("\n\nCREATE PROCEDURE %s(%s) AS", funcFQN, funcDef.Arguments)
+		PrintFunctionBodyOrPath(metadataFile, funcDef)
+		metadataFile.MustPrintf("LANGUAGE %s", funcDef.Language)
+		PrintProcedureModifiers(metadataFile, funcDef)
+	} else {
+		metadataFile.MustPrintf("\n\nCREATE FUNCTION %s(%s) RETURNS ", funcFQN, funcDef.Arguments)
+		metadataFile.MustPrintf("%s AS", funcDef.ResultType)
+		PrintFunctionBodyOrPath(metadataFile, funcDef)
+		metadataFile.MustPrintf("LANGUAGE %s", funcDef.Language)
+		PrintFunctionModifiers(metadataFile, funcDef)
+	}
 	metadataFile.MustPrintln(";")
 
 	section, entry := funcDef.GetMetadataEntry()
@@ -95,6 +102,19 @@ func PrintFunctionModifiers(metadataFile *utils.FileWithByteCount, funcDef Funct
 	}
 }
 
+/*
+ * Procedures do not return a value and cannot be called in queries, so only
+ * the modifiers that do not concern how a function is planned apply to them.
+ */
+func PrintProcedureModifiers(metadataFile *utils.FileWithByteCount, funcDef Function) {
+	if funcDef.IsSecurityDefiner {
+		metadataFile.MustPrintf(" SECURITY DEFINER")
+	}
+	if funcDef.Config != "" {
+		metadataFile.MustPrintf("\n%s", funcDef.Config)
+	}
+}
+
 func PrintCreateAggregateStatement(metadataFile *utils.FileWithByteCount, toc *toc.TOC, aggDef Aggregate, funcInfoMap map[uint32]FunctionInfo, aggMetadata ObjectMetadata) {
 	start := metadataFile.ByteCount
 	orderedStr := ""
@@ -57,7 +57,26 @@ GRANT ALL ON FUNCTION public.func_name(integer, integer) TO testrole;`,
 				testutils.AssertBufferContents(tocfile.PredataEntries, buffer, expectedStatements...)
 
 			})
+			It("prints a procedure definition with an owner, permissions, and modifiers that apply to procedures", func() {
+				funcDef.IsProcedure = true
+				funcDef.IsSecurityDefiner = true
+				funcDef.Volatility = "i"
+				funcDef.Config = "SET search_path TO 'public'"
+				funcMetadata := testutils.DefaultMetadata("PROCEDURE", true, true, false, false)
+				backup.PrintCreateFunctionStatement(backupfile, tocfile, funcDef, funcMetadata)
+				testutils.ExpectEntry(tocfile.PredataEntries, 0, "public", "", "func_name(integer, integer)", "PROCEDURE")
+				expectedStatements := []string{`CREATE PROCEDURE public.func_name(integer, integer) AS
+$$add_two_ints$$
+LANGUAGE internal SECURITY DEFINER
+SET search_path TO 'public';`,
+					"ALTER PROCEDURE public.func_name(integer, integer) OWNER TO testrole;",
+					`REVOKE ALL ON PROCEDURE public.func_name(integer, integer) FROM PUBLIC;
+REVOKE ALL ON PROCEDURE public.func_name(integer, integer) FROM testrole;
+GRANT ALL ON PROCEDURE public.func_name(integer, integer) TO testrole;`}
+				testutils.AssertBufferContents(tocfile.PredataEntries, buffer, expectedStatements...)
+			})
 		})
+
 		Describe("PrintFunctionBodyOrPath", func() {
 			It("prints a function definition for an internal function with 'NULL' binary path using '-'", func() {
 				funcDef.BinaryPath = "-"
@@ -121,3 +121,20 @@ func PrintCreateOperatorClassStatement(metadataFile *utils.FileWithByteCount, to
 	toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
 	PrintObjectMetadata(metadataFile, toc, operatorClassMetadata, operatorClass, "")
 }
+
+/*
+ * Access methods are not supported before GPDB 7, so this function is not
+ * used in backups of earlier versions.
+ */
+func PrintCreateAccessMethodStatement(metadataFile *utils.FileWithByteCount, toc *toc.TOC, accessMethod AccessMethod, accessMethodMetadata ObjectMetadata) {
+	start := metadataFile.ByteCount
+	methodType := "INDEX"
+	if accessMethod.Type == "t" {
+		methodType = "TABLE"
+	}
+	metadataFile.MustPrintf("\n\nCREATE ACCESS METHOD %s TYPE %s HANDLER %s;", accessMethod.Name, methodType, accessMethod.Handler)
+
+	section, entry := accessMethod.GetMetadataEntry()
+	toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+	PrintObjectMetadata(metadataFile, toc, accessMethodMetadata, accessMethod, "")
+}
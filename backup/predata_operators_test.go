@@ -95,6 +95,24 @@ var _ = Describe("backup/predata_operators tests", func() {
 			testutils.AssertBufferContents(tocfile.PredataEntries, buffer, expectedStatements...)
 		})
 	})
+	Describe("PrintCreateAccessMethodStatement", func() {
+		It("prints a table access method", func() {
+			accessMethod := backup.AccessMethod{Oid: 1, Name: "testam", Handler: "heap_tableam_handler", Type: "t"}
+
+			backup.PrintCreateAccessMethodStatement(backupfile, tocfile, accessMethod, backup.ObjectMetadata{})
+
+			testutils.ExpectEntry(tocfile.PredataEntries, 0, "", "", "testam", "ACCESS METHOD")
+			testutils.AssertBufferContents(tocfile.PredataEntries, buffer, `CREATE ACCESS METHOD testam TYPE TABLE HANDLER heap_tableam_handler;`)
+		})
+		It("prints an index access method with a comment", func() {
+			accessMethod := backup.AccessMethod{Oid: 1, Name: "testam", Handler: "bthandler", Type: "i"}
+
+			backup.PrintCreateAccessMethodStatement(backupfile, tocfile, accessMethod, testutils.DefaultMetadata("ACCESS METHOD", false, false, true, false))
+
+			testutils.AssertBufferContents(tocfile.PredataEntries, buffer, `CREATE ACCESS METHOD testam TYPE INDEX HANDLER bthandler;`,
+				`COMMENT ON ACCESS METHOD testam IS 'This is an access method comment.';`)
+		})
+	})
 	Describe("PrintCreateOperatorClassStatement", func() {
 		var (
 			operatorClass backup.OperatorClass
@@ -92,9 +92,50 @@ func PrintCreateTableStatement(metadataFile *utils.FileWithByteCount, toc *toc.T
 	}
 	section, entry := table.GetMetadataEntry()
 	toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
+	addTableDefinition(toc, table)
 	PrintPostCreateTableStatements(metadataFile, toc, table, tableMetadata)
 }
 
+/*
+ * External and foreign tables are not added to the structured metadata file,
+ * so gprestore restores them from the metadata file as-is.
+ */
+func addTableDefinition(tocfile *toc.TOC, table Table) {
+	if tocfile.ObjectDefinitions == nil || table.IsExternal || table.ForeignDef != (ForeignTableDefinition{}) {
+		return
+	}
+	_, entry := table.GetMetadataEntry()
+	definition := tocfile.ObjectDefinitions.Get(entry)
+	definition.Identifier = table.FQN()
+	definition.Table = &toc.TableDefinition{
+		Unlogged:        table.IsUnlogged,
+		OfType:          table.TableType,
+		Columns:         make([]toc.ColumnDefinition, 0, len(table.ColumnDefs)),
+		Inherits:        table.Inherits,
+		StorageOpts:     table.StorageOpts,
+		Tablespace:      table.TablespaceName,
+		DistPolicy:      table.DistPolicy,
+		PartDef:         strings.TrimSpace(table.PartDef),
+		PartTemplateDef: strings.TrimSpace(table.PartTemplateDef),
+	}
+	for _, column := range table.ColumnDefs {
+		columnDef := toc.ColumnDefinition{
+			Name:        column.Name,
+			Type:        column.Type,
+			Collation:   column.Collation,
+			NotNull:     column.NotNull,
+			Encoding:    column.Encoding,
+			StatTarget:  column.StatTarget,
+			StorageType: column.StorageType,
+			Options:     column.Options,
+		}
+		if column.HasDefault {
+			columnDef.Default = column.DefaultVal
+		}
+		definition.Table.Columns = append(definition.Table.Columns, columnDef)
+	}
+}
+
 func PrintRegularTableCreateStatement(metadataFile *utils.FileWithByteCount, toc *toc.TOC, table Table) {
 	start := metadataFile.ByteCount
 
@@ -196,16 +237,16 @@ func PrintPostCreateTableStatements(metadataFile *utils.FileWithByteCount, toc *
 	PrintObjectMetadata(metadataFile, toc, tableMetadata, table, "")
 	statements := make([]string, 0)
 	for _, att := range table.ColumnDefs {
-		if att.Comment != "" {
+		if att.Comment != "" && shouldBackupObjectType("COMMENT") {
 			escapedComment := utils.EscapeSingleQuotes(att.Comment)
 			statements = append(statements, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s';", table.FQN(), att.Name, escapedComment))
 		}
-		if att.Privileges.Valid {
+		if att.Privileges.Valid && shouldBackupObjectType("PRIVILEGES") {
 			columnMetadata := ObjectMetadata{Privileges: getColumnACL(att.Privileges, att.Kind), Owner: tableMetadata.Owner}
 			columnPrivileges := columnMetadata.GetPrivilegesStatements(table.FQN(), "COLUMN", att.Name)
 			statements = append(statements, strings.TrimSpace(columnPrivileges))
 		}
-		if att.SecurityLabel != "" {
+		if att.SecurityLabel != "" && shouldBackupObjectType("SECURITY LABEL") {
 			escapedLabel := utils.EscapeSingleQuotes(att.SecurityLabel)
 			statements = append(statements, fmt.Sprintf("SECURITY LABEL FOR %s ON COLUMN %s.%s IS '%s';", att.SecurityLabelProvider, table.FQN(), att.Name, escapedLabel))
 		}
@@ -224,6 +265,14 @@ func PrintPostCreateTableStatements(metadataFile *utils.FileWithByteCount, toc *
 		}
 	}
 
+	switch table.RowSecurity {
+	case "e":
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", table.FQN()))
+	case "f":
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY;", table.FQN()))
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY;", table.FQN()))
+	}
+
 	PrintStatements(metadataFile, toc, table, statements)
 }
 
@@ -2,12 +2,15 @@ package backup_test
 
 import (
 	"database/sql"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/testutils"
+	"github.com/greenplum-db/gpbackup/toc"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("backup/predata_relations tests", func() {
@@ -35,6 +38,30 @@ var _ = Describe("backup/predata_relations tests", func() {
 			testutils.AssertBufferContents(tocfile.PredataEntries, buffer, `CREATE TABLE public.tablename (
 ) DISTRIBUTED RANDOMLY;`, "ALTER TABLE public.tablename OWNER TO testrole;")
 		})
+		It("records a table definition that renders the CREATE TABLE statement when writing structured metadata", func() {
+			tocfile.ObjectDefinitions = toc.NewObjectDefinitions()
+			rowTwoDefault := backup.ColumnDefinition{Oid: 1, Num: 2, Name: "j", HasDefault: true, Type: "character varying(20)", StatTarget: -1, StorageType: "PLAIN", DefaultVal: "'bar'::text"}
+			testTable.ColumnDefs = []backup.ColumnDefinition{rowOne, rowTwoDefault}
+			testTable.IsUnlogged = true
+			testTable.StorageOpts = "appendonly=true"
+			testTable.TablespaceName = "test_tablespace"
+			testTable.PartDef = "PARTITION BY LIST(j) \n          (\n          PARTITION p1 VALUES('a') WITH (tablename='tablename_1_prt_p1', appendonly=true ) \n          ) "
+			testTable.IsExternal = false
+			backup.PrintCreateTableStatement(backupfile, tocfile, testTable, noMetadata)
+
+			hunks, _ := testutils.SliceBufferByEntries(tocfile.PredataEntries, buffer)
+			definition, ok := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Schema: "public", Name: "tablename", ObjectType: "TABLE"})
+			Expect(ok).To(BeTrue())
+			Expect(definition.RenderCreateTable()).To(Equal(strings.TrimSpace(hunks[0])))
+		})
+		It("does not record a table definition for an external table", func() {
+			tocfile.ObjectDefinitions = toc.NewObjectDefinitions()
+			testTable.IsExternal = true
+			backup.PrintCreateTableStatement(backupfile, tocfile, testTable, noMetadata)
+
+			definition, _ := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Schema: "public", Name: "tablename", ObjectType: "TABLE"})
+			Expect(definition.Table).To(BeNil())
+		})
 		It("calls PrintExternalTableCreateStatement for an external table", func() {
 			testTable.IsExternal = true
 			backup.PrintCreateTableStatement(backupfile, tocfile, testTable, noMetadata)
@@ -515,6 +542,18 @@ SET SUBPARTITION TEMPLATE
 			backup.PrintPostCreateTableStatements(backupfile, tocfile, testTable, noMetadata)
 			testhelper.ExpectRegexp(buffer, `ALTER TABLE public.tablename REPLICA IDENTITY FULL;`)
 		})
+		It("prints row-level security statements", func() {
+			testTable.RowSecurity = "e"
+			backup.PrintPostCreateTableStatements(backupfile, tocfile, testTable, noMetadata)
+			testhelper.ExpectRegexp(buffer, `ALTER TABLE public.tablename ENABLE ROW LEVEL SECURITY;`)
+			testhelper.NotExpectRegexp(buffer, `FORCE ROW LEVEL SECURITY`)
+		})
+		It("prints forced row-level security statements", func() {
+			testTable.RowSecurity = "f"
+			backup.PrintPostCreateTableStatements(backupfile, tocfile, testTable, noMetadata)
+			testhelper.ExpectRegexp(buffer, `ALTER TABLE public.tablename ENABLE ROW LEVEL SECURITY;`)
+			testhelper.ExpectRegexp(buffer, `ALTER TABLE public.tablename FORCE ROW LEVEL SECURITY;`)
+		})
 		It("prints replica identity nothing", func() {
 			testTable.ReplicaIdentity = "n"
 			backup.PrintPostCreateTableStatements(backupfile, tocfile, testTable, noMetadata)
@@ -32,6 +32,7 @@ type MetadataQueryParams struct {
 }
 
 var (
+	TYPE_ACCESSMETHOD       MetadataQueryParams
 	TYPE_AGGREGATE          MetadataQueryParams
 	TYPE_CAST               MetadataQueryParams
 	TYPE_COLLATION          MetadataQueryParams
@@ -44,17 +45,22 @@ var (
 	TYPE_FOREIGNSERVER      MetadataQueryParams
 	TYPE_FUNCTION           MetadataQueryParams
 	TYPE_INDEX              MetadataQueryParams
+	TYPE_LARGEOBJECT        MetadataQueryParams
 	TYPE_PROCLANGUAGE       MetadataQueryParams
 	TYPE_OPERATOR           MetadataQueryParams
 	TYPE_OPERATORCLASS      MetadataQueryParams
 	TYPE_OPERATORFAMILY     MetadataQueryParams
+	TYPE_POLICY             MetadataQueryParams
 	TYPE_PROTOCOL           MetadataQueryParams
+	TYPE_PUBLICATION        MetadataQueryParams
 	TYPE_RELATION           MetadataQueryParams
 	TYPE_RESOURCEGROUP      MetadataQueryParams
 	TYPE_RESOURCEQUEUE      MetadataQueryParams
 	TYPE_ROLE               MetadataQueryParams
 	TYPE_RULE               MetadataQueryParams
 	TYPE_SCHEMA             MetadataQueryParams
+	TYPE_STATISTICEXT       MetadataQueryParams
+	TYPE_SUBSCRIPTION       MetadataQueryParams
 	TYPE_TABLESPACE         MetadataQueryParams
 	TYPE_TSCONFIGURATION    MetadataQueryParams
 	TYPE_TSDICTIONARY       MetadataQueryParams
@@ -65,6 +71,7 @@ var (
 )
 
 func InitializeMetadataParams(connectionPool *dbconn.DBConn) {
+	TYPE_ACCESSMETHOD = MetadataQueryParams{NameField: "amname", OidField: "oid", CatalogTable: "pg_am"}
 	TYPE_AGGREGATE = MetadataQueryParams{NameField: "proname", SchemaField: "pronamespace", ACLField: "proacl", OwnerField: "proowner", CatalogTable: "pg_proc"}
 	TYPE_CAST = MetadataQueryParams{NameField: "typname", OidField: "oid", OidTable: "pg_type", CatalogTable: "pg_cast"}
 	TYPE_COLLATION = MetadataQueryParams{NameField: "collname", OidField: "oid", SchemaField: "collnamespace", OwnerField: "collowner", CatalogTable: "pg_collation"}
@@ -77,6 +84,8 @@ func InitializeMetadataParams(connectionPool *dbconn.DBConn) {
 	TYPE_FOREIGNSERVER = MetadataQueryParams{NameField: "srvname", ACLField: "srvacl", OwnerField: "srvowner", CatalogTable: "pg_foreign_server"}
 	TYPE_FUNCTION = TYPE_AGGREGATE // Aggregates are functions. So the metadata call to get them are the same.
 	TYPE_INDEX = MetadataQueryParams{NameField: "relname", OidField: "indexrelid", OidTable: "pg_class", CommentTable: "pg_class", CatalogTable: "pg_index"}
+	// Comments and security labels on large objects refer to pg_largeobject rather than the catalog table holding their owner and ACL
+	TYPE_LARGEOBJECT = MetadataQueryParams{NameField: "oid", OidField: "oid", ACLField: "lomacl", OwnerField: "lomowner", CommentTable: "pg_largeobject", CatalogTable: "pg_largeobject_metadata"}
 	TYPE_PROCLANGUAGE = MetadataQueryParams{NameField: "lanname", ACLField: "lanacl", CatalogTable: "pg_language"}
 	if connectionPool.Version.Before("5") {
 		TYPE_PROCLANGUAGE.OwnerField = "10" // In GPDB 4.3, there is no lanowner field in pg_language, but languages have an implicit owner
@@ -86,13 +95,17 @@ func InitializeMetadataParams(connectionPool *dbconn.DBConn) {
 	TYPE_OPERATOR = MetadataQueryParams{NameField: "oprname", SchemaField: "oprnamespace", OidField: "oid", OwnerField: "oprowner", CatalogTable: "pg_operator"}
 	TYPE_OPERATORCLASS = MetadataQueryParams{NameField: "opcname", SchemaField: "opcnamespace", OidField: "oid", OwnerField: "opcowner", CatalogTable: "pg_opclass"}
 	TYPE_OPERATORFAMILY = MetadataQueryParams{NameField: "opfname", SchemaField: "opfnamespace", OidField: "oid", OwnerField: "opfowner", CatalogTable: "pg_opfamily"}
+	TYPE_POLICY = MetadataQueryParams{NameField: "polname", OidField: "oid", CatalogTable: "pg_policy"}
 	TYPE_PROTOCOL = MetadataQueryParams{NameField: "ptcname", ACLField: "ptcacl", OwnerField: "ptcowner", CatalogTable: "pg_extprotocol"}
+	TYPE_PUBLICATION = MetadataQueryParams{NameField: "pubname", OidField: "oid", OwnerField: "pubowner", CatalogTable: "pg_publication"}
 	TYPE_RELATION = MetadataQueryParams{NameField: "relname", SchemaField: "relnamespace", ACLField: "relacl", OwnerField: "relowner", CatalogTable: "pg_class"}
 	TYPE_RESOURCEGROUP = MetadataQueryParams{NameField: "rsgname", OidField: "oid", CatalogTable: "pg_resgroup", Shared: true}
 	TYPE_RESOURCEQUEUE = MetadataQueryParams{NameField: "rsqname", OidField: "oid", CatalogTable: "pg_resqueue", Shared: true}
 	TYPE_ROLE = MetadataQueryParams{NameField: "rolname", OidField: "oid", CatalogTable: "pg_authid", Shared: true}
 	TYPE_RULE = MetadataQueryParams{NameField: "rulename", OidField: "oid", CatalogTable: "pg_rewrite"}
 	TYPE_SCHEMA = MetadataQueryParams{NameField: "nspname", ACLField: "nspacl", OwnerField: "nspowner", CatalogTable: "pg_namespace"}
+	TYPE_STATISTICEXT = MetadataQueryParams{NameField: "stxname", OidField: "oid", SchemaField: "stxnamespace", OwnerField: "stxowner", CatalogTable: "pg_statistic_ext"}
+	TYPE_SUBSCRIPTION = MetadataQueryParams{NameField: "subname", OidField: "oid", OwnerField: "subowner", CatalogTable: "pg_subscription", Shared: true}
 	TYPE_TABLESPACE = MetadataQueryParams{NameField: "spcname", ACLField: "spcacl", OwnerField: "spcowner", CatalogTable: "pg_tablespace", Shared: true}
 	TYPE_TSCONFIGURATION = MetadataQueryParams{NameField: "cfgname", OidField: "oid", SchemaField: "cfgnamespace", OwnerField: "cfgowner", CatalogTable: "pg_ts_config"}
 	TYPE_TSDICTIONARY = MetadataQueryParams{NameField: "dictname", OidField: "oid", SchemaField: "dictnamespace", OwnerField: "dictowner", CatalogTable: "pg_ts_dict"}
@@ -141,6 +154,10 @@ func GetMetadataForObjectType(connectionPool *dbconn.DBConn, params MetadataQuer
 		descFunc = "pg_shdescription"
 		subidStr = ""
 	}
+	commentTable := params.CatalogTable
+	if params.CommentTable != "" {
+		commentTable = params.CommentTable
+	}
 	ownerStr := "''"
 	if params.OwnerField != "" {
 		ownerStr = fmt.Sprintf("quote_ident(pg_get_userbyid(%s))", params.OwnerField)
@@ -155,7 +172,7 @@ func GetMetadataForObjectType(connectionPool *dbconn.DBConn, params MetadataQuer
 			secTable = "pg_shseclabel"
 			secSubidStr = ""
 		}
-		secStr = fmt.Sprintf("LEFT JOIN %s sec ON (sec.objoid = o.oid AND sec.classoid = '%s'::regclass%s)", secTable, params.CatalogTable, secSubidStr)
+		secStr = fmt.Sprintf("LEFT JOIN %s sec ON (sec.objoid = o.oid AND sec.classoid = '%s'::regclass%s)", secTable, commentTable, secSubidStr)
 	}
 
 	query := fmt.Sprintf(`
@@ -171,8 +188,8 @@ func GetMetadataForObjectType(connectionPool *dbconn.DBConn, params MetadataQuer
 		%s
 		%s
 		AND o.oid NOT IN (SELECT objid FROM pg_depend WHERE deptype='e')
-	ORDER BY o.oid`, params.CatalogTable, aclStr, kindStr, ownerStr, secCols,
-	params.CatalogTable, descFunc, params.CatalogTable, subidStr, secStr, schemaStr)
+	ORDER BY o.oid`, commentTable, aclStr, kindStr, ownerStr, secCols,
+	params.CatalogTable, descFunc, commentTable, subidStr, secStr, schemaStr)
 
 	results := make([]MetadataQueryStruct, 0)
 	err := connectionPool.Select(&results, query)
@@ -34,6 +34,20 @@ var _ = Describe("backup/queries_acl tests", func() {
 	ORDER BY o.oid`)).WillReturnRows(emptyRows)
 			backup.GetMetadataForObjectType(connectionPool, params)
 		})
+		It("queries metadata for an object whose comments refer to a different catalog table", func() {
+			mock.ExpectQuery(regexp.QuoteMeta(`
+	SELECT 'comment_table'::regclass::oid AS classid,
+		o.oid,
+		'' AS privileges,
+		'' AS kind,
+		quote_ident(pg_get_userbyid(owner)) AS owner,
+		coalesce(description,'') AS comment
+	FROM table o LEFT JOIN pg_description d ON (d.objoid = o.oid AND d.classoid = 'comment_table'::regclass AND d.objsubid = 0)
+	AND o.oid NOT IN (SELECT objid FROM pg_depend WHERE deptype='e')
+	ORDER BY o.oid`)).WillReturnRows(emptyRows)
+			params.CommentTable = "comment_table"
+			backup.GetMetadataForObjectType(connectionPool, params)
+		})
 		It("queries metadata for an object with a schema field", func() {
 			mock.ExpectQuery(regexp.QuoteMeta(`
 	SELECT 'table'::regclass::oid AS classid,
@@ -37,15 +37,20 @@ type Function struct {
 	Language          string
 	IsWindow          bool   `db:"proiswindow"`
 	ExecLocation      string `db:"proexeclocation"`
+	IsProcedure       bool
 }
 
 func (f Function) GetMetadataEntry() (string, toc.MetadataEntry) {
 	nameWithArgs := fmt.Sprintf("%s(%s)", f.Name, f.IdentArgs)
+	objectType := "FUNCTION"
+	if f.IsProcedure {
+		objectType = "PROCEDURE"
+	}
 	return "predata",
 		toc.MetadataEntry{
 			Schema:          f.Schema,
 			Name:            nameWithArgs,
-			ObjectType:      "FUNCTION",
+			ObjectType:      objectType,
 			ReferenceObject: "",
 			StartByte:       0,
 			EndByte:         0,
@@ -96,8 +101,15 @@ func GetFunctionsAllVersions(connectionPool *dbconn.DBConn) []Function {
 func GetFunctions(connectionPool *dbconn.DBConn) []Function {
 	excludeImplicitFunctionsClause := ""
 	masterAtts := "'a' AS proexeclocation,"
-	if connectionPool.Version.AtLeast("6") {
+	kindClause := "proisagg = 'f'"
+	if connectionPool.Version.AtLeast("7") {
+		// In GPDB 7, proisagg and proiswindow are replaced by prokind, which also identifies procedures
+		masterAtts = "prokind = 'w' AS proiswindow,prokind = 'p' AS isprocedure,proexeclocation,proleakproof,"
+		kindClause = "prokind <> 'a'"
+	} else if connectionPool.Version.AtLeast("6") {
 		masterAtts = "proiswindow,proexeclocation,proleakproof,"
+	}
+	if connectionPool.Version.AtLeast("6") {
 		// This excludes implicitly created functions. Currently this is only range type functions
 		excludeImplicitFunctionsClause = `
 	AND NOT EXISTS (
@@ -128,9 +140,9 @@ func GetFunctions(connectionPool *dbconn.DBConn) []Function {
 	FROM pg_proc p
 		LEFT JOIN pg_namespace n ON p.pronamespace = n.oid
 	WHERE %s
-		AND proisagg = 'f'
+		AND %s
 		AND %s%s
-	ORDER BY nspname, proname, identargs`, masterAtts, SchemaFilterClause("n"), ExtensionFilterClause("p"), excludeImplicitFunctionsClause)
+	ORDER BY nspname, proname, identargs`, masterAtts, SchemaFilterClause("n"), kindClause, ExtensionFilterClause("p"), excludeImplicitFunctionsClause)
 
 	results := make([]Function, 0)
 	err := connectionPool.Select(&results, query)
@@ -12,7 +12,7 @@ func GetAOIncrementalMetadata(connectionPool *dbconn.DBConn) map[string]toc.AOEn
 	gplog.Verbose("Querying table row mod counts")
 	var modCounts = getAllModCounts(connectionPool)
 	gplog.Verbose("Querying last DDL modification timestamp for tables")
-	var lastDDLTimestamps = getLastDDLTimestamps(connectionPool)
+	var lastDDLTimestamps = getLastDDLTimestamps(connectionPool, "c.relstorage IN ('ao', 'co')")
 	aoTableEntries := make(map[string]toc.AOEntry)
 	for aoTableFQN := range modCounts {
 		aoTableEntries[aoTableFQN] = toc.AOEntry{
@@ -75,7 +75,7 @@ func getModCount(connectionPool *dbconn.DBConn, aosegtablefqn string) int64 {
 	return results[0].Modcount
 }
 
-func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
+func getLastDDLTimestamps(connectionPool *dbconn.DBConn, storageClause string) map[string]string {
 	query := fmt.Sprintf(`
 	SELECT quote_ident(aoschema) || '.' || quote_ident(aorelname) as aotablefqn,
 		lastddltimestamp
@@ -84,7 +84,7 @@ func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
 				c.relname AS aorelname
 			FROM pg_class c
 			JOIN pg_namespace n ON c.relnamespace = n.oid
-			WHERE c.relstorage IN ('ao', 'co')
+			WHERE %s
 			AND %s
 		) aotables
 	JOIN ( SELECT lo.objid,
@@ -93,7 +93,7 @@ func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
 			WHERE lo.staactionname IN ('CREATE', 'ALTER', 'TRUNCATE')
 			GROUP BY lo.objid
 		) lastop
-	ON aotables.aooid = lastop.objid`, relationAndSchemaFilterClause())
+	ON aotables.aooid = lastop.objid`, storageClause, relationAndSchemaFilterClause())
 
 	var results []struct {
 		AOTableFQN       string
@@ -107,3 +107,128 @@ func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
 	}
 	return resultMap
 }
+
+/*
+ * Heap tables have no modcount, so changes to their data are detected using
+ * the tuple counts kept by the statistics collector on each segment. Those
+ * counts are lost when statistics are reset or a segment restarts after a
+ * crash, so the last time statistics were reset on any segment is recorded as
+ * well and a table is backed up again whenever it differs.
+ *
+ * The counts are also reported lazily, as a session only sends them to the
+ * collector every so often and may hold them while idle, and the collector
+ * may drop them. The relfilenode and the size of each table are recorded
+ * too, so that a table is only treated as unchanged if its files are the same
+ * files of the same size as in the previous backup.
+ */
+func GetHeapIncrementalMetadata(connectionPool *dbconn.DBConn) map[string]toc.HeapEntry {
+	heapTableEntries := make(map[string]toc.HeapEntry)
+	if connectionPool.Version.Before("6") {
+		return heapTableEntries
+	}
+	if trackCounts := dbconn.MustSelectString(connectionPool, "SELECT current_setting('track_counts') AS string"); trackCounts != "on" {
+		gplog.Warn("Changes to heap tables cannot be detected because track_counts is off, so all heap tables will be backed up")
+		return heapTableEntries
+	}
+	storageClause := "c.relkind = 'r' AND c.relstorage = 'h'"
+	if connectionPool.Version.AtLeast("7") {
+		storageClause = "c.relkind = 'r' AND c.relam = (SELECT oid FROM pg_am WHERE amname = 'heap')"
+	}
+	gplog.Verbose("Querying heap table tuple counts and sizes")
+	changeInfo := getHeapChangeInfo(connectionPool, storageClause)
+	gplog.Verbose("Querying last DDL modification timestamp for heap tables")
+	lastDDLTimestamps := getLastDDLTimestamps(connectionPool, storageClause)
+	statsResetTimestamp := dbconn.MustSelectString(connectionPool, `
+	SELECT COALESCE(max(pg_stat_get_db_stat_reset_time(d.oid))::text, '') AS string
+	FROM gp_dist_random('pg_database') d
+	WHERE d.datname = current_database()`)
+	for heapTableFQN, entry := range changeInfo {
+		entry.LastDDLTimestamp = lastDDLTimestamps[heapTableFQN]
+		entry.StatsResetTimestamp = statsResetTimestamp
+		heapTableEntries[heapTableFQN] = entry
+	}
+	return heapTableEntries
+}
+
+// Only the tuple counts, relfilenode, and size of each table are set in the entries returned
+func getHeapChangeInfo(connectionPool *dbconn.DBConn, storageClause string) map[string]toc.HeapEntry {
+	query := fmt.Sprintf(`
+	SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS heaptablefqn,
+		c.relfilenode,
+		counts.modcount,
+		counts.size
+	FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		JOIN (SELECT oid,
+				sum(pg_stat_get_tuples_inserted(oid) + pg_stat_get_tuples_updated(oid) + pg_stat_get_tuples_deleted(oid))::bigint AS modcount,
+				sum(pg_relation_size(oid))::bigint AS size
+			FROM gp_dist_random('pg_class')
+			WHERE oid IN (SELECT c.oid
+				FROM pg_class c
+					JOIN pg_namespace n ON c.relnamespace = n.oid
+				WHERE %[1]s
+					AND %[2]s)
+			GROUP BY oid
+		) counts ON c.oid = counts.oid
+	WHERE %[1]s
+		AND %[2]s`, storageClause, relationAndSchemaFilterClause())
+	results := make([]struct {
+		HeapTableFQN string
+		Relfilenode  uint32
+		Modcount     int64
+		Size         int64
+	}, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	resultMap := make(map[string]toc.HeapEntry)
+	for _, result := range results {
+		resultMap[result.HeapTableFQN] = toc.HeapEntry{Modcount: result.Modcount, Relfilenode: result.Relfilenode, Size: result.Size}
+	}
+	return resultMap
+}
+
+/*
+ * Returns the root partition table of each leaf partition, so that a backup
+ * without --leaf-partition-data can detect changes to a partition table from
+ * the change information of its leaf partitions.
+ */
+func GetLeafPartitionRoots(connectionPool *dbconn.DBConn) map[string]string {
+	gplog.Verbose("Querying root partition tables of leaf partitions")
+	query := fmt.Sprintf(`
+	SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS leaffqn,
+		quote_ident(rn.nspname) || '.' || quote_ident(rc.relname) AS rootfqn
+	FROM pg_partition p
+		JOIN pg_partition_rule r ON p.oid = r.paroid
+		JOIN (SELECT parrelid AS relid, max(parlevel) AS pl
+			FROM pg_partition GROUP BY parrelid) AS levels ON p.parrelid = levels.relid
+		JOIN pg_class rc ON p.parrelid = rc.oid
+		JOIN pg_namespace rn ON rc.relnamespace = rn.oid
+		JOIN pg_class c ON r.parchildrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+	WHERE p.parlevel = levels.pl
+		AND %s`, relationAndSchemaFilterClause())
+	if connectionPool.Version.AtLeast("7") {
+		query = fmt.Sprintf(`
+	SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS leaffqn,
+		quote_ident(rn.nspname) || '.' || quote_ident(rc.relname) AS rootfqn
+	FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		JOIN pg_class rc ON pg_catalog.pg_partition_root(c.oid) = rc.oid
+		JOIN pg_namespace rn ON rc.relnamespace = rn.oid
+	WHERE c.relispartition
+		AND c.relkind <> 'p'
+		AND %s`, relationAndSchemaFilterClause())
+	}
+
+	var results []struct {
+		LeafFQN string
+		RootFQN string
+	}
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	resultMap := make(map[string]string)
+	for _, result := range results {
+		resultMap[result.LeafFQN] = result.RootFQN
+	}
+	return resultMap
+}
@@ -0,0 +1,62 @@
+package backup
+
+/*
+ * This file contains structs and functions related to executing specific
+ * queries to gather large object metadata and contents.
+ */
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/toc"
+)
+
+type LargeObject struct {
+	Oid uint32
+}
+
+func (lo LargeObject) GetMetadataEntry() (string, toc.MetadataEntry) {
+	return "predata",
+		toc.MetadataEntry{
+			Schema:          "",
+			Name:            lo.FQN(),
+			ObjectType:      "LARGE OBJECT",
+			ReferenceObject: "",
+			StartByte:       0,
+			EndByte:         0,
+		}
+}
+
+func (lo LargeObject) GetUniqueID() UniqueID {
+	return UniqueID{ClassID: PG_LARGEOBJECT_OID, Oid: lo.Oid}
+}
+
+func (lo LargeObject) FQN() string {
+	return fmt.Sprintf("%d", lo.Oid)
+}
+
+func GetLargeObjects(connectionPool *dbconn.DBConn) []LargeObject {
+	results := make([]LargeObject, 0)
+	query := `
+	SELECT oid
+	FROM pg_largeobject_metadata
+	ORDER BY oid`
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
+
+/*
+ * The contents are selected hex-encoded and decoded here, so that they are
+ * returned as they are stored regardless of the bytea_output setting. An empty
+ * slice is returned once the offset is past the end of the large object.
+ */
+func GetLargeObjectChunk(connectionPool *dbconn.DBConn, oid uint32, offset int64, length int) []byte {
+	query := fmt.Sprintf(`SELECT encode(pg_catalog.lo_get(%d, %d, %d), 'hex') AS string`, oid, offset, length)
+	chunk, err := hex.DecodeString(dbconn.MustSelectString(connectionPool, query))
+	gplog.FatalOnError(err)
+	return chunk
+}
@@ -347,3 +347,53 @@ func GetOperatorClassFunctions(connectionPool *dbconn.DBConn) map[uint32][]Opera
 	}
 	return functions
 }
+
+/*
+ * Access methods other than the built-in index methods can only be created
+ * in GPDB 7 and later, so AccessMethod and GetAccessMethods are not used in
+ * backups of earlier versions.
+ */
+
+type AccessMethod struct {
+	Oid     uint32
+	Name    string
+	Handler string
+	Type    string
+}
+
+func (am AccessMethod) GetMetadataEntry() (string, toc.MetadataEntry) {
+	return "predata",
+		toc.MetadataEntry{
+			Schema:          "",
+			Name:            am.Name,
+			ObjectType:      "ACCESS METHOD",
+			ReferenceObject: "",
+			StartByte:       0,
+			EndByte:         0,
+		}
+}
+
+func (am AccessMethod) GetUniqueID() UniqueID {
+	return UniqueID{ClassID: PG_AM_OID, Oid: am.Oid}
+}
+
+func (am AccessMethod) FQN() string {
+	return am.Name
+}
+
+func GetAccessMethods(connectionPool *dbconn.DBConn) []AccessMethod {
+	results := make([]AccessMethod, 0)
+	query := fmt.Sprintf(`
+	SELECT a.oid AS oid,
+		quote_ident(a.amname) AS name,
+		a.amhandler::regproc AS handler,
+		a.amtype AS type
+	FROM pg_am a
+	WHERE a.oid >= %d
+		AND %s
+	ORDER BY a.amname`,
+		FIRST_NORMAL_OBJECT_ID, ExtensionFilterClause("a"))
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
@@ -299,3 +299,240 @@ func GetEventTriggers(connectionPool *dbconn.DBConn) []EventTrigger {
 	gplog.FatalOnError(err)
 	return results
 }
+
+/*
+ * Row-level security policies, extended statistics, publications, and
+ * subscriptions are not supported before GPDB 7, so the structs and functions
+ * below are not used in backups of earlier versions.
+ */
+
+type RLSPolicy struct {
+	Oid          uint32
+	Name         string
+	OwningSchema string
+	OwningTable  string
+	Command      string
+	IsPermissive bool
+	Roles        string
+	Qualifier    string
+	WithCheck    string
+}
+
+func (p RLSPolicy) GetMetadataEntry() (string, toc.MetadataEntry) {
+	tableFQN := utils.MakeFQN(p.OwningSchema, p.OwningTable)
+	return "postdata",
+		toc.MetadataEntry{
+			Schema:          p.OwningSchema,
+			Name:            p.Name,
+			ObjectType:      "POLICY",
+			ReferenceObject: tableFQN,
+			StartByte:       0,
+			EndByte:         0,
+		}
+}
+
+func (p RLSPolicy) GetUniqueID() UniqueID {
+	return UniqueID{ClassID: PG_POLICY_OID, Oid: p.Oid}
+}
+
+func (p RLSPolicy) FQN() string {
+	return p.Name
+}
+
+/*
+ * A policy that applies to all roles stores the PUBLIC role (OID 0) in
+ * polroles, in which case Roles is left empty.
+ */
+func GetPolicies(connectionPool *dbconn.DBConn) []RLSPolicy {
+	query := fmt.Sprintf(`
+	SELECT p.oid AS oid,
+		quote_ident(p.polname) AS name,
+		quote_ident(n.nspname) AS owningschema,
+		quote_ident(c.relname) AS owningtable,
+		p.polcmd AS command,
+		p.polpermissive AS ispermissive,
+		CASE WHEN p.polroles = '{0}' THEN ''
+			ELSE array_to_string(ARRAY(SELECT quote_ident(rolname) FROM pg_roles WHERE oid = ANY(p.polroles) ORDER BY rolname), ', ')
+		END AS roles,
+		coalesce(pg_get_expr(p.polqual, p.polrelid), '') AS qualifier,
+		coalesce(pg_get_expr(p.polwithcheck, p.polrelid), '') AS withcheck
+	FROM pg_policy p
+		JOIN pg_class c ON c.oid = p.polrelid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+	WHERE %s
+		AND %s
+	ORDER BY p.polname`,
+	relationAndSchemaFilterClause(), ExtensionFilterClause("c"))
+
+	results := make([]RLSPolicy, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
+
+type ExtendedStatistic struct {
+	Oid          uint32
+	Schema       string
+	Name         string
+	OwningSchema string
+	OwningTable  string
+	Def          string
+}
+
+func (s ExtendedStatistic) GetMetadataEntry() (string, toc.MetadataEntry) {
+	tableFQN := utils.MakeFQN(s.OwningSchema, s.OwningTable)
+	return "postdata",
+		toc.MetadataEntry{
+			Schema:          s.Schema,
+			Name:            s.Name,
+			ObjectType:      "EXTENDED STATISTICS",
+			ReferenceObject: tableFQN,
+			StartByte:       0,
+			EndByte:         0,
+		}
+}
+
+func (s ExtendedStatistic) GetUniqueID() UniqueID {
+	return UniqueID{ClassID: PG_STATISTIC_EXT_OID, Oid: s.Oid}
+}
+
+func (s ExtendedStatistic) FQN() string {
+	return utils.MakeFQN(s.Schema, s.Name)
+}
+
+func GetExtendedStatistics(connectionPool *dbconn.DBConn) []ExtendedStatistic {
+	query := fmt.Sprintf(`
+	SELECT s.oid AS oid,
+		quote_ident(sn.nspname) AS schema,
+		quote_ident(s.stxname) AS name,
+		quote_ident(n.nspname) AS owningschema,
+		quote_ident(c.relname) AS owningtable,
+		pg_get_statisticsobjdef(s.oid) AS def
+	FROM pg_statistic_ext s
+		JOIN pg_namespace sn ON s.stxnamespace = sn.oid
+		JOIN pg_class c ON c.oid = s.stxrelid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+	WHERE %s
+		AND %s
+	ORDER BY schema, name`,
+	relationAndSchemaFilterClause(), ExtensionFilterClause("s"))
+
+	results := make([]ExtendedStatistic, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
+
+type Publication struct {
+	Oid       uint32
+	Name      string
+	AllTables bool
+	Insert    bool
+	Update    bool
+	Delete    bool
+	Truncate  bool
+	Tables    string
+}
+
+func (p Publication) GetMetadataEntry() (string, toc.MetadataEntry) {
+	return "postdata",
+		toc.MetadataEntry{
+			Schema:          "",
+			Name:            p.Name,
+			ObjectType:      "PUBLICATION",
+			ReferenceObject: "",
+			StartByte:       0,
+			EndByte:         0,
+		}
+}
+
+func (p Publication) GetUniqueID() UniqueID {
+	return UniqueID{ClassID: PG_PUBLICATION_OID, Oid: p.Oid}
+}
+
+func (p Publication) FQN() string {
+	return p.Name
+}
+
+/*
+ * Tables that are excluded from the backup are left out of the list of
+ * tables in each publication, as they will not exist when it is restored.
+ */
+func GetPublications(connectionPool *dbconn.DBConn) []Publication {
+	query := fmt.Sprintf(`
+	SELECT p.oid AS oid,
+		quote_ident(p.pubname) AS name,
+		p.puballtables AS alltables,
+		p.pubinsert AS insert,
+		p.pubupdate AS update,
+		p.pubdelete AS delete,
+		p.pubtruncate AS truncate,
+		array_to_string(ARRAY(
+			SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname)
+			FROM pg_publication_rel pr
+				JOIN pg_class c ON c.oid = pr.prrelid
+				JOIN pg_namespace n ON c.relnamespace = n.oid
+			WHERE pr.prpubid = p.oid
+				AND %s
+			ORDER BY 1), ', ') AS tables
+	FROM pg_publication p
+	WHERE %s
+	ORDER BY p.pubname`,
+	relationAndSchemaFilterClause(), ExtensionFilterClause("p"))
+
+	results := make([]Publication, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
+
+type Subscription struct {
+	Oid               uint32
+	Name              string
+	ConnectionInfo    string
+	SlotName          string
+	SynchronousCommit string
+	Publications      string
+}
+
+func (s Subscription) GetMetadataEntry() (string, toc.MetadataEntry) {
+	return "postdata",
+		toc.MetadataEntry{
+			Schema:          "",
+			Name:            s.Name,
+			ObjectType:      "SUBSCRIPTION",
+			ReferenceObject: "",
+			StartByte:       0,
+			EndByte:         0,
+		}
+}
+
+func (s Subscription) GetUniqueID() UniqueID {
+	return UniqueID{ClassID: PG_SUBSCRIPTION_OID, Oid: s.Oid}
+}
+
+func (s Subscription) FQN() string {
+	return s.Name
+}
+
+/*
+ * pg_subscription is a shared catalog table, so only the subscriptions in the
+ * database being backed up are retrieved.
+ */
+func GetSubscriptions(connectionPool *dbconn.DBConn) []Subscription {
+	query := `
+	SELECT s.oid AS oid,
+		quote_ident(s.subname) AS name,
+		s.subconninfo AS connectioninfo,
+		coalesce(s.subslotname, '') AS slotname,
+		s.subsynccommit AS synchronouscommit,
+		array_to_string(ARRAY(SELECT quote_ident(x) FROM unnest(s.subpublications) AS t(x)), ', ') AS publications
+	FROM pg_subscription s
+	WHERE s.subdbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+	ORDER BY s.subname`
+
+	results := make([]Subscription, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
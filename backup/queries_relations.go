@@ -9,12 +9,15 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
 )
 
 func relationAndSchemaFilterClause() string {
@@ -107,6 +110,50 @@ func GetUserTableRelations(connectionPool *dbconn.DBConn) []Relation {
 	return results
 }
 
+// Filter patterns are matched against unquoted names, like filter lists
+func GetUserTableNames(connectionPool *dbconn.DBConn) []options.FqnStruct {
+	query := fmt.Sprintf(`
+	SELECT n.nspname AS schemaname,
+		c.relname AS tablename
+	FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+	WHERE %s
+		AND relkind = 'r'
+		AND %s
+		ORDER BY n.nspname, c.relname`,
+		SchemaFilterClause("n"), ExtensionFilterClause("c"))
+
+	results := make([]options.FqnStruct, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+
+	return results
+}
+
+// Only tables and sequences are returned, as other relations are backed up with the tables they belong to
+func GetTableAndSequenceNamesFromOids(connectionPool *dbconn.DBConn, oids []string) []options.FqnStruct {
+	results := make([]options.FqnStruct, 0)
+	if len(oids) == 0 {
+		return results
+	}
+	query := fmt.Sprintf(`
+	SELECT n.nspname AS schemaname,
+		c.relname AS tablename
+	FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+	WHERE c.oid IN (%s)
+		AND relkind IN ('r', 'S')
+		AND %s
+		AND %s
+		ORDER BY n.nspname, c.relname`,
+		strings.Join(oids, ", "), SchemaFilterClause("n"), ExtensionFilterClause("c"))
+
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+
+	return results
+}
+
 func GetUserTableRelationsWithIncludeFiltering(connectionPool *dbconn.DBConn, includedRelationsQuoted []string) []Relation {
 	includeOids := GetOidsFromRelationList(connectionPool, includedRelationsQuoted)
 	oidStr := strings.Join(includeOids, ", ")
@@ -381,8 +428,30 @@ func makeMaterializedView(view View) MaterializedView {
 	}
 }
 
-func LockTables(connectionPool *dbconn.DBConn, tables []Relation) {
+/*
+ * By default LOCK TABLE waits as long as it takes for a conflicting lock to be
+ * released. A LockPolicy bounds that wait, so that a long-running ALTER TABLE
+ * or VACUUM FULL fails the backup or delays it by a limited amount instead.
+ */
+type LockPolicy struct {
+	WaitTimeout  time.Duration // The maximum wait for each batch of tables; 0 waits indefinitely
+	NoWait       bool          // Fail at once if any table in a batch is locked
+	Retries      int           // How many more times to try a batch that could not be locked
+	RetryBackoff time.Duration // The wait before the first retry, doubled for each retry after it
+	SkipLocked   bool          // Leave out tables that still cannot be locked instead of failing
+}
+
+func (policy LockPolicy) isBounded() bool {
+	return policy.NoWait || policy.WaitTimeout > 0
+}
+
+/*
+ * Returns the time spent acquiring the locks and, when the policy skips locked
+ * tables, the tables that could not be locked.
+ */
+func LockTables(connectionPool *dbconn.DBConn, tables []Relation, policy LockPolicy) (time.Duration, []Relation) {
 	gplog.Info("Acquiring ACCESS SHARE locks on tables")
+	startTime := time.Now()
 
 	progressBar := utils.NewProgressBar(len(tables), "Locks acquired: ", utils.PB_VERBOSE)
 	progressBar.Start()
@@ -391,6 +460,7 @@ func LockTables(connectionPool *dbconn.DBConn, tables []Relation) {
 	lastBatchSize := len(tables) % batchSize
 	tableBatches := generateTableBatches(tables, batchSize)
 	currentBatchSize := batchSize
+	skippedTables := make([]Relation, 0)
 
 	// The LOCK TABLE query could block if someone else is
 	// holding an AccessExclusiveLock on the table. If gpbackup
@@ -398,23 +468,172 @@ func LockTables(connectionPool *dbconn.DBConn, tables []Relation) {
 	// we don't cancel the query.
 	queryContext, queryCancelFunc = context.WithCancel(context.Background())
 
-	for i, currentBatch := range tableBatches {
+	// statement_timeout bounds the wait for a whole batch, where lock_timeout
+	// would bound the wait for each table in it, and exists on all versions.
+	if policy.WaitTimeout > 0 {
 		connectionPool.MustExecContext(queryContext,
-			fmt.Sprintf("LOCK TABLE %s IN ACCESS SHARE MODE", currentBatch))
+			fmt.Sprintf("SET statement_timeout = %d", policy.WaitTimeout/time.Millisecond))
+	}
 
+	for i, currentBatch := range tableBatches {
 		if i == len(tableBatches)-1 && lastBatchSize > 0 {
 			currentBatchSize = lastBatchSize
 		}
 
+		batchTables := tables[i*batchSize : i*batchSize+currentBatchSize]
+		skippedTables = append(skippedTables, lockTableBatch(connectionPool, currentBatch, batchTables, policy)...)
+
 		progressBar.Add(currentBatchSize)
 	}
 
+	if policy.WaitTimeout > 0 {
+		connectionPool.MustExecContext(queryContext, "SET statement_timeout = 0")
+	}
+
 	// We're done grabbing table locks. Unset the Context globals
 	// so we don't use them during DoCleanup.
 	queryContext = context.TODO()
 	queryCancelFunc = nil
 
 	progressBar.Finish()
+
+	lockWaitDuration := time.Since(startTime)
+	gplog.Info("Acquired ACCESS SHARE locks on %d tables in %s", len(tables)-len(skippedTables), lockWaitDuration.Round(time.Millisecond))
+	if len(skippedTables) > 0 {
+		gplog.Warn("%d tables could not be locked and will not be backed up", len(skippedTables))
+	}
+	return lockWaitDuration, skippedTables
+}
+
+func lockTableBatch(connectionPool *dbconn.DBConn, batch string, batchTables []Relation, policy LockPolicy) []Relation {
+	if !policy.isBounded() {
+		connectionPool.MustExecContext(queryContext, fmt.Sprintf("LOCK TABLE %s IN ACCESS SHARE MODE", batch))
+		return nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		if tryLockTables(connectionPool, batch, policy) {
+			return nil
+		}
+
+		failureStr := fmt.Sprintf("Could not acquire ACCESS SHARE locks on a batch of %d tables within %s", len(batchTables), policy.WaitTimeout)
+		if policy.NoWait {
+			failureStr = fmt.Sprintf("Could not acquire ACCESS SHARE locks on a batch of %d tables without waiting", len(batchTables))
+		}
+		blockersStr := FormatLockBlockers(GetLockBlockers(connectionPool, batchTables))
+		if attempt < policy.Retries {
+			backoff := policy.RetryBackoff << uint(attempt)
+			gplog.Warn("%s. %s Retrying in %s.", failureStr, blockersStr, backoff)
+			time.Sleep(backoff)
+			continue
+		}
+		if !policy.SkipLocked {
+			gplog.Fatal(errors.Errorf("%s after %d attempt(s). %s", failureStr, attempt+1, blockersStr), "")
+		}
+
+		gplog.Warn("%s after %d attempt(s). %s Locking the tables in the batch one at a time and skipping those that cannot be locked.", failureStr, attempt+1, blockersStr)
+		skippedTables := make([]Relation, 0)
+		for _, table := range batchTables {
+			if !tryLockTables(connectionPool, table.FQN(), policy) {
+				gplog.Warn("Skipping table %s, which could not be locked", table.FQN())
+				skippedTables = append(skippedTables, table)
+			}
+		}
+		return skippedTables
+	}
+}
+
+/*
+ * Returns whether the locks were acquired. A failed LOCK TABLE aborts the
+ * backup transaction, so the attempt is made in a savepoint that is rolled
+ * back when the locks are not available.
+ */
+func tryLockTables(connectionPool *dbconn.DBConn, tableList string, policy LockPolicy) bool {
+	lockQuery := fmt.Sprintf("LOCK TABLE %s IN ACCESS SHARE MODE", tableList)
+	if policy.NoWait {
+		lockQuery += " NOWAIT"
+	}
+	connectionPool.MustExecContext(queryContext, "SAVEPOINT gpbackup_lock_tables")
+	_, err := connectionPool.ExecContext(queryContext, lockQuery)
+	if err == nil {
+		connectionPool.MustExecContext(queryContext, "RELEASE SAVEPOINT gpbackup_lock_tables")
+		return true
+	}
+	if !isLockNotAvailableError(err, policy) {
+		gplog.Fatal(err, "")
+	}
+	connectionPool.MustExecContext(queryContext, "ROLLBACK TO SAVEPOINT gpbackup_lock_tables")
+	return false
+}
+
+/*
+ * NOWAIT raises lock_not_available. statement_timeout raises query_canceled,
+ * but so does a cancel request, such as the one sent when gpbackup is
+ * interrupted, so that error is only treated as a lock timeout when a wait
+ * timeout is set and the lock query was not cancelled by gpbackup itself.
+ */
+func isLockNotAvailableError(err error, policy LockPolicy) bool {
+	pgErr, ok := err.(pgx.PgError)
+	if !ok {
+		return false
+	}
+	if pgErr.Code == "55P03" {
+		return true
+	}
+	return pgErr.Code == "57014" && policy.WaitTimeout > 0 && !wasTerminated && queryContext.Err() == nil
+}
+
+type LockBlocker struct {
+	Table    string `db:"tablename"`
+	Pid      int
+	Username string
+	Query    string
+}
+
+/*
+ * Only an ACCESS EXCLUSIVE lock conflicts with an ACCESS SHARE lock. Locks are
+ * matched to sessions by session id rather than pid so that locks held on the
+ * segments are attributed to the session that holds them on the master.
+ */
+func GetLockBlockers(connectionPool *dbconn.DBConn, tables []Relation) []LockBlocker {
+	pidColumn, queryColumn := "procpid", "current_query"
+	if connectionPool.Version.AtLeast("6") {
+		pidColumn, queryColumn = "pid", "query"
+	}
+	tableOids := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableOids = append(tableOids, fmt.Sprintf("%d", table.Oid))
+	}
+	query := fmt.Sprintf(`
+SELECT DISTINCT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS tablename,
+	a.%s AS pid,
+	coalesce(a.usename, '') AS username,
+	coalesce(a.%s, '') AS query
+FROM pg_locks l
+	JOIN pg_class c ON l.relation = c.oid
+	JOIN pg_namespace n ON c.relnamespace = n.oid
+	JOIN pg_stat_activity a ON l.mppsessionid = a.sess_id
+WHERE l.granted
+	AND l.mode = 'AccessExclusiveLock'
+	AND l.relation IN (%s)
+ORDER BY tablename, pid`, pidColumn, queryColumn, strings.Join(tableOids, ", "))
+
+	results := make([]LockBlocker, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
+
+func FormatLockBlockers(blockers []LockBlocker) string {
+	if len(blockers) == 0 {
+		return "No blocking sessions were found; they may have already released their locks."
+	}
+	blockerStrs := make([]string, 0, len(blockers))
+	for _, blocker := range blockers {
+		blockerStrs = append(blockerStrs, fmt.Sprintf("pid %d (user %s) holds an ACCESS EXCLUSIVE lock on %s: %s",
+			blocker.Pid, blocker.Username, blocker.Table, strings.TrimSpace(blocker.Query)))
+	}
+	return fmt.Sprintf("Blocking sessions: %s.", strings.Join(blockerStrs, "; "))
 }
 
 // generateTableBatches batches tables to reduce network congestion and
@@ -0,0 +1,116 @@
+package backup_test
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/jackc/pgx"
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("backup/queries_relations tests", func() {
+	Describe("LockTables", func() {
+		tables := []backup.Relation{{Oid: 1, Schema: "public", Name: "foo"}, {Oid: 2, Schema: "public", Name: "bar"}}
+		lockQuery := regexp.QuoteMeta("LOCK TABLE public.foo, public.bar IN ACCESS SHARE MODE")
+		lockNotAvailable := pgx.PgError{Code: "55P03", Message: `could not obtain lock on relation "foo"`}
+		blockerHeader := []string{"tablename", "pid", "username", "query"}
+		BeforeEach(func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+		})
+		It("waits indefinitely for locks by default", func() {
+			mock.ExpectExec(lockQuery + "$").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.LockTables(connectionPool, tables, backup.LockPolicy{})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			Expect(logfile).To(Say("Acquired ACCESS SHARE locks on 2 tables in"))
+		})
+		It("bounds the wait for each batch with a statement timeout", func() {
+			mock.ExpectExec(regexp.QuoteMeta("SET statement_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery + "$").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("RELEASE SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("SET statement_timeout = 0")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.LockTables(connectionPool, tables, backup.LockPolicy{WaitTimeout: 5 * time.Second})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("retries a batch that could not be locked and reports the blocking sessions", func() {
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery + " NOWAIT").WillReturnError(lockNotAvailable)
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta("AND l.relation IN (1, 2)")).WillReturnRows(sqlmock.NewRows(blockerHeader).AddRow("public.foo", 1234, "gpadmin", "VACUUM FULL public.foo"))
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery + " NOWAIT").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("RELEASE SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.LockTables(connectionPool, tables, backup.LockPolicy{NoWait: true, Retries: 1})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			Expect(logfile).To(Say(`Could not acquire ACCESS SHARE locks on a batch of 2 tables without waiting. Blocking sessions: pid 1234 \(user gpadmin\) holds an ACCESS EXCLUSIVE lock on public.foo: VACUUM FULL public.foo. Retrying in 0s.`))
+		})
+		It("panics with the blocking sessions when a batch cannot be locked within the timeout", func() {
+			mock.ExpectExec(regexp.QuoteMeta("SET statement_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery).WillReturnError(pgx.PgError{Code: "57014", Message: "canceling statement due to statement timeout"})
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta("a.pid AS pid")).WillReturnRows(sqlmock.NewRows(blockerHeader).AddRow("public.bar", 1234, "gpadmin", "ALTER TABLE public.bar ADD COLUMN j int"))
+
+			defer testhelper.ShouldPanicWithMessage("Could not acquire ACCESS SHARE locks on a batch of 2 tables within 5s after 1 attempt(s). Blocking sessions: pid 1234 (user gpadmin) holds an ACCESS EXCLUSIVE lock on public.bar: ALTER TABLE public.bar ADD COLUMN j int.")
+			backup.LockTables(connectionPool, tables, backup.LockPolicy{WaitTimeout: 5 * time.Second})
+		})
+		It("skips the tables of a batch that cannot be locked when skipping locked tables", func() {
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery + " NOWAIT").WillReturnError(lockNotAvailable)
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta("AND l.relation IN (1, 2)")).WillReturnRows(sqlmock.NewRows(blockerHeader).AddRow("public.foo", 1234, "gpadmin", "VACUUM FULL public.foo"))
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("LOCK TABLE public.foo IN ACCESS SHARE MODE NOWAIT")).WillReturnError(lockNotAvailable)
+			mock.ExpectExec("ROLLBACK TO SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta("LOCK TABLE public.bar IN ACCESS SHARE MODE NOWAIT")).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("RELEASE SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			_, skippedTables := backup.LockTables(connectionPool, tables, backup.LockPolicy{NoWait: true, SkipLocked: true})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			Expect(skippedTables).To(Equal([]backup.Relation{tables[0]}))
+			Expect(logfile).To(Say("Skipping table public.foo, which could not be locked"))
+		})
+		It("panics without retrying when the lock query is cancelled without a wait timeout", func() {
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery).WillReturnError(pgx.PgError{Code: "57014", Message: "canceling statement due to user request"})
+
+			defer testhelper.ShouldPanicWithMessage("canceling statement due to user request")
+			backup.LockTables(connectionPool, tables, backup.LockPolicy{NoWait: true, Retries: 3})
+		})
+		It("panics without retrying when locking fails for another reason", func() {
+			mock.ExpectExec("SAVEPOINT gpbackup_lock_tables").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(lockQuery).WillReturnError(errors.New("relation does not exist"))
+
+			defer testhelper.ShouldPanicWithMessage("relation does not exist")
+			backup.LockTables(connectionPool, tables, backup.LockPolicy{NoWait: true, Retries: 3})
+		})
+	})
+	Describe("GetLockBlockers", func() {
+		It("uses the pg_stat_activity columns of versions before 6", func() {
+			testhelper.SetDBVersion(connectionPool, "5.0.0")
+			mock.ExpectQuery(regexp.QuoteMeta("a.procpid AS pid,\n\tcoalesce(a.usename, '') AS username,\n\tcoalesce(a.current_query, '') AS query")).WillReturnRows(sqlmock.NewRows([]string{"tablename", "pid", "username", "query"}))
+
+			Expect(backup.GetLockBlockers(connectionPool, []backup.Relation{{Oid: 1, Schema: "public", Name: "foo"}})).To(BeEmpty())
+		})
+	})
+	Describe("FormatLockBlockers", func() {
+		It("says when no blocking sessions were found", func() {
+			Expect(backup.FormatLockBlockers([]backup.LockBlocker{})).To(Equal("No blocking sessions were found; they may have already released their locks."))
+		})
+	})
+})
@@ -70,6 +70,15 @@ func GetAllUserSchemas(connectionPool *dbconn.DBConn) []Schema {
 	return results
 }
 
+// Filter patterns are matched against unquoted names, like filter lists
+func GetUserSchemaNames(connectionPool *dbconn.DBConn) []string {
+	query := fmt.Sprintf(`
+	SELECT nspname AS string FROM pg_namespace n
+		WHERE %s AND %s ORDER BY nspname`,
+		SchemaFilterClause("n"), ExtensionFilterClause("n"))
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
+
 type Constraint struct {
 	Oid                uint32
 	Schema             string
@@ -57,6 +57,7 @@ type TableDefinition struct {
 	ForeignDef         ForeignTableDefinition
 	Inherits           []string
 	ReplicaIdentity    string
+	RowSecurity        string
 }
 
 /*
@@ -79,6 +80,7 @@ func ConstructDefinitionsForTables(connectionPool *dbconn.DBConn, tableRelations
 	foreignTableDefs := GetForeignTableDefinitions(connectionPool)
 	inheritanceMap := GetTableInheritance(connectionPool, tableRelations)
 	replicaIdentityMap := GetTableReplicaIdentity(connectionPool)
+	rowSecurityMap := GetTableRowSecurity(connectionPool)
 
 	gplog.Verbose("Constructing table definition map")
 	for _, tableRel := range tableRelations {
@@ -98,6 +100,7 @@ func ConstructDefinitionsForTables(connectionPool *dbconn.DBConn, tableRelations
 			ForeignDef:         foreignTableDefs[oid],
 			Inherits:           inheritanceMap[oid],
 			ReplicaIdentity:    replicaIdentityMap[oid],
+			RowSecurity:        rowSecurityMap[oid],
 		}
 		if tableDef.Inherits == nil {
 			tableDef.Inherits = []string{}
@@ -149,6 +152,125 @@ func GetPartitionTableMap(connectionPool *dbconn.DBConn) map[uint32]PartitionLev
 	return resultMap
 }
 
+/*
+ * Returns the on-disk size of each table on the segment where it is largest.
+ * A single size per table is recorded rather than one per segment, since a
+ * COPY ... ON SEGMENT takes as long as its slowest segment and the size is
+ * only used to order tables when more than one connection copies data. A
+ * parent partition table, whose data is backed up as a whole, is assigned the
+ * sum of the sizes of all of its partitions.
+ *
+ * pg_relation_size takes an AccessShareLock, so only the tables being backed
+ * up and their partitions, which are already locked, are queried.
+ */
+func GetTableSizes(connectionPool *dbconn.DBConn, tables []Table) map[uint32]int64 {
+	tableSizes := make(map[uint32]int64, len(tables))
+	if len(tables) == 0 {
+		return tableSizes
+	}
+	gplog.Verbose("Querying table sizes")
+	partitionOids := make(map[uint32][]uint32)
+	for _, table := range tables {
+		if table.PartitionLevelInfo.Level == "p" {
+			partitionOids = getPartitionOids(connectionPool)
+			break
+		}
+	}
+
+	oidList := make([]string, 0, len(tables))
+	for _, table := range tables {
+		oidList = append(oidList, fmt.Sprintf("%d", table.Oid))
+		if table.PartitionLevelInfo.Level == "p" {
+			for _, partitionOid := range partitionOids[table.Oid] {
+				oidList = append(oidList, fmt.Sprintf("%d", partitionOid))
+			}
+		}
+	}
+	query := fmt.Sprintf(`
+	SELECT oid, max(pg_relation_size(oid)) AS size
+	FROM gp_dist_random('pg_class')
+	WHERE relkind = 'r'
+		AND oid IN (%s)
+	GROUP BY oid`, strings.Join(oidList, ", "))
+
+	sizeResults := make([]struct {
+		Oid  uint32
+		Size int64
+	}, 0)
+	err := connectionPool.Select(&sizeResults, query)
+	gplog.FatalOnError(err)
+	relationSizes := make(map[uint32]int64, len(sizeResults))
+	for _, result := range sizeResults {
+		relationSizes[result.Oid] = result.Size
+	}
+
+	for _, table := range tables {
+		tableSizes[table.Oid] = relationSizes[table.Oid]
+		if table.PartitionLevelInfo.Level == "p" {
+			for _, partitionOid := range partitionOids[table.Oid] {
+				tableSizes[table.Oid] += relationSizes[partitionOid]
+			}
+		}
+	}
+	return tableSizes
+}
+
+/*
+ * Returns the number of segments spanned by each table that spans fewer
+ * segments than the cluster, such as a table that gpexpand has not yet
+ * redistributed onto the new segments. COPY ... ON SEGMENT writes the data of
+ * such a table only on the segments it spans.
+ */
+func GetTableSegmentCounts(connectionPool *dbconn.DBConn, tables []Table, clusterSegmentCount int) map[uint32]int {
+	tableSegmentCounts := make(map[uint32]int)
+	if connectionPool.Version.Before("6") {
+		return tableSegmentCounts
+	}
+	query := fmt.Sprintf(`
+	SELECT localoid AS oid, numsegments
+	FROM gp_distribution_policy
+	WHERE numsegments < %d`, clusterSegmentCount)
+
+	results := make([]struct {
+		Oid         uint32
+		NumSegments int
+	}, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	policySegmentCounts := make(map[uint32]int, len(results))
+	for _, result := range results {
+		policySegmentCounts[result.Oid] = result.NumSegments
+	}
+	for _, table := range tables {
+		if segmentCount, ok := policySegmentCounts[table.Oid]; ok {
+			tableSegmentCounts[table.Oid] = segmentCount
+		}
+	}
+	return tableSegmentCounts
+}
+
+func getPartitionOids(connectionPool *dbconn.DBConn) map[uint32][]uint32 {
+	query := `
+	SELECT p.parrelid AS rootoid,
+		r.parchildrelid AS oid
+	FROM pg_partition p
+		JOIN pg_partition_rule r ON p.oid = r.paroid
+	WHERE r.parchildrelid != 0`
+
+	results := make([]struct {
+		RootOid uint32
+		Oid     uint32
+	}, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+
+	partitionOids := make(map[uint32][]uint32)
+	for _, result := range results {
+		partitionOids[result.RootOid] = append(partitionOids[result.RootOid], result.Oid)
+	}
+	return partitionOids
+}
+
 type ColumnDefinition struct {
 	Oid                   uint32 `db:"attrelid"`
 	Num                   int    `db:"attnum"`
@@ -288,6 +410,22 @@ func GetTableReplicaIdentity(connectionPool *dbconn.DBConn) map[uint32]string {
 	return selectAsOidToStringMap(connectionPool, query)
 }
 
+/*
+ * This returns a map of tables with row-level security enabled; "e" indicates
+ * that it is enabled and "f" indicates that it is also forced for the owner.
+ */
+func GetTableRowSecurity(connectionPool *dbconn.DBConn) map[uint32]string {
+	if connectionPool.Version.Before("7") {
+		return map[uint32]string{}
+	}
+	query := `
+	SELECT oid,
+		CASE WHEN relforcerowsecurity THEN 'f' ELSE 'e' END AS value
+	FROM pg_class
+	WHERE relrowsecurity`
+	return selectAsOidToStringMap(connectionPool, query)
+}
+
 func GetPartitionDetails(connectionPool *dbconn.DBConn) (map[uint32]string, map[uint32]string) {
 	gplog.Info("Getting partition definitions")
 	query := fmt.Sprintf(`
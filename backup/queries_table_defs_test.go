@@ -0,0 +1,72 @@
+package backup_test
+
+import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/queries_table_defs tests", func() {
+	Describe("GetTableSizes", func() {
+		partitionQuery := regexp.QuoteMeta("SELECT p.parrelid AS rootoid")
+		It("returns the largest per-segment size of each table", func() {
+			sizeRows := sqlmock.NewRows([]string{"oid", "size"}).AddRow(1, 4096).AddRow(2, 8192)
+			mock.ExpectQuery(regexp.QuoteMeta("AND oid IN (1, 2)")).WillReturnRows(sizeRows)
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "foo"}},
+				{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "bar"}},
+			}
+
+			tableSizes := backup.GetTableSizes(connectionPool, tables)
+
+			Expect(tableSizes).To(Equal(map[uint32]int64{1: 4096, 2: 8192}))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("adds the sizes of all partitions to a parent partition table", func() {
+			partitionRows := sqlmock.NewRows([]string{"rootoid", "oid"}).AddRow(1, 2).AddRow(1, 3).AddRow(5, 4)
+			sizeRows := sqlmock.NewRows([]string{"oid", "size"}).AddRow(2, 4096).AddRow(3, 8192)
+			mock.ExpectQuery(partitionQuery).WillReturnRows(partitionRows)
+			mock.ExpectQuery(regexp.QuoteMeta("AND oid IN (1, 2, 3)")).WillReturnRows(sizeRows)
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "parent"}, TableDefinition: backup.TableDefinition{PartitionLevelInfo: backup.PartitionLevelInfo{Level: "p"}}},
+			}
+
+			tableSizes := backup.GetTableSizes(connectionPool, tables)
+
+			Expect(tableSizes).To(Equal(map[uint32]int64{1: 12288}))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("does not query sizes when there are no tables", func() {
+			tableSizes := backup.GetTableSizes(connectionPool, []backup.Table{})
+
+			Expect(tableSizes).To(BeEmpty())
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+	Describe("GetTableSegmentCounts", func() {
+		It("returns the segment count of each backed up table that does not span the cluster", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			countRows := sqlmock.NewRows([]string{"oid", "numsegments"}).AddRow(1, 2).AddRow(3, 3)
+			mock.ExpectQuery(regexp.QuoteMeta("WHERE numsegments < 4")).WillReturnRows(countRows)
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "foo"}},
+				{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "bar"}},
+			}
+
+			tableSegmentCounts := backup.GetTableSegmentCounts(connectionPool, tables, 4)
+
+			Expect(tableSegmentCounts).To(Equal(map[uint32]int{1: 2}))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("returns no segment counts before GPDB 6", func() {
+			testhelper.SetDBVersion(connectionPool, "5.0.0")
+
+			Expect(backup.GetTableSegmentCounts(connectionPool, []backup.Table{}, 4)).To(BeEmpty())
+		})
+	})
+})
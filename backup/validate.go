@@ -100,19 +100,76 @@ func DBValidate(conn *dbconn.DBConn, tableList []string, excludeSet bool) {
 func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	options.CheckExclusiveFlags(flags, options.DEBUG, options.QUIET, options.VERBOSE)
 	options.CheckExclusiveFlags(flags, options.DATA_ONLY, options.METADATA_ONLY, options.INCREMENTAL)
-	options.CheckExclusiveFlags(flags, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_FILE, options.INCLUDE_RELATION, options.INCLUDE_RELATION_FILE)
-	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_FILE, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_FILE)
-	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_FILE, options.EXCLUDE_RELATION, options.INCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.INCLUDE_RELATION_FILE)
-	options.CheckExclusiveFlags(flags, options.EXCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.LEAF_PARTITION_DATA)
+	options.CheckExclusiveFlags(flags, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_FILE, options.INCLUDE_SCHEMA_PATTERN, options.INCLUDE_RELATION, options.INCLUDE_RELATION_FILE, options.INCLUDE_RELATION_PATTERN)
+	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_FILE, options.EXCLUDE_SCHEMA_PATTERN, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_FILE, options.INCLUDE_SCHEMA_PATTERN)
+	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_FILE, options.EXCLUDE_SCHEMA_PATTERN, options.EXCLUDE_RELATION, options.INCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.INCLUDE_RELATION_FILE, options.EXCLUDE_RELATION_PATTERN, options.INCLUDE_RELATION_PATTERN)
+	options.CheckExclusiveFlags(flags, options.EXCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.EXCLUDE_RELATION_PATTERN, options.LEAF_PARTITION_DATA)
 	options.CheckExclusiveFlags(flags, options.JOBS, options.METADATA_ONLY, options.SINGLE_DATA_FILE)
 	options.CheckExclusiveFlags(flags, options.METADATA_ONLY, options.LEAF_PARTITION_DATA)
 	options.CheckExclusiveFlags(flags, options.NO_COMPRESSION, options.COMPRESSION_LEVEL)
 	options.CheckExclusiveFlags(flags, options.PLUGIN_CONFIG, options.BACKUP_DIR)
+	options.CheckExclusiveFlags(flags, options.ENCRYPTION_KEY_FILE, options.ENCRYPTION_KEY_COMMAND)
+	options.CheckExclusiveFlags(flags, options.LOCK_NOWAIT, options.LOCK_WAIT_TIMEOUT)
+	if MustGetFlagBool(options.ALL_DATABASES) && flags.Changed(options.DBNAME) {
+		gplog.Fatal(errors.Errorf("The following flags may not be specified together: %s, %s", options.DBNAME, options.ALL_DATABASES), "")
+	}
+	if len(MustGetFlagStringArray(options.DBNAME)) == 0 && !MustGetFlagBool(options.ALL_DATABASES) {
+		gplog.Fatal(errors.Errorf("Either --dbname or --all-databases must be specified"), "")
+	}
+	if IsClusterBackup() && MustGetFlagString(options.FROM_TIMESTAMP) != "" {
+		gplog.Fatal(errors.Errorf("--from-timestamp cannot be specified when backing up more than one database"), "")
+	}
 	if MustGetFlagString(options.FROM_TIMESTAMP) != "" && !MustGetFlagBool(options.INCREMENTAL) {
 		gplog.Fatal(errors.Errorf("--from-timestamp must be specified with --incremental"), "")
 	}
-	if MustGetFlagBool(options.INCREMENTAL) && !MustGetFlagBool(options.LEAF_PARTITION_DATA) {
-		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --incremental"), "")
+	if flags.Changed(options.ON_BROKEN_CHAIN) && !MustGetFlagBool(options.INCREMENTAL) {
+		gplog.Fatal(errors.Errorf("--on-broken-chain must be specified with --incremental"), "")
+	}
+	if (flags.Changed(options.LOCK_RETRIES) || flags.Changed(options.LOCK_RETRY_BACKOFF)) &&
+		MustGetFlagInt(options.LOCK_WAIT_TIMEOUT) == 0 && !MustGetFlagBool(options.LOCK_NOWAIT) {
+		gplog.Fatal(errors.Errorf("--lock-retries and --lock-retry-backoff must be specified with --lock-wait-timeout or --lock-nowait"), "")
+	}
+	if MustGetFlagBool(options.SKIP_LOCKED_TABLES) && MustGetFlagInt(options.LOCK_WAIT_TIMEOUT) == 0 && !MustGetFlagBool(options.LOCK_NOWAIT) {
+		gplog.Fatal(errors.Errorf("--skip-locked-tables must be specified with --lock-wait-timeout or --lock-nowait"), "")
+	}
+	if MustGetFlagBool(options.INCLUDE_DEPENDENCIES) && !flags.Changed(options.INCLUDE_RELATION) &&
+		!flags.Changed(options.INCLUDE_RELATION_FILE) && !flags.Changed(options.INCLUDE_RELATION_PATTERN) {
+		gplog.Fatal(errors.Errorf("--include-dependencies must be specified with --include-table, --include-table-file, or --include-table-pattern"), "")
+	}
+	if MustGetFlagBool(options.WITH_STATS) && !shouldBackupObjectType("STATISTICS") {
+		gplog.Fatal(errors.Errorf("--with-stats cannot be used when STATISTICS objects are not backed up"), "")
+	}
+}
+
+/*
+ * Filter patterns are resolved against the catalog before any other filter
+ * processing, and the matching names are added to the corresponding filter
+ * flags so that the rest of the backup treats them as if they had been
+ * passed individually.
+ */
+func ResolveFilterPatterns() {
+	includeSchemaPatterns := MustGetFlagStringArray(options.INCLUDE_SCHEMA_PATTERN)
+	excludeSchemaPatterns := MustGetFlagStringArray(options.EXCLUDE_SCHEMA_PATTERN)
+	if len(includeSchemaPatterns) > 0 || len(excludeSchemaPatterns) > 0 {
+		schemas := GetUserSchemaNames(connectionPool)
+		includeSchemas, err := options.MatchSchemaPatterns(includeSchemaPatterns, schemas)
+		gplog.FatalOnError(err)
+		excludeSchemas, err := options.MatchSchemaPatterns(excludeSchemaPatterns, schemas)
+		gplog.FatalOnError(err)
+		options.AddFilterPatternMatches(cmdFlags, options.INCLUDE_SCHEMA_PATTERN, options.INCLUDE_SCHEMA, includeSchemas)
+		options.AddFilterPatternMatches(cmdFlags, options.EXCLUDE_SCHEMA_PATTERN, options.EXCLUDE_SCHEMA, excludeSchemas)
+	}
+
+	includeRelationPatterns := MustGetFlagStringArray(options.INCLUDE_RELATION_PATTERN)
+	excludeRelationPatterns := MustGetFlagStringArray(options.EXCLUDE_RELATION_PATTERN)
+	if len(includeRelationPatterns) > 0 || len(excludeRelationPatterns) > 0 {
+		tables := GetUserTableNames(connectionPool)
+		includeRelations, err := options.MatchRelationPatterns(includeRelationPatterns, tables)
+		gplog.FatalOnError(err)
+		excludeRelations, err := options.MatchRelationPatterns(excludeRelationPatterns, tables)
+		gplog.FatalOnError(err)
+		options.AddFilterPatternMatches(cmdFlags, options.INCLUDE_RELATION_PATTERN, options.INCLUDE_RELATION, includeRelations)
+		options.AddFilterPatternMatches(cmdFlags, options.EXCLUDE_RELATION_PATTERN, options.EXCLUDE_RELATION, excludeRelations)
 	}
 }
 
@@ -121,7 +178,11 @@ func ValidateFlagValues() {
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(options.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
+	err = utils.ValidateFullPath(MustGetFlagString(options.ENCRYPTION_KEY_FILE))
+	gplog.FatalOnError(err)
 	ValidateCompressionLevel(MustGetFlagInt(options.COMPRESSION_LEVEL))
+	ValidateBrokenChainAction(MustGetFlagString(options.ON_BROKEN_CHAIN))
+	ValidateLockPolicy(GetLockPolicy())
 	if MustGetFlagString(options.FROM_TIMESTAMP) != "" && !filepath.IsValidTimestamp(MustGetFlagString(options.FROM_TIMESTAMP)) {
 		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
 			MustGetFlagString(options.FROM_TIMESTAMP)), "")
@@ -134,6 +195,12 @@ func ValidateCompressionLevel(compressionLevel int) {
 	}
 }
 
+func ValidateLockPolicy(policy LockPolicy) {
+	if policy.WaitTimeout < 0 || policy.Retries < 0 || policy.RetryBackoff < 0 {
+		gplog.Fatal(errors.Errorf("--lock-wait-timeout, --lock-retries, and --lock-retry-backoff cannot be negative"), "")
+	}
+}
+
 func ValidateFromTimestamp(fromTimestamp string) {
 	fromTimestampFPInfo := filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
 		fromTimestamp, globalFPInfo.UserSpecifiedSegPrefix)
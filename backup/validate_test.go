@@ -1,12 +1,15 @@
 package backup_test
 
 import (
+	"time"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/options"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("backup/validate tests", func() {
@@ -177,6 +180,101 @@ var _ = Describe("backup/validate tests", func() {
 			})
 		})
 	})
+	Describe("ValidateFlagCombinations", func() {
+		BeforeEach(func() {
+			_ = cmdFlags.Set(options.DBNAME, "testdb")
+		})
+		It("panics when statistics are requested but the STATISTICS object type is excluded", func() {
+			_ = cmdFlags.Set(options.WITH_STATS, "true")
+			_ = cmdFlags.Set(options.EXCLUDE_OBJECT_TYPE, "STATISTICS")
+			defer testhelper.ShouldPanicWithMessage("--with-stats cannot be used when STATISTICS objects are not backed up")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("panics when --include-dependencies is used without a table filter", func() {
+			_ = cmdFlags.Set(options.INCLUDE_DEPENDENCIES, "true")
+			defer testhelper.ShouldPanicWithMessage("--include-dependencies must be specified with --include-table, --include-table-file, or --include-table-pattern")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("passes when --include-dependencies is used with --include-table", func() {
+			_ = cmdFlags.Set(options.INCLUDE_DEPENDENCIES, "true")
+			_ = cmdFlags.Set(options.INCLUDE_RELATION, "public.foo")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("panics when --lock-retries is used without a bound on the lock wait", func() {
+			_ = cmdFlags.Set(options.LOCK_RETRIES, "3")
+			defer testhelper.ShouldPanicWithMessage("--lock-retries and --lock-retry-backoff must be specified with --lock-wait-timeout or --lock-nowait")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("panics when --skip-locked-tables is used without a bound on the lock wait", func() {
+			_ = cmdFlags.Set(options.SKIP_LOCKED_TABLES, "true")
+			defer testhelper.ShouldPanicWithMessage("--skip-locked-tables must be specified with --lock-wait-timeout or --lock-nowait")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("passes when --lock-retries is used with --lock-wait-timeout", func() {
+			_ = cmdFlags.Set(options.LOCK_RETRIES, "3")
+			_ = cmdFlags.Set(options.LOCK_WAIT_TIMEOUT, "60")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("passes when --dbname is specified multiple times", func() {
+			_ = cmdFlags.Set(options.DBNAME, "otherdb")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("panics when --dbname is used with --all-databases", func() {
+			_ = cmdFlags.Set(options.ALL_DATABASES, "true")
+			defer testhelper.ShouldPanicWithMessage("The following flags may not be specified together: dbname, all-databases")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("panics when --from-timestamp is used when backing up more than one database", func() {
+			_ = cmdFlags.Set(options.DBNAME, "otherdb")
+			_ = cmdFlags.Set(options.INCREMENTAL, "true")
+			_ = cmdFlags.Set(options.FROM_TIMESTAMP, "20170101010101")
+			defer testhelper.ShouldPanicWithMessage("--from-timestamp cannot be specified when backing up more than one database")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+	})
+	Describe("ValidateFlagCombinations without a database", func() {
+		It("panics when neither --dbname nor --all-databases is used", func() {
+			defer testhelper.ShouldPanicWithMessage("Either --dbname or --all-databases must be specified")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+		It("passes when --all-databases is used", func() {
+			_ = cmdFlags.Set(options.ALL_DATABASES, "true")
+			backup.ValidateFlagCombinations(cmdFlags)
+		})
+	})
+	Describe("ValidateLockPolicy", func() {
+		It("panics on a negative lock wait timeout", func() {
+			defer testhelper.ShouldPanicWithMessage("--lock-wait-timeout, --lock-retries, and --lock-retry-backoff cannot be negative")
+			backup.ValidateLockPolicy(backup.LockPolicy{WaitTimeout: -time.Second})
+		})
+	})
+	Describe("ResolveFilterPatterns", func() {
+		It("adds the tables in the database that match table patterns to the table filters", func() {
+			tableRows := sqlmock.NewRows([]string{"schemaname", "tablename"}).
+				AddRow("etl", "final").AddRow("etl", "stage_1").AddRow("etl", "stage_2")
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(tableRows)
+			_ = cmdFlags.Set(options.INCLUDE_RELATION_PATTERN, "etl.stage_*")
+
+			backup.ResolveFilterPatterns()
+
+			Expect(backup.MustGetFlagStringArray(options.INCLUDE_RELATION)).To(Equal([]string{"etl.stage_1", "etl.stage_2"}))
+		})
+		It("adds the schemas in the database that match schema patterns to the schema filters", func() {
+			schemaRows := sqlmock.NewRows([]string{"string"}).
+				AddRow("etl").AddRow("etl_old").AddRow("public")
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(schemaRows)
+			_ = cmdFlags.Set(options.EXCLUDE_SCHEMA_PATTERN, "/etl.*/")
+
+			backup.ResolveFilterPatterns()
+
+			Expect(backup.MustGetFlagStringArray(options.EXCLUDE_SCHEMA)).To(Equal([]string{"etl", "etl_old"}))
+		})
+		It("does not query the database when no patterns are given", func() {
+			backup.ResolveFilterPatterns()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
 	Describe("ValidateCompressionLevel", func() {
 		It("validates a compression level between 1 and 9", func() {
 			compressLevel := 5
@@ -5,13 +5,17 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/history"
 	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/report"
+	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/nightlyone/lockfile"
 	"github.com/pkg/errors"
 	"reflect"
+	"strings"
+	"time"
 )
 
 /*
@@ -36,7 +40,7 @@ func SetLoggerVerbosity() {
 }
 
 func InitializeConnectionPool() {
-	connectionPool = dbconn.NewDBConnFromEnvironment(MustGetFlagString(options.DBNAME))
+	connectionPool = dbconn.NewDBConnFromEnvironment(MustGetFlagStringArray(options.DBNAME)[0])
 	connectionPool.MustConnect(MustGetFlagInt(options.JOBS))
 	utils.ValidateGPDBVersionCompatibility(connectionPool)
 	InitializeMetadataParams(connectionPool)
@@ -45,6 +49,30 @@ func InitializeConnectionPool() {
 		connectionPool.MustBegin(connNum)
 		SetSessionGUCs(connNum)
 	}
+	SynchronizeSnapshots()
+}
+
+/*
+ * Each connection otherwise takes its snapshot when it runs its first query,
+ * so tables created or changed between those queries could be backed up
+ * inconsistently. On versions that support exporting distributed snapshots,
+ * every connection instead imports the snapshot of the first connection.
+ */
+func SynchronizeSnapshots() {
+	if !connectionPool.Version.AtLeast("6.21.0") {
+		gplog.Verbose("Exported snapshots are not supported by this version of GPDB; each connection will use its own snapshot")
+		return
+	}
+	backupSnapshotID = dbconn.MustSelectString(connectionPool, "SELECT pg_catalog.pg_export_snapshot() AS string", 0)
+	exportedSnapshot := dbconn.MustSelectString(connectionPool, "SELECT pg_catalog.txid_current_snapshot()::text AS string", 0)
+	for connNum := 1; connNum < connectionPool.NumConns; connNum++ {
+		connectionPool.MustExec(fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", backupSnapshotID), connNum)
+		importedSnapshot := dbconn.MustSelectString(connectionPool, "SELECT pg_catalog.txid_current_snapshot()::text AS string", connNum)
+		if importedSnapshot != exportedSnapshot {
+			gplog.Fatal(errors.Errorf("Connection %d is using snapshot %s instead of exported snapshot %s (%s)", connNum, importedSnapshot, backupSnapshotID, exportedSnapshot), "")
+		}
+	}
+	gplog.Verbose("Using exported snapshot %s on all %d connections", backupSnapshotID, connectionPool.NumConns)
 }
 
 func SetSessionGUCs(connNum int) {
@@ -76,14 +104,19 @@ func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plug
 	backupConfig := history.BackupConfig{
 		BackupDir:             MustGetFlagString(options.BACKUP_DIR),
 		BackupVersion:         backupVersion,
+		ClusterTimestamp:      MustGetFlagString(options.CLUSTER_TIMESTAMP),
 		Compressed:            !MustGetFlagBool(options.NO_COMPRESSION),
 		DatabaseName:          dbName,
 		DatabaseVersion:       dbVersion,
 		DataOnly:              MustGetFlagBool(options.DATA_ONLY),
+		EffectiveConfig:       effectiveConfig,
+		ExcludeObjectTypes:    opts.GetExcludedObjectTypes(),
 		ExcludeRelations:      MustGetFlagStringArray(options.EXCLUDE_RELATION),
 		ExcludeSchemaFiltered: len(MustGetFlagStringArray(options.EXCLUDE_SCHEMA)) > 0,
 		ExcludeSchemas:        MustGetFlagStringArray(options.EXCLUDE_SCHEMA),
 		ExcludeTableFiltered:  len(MustGetFlagStringArray(options.EXCLUDE_RELATION)) > 0,
+		IncludeDependencies:   MustGetFlagBool(options.INCLUDE_DEPENDENCIES),
+		IncludeObjectTypes:    opts.GetIncludedObjectTypes(),
 		IncludeRelations:      opts.GetOriginalIncludedTables(),
 		IncludeSchemaFiltered: len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) > 0,
 		IncludeSchemas:        MustGetFlagStringArray(options.INCLUDE_SCHEMA),
@@ -91,8 +124,10 @@ func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plug
 		Incremental:           MustGetFlagBool(options.INCREMENTAL),
 		LeafPartitionData:     MustGetFlagBool(options.LEAF_PARTITION_DATA),
 		MetadataOnly:          MustGetFlagBool(options.METADATA_ONLY),
+		NoClusterGlobals:      MustGetFlagBool(options.NO_CLUSTER_GLOBALS),
 		Plugin:                plugin,
 		SingleDataFile:        MustGetFlagBool(options.SINGLE_DATA_FILE),
+		StructuredMetadata:    MustGetFlagBool(options.STRUCTURED_METADATA),
 		Timestamp:             timestamp,
 		WithStatistics:        MustGetFlagBool(options.WITH_STATS),
 	}
@@ -108,6 +143,9 @@ func InitializeBackupReport(opts options.Options) {
 	}
 	config := NewBackupConfig(escapedDBName, connectionPool.Version.VersionString, version,
 		plugin, globalFPInfo.Timestamp, opts)
+	// The master is included in the cluster's content IDs as content -1
+	config.SegmentCount = len(globalCluster.ContentIDs) - 1
+	config.SnapshotID = backupSnapshotID
 
 	isFilteredBackup := config.IncludeTableFiltered || config.IncludeSchemaFiltered ||
 		config.ExcludeTableFiltered || config.ExcludeSchemaFiltered
@@ -150,12 +188,89 @@ func CreateBackupDirectoriesOnAllHosts() {
  * Metadata retrieval wrapper functions
  */
 
+/*
+ * The key is copied to the segments so that the data written by COPY and by
+ * gpbackup_helper can be encrypted there, which means gpbackup_helper is
+ * needed on the segments even without --single-data-file. Files on the
+ * coordinator are encrypted as they are written, except for the config and
+ * report files, which are needed to find out which key a backup was
+ * encrypted with.
+ */
+func InitializeEncryption() {
+	keyFile := MustGetFlagString(options.ENCRYPTION_KEY_FILE)
+	keyCommand := MustGetFlagString(options.ENCRYPTION_KEY_COMMAND)
+	if keyFile == "" && keyCommand == "" {
+		return
+	}
+	var err error
+	encryptionKey, err = utils.ReadEncryptionKey(keyFile, keyCommand, "")
+	gplog.FatalOnError(err)
+	decryptedFiles = utils.NewDecryptedFiles(encryptionKey)
+	gplog.Info("Backup files will be encrypted with key %s", encryptionKey.ID)
+	if !MustGetFlagBool(options.METADATA_ONLY) {
+		utils.VerifyHelperVersionOnSegments(version, globalCluster)
+		utils.WriteEncryptionKeyToSegments(encryptionKey, globalCluster, globalFPInfo)
+		utils.AddEncryptionToPipeThroughProgram(globalFPInfo.GetSegmentHelperFilePathForCopyCommand("key"))
+	}
+}
+
+func GetLockPolicy() LockPolicy {
+	return LockPolicy{
+		WaitTimeout:  time.Duration(MustGetFlagInt(options.LOCK_WAIT_TIMEOUT)) * time.Second,
+		NoWait:       MustGetFlagBool(options.LOCK_NOWAIT),
+		Retries:      MustGetFlagInt(options.LOCK_RETRIES),
+		RetryBackoff: time.Duration(MustGetFlagInt(options.LOCK_RETRY_BACKOFF)) * time.Second,
+		SkipLocked:   MustGetFlagBool(options.SKIP_LOCKED_TABLES),
+	}
+}
+
+/*
+ * Tables that could not be locked are left out of the rest of the backup by
+ * adding them to the relation filter, and are recorded in the report and TOC
+ * so that gprestore can warn that the backup is partial.
+ */
+func ExcludeSkippedTables(tableRelations []Relation, skippedTables []Relation) []Relation {
+	skippedOids := make(map[uint32]bool, len(skippedTables))
+	skippedOidStrs := make([]string, 0, len(skippedTables))
+	for _, table := range skippedTables {
+		skippedOids[table.Oid] = true
+		skippedOidStrs = append(skippedOidStrs, fmt.Sprintf("%d", table.Oid))
+		backupReport.SkippedTables = append(backupReport.SkippedTables, table.FQN())
+	}
+	globalTOC.SkippedTables = backupReport.SkippedTables
+	filterRelationClause = relationAndSchemaFilterClause() + fmt.Sprintf("\nAND c.oid NOT IN (%s)", strings.Join(skippedOidStrs, ", "))
+
+	lockedTables := make([]Relation, 0, len(tableRelations)-len(skippedTables))
+	for _, table := range tableRelations {
+		if !skippedOids[table.Oid] {
+			lockedTables = append(lockedTables, table)
+		}
+	}
+	return lockedTables
+}
+
+func WriteSkippedTablesFile() {
+	skippedTablesFilename := globalFPInfo.GetSkippedTablesFilePath()
+	gplog.Warn("Tables that were not backed up because they could not be locked are listed in %s", skippedTablesFilename)
+	skippedTablesFile := utils.NewEncryptedFileWithByteCountFromFile(skippedTablesFilename, encryptionKey)
+	for _, fqn := range backupReport.SkippedTables {
+		skippedTablesFile.MustPrintf("%s\n", fqn)
+	}
+	skippedTablesFile.Close()
+	err := operating.System.Chmod(skippedTablesFilename, 0444)
+	gplog.FatalOnError(err)
+}
+
 func RetrieveAndProcessTables() ([]Table, []Table) {
 	quotedIncludeRelations, err := options.QuoteTableNames(connectionPool, MustGetFlagStringArray(options.INCLUDE_RELATION))
 	gplog.FatalOnError(err)
 
 	tableRelations := GetIncludedUserTableRelations(connectionPool, quotedIncludeRelations)
-	LockTables(connectionPool, tableRelations)
+	lockWaitDuration, skippedTables := LockTables(connectionPool, tableRelations, GetLockPolicy())
+	backupReport.LockWaitDuration = lockWaitDuration
+	if len(skippedTables) > 0 {
+		tableRelations = ExcludeSkippedTables(tableRelations, skippedTables)
+	}
 
 	if connectionPool.Version.AtLeast("6") {
 		tableRelations = append(tableRelations, GetForeignTableRelations(connectionPool)...)
@@ -171,7 +286,7 @@ func RetrieveAndProcessTables() ([]Table, []Table) {
 
 func RetrieveFunctions(sortables *[]Sortable, metadataMap MetadataMap, procLangs []ProceduralLanguage) ([]Function, MetadataMap) {
 	gplog.Verbose("Retrieving function information")
-	functions := GetFunctionsAllVersions(connectionPool)
+	functions := FilterIncludedDependencies(GetFunctionsAllVersions(connectionPool)).([]Function)
 	objectCounts["Functions"] = len(functions)
 	functionMetadata := GetMetadataForObjectType(connectionPool, TYPE_FUNCTION)
 	langFuncs, otherFuncs := ExtractLanguageFunctions(functions, procLangs)
@@ -184,19 +299,22 @@ func RetrieveFunctions(sortables *[]Sortable, metadataMap MetadataMap, procLangs
 
 func RetrieveAndBackupTypes(metadataFile *utils.FileWithByteCount, sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving type information")
-	shells := GetShellTypes(connectionPool)
-	bases := GetBaseTypes(connectionPool)
-	composites := GetCompositeTypes(connectionPool)
-	domains := GetDomainTypes(connectionPool)
+	shells := FilterIncludedDependencies(GetShellTypes(connectionPool)).([]ShellType)
+	bases := FilterIncludedDependencies(GetBaseTypes(connectionPool)).([]BaseType)
+	composites := FilterIncludedDependencies(GetCompositeTypes(connectionPool)).([]CompositeType)
+	domains := FilterIncludedDependencies(GetDomainTypes(connectionPool)).([]Domain)
 	rangeTypes := make([]RangeType, 0)
 	if connectionPool.Version.AtLeast("6") {
-		rangeTypes = GetRangeTypes(connectionPool)
+		rangeTypes = FilterIncludedDependencies(GetRangeTypes(connectionPool)).([]RangeType)
 	}
 	typeMetadata := GetMetadataForObjectType(connectionPool, TYPE_TYPE)
 
-	BackupShellTypes(metadataFile, shells, bases, rangeTypes)
-	if connectionPool.Version.AtLeast("5") {
-		BackupEnumTypes(metadataFile, typeMetadata)
+	// Shell and enum types are printed here rather than sorted, so they are not filtered with the sortables
+	if shouldBackupObjectType("TYPE") {
+		BackupShellTypes(metadataFile, shells, bases, rangeTypes)
+		if connectionPool.Version.AtLeast("5") {
+			BackupEnumTypes(metadataFile, typeMetadata)
+		}
 	}
 
 	objectCounts["Types"] += len(shells)
@@ -227,7 +345,7 @@ func RetrieveSequences() ([]Sequence, map[string]string) {
 
 func RetrieveProtocols(sortables *[]Sortable, metadataMap MetadataMap) []ExternalProtocol {
 	gplog.Verbose("Retrieving protocols")
-	protocols := GetExternalProtocols(connectionPool)
+	protocols := FilterIncludedDependencies(GetExternalProtocols(connectionPool)).([]ExternalProtocol)
 	objectCounts["Protocols"] = len(protocols)
 	protoMetadata := GetMetadataForObjectType(connectionPool, TYPE_PROTOCOL)
 
@@ -248,7 +366,7 @@ func RetrieveViews(sortables *[]Sortable) {
 
 func RetrieveTSParsers(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving Text Search Parsers")
-	parsers := GetTextSearchParsers(connectionPool)
+	parsers := FilterIncludedDependencies(GetTextSearchParsers(connectionPool)).([]TextSearchParser)
 	objectCounts["Text Search Parsers"] = len(parsers)
 	parserMetadata := GetCommentsForObjectType(connectionPool, TYPE_TSPARSER)
 
@@ -258,7 +376,7 @@ func RetrieveTSParsers(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveTSTemplates(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving TEXT SEARCH TEMPLATE information")
-	templates := GetTextSearchTemplates(connectionPool)
+	templates := FilterIncludedDependencies(GetTextSearchTemplates(connectionPool)).([]TextSearchTemplate)
 	objectCounts["Text Search Templates"] = len(templates)
 	templateMetadata := GetCommentsForObjectType(connectionPool, TYPE_TSTEMPLATE)
 
@@ -268,7 +386,7 @@ func RetrieveTSTemplates(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveTSDictionaries(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving TEXT SEARCH DICTIONARY information")
-	dictionaries := GetTextSearchDictionaries(connectionPool)
+	dictionaries := FilterIncludedDependencies(GetTextSearchDictionaries(connectionPool)).([]TextSearchDictionary)
 	objectCounts["Text Search Dictionaries"] = len(dictionaries)
 	dictionaryMetadata := GetMetadataForObjectType(connectionPool, TYPE_TSDICTIONARY)
 
@@ -278,7 +396,7 @@ func RetrieveTSDictionaries(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveTSConfigurations(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving TEXT SEARCH CONFIGURATION information")
-	configurations := GetTextSearchConfigurations(connectionPool)
+	configurations := FilterIncludedDependencies(GetTextSearchConfigurations(connectionPool)).([]TextSearchConfiguration)
 	objectCounts["Text Search Configurations"] = len(configurations)
 	configurationMetadata := GetMetadataForObjectType(connectionPool, TYPE_TSCONFIGURATION)
 
@@ -288,7 +406,7 @@ func RetrieveTSConfigurations(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveOperators(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving OPERATOR information")
-	operators := GetOperators(connectionPool)
+	operators := FilterIncludedDependencies(GetOperators(connectionPool)).([]Operator)
 	objectCounts["Operators"] = len(operators)
 	operatorMetadata := GetMetadataForObjectType(connectionPool, TYPE_OPERATOR)
 
@@ -298,7 +416,7 @@ func RetrieveOperators(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveOperatorClasses(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving OPERATOR CLASS information")
-	operatorClasses := GetOperatorClasses(connectionPool)
+	operatorClasses := FilterIncludedDependencies(GetOperatorClasses(connectionPool)).([]OperatorClass)
 	objectCounts["Operator Classes"] = len(operatorClasses)
 	operatorClassMetadata := GetMetadataForObjectType(connectionPool, TYPE_OPERATORCLASS)
 
@@ -308,7 +426,7 @@ func RetrieveOperatorClasses(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveAggregates(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving AGGREGATE information")
-	aggregates := GetAggregates(connectionPool)
+	aggregates := FilterIncludedDependencies(GetAggregates(connectionPool)).([]Aggregate)
 	objectCounts["Aggregates"] = len(aggregates)
 	/* This call to get Metadata for Aggregates, although redundant, is preserved for
 	 * consistency with other, similar methods.  The metadata for aggregate
@@ -323,7 +441,7 @@ func RetrieveAggregates(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveCasts(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Retrieving CAST information")
-	casts := GetCasts(connectionPool)
+	casts := FilterIncludedDependencies(GetCasts(connectionPool)).([]Cast)
 	objectCounts["Casts"] = len(casts)
 	castMetadata := GetCommentsForObjectType(connectionPool, TYPE_CAST)
 
@@ -333,7 +451,7 @@ func RetrieveCasts(sortables *[]Sortable, metadataMap MetadataMap) {
 
 func RetrieveForeignDataWrappers(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Writing CREATE FOREIGN DATA WRAPPER statements to metadata file")
-	wrappers := GetForeignDataWrappers(connectionPool)
+	wrappers := FilterIncludedDependencies(GetForeignDataWrappers(connectionPool)).([]ForeignDataWrapper)
 	objectCounts["Foreign Data Wrappers"] = len(wrappers)
 	fdwMetadata := GetMetadataForObjectType(connectionPool, TYPE_FOREIGNDATAWRAPPER)
 
@@ -343,7 +461,7 @@ func RetrieveForeignDataWrappers(sortables *[]Sortable, metadataMap MetadataMap)
 
 func RetrieveForeignServers(sortables *[]Sortable, metadataMap MetadataMap) {
 	gplog.Verbose("Writing CREATE SERVER statements to metadata file")
-	servers := GetForeignServers(connectionPool)
+	servers := FilterIncludedDependencies(GetForeignServers(connectionPool)).([]ForeignServer)
 	objectCounts["Foreign Servers"] = len(servers)
 	serverMetadata := GetMetadataForObjectType(connectionPool, TYPE_FOREIGNSERVER)
 
@@ -351,9 +469,19 @@ func RetrieveForeignServers(sortables *[]Sortable, metadataMap MetadataMap) {
 	addToMetadataMap(serverMetadata, metadataMap)
 }
 
+func RetrieveAccessMethods(sortables *[]Sortable, metadataMap MetadataMap) {
+	gplog.Verbose("Retrieving ACCESS METHOD information")
+	accessMethods := FilterIncludedDependencies(GetAccessMethods(connectionPool)).([]AccessMethod)
+	objectCounts["Access Methods"] = len(accessMethods)
+	accessMethodMetadata := GetCommentsForObjectType(connectionPool, TYPE_ACCESSMETHOD)
+
+	*sortables = append(*sortables, convertToSortableSlice(accessMethods)...)
+	addToMetadataMap(accessMethodMetadata, metadataMap)
+}
+
 func RetrieveUserMappings(sortables *[]Sortable) {
 	gplog.Verbose("Writing CREATE USER MAPPING statements to metadata file")
-	mappings := GetUserMappings(connectionPool)
+	mappings := FilterIncludedDependencies(GetUserMappings(connectionPool)).([]UserMapping)
 	objectCounts["User Mappings"] = len(mappings)
 	// No comments, owners, or ACLs on UserMappings so no need to get metadata
 
@@ -436,7 +564,7 @@ func BackupRoleGrants(metadataFile *utils.FileWithByteCount) {
 
 func BackupSchemas(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing CREATE SCHEMA statements to metadata file")
-	schemas := GetAllUserSchemas(connectionPool)
+	schemas := FilterIncludedDependencies(GetAllUserSchemas(connectionPool)).([]Schema)
 	objectCounts["Schemas"] = len(schemas)
 	schemaMetadata := GetMetadataForObjectType(connectionPool, TYPE_SCHEMA)
 	PrintCreateSchemaStatements(metadataFile, globalTOC, schemas, schemaMetadata)
@@ -459,7 +587,7 @@ func BackupShellTypes(metadataFile *utils.FileWithByteCount, shellTypes []ShellT
 
 func BackupEnumTypes(metadataFile *utils.FileWithByteCount, typeMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE TYPE statements for enum types to metadata file")
-	enums := GetEnumTypes(connectionPool)
+	enums := FilterIncludedDependencies(GetEnumTypes(connectionPool)).([]EnumType)
 	objectCounts["Types"] += len(enums)
 	PrintCreateEnumTypeStatements(metadataFile, globalTOC, enums, typeMetadata)
 }
@@ -479,6 +607,55 @@ func createBackupSet(objSlice []Sortable) (backupSet map[UniqueID]bool) {
 	return backupSet
 }
 
+func shouldBackupObjectType(objectType string) bool {
+	return options.ObjectTypeMatchesFilter(MustGetFlagStringArray(options.INCLUDE_OBJECT_TYPE),
+		MustGetFlagStringArray(options.EXCLUDE_OBJECT_TYPE), objectType)
+}
+
+/*
+ * Some objects of different types are retrieved together, such as views and
+ * materialized views, so objects are also filtered by the type of their TOC
+ * entry before they are sorted and printed.
+ */
+func FilterSortablesByObjectType(sortables []Sortable) []Sortable {
+	if len(MustGetFlagStringArray(options.INCLUDE_OBJECT_TYPE)) == 0 && len(MustGetFlagStringArray(options.EXCLUDE_OBJECT_TYPE)) == 0 {
+		return sortables
+	}
+	filtered := make([]Sortable, 0, len(sortables))
+	for _, obj := range sortables {
+		if tocObj, ok := obj.(toc.TOCObject); ok {
+			_, entry := tocObj.GetMetadataEntry()
+			if !shouldBackupObjectType(entry.ObjectType) {
+				continue
+			}
+		}
+		filtered = append(filtered, obj)
+	}
+	return filtered
+}
+
+/*
+ * With --include-dependencies, objects other than relations are only backed up
+ * if the included tables depend on them. Relations are already limited to the
+ * included tables and the relations they depend on by the relation filters.
+ * The objects are passed as a slice of any type with a GetUniqueID method, and
+ * a slice of the same type is returned.
+ */
+func FilterIncludedDependencies(objSlice interface{}) interface{} {
+	if includedDependencies == nil {
+		return objSlice
+	}
+	s := reflect.ValueOf(objSlice)
+	filtered := reflect.MakeSlice(s.Type(), 0, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		uniqueID := s.Index(i).Interface().(interface{ GetUniqueID() UniqueID }).GetUniqueID()
+		if uniqueID.ClassID == PG_CLASS_OID || includedDependencies[uniqueID] {
+			filtered = reflect.Append(filtered, s.Index(i))
+		}
+	}
+	return filtered.Interface()
+}
+
 func convertToSortableSlice(objSlice interface{}) []Sortable {
 	sortableSlice := make([]Sortable, 0)
 	s := reflect.ValueOf(objSlice)
@@ -518,7 +695,10 @@ func BackupDependentObjects(metadataFile *utils.FileWithByteCount, tables []Tabl
 	}
 	sortedSlice := TopologicalSort(sortables, relevantDeps)
 
-	PrintDependentObjectStatements(metadataFile, globalTOC, sortedSlice, filteredMetadata, constraints, funcInfoMap)
+	PrintDependentObjectStatements(metadataFile, globalTOC, sortedSlice, filteredMetadata, constraints, funcInfoMap, relevantDeps)
+	if !shouldBackupObjectType("TABLE") {
+		return
+	}
 	extPartInfo, partInfoMap := GetExternalPartitionInfo(connectionPool)
 	if len(extPartInfo) > 0 {
 		gplog.Verbose("Writing EXCHANGE PARTITION statements to metadata file")
@@ -528,15 +708,23 @@ func BackupDependentObjects(metadataFile *utils.FileWithByteCount, tables []Tabl
 
 func BackupConversions(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing CREATE CONVERSION statements to metadata file")
-	conversions := GetConversions(connectionPool)
+	conversions := FilterIncludedDependencies(GetConversions(connectionPool)).([]Conversion)
 	objectCounts["Conversions"] = len(conversions)
 	convMetadata := GetMetadataForObjectType(connectionPool, TYPE_CONVERSION)
 	PrintCreateConversionStatements(metadataFile, globalTOC, conversions, convMetadata)
 }
 
+func BackupLargeObjects(metadataFile *utils.FileWithByteCount) {
+	gplog.Verbose("Writing large object creation statements to metadata file")
+	largeObjects := GetLargeObjects(connectionPool)
+	objectCounts["Large Objects"] = len(largeObjects)
+	largeObjectMetadata := GetMetadataForObjectType(connectionPool, TYPE_LARGEOBJECT)
+	PrintCreateLargeObjectStatements(metadataFile, globalTOC, largeObjects, largeObjectMetadata)
+}
+
 func BackupOperatorFamilies(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing CREATE OPERATOR FAMILY statements to metadata file")
-	operatorFamilies := GetOperatorFamilies(connectionPool)
+	operatorFamilies := FilterIncludedDependencies(GetOperatorFamilies(connectionPool)).([]OperatorFamily)
 	objectCounts["Operator Families"] = len(operatorFamilies)
 	operatorFamilyMetadata := GetMetadataForObjectType(connectionPool, TYPE_OPERATORFAMILY)
 	PrintCreateOperatorFamilyStatements(metadataFile, globalTOC, operatorFamilies, operatorFamilyMetadata)
@@ -544,7 +732,7 @@ func BackupOperatorFamilies(metadataFile *utils.FileWithByteCount) {
 
 func BackupCollations(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing CREATE COLLATION statements to metadata file")
-	collations := GetCollations(connectionPool)
+	collations := FilterIncludedDependencies(GetCollations(connectionPool)).([]Collation)
 	objectCounts["Collations"] = len(collations)
 	collationMetadata := GetMetadataForObjectType(connectionPool, TYPE_COLLATION)
 	PrintCreateCollationStatements(metadataFile, globalTOC, collations, collationMetadata)
@@ -552,7 +740,7 @@ func BackupCollations(metadataFile *utils.FileWithByteCount) {
 
 func BackupExtensions(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing CREATE EXTENSION statements to metadata file")
-	extensions := GetExtensions(connectionPool)
+	extensions := FilterIncludedDependencies(GetExtensions(connectionPool)).([]Extension)
 	objectCounts["Extensions"] = len(extensions)
 	extensionMetadata := GetCommentsForObjectType(connectionPool, TYPE_EXTENSION)
 	PrintCreateExtensionStatements(metadataFile, globalTOC, extensions, extensionMetadata)
@@ -600,6 +788,38 @@ func BackupEventTriggers(metadataFile *utils.FileWithByteCount) {
 	PrintCreateEventTriggerStatements(metadataFile, globalTOC, eventTriggers, eventTriggerMetadata)
 }
 
+func BackupPolicies(metadataFile *utils.FileWithByteCount) {
+	gplog.Verbose("Writing CREATE POLICY statements to metadata file")
+	policies := GetPolicies(connectionPool)
+	objectCounts["Policies"] = len(policies)
+	policyMetadata := GetCommentsForObjectType(connectionPool, TYPE_POLICY)
+	PrintCreatePolicyStatements(metadataFile, globalTOC, policies, policyMetadata)
+}
+
+func BackupExtendedStatistics(metadataFile *utils.FileWithByteCount) {
+	gplog.Verbose("Writing CREATE STATISTICS statements to metadata file")
+	statistics := GetExtendedStatistics(connectionPool)
+	objectCounts["Extended Statistics"] = len(statistics)
+	statisticsMetadata := GetMetadataForObjectType(connectionPool, TYPE_STATISTICEXT)
+	PrintCreateExtendedStatisticsStatements(metadataFile, globalTOC, statistics, statisticsMetadata)
+}
+
+func BackupPublications(metadataFile *utils.FileWithByteCount) {
+	gplog.Verbose("Writing CREATE PUBLICATION statements to metadata file")
+	publications := GetPublications(connectionPool)
+	objectCounts["Publications"] = len(publications)
+	publicationMetadata := GetMetadataForObjectType(connectionPool, TYPE_PUBLICATION)
+	PrintCreatePublicationStatements(metadataFile, globalTOC, publications, publicationMetadata)
+}
+
+func BackupSubscriptions(metadataFile *utils.FileWithByteCount) {
+	gplog.Verbose("Writing CREATE SUBSCRIPTION statements to metadata file")
+	subscriptions := GetSubscriptions(connectionPool)
+	objectCounts["Subscriptions"] = len(subscriptions)
+	subscriptionMetadata := GetMetadataForObjectType(connectionPool, TYPE_SUBSCRIPTION)
+	PrintCreateSubscriptionStatements(metadataFile, globalTOC, subscriptions, subscriptionMetadata)
+}
+
 func BackupDefaultPrivileges(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing ALTER DEFAULT PRIVILEGES statements to metadata file")
 	defaultPrivileges := GetDefaultPrivileges(connectionPool)
@@ -619,7 +839,29 @@ func BackupStatistics(statisticsFile *utils.FileWithByteCount, tables []Table) {
 	PrintStatisticsStatements(statisticsFile, globalTOC, tables, attStats, tupleStats)
 }
 
+func BackupLargeObjectData() {
+	largeObjects := GetLargeObjects(connectionPool)
+	if len(largeObjects) == 0 {
+		return
+	}
+	dataFile := utils.NewEncryptedFileWithByteCountFromFile(globalFPInfo.GetLargeObjectDataFilePath(), encryptionKey)
+	defer dataFile.Close()
+	getChunk := func(oid uint32, offset int64, length int) []byte {
+		return GetLargeObjectChunk(connectionPool, oid, offset, length)
+	}
+	for _, largeObject := range largeObjects {
+		if wasTerminated {
+			return
+		}
+		WriteLargeObjectContents(dataFile, globalTOC, largeObject, getChunk)
+	}
+}
+
 func BackupIncrementalMetadata() {
 	aoTableEntries := GetAOIncrementalMetadata(connectionPool)
 	globalTOC.IncrementalMetadata.AO = aoTableEntries
+	globalTOC.IncrementalMetadata.Heap = GetHeapIncrementalMetadata(connectionPool)
+	if !MustGetFlagBool(options.LEAF_PARTITION_DATA) {
+		globalTOC.IncrementalMetadata.PartitionRoots = GetLeafPartitionRoots(connectionPool)
+	}
 }
@@ -0,0 +1,32 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/report"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/wrappers tests", func() {
+	Describe("ExcludeSkippedTables", func() {
+		It("leaves the skipped tables out of the backup and records them in the report and TOC", func() {
+			backupReport := &report.Report{}
+			backupTOC := &toc.TOC{}
+			backup.SetReport(backupReport)
+			backup.SetTOC(backupTOC)
+			backup.SetFilterRelationClause("n.nspname = 'public'")
+			defer backup.SetFilterRelationClause("")
+			foo := backup.Relation{Oid: 1, Schema: "public", Name: "foo"}
+			bar := backup.Relation{Oid: 2, Schema: "public", Name: "bar"}
+			baz := backup.Relation{Oid: 3, Schema: "public", Name: "baz"}
+
+			lockedTables := backup.ExcludeSkippedTables([]backup.Relation{foo, bar, baz}, []backup.Relation{foo, baz})
+
+			Expect(lockedTables).To(Equal([]backup.Relation{bar}))
+			Expect(backupReport.SkippedTables).To(Equal([]string{"public.foo", "public.baz"}))
+			Expect(backupTOC.SkippedTables).To(Equal([]string{"public.foo", "public.baz"}))
+		})
+	})
+})
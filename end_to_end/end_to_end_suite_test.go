@@ -1250,6 +1250,27 @@ var _ = Describe("backup end to end integration tests", func() {
 			assertDataRestored(restoreConn, map[string]int{"public.foo": 0, "schema2.foo3": 0})
 			assertRelationsCreated(restoreConn, TOTAL_RELATIONS)
 		})
+		It("runs gprestore with the output-sql flag to write metadata to a file instead of restoring it", func() {
+			timestamp := gpbackup(gpbackupPath, backupHelperPath)
+			outputFile := path.Join(backupDir, "restore.sql")
+			gprestore(gprestorePath, restoreHelperPath, timestamp, "--redirect-db", "restoredb", "--metadata-only", "--output-sql", outputFile)
+
+			assertRelationsCreated(restoreConn, 0)
+			mustRunCommand(exec.Command("psql", "-d", "restoredb", "-v", "ON_ERROR_STOP=1", "-q", "-f", outputFile))
+			assertRelationsCreated(restoreConn, TOTAL_RELATIONS)
+		})
+		It("runs gprestore with the output-sql flag to write data restore statements to a file", func() {
+			timestamp := gpbackup(gpbackupPath, backupHelperPath, "--backup-dir", backupDir)
+			outputFile := path.Join(backupDir, "restore.sql")
+			gprestore(gprestorePath, restoreHelperPath, timestamp, "--redirect-db", "restoredb", "--backup-dir", backupDir, "--output-sql", outputFile)
+
+			contents, err := ioutil.ReadFile(outputFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("COPY public.foo(i) FROM PROGRAM"))
+			mustRunCommand(exec.Command("psql", "-d", "restoredb", "-v", "ON_ERROR_STOP=1", "-q", "-f", outputFile))
+			assertDataRestored(restoreConn, publicSchemaTupleCounts)
+			assertDataRestored(restoreConn, schema2TupleCounts)
+		})
 		It("runs gpbackup and gprestore with leaf-partition-data and backupDir flags", func() {
 			timestamp := gpbackup(gpbackupPath, backupHelperPath, "--leaf-partition-data", "--backup-dir", backupDir)
 			output := gprestore(gprestorePath, restoreHelperPath, timestamp, "--redirect-db", "restoredb", "--backup-dir", backupDir)
@@ -100,11 +100,14 @@ var metadataFilenameMap = map[string]string{
 	"config":                "config.yaml",
 	"metadata":              "metadata.sql",
 	"statistics":            "statistics.sql",
+	"structured metadata":   "metadata.yaml",
 	"table of contents":     "toc.yaml",
 	"report":                "report",
 	"plugin_config":         "plugin_config.yaml",
 	"error_tables_metadata": "error_tables_metadata",
 	"error_tables_data":     "error_tables_data",
+	"skipped_tables":        "skipped_tables",
+	"large_objects":         "large_objects",
 }
 
 func (backupFPInfo *FilePathInfo) GetBackupFilePath(filetype string) string {
@@ -120,6 +123,10 @@ func (backupFPInfo *FilePathInfo) GetMetadataFilePath() string {
 	return backupFPInfo.GetBackupFilePath("metadata")
 }
 
+func (backupFPInfo *FilePathInfo) GetStructuredMetadataFilePath() string {
+	return backupFPInfo.GetBackupFilePath("structured metadata")
+}
+
 func (backupFPInfo *FilePathInfo) GetStatisticsFilePath() string {
 	return backupFPInfo.GetBackupFilePath("statistics")
 }
@@ -132,6 +139,14 @@ func (backupFPInfo *FilePathInfo) GetBackupReportFilePath() string {
 	return backupFPInfo.GetBackupFilePath("report")
 }
 
+func (backupFPInfo *FilePathInfo) GetSkippedTablesFilePath() string {
+	return backupFPInfo.GetBackupFilePath("skipped_tables")
+}
+
+func (backupFPInfo *FilePathInfo) GetLargeObjectDataFilePath() string {
+	return backupFPInfo.GetBackupFilePath("large_objects")
+}
+
 func (backupFPInfo *FilePathInfo) GetRestoreFilePath(restoreTimestamp string, filetype string) string {
 	return path.Join(backupFPInfo.GetDirForContent(-1), fmt.Sprintf("gprestore_%s_%s_%s", backupFPInfo.Timestamp, restoreTimestamp, metadataFilenameMap[filetype]))
 }
@@ -161,7 +176,12 @@ func (backupFPInfo *FilePathInfo) GetPluginConfigPath() string {
 }
 
 func (backupFPInfo *FilePathInfo) GetSegmentHelperFilePath(contentID int, suffix string) string {
-	return path.Join(backupFPInfo.SegDirMap[contentID], fmt.Sprintf("gpbackup_%d_%s_%s_%d", contentID, backupFPInfo.Timestamp, suffix, backupFPInfo.PID))
+	templateFilePath := backupFPInfo.GetSegmentHelperFilePathForCopyCommand(suffix)
+	return backupFPInfo.replaceCopyFormatStringsInPath(templateFilePath, contentID)
+}
+
+func (backupFPInfo *FilePathInfo) GetSegmentHelperFilePathForCopyCommand(suffix string) string {
+	return fmt.Sprintf("<SEG_DATA_DIR>/gpbackup_<SEGID>_%s_%s_%d", backupFPInfo.Timestamp, suffix, backupFPInfo.PID)
 }
 
 func (backupFPInfo *FilePathInfo) GetHelperLogPath() string {
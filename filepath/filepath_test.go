@@ -103,6 +103,12 @@ var _ = Describe("filepath tests", func() {
 			Expect(fpInfo.GetBackupReportFilePath()).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_report"))
 		})
 	})
+	Describe("GetSkippedTablesFilePath", func() {
+		It("returns skipped tables file path", func() {
+			fpInfo := NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetSkippedTablesFilePath()).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_skipped_tables"))
+		})
+	})
 	Describe("GetTableBackupFilePath", func() {
 		It("returns table file path", func() {
 			fpInfo := NewFilePathInfo(c, "", "20170101010101", "gpseg")
@@ -121,6 +127,18 @@ var _ = Describe("filepath tests", func() {
 			Expect(fpInfo.GetTableBackupFilePath(-1, 1234, "", true)).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_-1_20170101010101"))
 		})
 	})
+	Describe("GetSegmentHelperFilePath", func() {
+		It("returns helper file path in the segment data directory", func() {
+			fpInfo := NewFilePathInfo(c, "/foo/bar", "20170101010101", "gpseg")
+			fpInfo.PID = 1234
+			Expect(fpInfo.GetSegmentHelperFilePath(-1, "key")).To(Equal("/data/gpseg-1/gpbackup_-1_20170101010101_key_1234"))
+		})
+		It("returns helper file path for copy command", func() {
+			fpInfo := NewFilePathInfo(c, "/foo/bar", "20170101010101", "gpseg")
+			fpInfo.PID = 1234
+			Expect(fpInfo.GetSegmentHelperFilePathForCopyCommand("key")).To(Equal("<SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_key_1234"))
+		})
+	})
 	Describe("ParseSegPrefix", func() {
 		AfterEach(func() {
 			operating.System.Glob = path.Glob
@@ -16,14 +16,44 @@ func main() {
 		Short:   "gpbackup is the parallel backup utility for Greenplum",
 		Args:    cobra.NoArgs,
 		Version: GetVersion(),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return ApplyConfig(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			if IsClusterBackup() {
+				defer DoClusterBackupTeardown()
+				DoFlagValidation(cmd)
+				DoClusterBackup()
+				return
+			}
 			defer DoTeardown()
 			DoFlagValidation(cmd)
 			DoSetup()
 			DoBackup()
 		}}
+	var consolidateCmd = &cobra.Command{
+		Use:   "consolidate",
+		Short: "Combine an incremental backup and the backups it depends on into a new full backup",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoConsolidateTeardown()
+			DoConsolidateValidation(cmd)
+			DoConsolidate()
+		}}
+	var chainHealthCmd = &cobra.Command{
+		Use:   "chain-health",
+		Short: "Check that the backups each backup in the backup history depends on are intact",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			defer DoChainHealthTeardown()
+			DoChainHealthValidation(cmd)
+			DoChainHealth()
+		}}
+	rootCmd.AddCommand(consolidateCmd, chainHealthCmd)
 	rootCmd.SetArgs(options.HandleSingleDashes(os.Args[1:]))
 	DoInit(rootCmd)
+	InitializeConsolidateFlags(consolidateCmd)
+	InitializeChainHealthFlags(chainHealthCmd)
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(2)
 	}
@@ -16,7 +16,16 @@ func main() {
 		Short:   "gprestore is the parallel restore utility for Greenplum",
 		Args:    cobra.NoArgs,
 		Version: GetVersion(),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return ApplyConfig(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			if IsClusterRestore() {
+				defer DoClusterRestoreTeardown()
+				DoValidation(cmd)
+				DoClusterRestore()
+				return
+			}
 			defer DoTeardown()
 			DoValidation(cmd)
 			DoSetup()
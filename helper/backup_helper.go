@@ -141,6 +141,12 @@ func getBackupPipeWriter(compressLevel int) (io.Writer, *gzip.Writer, *bufio.Wri
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
+	if *encryptionKeyFile != "" {
+		writeHandle, err = newEncryptedWriteCloser(writeHandle)
+		if err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+	}
 
 	var finalWriter io.Writer
 	var gzipWriter *gzip.Writer
@@ -0,0 +1,180 @@
+package helper
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Consolidate specific functions
+ */
+
+/*
+ * The oid file for consolidation has one line per table, in the format
+ * "<oid>\t<source data file>\t<source toc file>", where the source files are
+ * the single data file and segment TOC of the backup the table's data is in.
+ */
+type consolidateSource struct {
+	dataFile string
+	tocFile  string
+	oids     []uint
+	entries  map[uint]toc.SegmentDataEntry
+	reader   *bufio.Reader
+	handle   *os.File
+	lastRead uint64
+}
+
+/*
+ * The restore agent reads tables from a single data file in oid order, so
+ * the tables in each source data file, which were written in oid order, are
+ * merged into the new data file in oid order as well. Each source data file
+ * is therefore read only once, even if it is compressed.
+ */
+func doConsolidateAgent() error {
+	sources, err := getConsolidateSourcesFromFile()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, source := range sources {
+			if source.handle != nil {
+				_ = source.handle.Close()
+			}
+		}
+	}()
+	for _, source := range sources {
+		err = openConsolidateSource(source)
+		if err != nil {
+			return err
+		}
+	}
+
+	tocfile := &toc.SegmentTOC{}
+	tocfile.DataEntries = make(map[uint]toc.SegmentDataEntry)
+	finalWriter, gzipWriter, bufIoWriter, writeHandle, _, err := getBackupPipeWriter(*compressionLevel)
+	if err != nil {
+		return err
+	}
+
+	var lastWritten uint64
+	for {
+		if wasTerminated {
+			return errors.New("Terminated due to user request")
+		}
+		var next *consolidateSource
+		for _, source := range sources {
+			if len(source.oids) > 0 && (next == nil || source.oids[0] < next.oids[0]) {
+				next = source
+			}
+		}
+		if next == nil {
+			break
+		}
+		oid := next.oids[0]
+		next.oids = next.oids[1:]
+		numBytes, err := copyConsolidateSourceData(next, oid, finalWriter)
+		if err != nil {
+			return err
+		}
+		log(fmt.Sprintf("Copied %d bytes for oid %d from %s", numBytes, oid, next.dataFile))
+		tocfile.AddSegmentDataEntry(oid, lastWritten, lastWritten+uint64(numBytes))
+		lastWritten += uint64(numBytes)
+	}
+
+	if gzipWriter != nil {
+		_ = gzipWriter.Close()
+	}
+	_ = bufIoWriter.Flush()
+	_ = writeHandle.Close()
+	err = tocfile.WriteToFileAndMakeReadOnly(*tocFile)
+	if err != nil {
+		return err
+	}
+	log("Finished writing segment TOC")
+	return nil
+}
+
+func getConsolidateSourcesFromFile() ([]*consolidateSource, error) {
+	contents, err := operating.System.ReadFile(*oidFile)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]*consolidateSource, 0)
+	sourceIndex := make(map[string]*consolidateSource)
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, errors.Errorf("Invalid line in oid file %s: %s", *oidFile, line)
+		}
+		oid, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("Invalid oid in oid file %s: %s", *oidFile, fields[0])
+		}
+		source, ok := sourceIndex[fields[1]]
+		if !ok {
+			source = &consolidateSource{dataFile: fields[1], tocFile: fields[2]}
+			sourceIndex[fields[1]] = source
+			sources = append(sources, source)
+		}
+		source.oids = append(source.oids, uint(oid))
+	}
+	for _, source := range sources {
+		sort.Slice(source.oids, func(i, j int) bool {
+			return source.oids[i] < source.oids[j]
+		})
+	}
+	return sources, nil
+}
+
+func openConsolidateSource(source *consolidateSource) error {
+	source.entries = toc.NewSegmentTOC(source.tocFile).DataEntries
+	for _, oid := range source.oids {
+		if _, ok := source.entries[oid]; !ok {
+			return errors.Errorf("Oid %d not found in segment TOC %s", oid, source.tocFile)
+		}
+	}
+
+	var err error
+	source.handle, err = os.Open(source.dataFile)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(source.dataFile, ".gz") {
+		gzipReader, err := gzip.NewReader(source.handle)
+		if err != nil {
+			return err
+		}
+		source.reader = bufio.NewReader(gzipReader)
+	} else {
+		source.reader = bufio.NewReader(source.handle)
+	}
+	return nil
+}
+
+func copyConsolidateSourceData(source *consolidateSource, oid uint, writer io.Writer) (int64, error) {
+	start := source.entries[oid].StartByte
+	end := source.entries[oid].EndByte
+	if start < source.lastRead {
+		return 0, errors.Errorf("Data for oid %d in %s is not in oid order", oid, source.dataFile)
+	}
+	_, err := source.reader.Discard(int(start - source.lastRead))
+	if err != nil {
+		return 0, err
+	}
+	numBytes, err := io.CopyN(writer, source.reader, int64(end-start))
+	if err != nil {
+		return numBytes, err
+	}
+	source.lastRead = end
+	return numBytes, nil
+}
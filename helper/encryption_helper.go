@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * Encryption specific functions
+ */
+
+/*
+ * The filters are run as part of the COPY commands of backups with one data
+ * file per table, so they must not write anything but data to stdout.
+ */
+func doEncryptFilter() error {
+	key, err := utils.ReadEncryptionKey(*encryptionKeyFile, "", "")
+	if err != nil {
+		return err
+	}
+	bufIoWriter := bufio.NewWriter(os.Stdout)
+	encryptWriter, err := utils.NewEncryptWriter(bufIoWriter, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(encryptWriter, bufio.NewReader(os.Stdin))
+	if err != nil {
+		return err
+	}
+	err = encryptWriter.Close()
+	if err != nil {
+		return err
+	}
+	return bufIoWriter.Flush()
+}
+
+func doDecryptFilter() error {
+	key, err := utils.ReadEncryptionKey(*encryptionKeyFile, "", "")
+	if err != nil {
+		return err
+	}
+	decryptReader, err := utils.NewDecryptReader(bufio.NewReader(os.Stdin), key)
+	if err != nil {
+		return err
+	}
+	bufIoWriter := bufio.NewWriter(os.Stdout)
+	_, err = io.Copy(bufIoWriter, decryptReader)
+	if err != nil {
+		return err
+	}
+	return bufIoWriter.Flush()
+}
+
+/*
+ * Closing an encryptedWriteCloser writes the last encrypted chunk before
+ * closing the file or plugin command the data is written to.
+ */
+type encryptedWriteCloser struct {
+	*utils.EncryptWriter
+	handle io.WriteCloser
+}
+
+func (writer encryptedWriteCloser) Close() error {
+	err := writer.EncryptWriter.Close()
+	closeErr := writer.handle.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+func newEncryptedWriteCloser(handle io.WriteCloser) (io.WriteCloser, error) {
+	key, err := utils.ReadEncryptionKey(*encryptionKeyFile, "", "")
+	if err != nil {
+		return nil, err
+	}
+	encryptWriter, err := utils.NewEncryptWriter(handle, key)
+	if err != nil {
+		return nil, err
+	}
+	return encryptedWriteCloser{EncryptWriter: encryptWriter, handle: handle}, nil
+}
+
+func newDecryptReader(reader io.Reader) (io.Reader, error) {
+	key, err := utils.ReadEncryptionKey(*encryptionKeyFile, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return utils.NewDecryptReader(reader, key)
+}
@@ -39,17 +39,21 @@ var (
  * Command-line flags
  */
 var (
-	backupAgent      *bool
-	compressionLevel *int
-	content          *int
-	dataFile         *string
-	oidFile          *string
-	onErrorContinue  *bool
-	pipeFile         *string
-	pluginConfigFile *string
-	printVersion     *bool
-	restoreAgent     *bool
-	tocFile          *string
+	backupAgent       *bool
+	compressionLevel  *int
+	consolidateAgent  *bool
+	content           *int
+	dataFile          *string
+	decrypt           *bool
+	encrypt           *bool
+	encryptionKeyFile *string
+	oidFile           *string
+	onErrorContinue   *bool
+	pipeFile          *string
+	pluginConfigFile  *string
+	printVersion      *bool
+	restoreAgent      *bool
+	tocFile           *string
 )
 
 func DoHelper() {
@@ -81,11 +85,20 @@ func DoHelper() {
 		err = doBackupAgent()
 	} else if *restoreAgent {
 		err = doRestoreAgent()
+	} else if *consolidateAgent {
+		err = doConsolidateAgent()
+	} else if *encrypt {
+		err = doEncryptFilter()
+	} else if *decrypt {
+		err = doDecryptFilter()
 	}
 	if err != nil {
 		gplog.Error(fmt.Sprintf("%v: %s", err, debug.Stack()))
-		handle, _ := iohelper.OpenFileForWriting(fmt.Sprintf("%s_error", *pipeFile))
-		_ = handle.Close()
+		// The consolidate agent runs in the foreground and reports errors through its exit code
+		if *pipeFile != "" {
+			handle, _ := iohelper.OpenFileForWriting(fmt.Sprintf("%s_error", *pipeFile))
+			_ = handle.Close()
+		}
 	}
 }
 
@@ -97,7 +110,11 @@ func InitializeGlobals() {
 	backupAgent = flag.Bool("backup-agent", false, "Use gpbackup_helper as an agent for backup")
 	content = flag.Int("content", -2, "Content ID of the corresponding segment")
 	compressionLevel = flag.Int("compression-level", 0, "The level of compression to use with gzip. O indicates no compression.")
+	consolidateAgent = flag.Bool("consolidate-agent", false, "Use gpbackup_helper as an agent to consolidate single data files from several backups into one")
 	dataFile = flag.String("data-file", "", "Absolute path to the data file")
+	decrypt = flag.Bool("decrypt", false, "Decrypt data read from stdin and write it to stdout")
+	encrypt = flag.Bool("encrypt", false, "Encrypt data read from stdin and write it to stdout")
+	encryptionKeyFile = flag.String("encryption-key-file", "", "Absolute path to the file containing the key with which data files are encrypted")
 	oidFile = flag.String("oid-file", "", "Absolute path to the file containing a list of oids to restore")
 	onErrorContinue = flag.Bool("on-error-continue", false, "Continue restore even when encountering an error")
 	pipeFile = flag.String("pipe-file", "", "Absolute path to the pipe file")
@@ -182,7 +199,7 @@ func removeFileIfExists(filename string) error {
 
 func DoCleanup() {
 	defer CleanupGroup.Done()
-	if wasTerminated {
+	if wasTerminated && *pipeFile != "" {
 		/*
 		 * If the agent dies during the last table copy, it can still report
 		 * success, so we create an error file and check for its presence in
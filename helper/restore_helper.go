@@ -131,6 +131,12 @@ func getRestoreDataReader() (*bufio.Reader, error) {
 	if err != nil {
 		return nil, err
 	}
+	if *encryptionKeyFile != "" {
+		readHandle, err = newDecryptReader(readHandle)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var bufIoReader *bufio.Reader
 	if strings.HasSuffix(*dataFile, ".gz") {
@@ -19,15 +19,22 @@ type RestorePlanEntry struct {
 type BackupConfig struct {
 	BackupDir             string
 	BackupVersion         string
+	ClusterTimestamp      string `yaml:",omitempty"`
 	Compressed            bool
+	ConsolidatedFrom      string `yaml:",omitempty"`
 	DatabaseName          string
 	DatabaseVersion       string
 	DataOnly              bool
 	DateDeleted           string
+	EffectiveConfig       map[string]interface{} `yaml:",omitempty"`
+	EncryptionKeyID       string                 `yaml:",omitempty"`
+	ExcludeObjectTypes    []string               `yaml:",omitempty"`
 	ExcludeRelations      []string
 	ExcludeSchemaFiltered bool
 	ExcludeSchemas        []string
 	ExcludeTableFiltered  bool
+	IncludeDependencies   bool     `yaml:",omitempty"`
+	IncludeObjectTypes    []string `yaml:",omitempty"`
 	IncludeRelations      []string
 	IncludeSchemaFiltered bool
 	IncludeSchemas        []string
@@ -35,10 +42,14 @@ type BackupConfig struct {
 	Incremental           bool
 	LeafPartitionData     bool
 	MetadataOnly          bool
+	NoClusterGlobals      bool `yaml:",omitempty"`
 	Plugin                string
 	PluginVersion         string
 	RestorePlan           []RestorePlanEntry
+	SegmentCount          int `yaml:",omitempty"`
 	SingleDataFile        bool
+	SnapshotID            string `yaml:",omitempty"`
+	StructuredMetadata    bool   `yaml:",omitempty"`
 	Timestamp             string
 	EndTime               string
 	WithStatistics        bool
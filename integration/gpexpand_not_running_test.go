@@ -76,10 +76,10 @@ var _ = Describe("gpexpand_sensor", func() {
 			}
 		}()
 
-		defer testhelper.ShouldPanicWithMessage(`[CRITICAL]:-Greenplum expansion currently in process, please re-run gpbackup when the expansion has completed`)
+		defer testhelper.ShouldPanicWithMessage(`[CRITICAL]:-Greenplum expansion is currently adding new segments, please re-run gpbackup when the new segments have been added or the expansion has completed`)
 		backup.DoSetup()
 	})
-	It("should prevent gpbackup from starting when gpexpand is in phase 2", func() {
+	It("should detect that gpexpand is redistributing tables in phase 2", func() {
 		postgresConn := dbconn.NewDBConnFromEnvironment("postgres")
 		postgresConn.MustConnect(1)
 		defer postgresConn.Close()
@@ -89,7 +89,6 @@ var _ = Describe("gpexpand_sensor", func() {
 		testhelper.AssertQueryRuns(postgresConn, "CREATE TABLE gpexpand.status (status text, updated timestamp)")
 		testhelper.AssertQueryRuns(postgresConn, "INSERT INTO gpexpand.status VALUES ('IN PROGRESS', now())")
 
-		defer testhelper.ShouldPanicWithMessage(`[CRITICAL]:-Greenplum expansion currently in process, please re-run gpbackup when the expansion has completed`)
-		backup.DoSetup()
+		Expect(utils.GetGpexpandPhase()).To(Equal(utils.GpexpandRedistributingTables))
 	})
 })
@@ -28,6 +28,7 @@ var (
 	dataFileFullPath = filepath.Join(testDir, "test_data")
 	pluginBackupPath = filepath.Join(pluginDir, "test_data")
 	errorFile        = fmt.Sprintf("%s_error", pipeFile)
+	keyFile          = fmt.Sprintf("%s/test_key", testDir)
 	pluginConfigPath = fmt.Sprintf("%s/go/src/github.com/greenplum-db/gpbackup/plugins/example_plugin_config.yaml", os.Getenv("HOME"))
 )
 
@@ -268,6 +269,42 @@ var _ = Describe("gpbackup_helper end to end integration tests", func() {
 			Expect(errorFile).To(BeAnExistingFile())
 		})
 	})
+	Context("encryption tests", func() {
+		BeforeEach(func() {
+			_ = ioutil.WriteFile(keyFile, []byte(strings.Repeat("0123456789abcdef", 4)), 0600)
+		})
+		It("restores data backed up by gpbackup_helper with compression and encryption", func() {
+			f, _ := os.Create(oidFile)
+			_, _ = f.WriteString("1\n2\n3\n")
+			helperCmd := gpbackupHelper(gpbackupHelperPath, "--backup-agent", "--compression-level", "1", "--data-file", dataFileFullPath+".gz", "--encryption-key-file", keyFile)
+			writeToPipes(defaultData)
+			err := helperCmd.Wait()
+			printHelperLogOnError(err)
+			Expect(err).ToNot(HaveOccurred())
+			contents, _ := ioutil.ReadFile(dataFileFullPath + ".gz")
+			Expect(string(contents)).ToNot(ContainSubstring(defaultData))
+
+			err = syscall.Mkfifo(fmt.Sprintf("%s_%d", pipeFile, 1), 0777)
+			Expect(err).ToNot(HaveOccurred())
+			f, _ = os.Create(oidFile)
+			_, _ = f.WriteString("1\n3\n")
+			helperCmd = gpbackupHelper(gpbackupHelperPath, "--restore-agent", "--data-file", dataFileFullPath+".gz", "--encryption-key-file", keyFile)
+			for _, i := range []int{1, 3} {
+				contents, _ := ioutil.ReadFile(fmt.Sprintf("%s_%d", pipeFile, i))
+				Expect(string(contents)).To(Equal("here is some data\n"))
+			}
+			err = helperCmd.Wait()
+			printHelperLogOnError(err)
+			Expect(err).ToNot(HaveOccurred())
+			assertNoErrors()
+		})
+		It("encrypts and decrypts data piped through gpbackup_helper", func() {
+			command := fmt.Sprintf("echo '%s' | %[2]s --encrypt --encryption-key-file %[3]s | %[2]s --decrypt --encryption-key-file %[3]s", "here is some data", gpbackupHelperPath, keyFile)
+			output, err := exec.Command("bash", "-c", command).Output()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(output)).To(Equal(defaultData))
+		})
+	})
 })
 
 func setupRestoreFiles(withCompression bool, withPlugin bool) {
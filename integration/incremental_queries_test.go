@@ -6,6 +6,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/testutils"
 	"github.com/greenplum-db/gpbackup/toc"
 
 	. "github.com/onsi/ginkgo"
@@ -263,4 +264,56 @@ var _ = Describe("backup integration tests", func() {
 			})
 		})
 	})
+	Describe("GetHeapIncrementalMetadata", func() {
+		var heapTableFQN = "public.heap_foo"
+		BeforeEach(func() {
+			testutils.SkipIfBefore6(connectionPool)
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf("CREATE TABLE %s (i int)", heapTableFQN))
+		})
+		AfterEach(func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(dropTableSQL, heapTableFQN))
+		})
+		It("only retrieves metadata for heap tables", func() {
+			heapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool)
+
+			Expect(heapIncrementalMetadata).To(HaveKey(heapTableFQN))
+			Expect(heapIncrementalMetadata).To(Not(HaveKey(aoTableFQN)))
+			Expect(heapIncrementalMetadata[heapTableFQN].LastDDLTimestamp).To(Not(BeEmpty()))
+			Expect(heapIncrementalMetadata[heapTableFQN].StatsResetTimestamp).To(Not(BeEmpty()))
+			Expect(heapIncrementalMetadata[heapTableFQN].Relfilenode).To(Not(BeZero()))
+		})
+		It("should have a changed size after inserting data, whether or not the tuple counts have been reported", func() {
+			initialHeapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool)
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf("INSERT INTO %s SELECT generate_series(1, 1000)", heapTableFQN))
+
+			heapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool)
+			Expect(heapIncrementalMetadata[heapTableFQN].Size).To(BeNumerically(">", initialHeapIncrementalMetadata[heapTableFQN].Size))
+		})
+		It("should have a changed modcount after DML changes", func() {
+			initialHeapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool)
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(insertSQL, heapTableFQN))
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(deleteSQL, heapTableFQN))
+			// The statistics collector receives tuple counts asynchronously
+			Eventually(func() int64 {
+				return backup.GetHeapIncrementalMetadata(connectionPool)[heapTableFQN].Modcount
+			}, "5s", "100ms").Should(Equal(initialHeapIncrementalMetadata[heapTableFQN].Modcount + 2))
+		})
+		It("should have a changed last DDL timestamp after a truncate", func() {
+			initialHeapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool)
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf("TRUNCATE TABLE %s", heapTableFQN))
+
+			heapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool)
+			Expect(heapIncrementalMetadata[heapTableFQN].LastDDLTimestamp).
+				To(Not(Equal(initialHeapIncrementalMetadata[heapTableFQN].LastDDLTimestamp)))
+		})
+	})
+	Describe("GetLeafPartitionRoots", func() {
+		It("maps each leaf partition to its root partition table", func() {
+			leafPartitionRoots := backup.GetLeafPartitionRoots(connectionPool)
+
+			Expect(leafPartitionRoots).To(HaveKeyWithValue(aoPartChildTableFQN, aoPartParentTableFQN))
+			Expect(leafPartitionRoots).To(Not(HaveKey(aoPartParentTableFQN)))
+			Expect(leafPartitionRoots).To(Not(HaveKey(aoTableFQN)))
+		})
+	})
 })
@@ -5,6 +5,8 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/report"
+	"github.com/greenplum-db/gpbackup/testutils"
 	"github.com/spf13/cobra"
 
 	. "github.com/onsi/ginkgo"
@@ -18,6 +20,7 @@ var _ = Describe("Wrappers Integration", func() {
 			var rootCmd = &cobra.Command{}
 			backup.DoInit(rootCmd) // initialize the ObjectCount
 			backup.SetCmdFlags(backupCmdFlags)
+			backup.SetReport(&report.Report{})
 		})
 		It("returns the data tables that have names with special characters", func() {
 			_ = backupCmdFlags.Set(options.INCLUDE_RELATION, "public.foo")
@@ -38,4 +41,24 @@ var _ = Describe("Wrappers Integration", func() {
 			Expect(dataTables[1].Name).To(Equal(`"BAR"`))
 		})
 	})
+	Describe("LockTables", func() {
+		It("panics with the blocking session when a table cannot be locked without waiting", func() {
+			gplog.SetVerbosity(gplog.LOGERROR)
+			testhelper.AssertQueryRuns(connectionPool, "CREATE TABLE public.foo(i int)")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP TABLE public.foo")
+			fooOid := testutils.OidFromObjectName(connectionPool, "public", "foo", backup.TYPE_RELATION)
+
+			blockingConn := testutils.SetupTestDbConn("testdb")
+			defer blockingConn.Close()
+			blockingConn.MustBegin()
+			defer blockingConn.MustRollback()
+			blockingConn.MustExec("LOCK TABLE public.foo IN ACCESS EXCLUSIVE MODE")
+
+			connectionPool.MustBegin(0)
+			defer connectionPool.MustRollback(0)
+
+			defer testhelper.ShouldPanicWithMessage("Could not acquire ACCESS SHARE locks on a batch of 1 tables without waiting after 1 attempt(s). Blocking sessions: pid")
+			backup.LockTables(connectionPool, []backup.Relation{{Oid: fooOid, Schema: "public", Name: "foo"}}, backup.LockPolicy{NoWait: true})
+		})
+	})
 })
@@ -0,0 +1,217 @@
+package options
+
+/*
+ * This file contains functions relating to setting flags from environment
+ * variables and configuration files.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+ * Each top-level key of a configuration file is the name of a flag, such as
+ * "jobs" or "include-table", and each value is the value of that flag, or a
+ * list of values for a flag that can be specified multiple times. The flags
+ * under a database name in "profiles" apply only to that database, and take
+ * precedence over the top-level flags.
+ */
+type ConfigFile struct {
+	Flags    map[string]interface{}            `yaml:",inline"`
+	Profiles map[string]map[string]interface{} `yaml:",omitempty"`
+}
+
+func isConfigurableFlag(flagName string) bool {
+	return flagName != CONFIG && flagName != "help" && flagName != "version"
+}
+
+func isArrayFlag(flag *pflag.Flag) bool {
+	return flag.Value.Type() == "stringArray" || flag.Value.Type() == "stringSlice"
+}
+
+/*
+ * The environment variable for a flag is the utility name and the flag name in
+ * upper case with dashes replaced by underscores, such as GPBACKUP_BACKUP_DIR.
+ */
+func GetFlagEnvironmentVariable(utility string, flagName string) string {
+	return strings.ToUpper(strings.Replace(fmt.Sprintf("%s_%s", utility, flagName), "-", "_", -1))
+}
+
+func ReadConfigFile(filename string) (*ConfigFile, error) {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config := &ConfigFile{}
+	err = yaml.UnmarshalStrict(contents, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not parse configuration file %s", filename)
+	}
+	return config, nil
+}
+
+/*
+ * Flags given on the command line take precedence over environment variables,
+ * which take precedence over the configuration file, which takes precedence
+ * over the flag defaults. The configuration file is given by the --config flag
+ * or its environment variable, and the profile applied is the one named by the
+ * value of profileFlag.
+ *
+ * Flags set from the environment or the configuration file are marked as
+ * changed, so they are validated in the same way as flags on the command line.
+ */
+func ApplyConfig(flags *pflag.FlagSet, utility string, profileFlag string) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" || flag.Name == "version" {
+			return
+		}
+		envVar := GetFlagEnvironmentVariable(utility, flag.Name)
+		value := operating.System.Getenv(envVar)
+		if value == "" {
+			return
+		}
+		values := []string{value}
+		if isArrayFlag(flag) {
+			values = strings.Split(value, ",")
+		}
+		for _, value := range values {
+			if setErr := flags.Set(flag.Name, value); setErr != nil {
+				err = errors.Errorf("Invalid environment variable %s: %v", envVar, setErr)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	configFilename, err := flags.GetString(CONFIG)
+	if err != nil || configFilename == "" {
+		return err
+	}
+	config, err := ReadConfigFile(configFilename)
+	if err != nil {
+		return err
+	}
+	configFlags, err := getConfigFlags(flags, config, profileFlag)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid configuration file %s", configFilename)
+	}
+	for _, flagName := range sortedKeys(configFlags) {
+		if flags.Changed(flagName) {
+			continue
+		}
+		if err := setFlagFromConfig(flags, flagName, configFlags[flagName]); err != nil {
+			return errors.Wrapf(err, "Invalid configuration file %s", configFilename)
+		}
+	}
+	return nil
+}
+
+/*
+ * A profile applies only when the profile flag names a single database, as
+ * each database of a backup of several databases is backed up separately.
+ */
+func getProfileName(flags *pflag.FlagSet, config *ConfigFile, profileFlag string) string {
+	if !flags.Changed(profileFlag) {
+		switch value := config.Flags[profileFlag].(type) {
+		case nil:
+			return ""
+		case []interface{}:
+			if len(value) == 1 {
+				return fmt.Sprint(value[0])
+			}
+			return ""
+		default:
+			return fmt.Sprint(value)
+		}
+	}
+	if isArrayFlag(flags.Lookup(profileFlag)) {
+		if values, _ := flags.GetStringArray(profileFlag); len(values) == 1 {
+			return values[0]
+		}
+		return ""
+	}
+	profileName, _ := flags.GetString(profileFlag)
+	return profileName
+}
+
+func getConfigFlags(flags *pflag.FlagSet, config *ConfigFile, profileFlag string) (map[string]interface{}, error) {
+	profileName := getProfileName(flags, config, profileFlag)
+	configFlags := make(map[string]interface{}, len(config.Flags))
+	for flagName, value := range config.Flags {
+		configFlags[flagName] = value
+	}
+	for flagName, value := range config.Profiles[profileName] {
+		configFlags[flagName] = value
+	}
+	for flagName := range configFlags {
+		if flags.Lookup(flagName) == nil || !isConfigurableFlag(flagName) {
+			return nil, errors.Errorf("Unrecognized flag %s", flagName)
+		}
+	}
+	return configFlags, nil
+}
+
+func setFlagFromConfig(flags *pflag.FlagSet, flagName string, value interface{}) error {
+	if _, ok := value.(map[interface{}]interface{}); ok {
+		return errors.Errorf("Invalid value for flag %s", flagName)
+	}
+	values := []interface{}{value}
+	if list, ok := value.([]interface{}); ok {
+		if !isArrayFlag(flags.Lookup(flagName)) {
+			return errors.Errorf("Flag %s cannot be specified multiple times", flagName)
+		}
+		values = list
+	}
+	for _, value := range values {
+		if err := flags.Set(flagName, fmt.Sprint(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+ * The effective configuration holds the value of every flag that was set on
+ * the command line, in the environment, or in a configuration file, in the
+ * format of a configuration file so that it can be used to reproduce the run.
+ */
+func GetEffectiveConfig(flags *pflag.FlagSet) map[string]interface{} {
+	effectiveConfig := make(map[string]interface{})
+	flags.Visit(func(flag *pflag.Flag) {
+		if !isConfigurableFlag(flag.Name) {
+			return
+		}
+		switch flag.Value.Type() {
+		case "bool":
+			effectiveConfig[flag.Name], _ = flags.GetBool(flag.Name)
+		case "int":
+			effectiveConfig[flag.Name], _ = flags.GetInt(flag.Name)
+		case "stringArray":
+			effectiveConfig[flag.Name], _ = flags.GetStringArray(flag.Name)
+		case "stringSlice":
+			effectiveConfig[flag.Name], _ = flags.GetStringSlice(flag.Name)
+		default:
+			effectiveConfig[flag.Name] = flag.Value.String()
+		}
+	})
+	return effectiveConfig
+}
+
+func sortedKeys(configFlags map[string]interface{}) []string {
+	keys := make([]string, 0, len(configFlags))
+	for key := range configFlags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,178 @@
+package options_test
+
+import (
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/spf13/pflag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("options/config tests", func() {
+	var (
+		flagSet     *pflag.FlagSet
+		environment map[string]string
+		configFile  string
+	)
+	BeforeEach(func() {
+		flagSet = pflag.NewFlagSet("testFlags", pflag.ContinueOnError)
+		flagSet.String(options.CONFIG, "", "")
+		flagSet.String(options.DBNAME, "", "")
+		flagSet.Int(options.JOBS, 1, "")
+		flagSet.Bool(options.WITH_STATS, false, "")
+		flagSet.StringArray(options.INCLUDE_SCHEMA, []string{}, "")
+		environment = map[string]string{}
+		configFile = ""
+		operating.System.Getenv = func(key string) string {
+			return environment[key]
+		}
+		operating.System.ReadFile = func(filename string) ([]byte, error) {
+			return []byte(configFile), nil
+		}
+	})
+	AfterEach(func() {
+		operating.System = operating.InitializeSystemFunctions()
+	})
+	Describe("GetFlagEnvironmentVariable", func() {
+		It("returns the upper-case utility and flag name with underscores", func() {
+			Expect(options.GetFlagEnvironmentVariable("gpbackup", options.BACKUP_DIR)).To(Equal("GPBACKUP_BACKUP_DIR"))
+		})
+	})
+	Describe("ApplyConfig", func() {
+		It("sets flags from the configuration file", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml"})
+			configFile = `
+dbname: testdb
+jobs: 4
+with-stats: true
+include-schema: [foo, bar]`
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(Succeed())
+
+			Expect(options.MustGetFlagString(flagSet, options.DBNAME)).To(Equal("testdb"))
+			Expect(options.MustGetFlagInt(flagSet, options.JOBS)).To(Equal(4))
+			Expect(options.MustGetFlagBool(flagSet, options.WITH_STATS)).To(BeTrue())
+			Expect(options.MustGetFlagStringArray(flagSet, options.INCLUDE_SCHEMA)).To(Equal([]string{"foo", "bar"}))
+			Expect(flagSet.Changed(options.JOBS)).To(BeTrue())
+		})
+		It("gives the command line precedence over the environment and the environment precedence over the configuration file", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml", "--dbname", "testdb"})
+			environment["GPBACKUP_DBNAME"] = "envdb"
+			environment["GPBACKUP_JOBS"] = "3"
+			environment["GPBACKUP_INCLUDE_SCHEMA"] = "foo,bar"
+			configFile = `
+dbname: filedb
+jobs: 4
+include-schema: [baz]`
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(Succeed())
+
+			Expect(options.MustGetFlagString(flagSet, options.DBNAME)).To(Equal("testdb"))
+			Expect(options.MustGetFlagInt(flagSet, options.JOBS)).To(Equal(3))
+			Expect(options.MustGetFlagStringArray(flagSet, options.INCLUDE_SCHEMA)).To(Equal([]string{"foo", "bar"}))
+		})
+		It("reads the configuration file named in the environment", func() {
+			environment["GPBACKUP_CONFIG"] = "/tmp/config.yaml"
+			configFile = "jobs: 4"
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(Succeed())
+
+			Expect(options.MustGetFlagInt(flagSet, options.JOBS)).To(Equal(4))
+		})
+		It("gives the profile of the database precedence over the top-level flags", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml"})
+			configFile = `
+dbname: testdb
+jobs: 4
+with-stats: true
+profiles:
+  testdb:
+    jobs: 8
+  otherdb:
+    with-stats: false`
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(Succeed())
+
+			Expect(options.MustGetFlagInt(flagSet, options.JOBS)).To(Equal(8))
+			Expect(options.MustGetFlagBool(flagSet, options.WITH_STATS)).To(BeTrue())
+		})
+		It("applies the profile of a database given to a flag that can be specified multiple times", func() {
+			flagSet.StringArray("databases", []string{}, "")
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml", "--databases", "testdb"})
+			configFile = `
+jobs: 4
+profiles:
+  testdb:
+    jobs: 8`
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", "databases")).To(Succeed())
+
+			Expect(options.MustGetFlagInt(flagSet, options.JOBS)).To(Equal(8))
+		})
+		It("does not apply a profile when more than one database is given", func() {
+			flagSet.StringArray("databases", []string{}, "")
+			configFile = `
+jobs: 4
+databases: [testdb, otherdb]
+profiles:
+  testdb:
+    jobs: 8`
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml"})
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", "databases")).To(Succeed())
+
+			Expect(options.MustGetFlagInt(flagSet, options.JOBS)).To(Equal(4))
+			Expect(options.MustGetFlagStringArray(flagSet, "databases")).To(Equal([]string{"testdb", "otherdb"}))
+		})
+		It("does not read a configuration file when none is given", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) {
+				Fail("configuration file should not be read")
+				return nil, nil
+			}
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(Succeed())
+		})
+		It("returns an error for an unrecognized flag in the configuration file", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml"})
+			configFile = "no-such-flag: true"
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(MatchError("Invalid configuration file /tmp/config.yaml: Unrecognized flag no-such-flag"))
+		})
+		It("returns an error for a list of values for a flag that cannot be specified multiple times", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml"})
+			configFile = "jobs: [1, 2]"
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(MatchError("Invalid configuration file /tmp/config.yaml: Flag jobs cannot be specified multiple times"))
+		})
+		It("returns an error for an invalid value in the environment", func() {
+			environment["GPBACKUP_JOBS"] = "many"
+
+			err := options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Invalid environment variable GPBACKUP_JOBS: "))
+		})
+		It("returns an error when the configuration file cannot be read", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml"})
+			operating.System.ReadFile = func(filename string) ([]byte, error) {
+				return nil, os.ErrNotExist
+			}
+
+			Expect(options.ApplyConfig(flagSet, "gpbackup", options.DBNAME)).To(Equal(os.ErrNotExist))
+		})
+	})
+	Describe("GetEffectiveConfig", func() {
+		It("returns the values of the flags that were set, except the configuration file", func() {
+			_ = flagSet.Parse([]string{"--config", "/tmp/config.yaml", "--jobs", "4", "--with-stats", "--include-schema", "foo"})
+
+			Expect(options.GetEffectiveConfig(flagSet)).To(Equal(map[string]interface{}{
+				options.JOBS:           4,
+				options.WITH_STATS:     true,
+				options.INCLUDE_SCHEMA: []string{"foo"},
+			}))
+		})
+	})
+})
@@ -5,6 +5,7 @@ package options
  */
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -14,35 +15,65 @@ import (
 )
 
 const (
-	BACKUP_DIR            = "backup-dir"
-	COMPRESSION_LEVEL     = "compression-level"
-	DATA_ONLY             = "data-only"
-	DBNAME                = "dbname"
-	DEBUG                 = "debug"
-	EXCLUDE_RELATION      = "exclude-table"
-	EXCLUDE_RELATION_FILE = "exclude-table-file"
-	EXCLUDE_SCHEMA        = "exclude-schema"
-	EXCLUDE_SCHEMA_FILE   = "exclude-schema-file"
-	FROM_TIMESTAMP        = "from-timestamp"
-	INCLUDE_RELATION      = "include-table"
-	INCLUDE_RELATION_FILE = "include-table-file"
-	INCLUDE_SCHEMA        = "include-schema"
-	INCLUDE_SCHEMA_FILE   = "include-schema-file"
-	INCREMENTAL           = "incremental"
-	JOBS                  = "jobs"
-	LEAF_PARTITION_DATA   = "leaf-partition-data"
-	METADATA_ONLY         = "metadata-only"
-	NO_COMPRESSION        = "no-compression"
-	PLUGIN_CONFIG         = "plugin-config"
-	QUIET                 = "quiet"
-	SINGLE_DATA_FILE      = "single-data-file"
-	VERBOSE               = "verbose"
-	WITH_STATS            = "with-stats"
-	CREATE_DB             = "create-db"
-	ON_ERROR_CONTINUE     = "on-error-continue"
-	REDIRECT_DB           = "redirect-db"
-	TIMESTAMP             = "timestamp"
-	WITH_GLOBALS          = "with-globals"
+	ALL_DATABASES            = "all-databases"
+	BACKUP_DIR               = "backup-dir"
+	CLUSTER_TIMESTAMP        = "cluster-timestamp"
+	COMPRESSION_LEVEL        = "compression-level"
+	CONFIG                   = "config"
+	DATA_ONLY                = "data-only"
+	DBNAME                   = "dbname"
+	DEBUG                    = "debug"
+	ENCRYPTION_KEY_COMMAND   = "encryption-key-command"
+	ENCRYPTION_KEY_FILE      = "encryption-key-file"
+	EXCLUDE_OBJECT_TYPE      = "exclude-object-type"
+	EXCLUDE_RELATION         = "exclude-table"
+	EXCLUDE_RELATION_FILE    = "exclude-table-file"
+	EXCLUDE_RELATION_PATTERN = "exclude-table-pattern"
+	EXCLUDE_SCHEMA           = "exclude-schema"
+	EXCLUDE_SCHEMA_FILE      = "exclude-schema-file"
+	EXCLUDE_SCHEMA_PATTERN   = "exclude-schema-pattern"
+	FROM_TIMESTAMP           = "from-timestamp"
+	INCLUDE_DEPENDENCIES     = "include-dependencies"
+	INCLUDE_OBJECT_TYPE      = "include-object-type"
+	INCLUDE_RELATION         = "include-table"
+	INCLUDE_RELATION_FILE    = "include-table-file"
+	INCLUDE_RELATION_PATTERN = "include-table-pattern"
+	INCLUDE_SCHEMA           = "include-schema"
+	INCLUDE_SCHEMA_FILE      = "include-schema-file"
+	INCLUDE_SCHEMA_PATTERN   = "include-schema-pattern"
+	INCREMENTAL              = "incremental"
+	JOBS                     = "jobs"
+	LEAF_PARTITION_DATA      = "leaf-partition-data"
+	LOCK_NOWAIT              = "lock-nowait"
+	LOCK_RETRIES             = "lock-retries"
+	LOCK_RETRY_BACKOFF       = "lock-retry-backoff"
+	LOCK_WAIT_TIMEOUT        = "lock-wait-timeout"
+	METADATA_ONLY            = "metadata-only"
+	NO_CLUSTER_GLOBALS       = "no-cluster-globals"
+	NO_COMPRESSION           = "no-compression"
+	ON_BROKEN_CHAIN          = "on-broken-chain"
+	PLUGIN_CONFIG            = "plugin-config"
+	QUIET                    = "quiet"
+	SINGLE_DATA_FILE         = "single-data-file"
+	SKIP_LOCKED_TABLES       = "skip-locked-tables"
+	STRUCTURED_METADATA      = "structured-metadata"
+	VERBOSE                  = "verbose"
+	WITH_STATS               = "with-stats"
+	CREATE_DB                = "create-db"
+	NO_OWNER                 = "no-owner"
+	NO_PRIVILEGES            = "no-privileges"
+	NO_TABLESPACES           = "no-tablespaces"
+	ON_ERROR_CONTINUE        = "on-error-continue"
+	OUTPUT_SQL               = "output-sql"
+	REDIRECT_DB              = "redirect-db"
+	RESIZE_CLUSTER           = "resize-cluster"
+	RESIZE_MAPPING_FILE      = "resize-mapping-file"
+	ROLE_MAPPING             = "role-mapping"
+	SECTION                  = "section"
+	TABLESPACE_MAPPING       = "tablespace-mapping"
+	TABLE_RULES_FILE         = "table-rules-file"
+	TIMESTAMP                = "timestamp"
+	WITH_GLOBALS             = "with-globals"
 )
 
 /*
@@ -80,6 +111,31 @@ func HandleSingleDashes(args []string) []string {
 	return newArgs
 }
 
+/*
+ * Remove the given flags, and their values, from command-line arguments that
+ * have already been passed through HandleSingleDashes.
+ */
+func RemoveFlagsFromArgs(flags *pflag.FlagSet, args []string, flagNames ...string) []string {
+	newArgs := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		removed := false
+		for _, name := range flagNames {
+			if strings.HasPrefix(args[i], fmt.Sprintf("--%s=", name)) {
+				removed = true
+			} else if args[i] == fmt.Sprintf("--%s", name) {
+				removed = true
+				if flags.Lookup(name).NoOptDefVal == "" {
+					i++
+				}
+			}
+		}
+		if !removed {
+			newArgs = append(newArgs, args[i])
+		}
+	}
+	return newArgs
+}
+
 func MustGetFlagString(cmdFlags *pflag.FlagSet, flagName string) string {
 	value, err := cmdFlags.GetString(flagName)
 	gplog.FatalOnError(err)
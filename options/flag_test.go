@@ -57,5 +57,15 @@ var _ = Describe("utils/flag tests", func() {
 				Expect(result).To(Equal([]string{"-s", "some_argument"}))
 			})
 		})
+		Context("RemoveFlagsFromArgs", func() {
+			It("removes flags and their values", func() {
+				result := options.RemoveFlagsFromArgs(flagSet, []string{"--stringFlag", "value", "--other", "--stringFlag=value2", "argument"}, "stringFlag")
+				Expect(result).To(Equal([]string{"--other", "argument"}))
+			})
+			It("removes boolean flags without removing the next argument", func() {
+				result := options.RemoveFlagsFromArgs(flagSet, []string{"--boolFlag", "--other", "--boolFlag=false"}, "boolFlag")
+				Expect(result).To(Equal([]string{"--other"}))
+			})
+		})
 	})
 })
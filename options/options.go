@@ -3,9 +3,11 @@ package options
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
@@ -19,6 +21,8 @@ type Options struct {
 	excludedSchemas           []string
 	includedSchemas           []string
 	originalIncludedRelations []string
+	includedObjectTypes       []string
+	excludedObjectTypes       []string
 }
 
 func NewOptions(initialFlags *pflag.FlagSet) (*Options, error) {
@@ -55,6 +59,16 @@ func NewOptions(initialFlags *pflag.FlagSet) (*Options, error) {
 		return nil, err
 	}
 
+	includedObjectTypes, err := getObjectTypeFilters(initialFlags, INCLUDE_OBJECT_TYPE)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedObjectTypes, err := getObjectTypeFilters(initialFlags, EXCLUDE_OBJECT_TYPE)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Options{
 		includedRelations:         includedRelations,
 		excludedRelations:		   excludedRelations,
@@ -62,6 +76,8 @@ func NewOptions(initialFlags *pflag.FlagSet) (*Options, error) {
 		excludedSchemas:           excludedSchemas,
 		isLeafPartitionData:       leafPartitionData,
 		originalIncludedRelations: includedRelations,
+		includedObjectTypes:       includedObjectTypes,
+		excludedObjectTypes:       excludedObjectTypes,
 	}, nil
 }
 
@@ -119,6 +135,14 @@ func (o Options) GetExcludedSchemas() []string {
 	return o.excludedSchemas
 }
 
+func (o Options) GetIncludedObjectTypes() []string {
+	return o.includedObjectTypes
+}
+
+func (o Options) GetExcludedObjectTypes() []string {
+	return o.excludedObjectTypes
+}
+
 func (o *Options) AddIncludedRelation(relation string) {
 	o.includedRelations = append(o.includedRelations, relation)
 }
@@ -196,6 +220,199 @@ func ValidateCharacters(tableList []string) error {
 	return nil
 }
 
+/*
+ * Filter patterns use glob syntax, where * matches any string of characters
+ * and ? matches any single character, unless they are enclosed in slashes, in
+ * which case they are regular expressions. Either way, a pattern must match
+ * the whole name.
+ */
+func CompileFilterPattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		regex, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern[1:len(pattern)-1]))
+		if err != nil {
+			return nil, errors.Errorf("Invalid regular expression in pattern %s: %s", pattern, err.Error())
+		}
+		return regex, nil
+	}
+	regexStr := regexp.QuoteMeta(pattern)
+	regexStr = strings.Replace(regexStr, `\*`, ".*", -1)
+	regexStr = strings.Replace(regexStr, `\?`, ".", -1)
+	return regexp.Compile(fmt.Sprintf("^%s$", regexStr))
+}
+
+func MatchSchemaPatterns(patterns []string, schemas []string) ([]string, error) {
+	matches := make([]string, 0)
+	isMatched := make(map[string]bool)
+	for _, pattern := range patterns {
+		regex, err := CompileFilterPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, schema := range schemas {
+			if !isMatched[schema] && regex.MatchString(schema) {
+				isMatched[schema] = true
+				matches = append(matches, schema)
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+/*
+ * Like pg_dump, a glob pattern without a dot matches tables with that name in
+ * any schema, and a pattern with a dot matches the schema and table name on
+ * either side of its first dot separately. Regular expressions are matched
+ * against the whole schema.table name.
+ */
+func CompileRelationPattern(pattern string) (func(schema string, table string) bool, error) {
+	if strings.HasPrefix(pattern, "/") {
+		fqnRegex, err := CompileFilterPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(schema string, table string) bool {
+			return fqnRegex.MatchString(fmt.Sprintf("%s.%s", schema, table))
+		}, nil
+	}
+	var schemaRegex, tableRegex *regexp.Regexp
+	var err error
+	if dotIndex := strings.Index(pattern, "."); dotIndex != -1 {
+		schemaRegex, err = CompileFilterPattern(pattern[:dotIndex])
+		if err == nil {
+			tableRegex, err = CompileFilterPattern(pattern[dotIndex+1:])
+		}
+	} else {
+		tableRegex, err = CompileFilterPattern(pattern)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(schema string, table string) bool {
+		return (schemaRegex == nil || schemaRegex.MatchString(schema)) && tableRegex.MatchString(table)
+	}, nil
+}
+
+func MatchRelationPatterns(patterns []string, relations []FqnStruct) ([]string, error) {
+	matchedRelations, err := FilterRelationPatterns(patterns, relations)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0, len(matchedRelations))
+	for _, relation := range matchedRelations {
+		matches = append(matches, fmt.Sprintf("%s.%s", relation.SchemaName, relation.TableName))
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Returns the relations that match any of the patterns, in the order they are given
+func FilterRelationPatterns(patterns []string, relations []FqnStruct) ([]FqnStruct, error) {
+	matches := make([]FqnStruct, 0)
+	isMatched := make(map[FqnStruct]bool)
+	for _, pattern := range patterns {
+		isMatch, err := CompileRelationPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, relation := range relations {
+			if !isMatched[relation] && isMatch(relation.SchemaName, relation.TableName) {
+				isMatched[relation] = true
+				matches = append(matches, relation)
+			}
+		}
+	}
+	return matches, nil
+}
+
+/*
+ * Names matched by a filter pattern are added to the corresponding filter
+ * flag, in the same way as names read from a filter file. An include pattern
+ * that matches nothing is an error, since it would otherwise silently filter
+ * out everything.
+ */
+func AddFilterPatternMatches(initialFlags *pflag.FlagSet, patternFlag string, filterFlag string, matches []string) {
+	patterns, err := initialFlags.GetStringArray(patternFlag)
+	gplog.FatalOnError(err)
+	if len(patterns) == 0 {
+		return
+	}
+	patternStr := strings.Join(patterns, ", ")
+	if len(matches) == 0 {
+		if filterFlag == INCLUDE_SCHEMA || filterFlag == INCLUDE_RELATION {
+			gplog.Fatal(errors.Errorf("No objects match the --%s pattern(s): %s", patternFlag, patternStr), "")
+		}
+		gplog.Warn("No objects match the --%s pattern(s): %s", patternFlag, patternStr)
+		return
+	}
+	gplog.Info("The --%s pattern(s) %s matched: %s", patternFlag, patternStr, strings.Join(matches, ", "))
+	for _, match := range matches {
+		err = initialFlags.Set(filterFlag, match)
+		gplog.FatalOnError(err)
+	}
+}
+
+/*
+ * Object types are the types recorded for metadata entries in the TOC, plus
+ * the types of the comment, privilege, and security label statements that
+ * are written along with most objects.
+ */
+var ObjectTypes = []string{"ACCESS METHOD", "AGGREGATE", "CAST", "COLLATION", "CONSTRAINT", "CONVERSION",
+	"DATABASE", "DATABASE GUC", "DATABASE METADATA", "DEFAULT PRIVILEGES", "DOMAIN",
+	"EVENT TRIGGER", "EXCHANGE PARTITION", "EXTENDED STATISTICS", "EXTENSION", "FOREIGN DATA WRAPPER", "FOREIGN SERVER",
+	"FOREIGN TABLE", "FUNCTION",
+	"INDEX", "LANGUAGE", "LARGE OBJECT", "MATERIALIZED VIEW", "OPERATOR", "OPERATOR CLASS", "OPERATOR FAMILY",
+	"POLICY", "PROCEDURE", "PROTOCOL", "PUBLICATION", "RESOURCE GROUP", "RESOURCE QUEUE", "ROLE", "ROLE GRANT", "ROLE GUCS", "RULE",
+	"SCHEMA", "SEQUENCE", "SEQUENCE OWNER", "STATISTICS", "SUBSCRIPTION", "TABLE", "TABLESPACE",
+	"TEXT SEARCH CONFIGURATION", "TEXT SEARCH DICTIONARY", "TEXT SEARCH PARSER",
+	"TEXT SEARCH TEMPLATE", "TRIGGER", "TYPE", "USER MAPPING", "VIEW"}
+
+var MetadataAttributeTypes = []string{"COMMENT", "PRIVILEGES", "SECURITY LABEL"}
+
+func getObjectTypeFilters(initialFlags *pflag.FlagSet, filterFlag string) ([]string, error) {
+	filters, err := initialFlags.GetStringArray(filterFlag)
+	if err != nil {
+		return nil, err
+	}
+	var objectTypes []string
+	for _, filter := range filters {
+		objectType := strings.ToUpper(strings.TrimSpace(filter))
+		isAttributeType := utils.Exists(MetadataAttributeTypes, objectType)
+		if !utils.Exists(ObjectTypes, objectType) && !isAttributeType {
+			return nil, errors.Errorf(`Invalid object type "%s" for --%s. Valid object types are: %s.`,
+				filter, filterFlag, strings.Join(append(ObjectTypes, MetadataAttributeTypes...), ", "))
+		}
+		if isAttributeType && filterFlag == INCLUDE_OBJECT_TYPE {
+			return nil, errors.Errorf("Object type %s can only be used with --%s.", objectType, EXCLUDE_OBJECT_TYPE)
+		}
+		objectTypes = append(objectTypes, objectType)
+	}
+	return objectTypes, nil
+}
+
+/*
+ * Comments, privileges, and security labels are always included along with
+ * the objects they belong to unless they are explicitly excluded, so they are
+ * not affected by a list of included object types.
+ */
+func ObjectTypeMatchesFilter(includedObjectTypes []string, excludedObjectTypes []string, objectType string) bool {
+	objectType = strings.ToUpper(objectType)
+	for _, excluded := range excludedObjectTypes {
+		if strings.ToUpper(excluded) == objectType {
+			return false
+		}
+	}
+	if len(includedObjectTypes) == 0 || utils.Exists(MetadataAttributeTypes, objectType) {
+		return true
+	}
+	for _, included := range includedObjectTypes {
+		if strings.ToUpper(included) == objectType {
+			return true
+		}
+	}
+	return false
+}
+
 func (o *Options) ExpandIncludesForPartitions(conn *dbconn.DBConn, flags *pflag.FlagSet) error {
 	if len(o.GetIncludedTables()) == 0 {
 		return nil
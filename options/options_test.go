@@ -139,6 +139,37 @@ var _ = Describe("options", func() {
 			_, err = options.NewOptions(myflags)
 			Expect(err).To(HaveOccurred())
 		})
+		It("returns the included and excluded object types in upper case", func() {
+			_ = myflags.Set(options.INCLUDE_OBJECT_TYPE, "table")
+			_ = myflags.Set(options.INCLUDE_OBJECT_TYPE, "Text Search Parser")
+			_ = myflags.Set(options.EXCLUDE_OBJECT_TYPE, "comment")
+
+			subject, err := options.NewOptions(myflags)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Expect(subject.GetIncludedObjectTypes()).To(Equal([]string{"TABLE", "TEXT SEARCH PARSER"}))
+			Expect(subject.GetExcludedObjectTypes()).To(Equal([]string{"COMMENT"}))
+		})
+		It("accepts the object types of foreign tables and exchanged partitions", func() {
+			_ = myflags.Set(options.INCLUDE_OBJECT_TYPE, "foreign table")
+			_ = myflags.Set(options.EXCLUDE_OBJECT_TYPE, "Exchange Partition")
+
+			subject, err := options.NewOptions(myflags)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Expect(subject.GetIncludedObjectTypes()).To(Equal([]string{"FOREIGN TABLE"}))
+			Expect(subject.GetExcludedObjectTypes()).To(Equal([]string{"EXCHANGE PARTITION"}))
+		})
+		It("returns an error upon an invalid object type", func() {
+			_ = myflags.Set(options.EXCLUDE_OBJECT_TYPE, "WIDGET")
+			_, err := options.NewOptions(myflags)
+			Expect(err).To(MatchError(ContainSubstring(`Invalid object type "WIDGET" for --exclude-object-type.`)))
+		})
+		It("returns an error when including comments, privileges, or security labels", func() {
+			_ = myflags.Set(options.INCLUDE_OBJECT_TYPE, "privileges")
+			_, err := options.NewOptions(myflags)
+			Expect(err).To(MatchError("Object type PRIVILEGES can only be used with --exclude-object-type."))
+		})
 		Describe("AddIncludeRelation", func() {
 			It("it adds a relation", func() {
 				subject, err := options.NewOptions(myflags)
@@ -149,6 +180,95 @@ var _ = Describe("options", func() {
 			})
 		})
 	})
+	Describe("ObjectTypeMatchesFilter", func() {
+		It("matches every object type when there are no filters", func() {
+			Expect(options.ObjectTypeMatchesFilter([]string{}, []string{}, "TABLE")).To(BeTrue())
+		})
+		It("matches only included object types", func() {
+			Expect(options.ObjectTypeMatchesFilter([]string{"TABLE"}, []string{}, "TABLE")).To(BeTrue())
+			Expect(options.ObjectTypeMatchesFilter([]string{"TABLE"}, []string{}, "VIEW")).To(BeFalse())
+		})
+		It("does not match excluded object types, ignoring case", func() {
+			Expect(options.ObjectTypeMatchesFilter([]string{}, []string{"role"}, "ROLE")).To(BeFalse())
+			Expect(options.ObjectTypeMatchesFilter([]string{}, []string{"role"}, "TABLE")).To(BeTrue())
+		})
+		It("matches comments, privileges, and security labels unless they are excluded", func() {
+			Expect(options.ObjectTypeMatchesFilter([]string{"TABLE"}, []string{}, "COMMENT")).To(BeTrue())
+			Expect(options.ObjectTypeMatchesFilter([]string{"TABLE"}, []string{"COMMENT"}, "COMMENT")).To(BeFalse())
+		})
+	})
+	Describe("CompileFilterPattern", func() {
+		It("matches whole names against a glob pattern", func() {
+			regex, err := options.CompileFilterPattern("stage_*")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(regex.MatchString("stage_2020")).To(BeTrue())
+			Expect(regex.MatchString("old_stage_2020")).To(BeFalse())
+		})
+		It("treats ? as any single character and other characters literally", func() {
+			regex, err := options.CompileFilterPattern("t?.(1)")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(regex.MatchString("t1.(1)")).To(BeTrue())
+			Expect(regex.MatchString("t12.(1)")).To(BeFalse())
+		})
+		It("matches whole names against a regular expression enclosed in slashes", func() {
+			regex, err := options.CompileFilterPattern("/stage_[0-9]+/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(regex.MatchString("stage_2020")).To(BeTrue())
+			Expect(regex.MatchString("stage_2020_old")).To(BeFalse())
+		})
+		It("returns an error for an invalid regular expression", func() {
+			_, err := options.CompileFilterPattern("/stage_[/")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("MatchSchemaPatterns", func() {
+		It("returns the sorted schemas matching any pattern", func() {
+			matches, err := options.MatchSchemaPatterns([]string{"etl*", "/stag(e|ing)/"}, []string{"public", "etl_2", "staging", "etl_1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(Equal([]string{"etl_1", "etl_2", "staging"}))
+		})
+	})
+	Describe("MatchRelationPatterns", func() {
+		relations := []options.FqnStruct{
+			{SchemaName: "etl", TableName: "stage_1"},
+			{SchemaName: "etl", TableName: "final"},
+			{SchemaName: "public", TableName: "stage_1"},
+		}
+		It("matches the schema and table name separately for a glob pattern with a dot", func() {
+			matches, err := options.MatchRelationPatterns([]string{"etl.stage_*"}, relations)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(Equal([]string{"etl.stage_1"}))
+		})
+		It("matches tables in any schema for a glob pattern without a dot", func() {
+			matches, err := options.MatchRelationPatterns([]string{"stage_*"}, relations)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(Equal([]string{"etl.stage_1", "public.stage_1"}))
+		})
+		It("matches the fully-qualified name for a regular expression", func() {
+			matches, err := options.MatchRelationPatterns([]string{`/etl\.(final|stage_1)/`}, relations)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(Equal([]string{"etl.final", "etl.stage_1"}))
+		})
+	})
+	Describe("AddFilterPatternMatches", func() {
+		It("adds the matches to the filter flag", func() {
+			_ = myflags.Set(options.INCLUDE_RELATION_PATTERN, "etl.stage_*")
+			options.AddFilterPatternMatches(myflags, options.INCLUDE_RELATION_PATTERN, options.INCLUDE_RELATION, []string{"etl.stage_1", "etl.stage_2"})
+
+			Expect(options.MustGetFlagStringArray(myflags, options.INCLUDE_RELATION)).To(Equal([]string{"etl.stage_1", "etl.stage_2"}))
+		})
+		It("panics when an include pattern matches nothing", func() {
+			_ = myflags.Set(options.INCLUDE_RELATION_PATTERN, "etl.stage_*")
+			defer testhelper.ShouldPanicWithMessage("No objects match the --include-table-pattern pattern(s): etl.stage_*")
+			options.AddFilterPatternMatches(myflags, options.INCLUDE_RELATION_PATTERN, options.INCLUDE_RELATION, []string{})
+		})
+		It("does not panic when an exclude pattern matches nothing", func() {
+			_ = myflags.Set(options.EXCLUDE_SCHEMA_PATTERN, "etl*")
+			options.AddFilterPatternMatches(myflags, options.EXCLUDE_SCHEMA_PATTERN, options.EXCLUDE_SCHEMA, []string{})
+
+			Expect(options.MustGetFlagStringArray(myflags, options.EXCLUDE_SCHEMA)).To(BeEmpty())
+		})
+	})
 	Describe("character validation", func() {
 		It("succeeds if characters are valid", func() {
 			tableList := []string{"foo.bar", "foo.Bar", "FOO.Bar", "FO!@#.BAR"}
@@ -29,6 +29,8 @@ import (
 type Report struct {
 	BackupParamsString string
 	DatabaseSize       string
+	LockWaitDuration   time.Duration
+	SkippedTables      []string
 	history.BackupConfig
 }
 
@@ -54,6 +56,9 @@ func (report *Report) ConstructBackupParamsString() {
 	}
 	if report.IncludeTableFiltered {
 		filterStr = "Include Table Filter"
+		if report.IncludeDependencies {
+			filterStr += " With Dependencies"
+		}
 	}
 	if report.ExcludeSchemaFiltered {
 		filterStr = "Exclude Schema Filter"
@@ -103,6 +108,9 @@ data file format: %s
 
 func (report *Report) constructIncrementalSection() string {
 	if !report.Incremental {
+		if report.ConsolidatedFrom != "" {
+			return fmt.Sprintf("incremental: False\nconsolidated from: %s", report.ConsolidatedFrom)
+		}
 		return "incremental: False"
 	}
 	backupTimestamps := make([]string, 0)
@@ -127,19 +135,33 @@ func (report *Report) WriteBackupReportFile(reportFilename string, timestamp str
 	reportInfo := make([]LineInfo, 0)
 	reportInfo = append(reportInfo,
 		LineInfo{Key: "timestamp key:", Value: timestamp},
+	)
+	if report.ClusterTimestamp != "" {
+		reportInfo = append(reportInfo, LineInfo{Key: "cluster timestamp:", Value: report.ClusterTimestamp})
+	}
+	reportInfo = append(reportInfo,
 		LineInfo{Key: "gpdb version:", Value: report.DatabaseVersion},
 		LineInfo{Key: "gpbackup version:", Value: fmt.Sprintf("%s\n", report.BackupVersion)},
 		LineInfo{Key: "database name:", Value: report.DatabaseName},
 		LineInfo{Key: "command line:", Value: gpbackupCommandLine},
 	)
+	if len(report.EffectiveConfig) > 0 {
+		reportInfo = append(reportInfo, LineInfo{Key: "effective config:", Value: FormatEffectiveConfig(report.EffectiveConfig)})
+	}
 
 	AppendBackupParams(&reportInfo, report.BackupParamsString)
+	if report.SnapshotID != "" {
+		reportInfo = append(reportInfo, LineInfo{Key: "snapshot id:", Value: report.SnapshotID})
+	}
 
 	reportInfo = append(reportInfo,
 		LineInfo{},
 		LineInfo{Key: "start time:", Value: start},
 		LineInfo{Key: "end time:", Value: end},
 		LineInfo{Key: "duration:", Value: duration})
+	if report.LockWaitDuration != 0 {
+		reportInfo = append(reportInfo, LineInfo{Key: "lock wait time:", Value: reformatDuration(report.LockWaitDuration)})
+	}
 
 	if errMsg != "" {
 		reportInfo = append(reportInfo,
@@ -151,6 +173,10 @@ func (report *Report) WriteBackupReportFile(reportFilename string, timestamp str
 			LineInfo{},
 			LineInfo{Key: "backup status:", Value: "Success"})
 	}
+	if len(report.SkippedTables) > 0 {
+		reportInfo = append(reportInfo,
+			LineInfo{Key: "skipped tables:", Value: strings.Join(report.SkippedTables, ", ")})
+	}
 	if report.DatabaseSize != "" {
 		reportInfo = append(reportInfo,
 			LineInfo{},
@@ -172,7 +198,7 @@ func (report *Report) WriteBackupReportFile(reportFilename string, timestamp str
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
-func WriteRestoreReportFile(reportFilename string, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn, restoreVersion string, errMsg string) {
+func WriteRestoreReportFile(reportFilename string, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn, restoreVersion string, effectiveConfig map[string]interface{}, errMsg string) {
 	reportFile, err := iohelper.OpenFileForWriting(reportFilename)
 	if err != nil {
 		gplog.Error("Unable to open restore report file %s", reportFilename)
@@ -190,7 +216,13 @@ func WriteRestoreReportFile(reportFilename string, backupTimestamp string, start
 		LineInfo{Key: "gpdb version:", Value: connectionPool.Version.VersionString},
 		LineInfo{Key: "gprestore version:", Value: fmt.Sprintf("%s\n", restoreVersion)},
 		LineInfo{Key: "database name:", Value: connectionPool.DBName},
-		LineInfo{Key: "command line:", Value: fmt.Sprintf("%s\n", gprestoreCommandLine)},
+		LineInfo{Key: "command line:", Value: gprestoreCommandLine},
+	)
+	if len(effectiveConfig) > 0 {
+		reportInfo = append(reportInfo, LineInfo{Key: "effective config:", Value: FormatEffectiveConfig(effectiveConfig)})
+	}
+	reportInfo = append(reportInfo,
+		LineInfo{},
 		LineInfo{Key: "start time:", Value: start},
 		LineInfo{Key: "end time:", Value: end},
 		LineInfo{Key: "duration:", Value: duration},
@@ -221,6 +253,40 @@ func WriteRestoreReportFile(reportFilename string, backupTimestamp string, start
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
+/*
+ * The effective configuration is printed as the flags that would reproduce it
+ * on the command line, in alphabetical order.
+ */
+func FormatEffectiveConfig(effectiveConfig map[string]interface{}) string {
+	flagNames := make([]string, 0, len(effectiveConfig))
+	for flagName := range effectiveConfig {
+		flagNames = append(flagNames, flagName)
+	}
+	sort.Strings(flagNames)
+	flags := make([]string, 0, len(flagNames))
+	for _, flagName := range flagNames {
+		switch value := effectiveConfig[flagName].(type) {
+		case bool:
+			if value {
+				flags = append(flags, fmt.Sprintf("--%s", flagName))
+			} else {
+				flags = append(flags, fmt.Sprintf("--%s=false", flagName))
+			}
+		case []string:
+			for _, item := range value {
+				flags = append(flags, fmt.Sprintf("--%s=%s", flagName, item))
+			}
+		case []interface{}:
+			for _, item := range value {
+				flags = append(flags, fmt.Sprintf("--%s=%v", flagName, item))
+			}
+		default:
+			flags = append(flags, fmt.Sprintf("--%s=%v", flagName, value))
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
 func logOutputReport(reportFile io.WriteCloser, reportInfo []LineInfo) {
 	maxSize := 0
 	for _, lineInfo := range reportInfo {
@@ -149,6 +149,42 @@ count of database objects in backup:
 sequences   1
 tables      42
 types       1000`))
+		})
+		It("writes a report with the snapshot used by the backup", func() {
+			backupReport.SnapshotID = "00000005-00000002-1"
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`data file format:      Single Data File Per Segment
+snapshot id:           00000005-00000002-1
+
+start time:            Sun Jan 01 2017 01:01:01`))
+		})
+		It("writes a report with the timestamp of the backup of several databases that the backup is part of", func() {
+			backupReport.ClusterTimestamp = "20170101010100"
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`timestamp key:         20170101010101
+cluster timestamp:     20170101010100
+gpdb version:          5\.0\.0 build test`))
+		})
+		It("writes a report with the effective configuration of the backup", func() {
+			backupReport.EffectiveConfig = map[string]interface{}{"dbname": "testdb", "jobs": 4}
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`command line:          .*
+effective config:      --dbname=testdb --jobs=4
+compression:           gzip`))
+		})
+		It("writes a report with the time spent acquiring locks", func() {
+			backupReport.LockWaitDuration = 75 * time.Second
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`duration:              4:03:02
+lock wait time:        0:01:15
+
+backup status:         Success`))
+		})
+		It("writes a report listing the tables that could not be locked", func() {
+			backupReport.SkippedTables = []string{"public.foo", "public.bar"}
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`backup status:         Success
+skipped tables:        public.foo, public.bar`))
 		})
 		It("writes a report without database size information", func() {
 			backupReport.DatabaseSize = ""
@@ -239,7 +275,7 @@ incremental backup set:
 
 		It("writes a report for a failed restore", func() {
 			gplog.SetErrorCode(2)
-			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "Cannot access /tmp/backups: Permission denied")
+			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, "Cannot access /tmp/backups: Permission denied")
 			Expect(buffer).To(Say(`Greenplum Database Restore Report
 
 timestamp key:       20170101010101
@@ -258,7 +294,7 @@ restore error:       Cannot access /tmp/backups: Permission denied`))
 		})
 		It("writes a report for a successful restore", func() {
 			gplog.SetErrorCode(0)
-			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "")
+			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, "")
 			Expect(buffer).To(Say(`Greenplum Database Restore Report
 
 timestamp key:       20170101010101
@@ -276,7 +312,7 @@ restore status:      Success`))
 		})
 		It("writes a report for a successful restore with errors", func() {
 			gplog.SetErrorCode(1)
-			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "")
+			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, nil, "")
 			Expect(buffer).To(Say(`Greenplum Database Restore Report
 
 timestamp key:       20170101010101
@@ -292,6 +328,25 @@ duration:            4:03:01
 
 restore status:      Success but non-fatal errors occurred. See log file .+ for details.`))
 		})
+		It("writes a report with the effective configuration of the restore", func() {
+			WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, map[string]interface{}{"timestamp": timestamp}, "")
+			Expect(buffer).To(Say(`command line:        .*
+effective config:    --timestamp=20170101010101
+
+start time:`))
+		})
+	})
+	Describe("FormatEffectiveConfig", func() {
+		It("formats the configuration as command-line flags in alphabetical order", func() {
+			effectiveConfig := map[string]interface{}{
+				"with-stats":          true,
+				"leaf-partition-data": false,
+				"include-schema":      []string{"foo", "bar"},
+				"exclude-table":       []interface{}{"public.baz"},
+				"jobs":                4,
+			}
+			Expect(FormatEffectiveConfig(effectiveConfig)).To(Equal("--exclude-table=public.baz --include-schema=foo --include-schema=bar --jobs=4 --leaf-partition-data=false --with-stats"))
+		})
 	})
 	Describe("SetBackupParamFromFlags", func() {
 		AfterEach(func() {
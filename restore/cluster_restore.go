@@ -0,0 +1,161 @@
+package restore
+
+/*
+ * This file contains functions related to restoring the databases of a backup
+ * of several databases, which gpbackup takes as one backup per database that
+ * records the timestamp of the cluster backup in the backup history.
+ */
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+func IsClusterRestore() bool {
+	return MustGetFlagString(options.CLUSTER_TIMESTAMP) != ""
+}
+
+/*
+ * The gprestore process that restores one database of a cluster backup is
+ * given an empty --cluster-timestamp, so the flags are checked by value.
+ */
+func ValidateClusterRestoreFlags(flags *pflag.FlagSet) {
+	timestamp, _ := flags.GetString(options.TIMESTAMP)
+	clusterTimestamp, _ := flags.GetString(options.CLUSTER_TIMESTAMP)
+	redirectDB, _ := flags.GetString(options.REDIRECT_DB)
+	dbNames, _ := flags.GetStringArray(options.DBNAME)
+	if timestamp != "" && clusterTimestamp != "" {
+		gplog.Fatal(errors.Errorf("The following flags may not be specified together: %s, %s", options.TIMESTAMP, options.CLUSTER_TIMESTAMP), "")
+	}
+	if timestamp == "" && clusterTimestamp == "" {
+		gplog.Fatal(errors.Errorf("Either --timestamp or --cluster-timestamp must be specified"), "")
+	}
+	if clusterTimestamp != "" && redirectDB != "" {
+		gplog.Fatal(errors.Errorf("The following flags may not be specified together: %s, %s", options.CLUSTER_TIMESTAMP, options.REDIRECT_DB), "")
+	}
+	if len(dbNames) > 0 && !flags.Changed(options.CLUSTER_TIMESTAMP) {
+		gplog.Fatal(errors.Errorf("--dbname must be specified with --cluster-timestamp"), "")
+	}
+}
+
+/*
+ * Returns the backups of the given databases, or of all databases if none are
+ * given, in the cluster backup. The backup that holds the global objects shared
+ * by all databases is returned first, so that they exist before the other
+ * databases are restored.
+ */
+func GetClusterBackupConfigs(backupHistory *history.History, clusterTimestamp string, dbNames []string) []history.BackupConfig {
+	configs := make([]history.BackupConfig, 0)
+	configsByDBName := make(map[string]history.BackupConfig)
+	// The history is sorted from the newest backup to the oldest
+	for i := len(backupHistory.BackupConfigs) - 1; i >= 0; i-- {
+		config := backupHistory.BackupConfigs[i]
+		if config.ClusterTimestamp != clusterTimestamp || config.DateDeleted != "" {
+			continue
+		}
+		configsByDBName[utils.UnquoteIdent(config.DatabaseName)] = config
+		if len(dbNames) == 0 {
+			configs = append(configs, config)
+		}
+	}
+	if len(configsByDBName) == 0 {
+		gplog.Fatal(errors.Errorf("No backups with cluster timestamp %s were found in the backup history", clusterTimestamp), "")
+	}
+	for _, dbName := range dbNames {
+		config, ok := configsByDBName[dbName]
+		if !ok {
+			gplog.Fatal(errors.Errorf("Backup with cluster timestamp %s does not contain a backup of database %s", clusterTimestamp, dbName), "")
+		}
+		configs = append(configs, config)
+	}
+	for i, config := range configs {
+		if !config.NoClusterGlobals {
+			copy(configs[1:i+1], configs[:i])
+			configs[0] = config
+			break
+		}
+	}
+	return configs
+}
+
+/*
+ * The arguments of the gprestore process that restores one database are those
+ * of this process with the timestamp of that database's backup in place of
+ * the cluster timestamp.
+ */
+func GetDatabaseRestoreArgs(flags *pflag.FlagSet, args []string, timestamp string) []string {
+	dbArgs := options.RemoveFlagsFromArgs(flags, options.HandleSingleDashes(args), options.CLUSTER_TIMESTAMP, options.DBNAME, options.TIMESTAMP)
+	return append(dbArgs,
+		fmt.Sprintf("--%s", options.TIMESTAMP), timestamp,
+		fmt.Sprintf("--%s=", options.CLUSTER_TIMESTAMP))
+}
+
+func DoClusterRestore() {
+	SetLoggerVerbosity()
+	gplog.Verbose("Cluster Restore Command: %s", os.Args)
+
+	conn := dbconn.NewDBConnFromEnvironment("postgres")
+	conn.MustConnect(1)
+	segPrefix := filepath.GetSegPrefix(conn)
+	globalCluster = cluster.NewCluster(cluster.MustGetSegmentConfiguration(conn))
+	conn.Close()
+
+	clusterTimestamp := MustGetFlagString(options.CLUSTER_TIMESTAMP)
+	helperFPInfo := filepath.NewFilePathInfo(globalCluster, "", clusterTimestamp, segPrefix)
+	historyFilename := helperFPInfo.GetBackupHistoryFilePath()
+	if !iohelper.FileExistsAndIsReadable(historyFilename) {
+		gplog.Fatal(errors.Errorf("Backup history file %s does not exist", historyFilename), "")
+	}
+	backupHistory, err := history.NewHistory(historyFilename)
+	gplog.FatalOnError(err)
+	configs := GetClusterBackupConfigs(backupHistory, clusterTimestamp, MustGetFlagStringArray(options.DBNAME))
+	if configs[0].NoClusterGlobals && ShouldRestoreSection("global") {
+		gplog.Warn("The global objects shared by all databases, such as roles and tablespaces, are not in the backups of the databases being restored and will not be restored")
+	}
+	executable, err := os.Executable()
+	gplog.FatalOnError(err)
+
+	dbNames := make([]string, 0, len(configs))
+	for _, config := range configs {
+		dbNames = append(dbNames, config.DatabaseName)
+	}
+	gplog.Info("Restoring %d databases: %s", len(dbNames), strings.Join(dbNames, ", "))
+
+	failedDBNames := make([]string, 0)
+	for i, config := range configs {
+		if wasTerminated {
+			return
+		}
+		gplog.Info("Restoring database %s from backup %s (%d of %d)", config.DatabaseName, config.Timestamp, i+1, len(configs))
+		cmd := exec.Command(executable, GetDatabaseRestoreArgs(cmdFlags, os.Args[1:], config.Timestamp)...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			gplog.Error("Restore of database %s completed with errors", config.DatabaseName)
+		} else if err != nil {
+			gplog.Error("Restore of database %s failed: %v", config.DatabaseName, err)
+			failedDBNames = append(failedDBNames, config.DatabaseName)
+		}
+	}
+	if len(failedDBNames) > 0 {
+		gplog.Fatal(errors.Errorf("Restores of %d of %d databases failed: %s", len(failedDBNames), len(configs), strings.Join(failedDBNames, ", ")), "")
+	}
+}
+
+func DoClusterRestoreTeardown() {
+	utils.TeardownAndExit(recover(), DoCleanup, CleanupGroup, &wasTerminated, "Cluster restore completed successfully")
+}
@@ -0,0 +1,87 @@
+package restore_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/cluster_restore tests", func() {
+	Describe("ValidateClusterRestoreFlags", func() {
+		It("passes when restoring one backup", func() {
+			_ = cmdFlags.Set(options.TIMESTAMP, "20170101010101")
+			restore.ValidateClusterRestoreFlags(cmdFlags)
+		})
+		It("passes when restoring some of the databases of a cluster backup", func() {
+			_ = cmdFlags.Set(options.CLUSTER_TIMESTAMP, "20170101010101")
+			_ = cmdFlags.Set(options.DBNAME, "testdb")
+			restore.ValidateClusterRestoreFlags(cmdFlags)
+		})
+		It("panics when neither --timestamp nor --cluster-timestamp is used", func() {
+			defer testhelper.ShouldPanicWithMessage("Either --timestamp or --cluster-timestamp must be specified")
+			restore.ValidateClusterRestoreFlags(cmdFlags)
+		})
+		It("panics when --timestamp is used with --cluster-timestamp", func() {
+			_ = cmdFlags.Set(options.TIMESTAMP, "20170101010101")
+			_ = cmdFlags.Set(options.CLUSTER_TIMESTAMP, "20170101010101")
+			defer testhelper.ShouldPanicWithMessage("The following flags may not be specified together: timestamp, cluster-timestamp")
+			restore.ValidateClusterRestoreFlags(cmdFlags)
+		})
+		It("panics when --redirect-db is used with --cluster-timestamp", func() {
+			_ = cmdFlags.Set(options.CLUSTER_TIMESTAMP, "20170101010101")
+			_ = cmdFlags.Set(options.REDIRECT_DB, "otherdb")
+			defer testhelper.ShouldPanicWithMessage("The following flags may not be specified together: cluster-timestamp, redirect-db")
+			restore.ValidateClusterRestoreFlags(cmdFlags)
+		})
+		It("panics when --dbname is used without --cluster-timestamp", func() {
+			_ = cmdFlags.Set(options.TIMESTAMP, "20170101010101")
+			_ = cmdFlags.Set(options.DBNAME, "testdb")
+			defer testhelper.ShouldPanicWithMessage("--dbname must be specified with --cluster-timestamp")
+			restore.ValidateClusterRestoreFlags(cmdFlags)
+		})
+	})
+	Describe("GetClusterBackupConfigs", func() {
+		// The history is sorted from the newest backup to the oldest
+		backupHistory := &history.History{BackupConfigs: []history.BackupConfig{
+			{Timestamp: "20170101010106", DatabaseName: "testdb", ClusterTimestamp: "20170101010104"},
+			{Timestamp: "20170101010103", DatabaseName: `"Other DB"`, ClusterTimestamp: "20170101010101", NoClusterGlobals: true},
+			{Timestamp: "20170101010102", DatabaseName: "postgres", ClusterTimestamp: "20170101010101"},
+			{Timestamp: "20170101010101", DatabaseName: "testdb"},
+		}}
+		It("returns the backups of all databases in the cluster backup in the order they were taken", func() {
+			configs := restore.GetClusterBackupConfigs(backupHistory, "20170101010101", []string{})
+
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0].Timestamp).To(Equal("20170101010102"))
+			Expect(configs[1].Timestamp).To(Equal("20170101010103"))
+		})
+		It("returns the backup with the global objects first", func() {
+			configs := restore.GetClusterBackupConfigs(backupHistory, "20170101010101", []string{"Other DB", "postgres"})
+
+			Expect(configs).To(HaveLen(2))
+			Expect(configs[0].DatabaseName).To(Equal("postgres"))
+			Expect(configs[1].DatabaseName).To(Equal(`"Other DB"`))
+		})
+		It("panics when the cluster backup does not contain a database", func() {
+			defer testhelper.ShouldPanicWithMessage("Backup with cluster timestamp 20170101010101 does not contain a backup of database testdb")
+			restore.GetClusterBackupConfigs(backupHistory, "20170101010101", []string{"testdb"})
+		})
+		It("panics when there is no cluster backup with the timestamp", func() {
+			defer testhelper.ShouldPanicWithMessage("No backups with cluster timestamp 20170101010105 were found in the backup history")
+			restore.GetClusterBackupConfigs(backupHistory, "20170101010105", []string{})
+		})
+	})
+	Describe("GetDatabaseRestoreArgs", func() {
+		It("replaces the cluster timestamp and databases with the timestamp of one backup", func() {
+			args := []string{"--cluster-timestamp", "20170101010101", "--dbname=testdb", "-with-globals", "--jobs", "4"}
+
+			dbArgs := restore.GetDatabaseRestoreArgs(cmdFlags, args, "20170101010102")
+
+			Expect(dbArgs).To(Equal([]string{"--with-globals", "--jobs", "4", "--timestamp", "20170101010102", "--cluster-timestamp="}))
+		})
+	})
+})
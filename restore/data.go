@@ -6,6 +6,7 @@ package restore
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -24,8 +25,7 @@ var (
 	tableDelim = ","
 )
 
-func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
-	whichConn = connectionPool.ValidateConnNum(whichConn)
+func GetCopyTableInQuery(tableName string, tableAttributes string, destinationToRead string, singleDataFile bool) string {
 	copyCommand := ""
 	readFromDestinationCommand := "cat"
 	customPipeThroughCommand := utils.GetPipeThroughProgram().InputCommand
@@ -39,7 +39,12 @@ func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttribute
 
 	copyCommand = fmt.Sprintf("PROGRAM '%s %s | %s'", readFromDestinationCommand, destinationToRead, customPipeThroughCommand)
 
-	query := fmt.Sprintf("COPY %s%s FROM %s WITH CSV DELIMITER '%s' ON SEGMENT;", tableName, tableAttributes, copyCommand, tableDelim)
+	return fmt.Sprintf("COPY %s%s FROM %s WITH CSV DELIMITER '%s' ON SEGMENT;", tableName, tableAttributes, copyCommand, tableDelim)
+}
+
+func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	query := GetCopyTableInQuery(tableName, tableAttributes, destinationToRead, singleDataFile)
 	result, err := connectionPool.Exec(query, whichConn)
 	if err != nil {
 		errStr := fmt.Sprintf("Error loading data into table %s", tableName)
@@ -56,6 +61,9 @@ func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttribute
 }
 
 func restoreSingleTableData(fpInfo *filepath.FilePathInfo, entry toc.MasterDataEntry, tableName string, whichConn int) error {
+	if _, isRedistributed := getRedistributedTable(entry); isResizeRestoreForEntry(entry) || isRedistributed {
+		return restoreSingleTableDataWithResize(fpInfo, entry, tableName, whichConn)
+	}
 	destinationToRead := ""
 	if backupConfig.SingleDataFile {
 		destinationToRead = fmt.Sprintf("%s_%d", fpInfo.GetSegmentPipePathForCopyCommand(), entry.Oid)
@@ -82,6 +90,20 @@ func CheckRowsRestored(rowsRestored int64, rowsBackedUp int64, tableName string)
 	return nil
 }
 
+/*
+ * Tables are restored largest first for the same reason they are backed up
+ * largest first; see SortTablesBySize in backup/data.go. Entries from backups
+ * that did not record table sizes keep their original order.
+ */
+func SortDataEntriesBySize(dataEntries []toc.MasterDataEntry) []toc.MasterDataEntry {
+	sortedEntries := make([]toc.MasterDataEntry, len(dataEntries))
+	copy(sortedEntries, dataEntries)
+	sort.SliceStable(sortedEntries, func(i, j int) bool {
+		return sortedEntries[i].SegmentSize > sortedEntries[j].SegmentSize
+	})
+	return sortedEntries
+}
+
 func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.MasterDataEntry,
 	gucStatements []toc.StatementWithType, dataProgressBar utils.ProgressBar) {
 	totalTables := len(dataEntries)
@@ -89,6 +111,9 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Ma
 		gplog.Verbose("No data to restore for timestamp = %s", fpInfo.Timestamp)
 		return
 	}
+	if connectionPool.NumConns > 1 {
+		dataEntries = SortDataEntriesBySize(dataEntries)
+	}
 
 	if backupConfig.SingleDataFile {
 		gplog.Verbose("Initializing pipes and gpbackup_helper on segments for single data file restore")
@@ -103,7 +128,7 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Ma
 		if wasTerminated {
 			return
 		}
-		utils.StartGpbackupHelpers(globalCluster, fpInfo, "--restore-agent", MustGetFlagString(options.PLUGIN_CONFIG), "", MustGetFlagBool(options.ON_ERROR_CONTINUE))
+		utils.StartGpbackupHelpers(globalCluster, fpInfo, "--restore-agent", MustGetFlagString(options.PLUGIN_CONFIG), "", MustGetFlagBool(options.ON_ERROR_CONTINUE), encryptionKey != nil)
 	}
 	/*
 	 * We break when an interrupt is received and rely on
@@ -150,7 +175,7 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Ma
 				}
 
 				if backupConfig.SingleDataFile {
-					agentErr := utils.CheckAgentErrorsOnSegments(globalCluster, globalFPInfo)
+					agentErr := utils.CheckAgentErrorsOnSegments(globalCluster, fpInfo)
 					if agentErr != nil {
 						gplog.Error(agentErr.Error())
 						return
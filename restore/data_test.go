@@ -7,6 +7,7 @@ import (
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/jackc/pgx"
 
@@ -89,6 +90,24 @@ var _ = Describe("restore/data tests", func() {
 				"ERROR: value of distribution key doesn't belong to segment with ID 0, it belongs to segment with ID 1 (SQLSTATE 22P04)"))
 		})
 	})
+	Describe("SortDataEntriesBySize", func() {
+		It("sorts entries largest first", func() {
+			small := toc.MasterDataEntry{Schema: "public", Name: "small", SegmentSize: 10}
+			medium := toc.MasterDataEntry{Schema: "public", Name: "medium", SegmentSize: 100}
+			large := toc.MasterDataEntry{Schema: "public", Name: "large", SegmentSize: 1000}
+
+			sortedEntries := restore.SortDataEntriesBySize([]toc.MasterDataEntry{small, large, medium})
+
+			Expect(sortedEntries).To(Equal([]toc.MasterDataEntry{large, medium, small}))
+		})
+		It("keeps the original order of entries without a recorded size", func() {
+			entries := []toc.MasterDataEntry{{Name: "foo"}, {Name: "bar"}, {Name: "baz"}}
+
+			sortedEntries := restore.SortDataEntriesBySize(entries)
+
+			Expect(sortedEntries).To(Equal(entries))
+		})
+	})
 	Describe("CheckRowsRestored", func() {
 		var (
 			expectedRows int64 = 10
@@ -0,0 +1,181 @@
+package restore
+
+/*
+ * This file contains functions related to rendering metadata statements from
+ * the structured metadata file that gpbackup writes with --structured-metadata,
+ * so that role, tablespace, and table rule options change the attributes of
+ * each object rather than the text of its statements.
+ */
+
+import (
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+func InitializeObjectDefinitions() {
+	if !backupConfig.StructuredMetadata {
+		return
+	}
+	filename := decryptedFiles.MustGetPath(globalFPInfo.GetStructuredMetadataFilePath())
+	objectDefinitions = toc.ReadObjectDefinitions(filename)
+	gplog.Verbose("Metadata statements will be rendered from %s where possible", filename)
+}
+
+/*
+ * A statement is only rendered from the definition of its object if that
+ * definition, unchanged, renders exactly the statement in the metadata file.
+ * Any other statement, such as a column comment or a statement from an older
+ * backup, is restored from the metadata file as before and changed by the
+ * functions that rewrite statement text, which skip rendered statements.
+ */
+func RenderStatementsFromDefinitions(statements []toc.StatementWithType) []toc.StatementWithType {
+	if objectDefinitions == nil {
+		return statements
+	}
+	renderedStatements := make([]toc.StatementWithType, 0, len(statements))
+	for _, statement := range statements {
+		definition, ok := objectDefinitions.Find(statement)
+		trimmed := strings.TrimSpace(statement.Statement)
+		if !ok {
+			renderedStatements = append(renderedStatements, statement)
+			continue
+		}
+		render := getDefinitionRenderer(statement, definition)
+		if render == nil || render(definition) != trimmed {
+			renderedStatements = append(renderedStatements, statement)
+			continue
+		}
+		definition = applyRoleOptionsToDefinition(definition)
+		if definition.Table != nil && strings.HasPrefix(trimmed, "CREATE ") {
+			definition = applyTableOptionsToDefinition(definition)
+		}
+		if isDatabaseStatement(statement) {
+			definition = applyDatabaseOptionsToDefinition(definition)
+		}
+		rendered := render(definition)
+		if rendered == "" {
+			continue
+		}
+		statement.Statement = strings.Replace(statement.Statement, trimmed, rendered, 1)
+		statement.FromDefinition = true
+		renderedStatements = append(renderedStatements, statement)
+	}
+	return renderedStatements
+}
+
+func getDefinitionRenderer(statement toc.StatementWithType, definition toc.ObjectDefinition) func(toc.ObjectDefinition) string {
+	switch statement.ObjectType {
+	case "DATABASE":
+		return toc.ObjectDefinition.RenderCreateDatabase
+	case "DATABASE GUC":
+		return getDatabaseGUCRenderer(statement, definition)
+	}
+	switch toc.GetMetadataAttributeType(statement) {
+	case "COMMENT":
+		return toc.ObjectDefinition.RenderComment
+	case "SECURITY LABEL":
+		return toc.ObjectDefinition.RenderSecurityLabel
+	case "PRIVILEGES":
+		return toc.ObjectDefinition.RenderPrivileges
+	}
+	trimmed := strings.TrimSpace(statement.Statement)
+	if strings.HasPrefix(trimmed, "ALTER ") {
+		return toc.ObjectDefinition.RenderOwner
+	} else if strings.HasPrefix(trimmed, "CREATE ") {
+		return toc.ObjectDefinition.RenderCreateTable
+	}
+	return nil
+}
+
+// The configuration parameter a statement sets is found by its position in the definition
+func getDatabaseGUCRenderer(statement toc.StatementWithType, definition toc.ObjectDefinition) func(toc.ObjectDefinition) string {
+	if definition.Database == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(statement.Statement)
+	for i := range definition.Database.GUCs {
+		if definition.RenderDatabaseGUC(i) == trimmed {
+			index := i
+			return func(definition toc.ObjectDefinition) string {
+				return definition.RenderDatabaseGUC(index)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+ * This applies --no-owner, --no-privileges, and --role-mapping in the same way
+ * as ApplyRoleOptions: grants to roles that are not mapped are removed, and an
+ * owner that is not mapped is neither restored nor has its privileges revoked.
+ */
+func applyRoleOptionsToDefinition(definition toc.ObjectDefinition) toc.ObjectDefinition {
+	if roleMapping != nil && definition.Owner != "" {
+		owner, ok := mapRole(definition.Owner)
+		if !ok {
+			owner = ""
+		}
+		definition.Owner = owner
+	}
+	if MustGetFlagBool(options.NO_OWNER) {
+		definition.OwnerType = ""
+	}
+	if MustGetFlagBool(options.NO_PRIVILEGES) {
+		definition.HasPrivileges = false
+		definition.Grants = nil
+	} else if roleMapping != nil {
+		grants := make([]toc.Grant, 0, len(definition.Grants))
+		for _, grant := range definition.Grants {
+			if grantee, ok := mapRole(grant.Grantee); ok {
+				grant.Grantee = grantee
+				grants = append(grants, grant)
+			}
+		}
+		definition.Grants = grants
+	}
+	return definition
+}
+
+// This applies tablespace options and table rules to a table definition
+func applyTableOptionsToDefinition(definition toc.ObjectDefinition) toc.ObjectDefinition {
+	table := *definition.Table
+	noTablespaces := MustGetFlagBool(options.NO_TABLESPACES)
+	if noTablespaces || tablespaceMapping != nil {
+		if table.Tablespace != "" {
+			table.Tablespace = mapOrRemoveTablespace(table.Tablespace, noTablespaces)
+		}
+		table.PartDef = replaceTablespaceClauses(table.PartDef, noTablespaces)
+		table.PartTemplateDef = replaceTablespaceClauses(table.PartTemplateDef, noTablespaces)
+	}
+	if rule := findTableRule(utils.UnquoteIdent(definition.Schema), utils.UnquoteIdent(definition.Name)); rule != nil {
+		createTable := CreateTableStatement{StorageOpts: table.StorageOpts, DistPolicy: table.DistPolicy, PartDef: table.PartDef, PartTemplateDef: table.PartTemplateDef}
+		applyTableRule(rule, utils.MakeFQN(definition.Schema, definition.Name), &createTable)
+		table.StorageOpts = createTable.StorageOpts
+		table.DistPolicy = createTable.DistPolicy
+		table.PartDef = createTable.PartDef
+		table.PartTemplateDef = createTable.PartTemplateDef
+	}
+	definition.Table = &table
+	return definition
+}
+
+func isDatabaseStatement(statement toc.StatementWithType) bool {
+	return statement.ObjectType == "DATABASE" || statement.ObjectType == "DATABASE GUC" || statement.ObjectType == "DATABASE METADATA"
+}
+
+// This applies --redirect-db and tablespace options to the definition of a database
+func applyDatabaseOptionsToDefinition(definition toc.ObjectDefinition) toc.ObjectDefinition {
+	if redirectDB := MustGetFlagString(options.REDIRECT_DB); redirectDB != "" {
+		definition.Identifier = utils.QuoteIdent(connectionPool, redirectDB)
+	}
+	if definition.Database != nil && definition.Database.Tablespace != "" {
+		database := *definition.Database
+		database.Tablespace = mapOrRemoveTablespace(database.Tablespace, MustGetFlagBool(options.NO_TABLESPACES))
+		definition.Database = &database
+	}
+	return definition
+}
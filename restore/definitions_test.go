@@ -0,0 +1,162 @@
+package restore_test
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/definitions tests", func() {
+	Describe("RenderStatementsFromDefinitions", func() {
+		tableEntry := toc.MetadataEntry{Schema: "public", Name: "foo", ObjectType: "TABLE"}
+		createTable := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.foo (\n\ti integer\n) TABLESPACE fast_ssd DISTRIBUTED BY (i);\n"}
+		tableOwner := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nALTER TABLE public.foo OWNER TO prod_etl;\n"}
+		tablePrivileges := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nREVOKE ALL ON TABLE public.foo FROM PUBLIC;\nREVOKE ALL ON TABLE public.foo FROM prod_etl;\nGRANT ALL ON TABLE public.foo TO prod_etl;\nGRANT SELECT ON TABLE public.foo TO \"Prod Reader\";\n"}
+		columnComment := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCOMMENT ON COLUMN public.foo.i IS 'Owned by prod_etl';\n"}
+		statements := []toc.StatementWithType{createTable, tableOwner, tablePrivileges, columnComment}
+
+		BeforeEach(func() {
+			definitions := toc.NewObjectDefinitions()
+			*definitions.Get(tableEntry) = toc.ObjectDefinition{
+				Schema:        "public",
+				Name:          "foo",
+				ObjectType:    "TABLE",
+				StatementType: "TABLE",
+				Identifier:    "public.foo",
+				OwnerType:     "TABLE",
+				Owner:         "prod_etl",
+				PrivilegeType: "TABLE ",
+				HasPrivileges: true,
+				Grants:        []toc.Grant{{Grantee: "prod_etl", Privileges: "ALL"}, {Grantee: `"Prod Reader"`, Privileges: "SELECT"}},
+				Table: &toc.TableDefinition{
+					Columns:    []toc.ColumnDefinition{{Name: "i", Type: "integer", StatTarget: -1}},
+					Tablespace: "fast_ssd",
+					DistPolicy: "DISTRIBUTED BY (i)",
+				},
+			}
+			restore.SetObjectDefinitions(definitions)
+		})
+		AfterEach(func() {
+			restore.SetObjectDefinitions(nil)
+			restore.SetRoleMapping(nil)
+			restore.SetTablespaceMapping(nil)
+		})
+		It("returns statements unchanged when there is no structured metadata", func() {
+			restore.SetObjectDefinitions(nil)
+
+			Expect(restore.RenderStatementsFromDefinitions(statements)).To(Equal(statements))
+		})
+		It("renders statements that match the definition of their object", func() {
+			result := restore.RenderStatementsFromDefinitions(statements)
+
+			Expect(result).To(HaveLen(4))
+			for i := 0; i < 3; i++ {
+				Expect(result[i].Statement).To(Equal(statements[i].Statement))
+				Expect(result[i].FromDefinition).To(BeTrue())
+			}
+			Expect(result[3]).To(Equal(columnComment))
+		})
+		It("maps the owner and grantees of an object", func() {
+			restore.SetRoleMapping(map[string]string{"prod_etl": "dev_etl"})
+
+			result := restore.RenderStatementsFromDefinitions(statements)
+
+			Expect(result).To(HaveLen(4))
+			Expect(result[1].Statement).To(Equal("\n\nALTER TABLE public.foo OWNER TO dev_etl;\n"))
+			Expect(result[2].Statement).To(Equal("\n\nREVOKE ALL ON TABLE public.foo FROM PUBLIC;\nREVOKE ALL ON TABLE public.foo FROM dev_etl;\nGRANT ALL ON TABLE public.foo TO dev_etl;\n"))
+			Expect(result[3]).To(Equal(columnComment))
+		})
+		It("removes the owner and privileges of an object when --no-owner and --no-privileges are set", func() {
+			_ = cmdFlags.Set(options.NO_OWNER, "true")
+			_ = cmdFlags.Set(options.NO_PRIVILEGES, "true")
+
+			result := restore.RenderStatementsFromDefinitions(statements)
+
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].Statement).To(Equal(createTable.Statement))
+			Expect(result[1]).To(Equal(columnComment))
+		})
+		It("maps the tablespace of a table", func() {
+			restore.SetTablespaceMapping(map[string]string{"fast_ssd": "pg_default"})
+
+			result := restore.RenderStatementsFromDefinitions(statements)
+
+			Expect(result[0].Statement).To(Equal("\n\nCREATE TABLE public.foo (\n\ti integer\n) TABLESPACE pg_default DISTRIBUTED BY (i);\n"))
+			Expect(restore.ApplyTablespaceOptions(result)).To(Equal(result))
+		})
+		It("does not render a statement that does not match the definition of its object", func() {
+			restore.SetRoleMapping(map[string]string{"prod_etl": "dev_etl"})
+			otherOwner := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nALTER TABLE public.foo OWNER TO gpadmin;\n"}
+
+			result := restore.RenderStatementsFromDefinitions([]toc.StatementWithType{otherOwner})
+
+			Expect(result).To(Equal([]toc.StatementWithType{otherOwner}))
+		})
+		Context("database statements", func() {
+			createDatabase := toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE", Statement: "\n\nCREATE DATABASE testdb TEMPLATE template0 TABLESPACE fast_ssd ENCODING 'UTF8';"}
+			databaseGUC := toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE GUC", Statement: "\nALTER DATABASE testdb SET search_path TO public;"}
+			databaseOwner := toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE METADATA", Statement: "\n\nALTER DATABASE testdb OWNER TO prod_etl;\n"}
+			databaseStatements := []toc.StatementWithType{createDatabase, databaseGUC, databaseOwner}
+
+			BeforeEach(func() {
+				definitions := toc.NewObjectDefinitions()
+				*definitions.Get(toc.MetadataEntry{Name: "testdb", ObjectType: "DATABASE"}) = toc.ObjectDefinition{
+					Name:       "testdb",
+					ObjectType: "DATABASE",
+					Identifier: "testdb",
+					Database:   &toc.DatabaseDefinition{Tablespace: "fast_ssd", Encoding: "UTF8"},
+				}
+				*definitions.Get(toc.MetadataEntry{Name: "testdb", ObjectType: "DATABASE GUC"}) = toc.ObjectDefinition{
+					Name:       "testdb",
+					ObjectType: "DATABASE GUC",
+					Identifier: "testdb",
+					Database:   &toc.DatabaseDefinition{GUCs: []string{"SET search_path TO public"}},
+				}
+				*definitions.Get(toc.MetadataEntry{Name: "testdb", ObjectType: "DATABASE METADATA"}) = toc.ObjectDefinition{
+					Name:          "testdb",
+					ObjectType:    "DATABASE METADATA",
+					StatementType: "DATABASE",
+					Identifier:    "testdb",
+					OwnerType:     "DATABASE",
+					Owner:         "prod_etl",
+				}
+				restore.SetObjectDefinitions(definitions)
+			})
+			It("renders the statements of a database", func() {
+				result := restore.RenderStatementsFromDefinitions(databaseStatements)
+
+				Expect(result).To(HaveLen(3))
+				for i := range result {
+					Expect(result[i].Statement).To(Equal(databaseStatements[i].Statement))
+					Expect(result[i].FromDefinition).To(BeTrue())
+				}
+			})
+			It("renders the statements of a database with the name of the redirect database", func() {
+				_ = cmdFlags.Set(options.REDIRECT_DB, "New DB")
+				for i := 0; i < 3; i++ {
+					mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow(`"New DB"`))
+				}
+				restore.SetTablespaceMapping(map[string]string{"fast_ssd": "pg_default"})
+
+				result := restore.RenderStatementsFromDefinitions(databaseStatements)
+
+				Expect(result).To(HaveLen(3))
+				Expect(result[0].Statement).To(Equal("\n\nCREATE DATABASE \"New DB\" TEMPLATE template0 TABLESPACE pg_default ENCODING 'UTF8';"))
+				Expect(result[1].Statement).To(Equal("\nALTER DATABASE \"New DB\" SET search_path TO public;"))
+				Expect(result[2].Statement).To(Equal("\n\nALTER DATABASE \"New DB\" OWNER TO prod_etl;\n"))
+				Expect(toc.SubstituteRedirectDatabaseInStatements(result, "testdb", `"New DB"`)).To(Equal(result))
+			})
+			It("does not render a database parameter that is not in the definition of its database", func() {
+				otherGUC := toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE GUC", Statement: "\nALTER DATABASE testdb SET work_mem TO '1GB';"}
+
+				result := restore.RenderStatementsFromDefinitions([]toc.StatementWithType{otherGUC})
+
+				Expect(result).To(Equal([]toc.StatementWithType{otherGUC}))
+			})
+		})
+	})
+})
@@ -34,11 +34,22 @@ var (
 	globalFPInfo        filepath.FilePathInfo
 	globalTOC           *toc.TOC
 	pluginConfig        *utils.PluginConfig
+	resizeMapping       map[int][]int
+	roleMapping         map[string]string
+	tablespaceMapping   map[string]string
+	tableRules          []TableRule
+	redistributedTables map[string]bool
+	largeObjectOids     map[uint32]uint32 // large objects restored with a new OID because the original was taken
+	objectDefinitions   *toc.ObjectDefinitions
 	restoreStartTime    string
+	sqlOutputFile       *utils.FileWithByteCount
 	version             string
 	wasTerminated       bool
 	errorTablesMetadata map[string]Empty
 	errorTablesData     map[string]Empty
+	encryptionKey       *utils.EncryptionKey
+	decryptedFiles      *utils.DecryptedFiles
+	effectiveConfig     map[string]interface{}
 	/*
 	 * Used for synchronizing DoCleanup.  In DoInit() we increment the group
 	 * and then wait for at least one DoCleanup to finish, either in DoTeardown
@@ -51,6 +62,7 @@ func init() {
 	// Initialize global variables
 	errorTablesMetadata = make(map[string]Empty)
 	errorTablesData = make(map[string]Empty)
+	largeObjectOids = make(map[uint32]uint32)
 }
 
 /*
@@ -86,10 +98,39 @@ func SetPluginConfig(config *utils.PluginConfig) {
 	pluginConfig = config
 }
 
+func SetRoleMapping(mapping map[string]string) {
+	roleMapping = mapping
+}
+
+func SetTablespaceMapping(mapping map[string]string) {
+	tablespaceMapping = mapping
+}
+
+func SetTableRules(rules []TableRule) {
+	tableRules = rules
+	redistributedTables = make(map[string]bool)
+}
+
+func SetLargeObjectOids(oids map[uint32]uint32) {
+	largeObjectOids = oids
+}
+
+func SetObjectDefinitions(definitions *toc.ObjectDefinitions) {
+	objectDefinitions = definitions
+}
+
 func SetTOC(toc *toc.TOC) {
 	globalTOC = toc
 }
 
+func SetSQLOutputFile(file *utils.FileWithByteCount) {
+	sqlOutputFile = file
+}
+
+func SetEncryptionKey(key *utils.EncryptionKey) {
+	encryptionKey = key
+}
+
 // Util functions to enable ease of access to global flag values
 
 func MustGetFlagString(flagName string) string {
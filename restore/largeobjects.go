@@ -0,0 +1,234 @@
+package restore
+
+/*
+ * This file contains functions related to restoring large objects, which are
+ * stored in pg_largeobject rather than in user tables.
+ */
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * Large objects do not belong to any schema, so they are not restored by
+ * restores that only include specific schemas or tables, in the same way that
+ * they are not backed up by such backups.
+ */
+func shouldRestoreLargeObjects() bool {
+	return len(globalTOC.LargeObjectEntries) > 0 &&
+		len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) == 0 &&
+		len(MustGetFlagStringArray(options.INCLUDE_RELATION)) == 0 &&
+		options.ObjectTypeMatchesFilter(MustGetFlagStringArray(options.INCLUDE_OBJECT_TYPE), MustGetFlagStringArray(options.EXCLUDE_OBJECT_TYPE), "LARGE OBJECT")
+}
+
+/*
+ * Large objects are restored with their original OIDs, as applications refer
+ * to them by OID. A large object whose OID is already taken in the restore
+ * database is created with a new OID instead, and the statements for its
+ * metadata and the restore of its contents use the new OID.
+ */
+func RemapExistingLargeObjects(statements []toc.StatementWithType) []toc.StatementWithType {
+	oidList := make([]string, 0)
+	for _, statement := range statements {
+		if isCreateLargeObjectStatement(statement) {
+			oidList = append(oidList, statement.Name)
+		}
+	}
+	if len(oidList) == 0 {
+		return statements
+	}
+	query := fmt.Sprintf(`
+	SELECT oid
+	FROM pg_largeobject_metadata
+	WHERE oid IN (%s)
+	ORDER BY oid`, strings.Join(oidList, ", "))
+	existingOids := make([]uint32, 0)
+	err := connectionPool.Select(&existingOids, query)
+	gplog.FatalOnError(err)
+	if len(existingOids) == 0 {
+		return statements
+	}
+	for _, oid := range existingOids {
+		var newOid uint32
+		err = connectionPool.Get(&newOid, "SELECT pg_catalog.lo_create(0)")
+		gplog.FatalOnError(err)
+		largeObjectOids[oid] = newOid
+		gplog.Warn("Large object %d already exists in the restore database; restoring it as large object %d", oid, newOid)
+	}
+
+	remappedStatements := make([]toc.StatementWithType, 0, len(statements))
+	for _, statement := range statements {
+		if statement.ObjectType == "LARGE OBJECT" {
+			oid, _ := strconv.ParseUint(statement.Name, 10, 32)
+			if newOid, ok := largeObjectOids[uint32(oid)]; ok {
+				if isCreateLargeObjectStatement(statement) {
+					// The large object was already created with its new OID above
+					continue
+				}
+				oidRegex := regexp.MustCompile(fmt.Sprintf(`LARGE OBJECT %d\b`, oid))
+				statement.Name = fmt.Sprintf("%d", newOid)
+				statement.Statement = oidRegex.ReplaceAllString(statement.Statement, fmt.Sprintf("LARGE OBJECT %d", newOid))
+			}
+		}
+		remappedStatements = append(remappedStatements, statement)
+	}
+	return remappedStatements
+}
+
+func isCreateLargeObjectStatement(statement toc.StatementWithType) bool {
+	return statement.ObjectType == "LARGE OBJECT" && strings.Contains(statement.Statement, "lo_create(")
+}
+
+func getRestoreLargeObjectOid(oid uint32) uint32 {
+	if newOid, ok := largeObjectOids[oid]; ok {
+		return newOid
+	}
+	return oid
+}
+
+/*
+ * The contents of a large object are read from the large object data file one
+ * chunk at a time and passed to handleStatement as statements that replace the
+ * contents of the large object, so that large objects of any size can be
+ * restored without holding them in memory.
+ */
+func GenerateLargeObjectStatements(dataFile io.ReaderAt, entry toc.LargeObjectDataEntry, oid uint32, handleStatement func(statement string) error) error {
+	err := handleStatement(fmt.Sprintf("SELECT pg_catalog.lo_truncate(pg_catalog.lo_open(%d, 131072), 0);", oid))
+	if err != nil {
+		return err
+	}
+	reader := io.NewSectionReader(dataFile, int64(entry.StartByte), int64(entry.EndByte-entry.StartByte))
+	chunk := make([]byte, utils.LargeObjectChunkSize)
+	offset := int64(0)
+	for {
+		chunkLength, err := io.ReadFull(reader, chunk)
+		if err == io.EOF {
+			return nil
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		err = handleStatement(fmt.Sprintf("SELECT pg_catalog.lo_put(%d, %d, decode('%s', 'hex'));", oid, offset, hex.EncodeToString(chunk[:chunkLength])))
+		if err != nil {
+			return err
+		}
+		offset += int64(chunkLength)
+		if chunkLength < utils.LargeObjectChunkSize {
+			return nil
+		}
+	}
+}
+
+/*
+ * Large objects are restored in parallel across the restore connections, one
+ * large object per connection at a time.
+ */
+func RestoreLargeObjectData(dataFile io.ReaderAt, entries []toc.LargeObjectDataEntry, progressBar utils.ProgressBar) {
+	tasks := make(chan toc.LargeObjectDataEntry, len(entries))
+	for _, entry := range entries {
+		tasks <- entry
+	}
+	close(tasks)
+
+	var workerPool sync.WaitGroup
+	var fatalErr error
+	var numErrors int32
+	var mutex = &sync.Mutex{}
+	hasFatalErr := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return fatalErr != nil
+	}
+	for i := 0; i < connectionPool.NumConns; i++ {
+		workerPool.Add(1)
+		go func(whichConn int) {
+			defer workerPool.Done()
+			for entry := range tasks {
+				if wasTerminated || hasFatalErr() {
+					return
+				}
+				oid := getRestoreLargeObjectOid(entry.Oid)
+				err := GenerateLargeObjectStatements(dataFile, entry, oid, func(statement string) error {
+					_, err := connectionPool.Exec(statement, whichConn)
+					return err
+				})
+				if err != nil {
+					gplog.Verbose("Error encountered when restoring large object %d: %s", oid, err.Error())
+					if MustGetFlagBool(options.ON_ERROR_CONTINUE) {
+						atomic.AddInt32(&numErrors, 1)
+					} else {
+						mutex.Lock()
+						if fatalErr == nil {
+							fatalErr = err
+						}
+						mutex.Unlock()
+					}
+				}
+				progressBar.Increment()
+			}
+		}(i)
+	}
+	workerPool.Wait()
+
+	if fatalErr != nil {
+		fmt.Println("")
+		gplog.Fatal(fatalErr, "")
+	} else if numErrors > 0 {
+		fmt.Println("")
+		gplog.Error("Encountered %d errors during large object data restore; see log file %s for a list of errors.", numErrors, gplog.GetLogFilePath())
+	}
+}
+
+func WriteLargeObjectStatementsToOutputFile(dataFile io.ReaderAt, entries []toc.LargeObjectDataEntry) {
+	for _, entry := range entries {
+		sqlOutputFile.MustPrint("\n")
+		err := GenerateLargeObjectStatements(dataFile, entry, getRestoreLargeObjectOid(entry.Oid), func(statement string) error {
+			sqlOutputFile.MustPrintf("\n%s", statement)
+			return nil
+		})
+		gplog.FatalOnError(err)
+	}
+}
+
+func restoreLargeObjects() {
+	if wasTerminated {
+		return
+	}
+	gplog.Info("Restoring large object data")
+
+	filename := globalFPInfo.GetLargeObjectDataFilePath()
+	if MustGetFlagString(options.PLUGIN_CONFIG) != "" {
+		pluginConfig.MustRestoreFile(filename)
+	}
+	dataFile, err := os.Open(decryptedFiles.MustGetPath(filename))
+	gplog.FatalOnError(err)
+	defer dataFile.Close()
+
+	entries := globalTOC.LargeObjectEntries
+	if isOutputToFile() {
+		WriteLargeObjectStatementsToOutputFile(dataFile, entries)
+		gplog.Info("Large object data restore statements written for %d large objects", len(entries))
+		return
+	}
+	progressBar := utils.NewProgressBar(len(entries), "Large objects restored: ", utils.PB_VERBOSE)
+	progressBar.Start()
+	RestoreLargeObjectData(dataFile, entries, progressBar)
+	progressBar.Finish()
+	if wasTerminated {
+		gplog.Info("Large object data restore incomplete")
+	} else {
+		gplog.Info("Large object data restore complete")
+	}
+}
@@ -0,0 +1,94 @@
+package restore_test
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/largeobjects tests", func() {
+	Describe("RemapExistingLargeObjects", func() {
+		createStatement := toc.StatementWithType{Name: "16384", ObjectType: "LARGE OBJECT", Statement: "\n\nSELECT pg_catalog.lo_create(16384);\n"}
+		ownerStatement := toc.StatementWithType{Name: "16384", ObjectType: "LARGE OBJECT", Statement: "\n\nALTER LARGE OBJECT 16384 OWNER TO testrole;\n"}
+		otherCreateStatement := toc.StatementWithType{Name: "16385", ObjectType: "LARGE OBJECT", Statement: "\n\nSELECT pg_catalog.lo_create(16385);\n"}
+		existingQuery := regexp.QuoteMeta("WHERE oid IN (16384, 16385)")
+
+		BeforeEach(func() {
+			restore.SetLargeObjectOids(make(map[uint32]uint32))
+		})
+		It("leaves the statements unchanged when no large object OIDs are taken", func() {
+			mock.ExpectQuery(existingQuery).WillReturnRows(sqlmock.NewRows([]string{"oid"}))
+			statements := []toc.StatementWithType{createStatement, ownerStatement, otherCreateStatement}
+
+			remappedStatements := restore.RemapExistingLargeObjects(statements)
+
+			Expect(remappedStatements).To(Equal(statements))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("creates a new large object in place of one whose OID is taken and points its statements at the new OID", func() {
+			mock.ExpectQuery(existingQuery).WillReturnRows(sqlmock.NewRows([]string{"oid"}).AddRow(16384))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_catalog.lo_create(0)")).WillReturnRows(sqlmock.NewRows([]string{"lo_create"}).AddRow(20000))
+			statements := []toc.StatementWithType{createStatement, ownerStatement, otherCreateStatement}
+
+			remappedStatements := restore.RemapExistingLargeObjects(statements)
+
+			Expect(remappedStatements).To(Equal([]toc.StatementWithType{
+				{Name: "20000", ObjectType: "LARGE OBJECT", Statement: "\n\nALTER LARGE OBJECT 20000 OWNER TO testrole;\n"},
+				otherCreateStatement,
+			}))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("does not query the restore database when there are no large objects", func() {
+			statements := []toc.StatementWithType{{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (i int);"}}
+
+			remappedStatements := restore.RemapExistingLargeObjects(statements)
+
+			Expect(remappedStatements).To(Equal(statements))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+	Describe("GenerateLargeObjectStatements", func() {
+		var statements []string
+		handleStatement := func(statement string) error {
+			statements = append(statements, statement)
+			return nil
+		}
+
+		BeforeEach(func() {
+			statements = make([]string, 0)
+		})
+		It("generates a statement that empties a large object with no contents", func() {
+			dataFile := bytes.NewReader([]byte{0xde, 0xad})
+			err := restore.GenerateLargeObjectStatements(dataFile, toc.LargeObjectDataEntry{Oid: 16384, StartByte: 2, EndByte: 2}, 16384, handleStatement)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statements).To(Equal([]string{"SELECT pg_catalog.lo_truncate(pg_catalog.lo_open(16384, 131072), 0);"}))
+		})
+		It("reads the contents of a large object from its place in the data file", func() {
+			dataFile := bytes.NewReader([]byte{0x01, 0xde, 0xad, 0xbe, 0xef, 0x02})
+			err := restore.GenerateLargeObjectStatements(dataFile, toc.LargeObjectDataEntry{Oid: 16384, StartByte: 1, EndByte: 5}, 20000, handleStatement)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statements).To(Equal([]string{
+				"SELECT pg_catalog.lo_truncate(pg_catalog.lo_open(20000, 131072), 0);",
+				"SELECT pg_catalog.lo_put(20000, 0, decode('deadbeef', 'hex'));",
+			}))
+		})
+		It("splits a large object larger than the chunk size into multiple statements", func() {
+			contents := append(bytes.Repeat([]byte{0xab}, utils.LargeObjectChunkSize), 0xcd)
+			dataFile := bytes.NewReader(contents)
+			err := restore.GenerateLargeObjectStatements(dataFile, toc.LargeObjectDataEntry{Oid: 16384, StartByte: 0, EndByte: uint64(len(contents))}, 16384, handleStatement)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(statements).To(HaveLen(3))
+			Expect(statements[2]).To(Equal("SELECT pg_catalog.lo_put(16384, 1048576, decode('cd', 'hex'));"))
+		})
+	})
+})
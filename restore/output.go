@@ -0,0 +1,91 @@
+package restore
+
+/*
+ * This file contains functions related to writing restore statements to a
+ * SQL file instead of executing them against the restore database.
+ */
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+func isOutputToFile() bool {
+	return sqlOutputFile != nil
+}
+
+func InitializeSQLOutputFile(filename string) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to create SQL output file %s", filename))
+	sqlOutputFile = &utils.FileWithByteCount{Filename: "", Writer: file, File: file}
+	gplog.Info("Restore statements will be written to %s", filename)
+}
+
+func CloseSQLOutputFile() {
+	if sqlOutputFile == nil {
+		return
+	}
+	sqlOutputFile.MustPrintln()
+	sqlOutputFile.Close()
+	sqlOutputFile = nil
+}
+
+/*
+ * The session settings gprestore applies to each of its connections are also
+ * written to the output file, so that replaying the file behaves the same way
+ * as a restore run directly against the database.
+ */
+func WriteSessionSettingsToOutputFile(setupQuery string, gucStatements []toc.StatementWithType) {
+	for _, setting := range strings.Split(strings.TrimSpace(setupQuery), "\n") {
+		if strings.Contains(setting, "application_name") {
+			continue
+		}
+		sqlOutputFile.MustPrintf("\n%s", setting)
+	}
+	WriteStatementsToOutputFile(gucStatements, nil)
+}
+
+func WriteConnectToOutputFile(quotedDBName string) {
+	sqlOutputFile.MustPrintf("\n\n\\connect %s\n", quotedDBName)
+}
+
+func WriteStatementsToOutputFile(statements []toc.StatementWithType, progressBar utils.ProgressBar) {
+	for _, statement := range statements {
+		if !strings.HasPrefix(statement.Statement, "\n") {
+			sqlOutputFile.MustPrint("\n\n")
+		}
+		sqlOutputFile.MustPrint(statement.Statement)
+		if progressBar != nil {
+			progressBar.Increment()
+		}
+	}
+}
+
+/*
+ * COPY statements written to the output file read directly from the data files
+ * of the backup, so the file can only be replayed against a cluster that has
+ * access to those files.
+ */
+func WriteDataStatementsToOutputFile(restorePlanEntries []history.RestorePlanEntry, filteredDataEntries map[string][]toc.MasterDataEntry, truncateTables bool) {
+	for _, restorePlanEntry := range restorePlanEntries {
+		entries := filteredDataEntries[restorePlanEntry.Timestamp]
+		if len(entries) == 0 {
+			continue
+		}
+		fpInfo := GetBackupFPInfoForTimestamp(restorePlanEntry.Timestamp)
+		if truncateTables {
+			sqlOutputFile.MustPrintf("\n\n%s", GetTruncateTablesQuery(entries))
+		}
+		for _, entry := range entries {
+			tableName := utils.MakeFQN(entry.Schema, entry.Name)
+			destinationToRead := fpInfo.GetTableBackupFilePathForCopyCommand(entry.Oid, utils.GetPipeThroughProgram().Extension, false)
+			sqlOutputFile.MustPrintf("\n\n%s", GetCopyTableInQuery(tableName, entry.AttributeString, destinationToRead, false))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package restore_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/output tests", func() {
+	schema := toc.StatementWithType{Schema: "foo", Name: "foo", ObjectType: "SCHEMA", Statement: "\n\nCREATE SCHEMA foo;\n"}
+	table := toc.StatementWithType{Schema: "foo", Name: "bar", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE foo.bar (i int);\n"}
+	BeforeEach(func() {
+		restore.SetSQLOutputFile(utils.NewFileWithByteCount(buffer))
+		utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -1", InputCommand: "gzip -d -c", Extension: ".gz"})
+	})
+	AfterEach(func() {
+		restore.SetSQLOutputFile(nil)
+	})
+	Describe("WriteStatementsToOutputFile", func() {
+		It("writes statements in order", func() {
+			restore.WriteStatementsToOutputFile([]toc.StatementWithType{schema, table}, nil)
+
+			Expect(string(buffer.Contents())).To(Equal("\n\nCREATE SCHEMA foo;\n\n\nCREATE TABLE foo.bar (i int);\n"))
+		})
+		It("separates statements that do not begin with a newline", func() {
+			copyStatement := toc.StatementWithType{Statement: "TRUNCATE foo.bar;"}
+			restore.WriteStatementsToOutputFile([]toc.StatementWithType{copyStatement, copyStatement}, nil)
+
+			Expect(string(buffer.Contents())).To(Equal("\n\nTRUNCATE foo.bar;\n\nTRUNCATE foo.bar;"))
+		})
+	})
+	Describe("ExecuteRestoreMetadataStatements", func() {
+		It("writes statements to the output file instead of executing them", func() {
+			restore.ExecuteRestoreMetadataStatements([]toc.StatementWithType{table}, "", nil, utils.PB_NONE, false)
+
+			Expect(string(buffer.Contents())).To(Equal(table.Statement))
+		})
+	})
+	Describe("RestoreSchemas", func() {
+		It("writes schema statements to the output file instead of executing them", func() {
+			progressBar := utils.NewProgressBar(1, "", utils.PB_NONE)
+			progressBar.Start()
+			restore.RestoreSchemas([]toc.StatementWithType{schema}, progressBar)
+
+			Expect(string(buffer.Contents())).To(Equal(schema.Statement))
+		})
+	})
+	Describe("WriteSessionSettingsToOutputFile", func() {
+		It("writes the setup query without the application name, followed by session GUCs", func() {
+			gucs := toc.StatementWithType{ObjectType: "SESSION GUCS", Statement: "\nSET client_encoding = 'UTF8';\n"}
+			restore.WriteSessionSettingsToOutputFile("\nSET application_name TO 'gprestore';\nSET search_path TO pg_catalog;\n", []toc.StatementWithType{gucs})
+
+			Expect(string(buffer.Contents())).To(Equal("\nSET search_path TO pg_catalog;\nSET client_encoding = 'UTF8';\n"))
+		})
+	})
+	Describe("WriteDataStatementsToOutputFile", func() {
+		restorePlan := []history.RestorePlanEntry{
+			{Timestamp: "20170101010101", TableFQNs: []string{"public.foo"}},
+			{Timestamp: "20170102010101", TableFQNs: []string{"public.bar"}},
+		}
+		dataEntries := map[string][]toc.MasterDataEntry{
+			"20170101010101": {{Schema: "public", Name: "foo", Oid: 1, AttributeString: "(i)"}},
+			"20170102010101": {{Schema: "public", Name: "bar", Oid: 2, AttributeString: "(j)"}},
+		}
+		It("writes COPY statements referencing the data file of each timestamp in restore plan order", func() {
+			restore.WriteDataStatementsToOutputFile(restorePlan, dataEntries, false)
+
+			Expect(string(buffer.Contents())).To(Equal(`
+
+COPY public.foo(i) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_1.gz | gzip -d -c' WITH CSV DELIMITER ',' ON SEGMENT;
+
+COPY public.bar(j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170102/20170102010101/gpbackup_<SEGID>_20170102010101_2.gz | gzip -d -c' WITH CSV DELIMITER ',' ON SEGMENT;`))
+		})
+		It("writes TRUNCATE statements before the COPY statements when restoring incrementally", func() {
+			restore.WriteDataStatementsToOutputFile(restorePlan[1:], dataEntries, true)
+
+			Expect(string(buffer.Contents())).To(Equal(`
+
+TRUNCATE public.bar;
+
+COPY public.bar(j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170102/20170102010101/gpbackup_<SEGID>_20170102010101_2.gz | gzip -d -c' WITH CSV DELIMITER ',' ON SEGMENT;`))
+		})
+	})
+	Describe("ValidateBackupFlagCombinations", func() {
+		BeforeEach(func() {
+			_ = cmdFlags.Set(options.OUTPUT_SQL, "/tmp/restore.sql")
+		})
+		It("panics when writing data statements for a single data file backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SingleDataFile: true})
+			_ = cmdFlags.Set(options.JOBS, "1")
+			defer testhelper.ShouldPanicWithMessage("Cannot write data restore statements for a backup taken with a single data file per segment.")
+			restore.ValidateBackupFlagCombinations()
+		})
+		It("panics when writing data statements for a plugin backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{Plugin: "/tmp/plugin.sh"})
+			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config.yaml")
+			defer testhelper.ShouldPanicWithMessage("Cannot write data restore statements for a backup taken with a plugin.")
+			restore.ValidateBackupFlagCombinations()
+		})
+		It("passes when only writing metadata statements for a single data file backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SingleDataFile: true})
+			_ = cmdFlags.Set(options.METADATA_ONLY, "true")
+			restore.ValidateBackupFlagCombinations()
+		})
+	})
+})
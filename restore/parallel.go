@@ -54,11 +54,7 @@ func ExecuteStatements(statements []toc.StatementWithType, progressBar utils.Pro
 	var workerPool sync.WaitGroup
 	var fatalErr error
 	var numErrors int32
-	tasks := make(chan toc.StatementWithType, len(statements))
-	for _, statement := range statements {
-		tasks <- statement
-	}
-	close(tasks)
+	tasks := makeStatementChannel(statements)
 
 	if !executeInParallel {
 		connNum := connectionPool.ValidateConnNum(whichConn...)
@@ -74,6 +70,19 @@ func ExecuteStatements(statements []toc.StatementWithType, progressBar utils.Pro
 		}
 		workerPool.Wait()
 	}
+	reportStatementErrors(fatalErr, numErrors)
+}
+
+func makeStatementChannel(statements []toc.StatementWithType) chan toc.StatementWithType {
+	tasks := make(chan toc.StatementWithType, len(statements))
+	for _, statement := range statements {
+		tasks <- statement
+	}
+	close(tasks)
+	return tasks
+}
+
+func reportStatementErrors(fatalErr error, numErrors int32) {
 	if fatalErr != nil {
 		fmt.Println("")
 		gplog.Fatal(fatalErr, "")
@@ -83,6 +92,129 @@ func ExecuteStatements(statements []toc.StatementWithType, progressBar utils.Pro
 	}
 }
 
+/*
+ * All statements for a single object, such as its CREATE statement followed by
+ * its COMMENT and ALTER OWNER statements, must be executed in order on one
+ * connection. Dependents holds the indexes of the groups that cannot start
+ * until this group is finished, and NumDependencies the number of groups this
+ * group is still waiting on.
+ */
+type StatementGroup struct {
+	Statements      []toc.StatementWithType
+	Dependents      []int
+	NumDependencies int
+}
+
+/*
+ * Groups statements by the object they belong to and links each group to the
+ * groups for the objects it depends on. gpbackup writes dependent objects in
+ * topologically sorted order, so only dependencies on earlier groups are
+ * considered; this guarantees the resulting graph has no cycles. Dependencies
+ * on objects that are not being restored are ignored.
+ */
+func GroupStatementsByDependency(statements []toc.StatementWithType) []StatementGroup {
+	groups := make([]StatementGroup, 0)
+	groupForObject := make(map[toc.UniqueID]int)
+	for _, statement := range statements {
+		index, ok := groupForObject[statement.ObjectID]
+		if !ok {
+			index = len(groups)
+			groupForObject[statement.ObjectID] = index
+			groups = append(groups, StatementGroup{})
+		}
+		groups[index].Statements = append(groups[index].Statements, statement)
+	}
+	for i := range groups {
+		isDependentOn := make(map[int]bool)
+		for _, statement := range groups[i].Statements {
+			for _, dependency := range statement.Dependencies {
+				j, ok := groupForObject[dependency]
+				if !ok || j >= i || isDependentOn[j] {
+					continue
+				}
+				isDependentOn[j] = true
+				groups[j].Dependents = append(groups[j].Dependents, i)
+				groups[i].NumDependencies++
+			}
+		}
+	}
+	return groups
+}
+
+/*
+ * This function creates a worker pool of N goroutines that each execute a group
+ * of statements as soon as all of the groups it depends on have been executed.
+ */
+func executeStatementGroups(groups []StatementGroup, fatalErr *error, numErrors *int32, progressBar utils.ProgressBar) {
+	if len(groups) == 0 {
+		return
+	}
+	var workerPool sync.WaitGroup
+	var groupMutex sync.Mutex
+	remaining := len(groups)
+	isDone := false
+	ready := make(chan int, len(groups))
+	for i, group := range groups {
+		if group.NumDependencies == 0 {
+			ready <- i
+		}
+	}
+
+	for i := 0; i < connectionPool.NumConns; i++ {
+		workerPool.Add(1)
+		go func(connNum int) {
+			defer workerPool.Done()
+			connNum = connectionPool.ValidateConnNum(connNum)
+			for index := range ready {
+				executeStatementsForConn(makeStatementChannel(groups[index].Statements), fatalErr, numErrors, progressBar, connNum, true)
+
+				groupMutex.Lock()
+				remaining--
+				if !isDone && (remaining == 0 || wasTerminated || *fatalErr != nil) {
+					isDone = true
+					close(ready)
+				} else if !isDone {
+					for _, dependent := range groups[index].Dependents {
+						groups[dependent].NumDependencies--
+						if groups[dependent].NumDependencies == 0 {
+							ready <- dependent
+						}
+					}
+				}
+				groupMutex.Unlock()
+			}
+		}(i)
+	}
+	workerPool.Wait()
+}
+
+/*
+ * Statements that carry dependency information are executed in parallel
+ * across all connections, respecting the dependencies between objects.
+ * Statements without it, such as those for objects gpbackup does not sort by
+ * dependency or those from backups taken before this information was recorded,
+ * are executed serially in their original order and act as barriers between
+ * the parallel batches.
+ */
+func ExecuteDependentStatements(statements []toc.StatementWithType, progressBar utils.ProgressBar) {
+	var fatalErr error
+	var numErrors int32
+	for start := 0; start < len(statements) && fatalErr == nil && !wasTerminated; {
+		hasDependencyInfo := statements[start].ObjectID != toc.UniqueID{}
+		end := start + 1
+		for end < len(statements) && (statements[end].ObjectID != toc.UniqueID{}) == hasDependencyInfo {
+			end++
+		}
+		if hasDependencyInfo {
+			executeStatementGroups(GroupStatementsByDependency(statements[start:end]), &fatalErr, &numErrors, progressBar)
+		} else {
+			executeStatementsForConn(makeStatementChannel(statements[start:end]), &fatalErr, &numErrors, progressBar, 0, true)
+		}
+		start = end
+	}
+	reportStatementErrors(fatalErr, numErrors)
+}
+
 func ExecuteStatementsAndCreateProgressBar(statements []toc.StatementWithType, objectsTitle string, showProgressBar int, executeInParallel bool, whichConn ...int) {
 	progressBar := utils.NewProgressBar(len(statements), fmt.Sprintf("%s restored: ", objectsTitle), showProgressBar)
 	progressBar.Start()
@@ -1,8 +1,12 @@
 package restore_test
 
 import (
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -34,4 +38,60 @@ var _ = Describe("restore/parallel tests", func() {
 		})
 
 	})
+	Describe("dependency-ordered statements", func() {
+		typeID := toc.UniqueID{ClassID: 1247, Oid: 1}
+		funcID := toc.UniqueID{ClassID: 1255, Oid: 2}
+		tableID := toc.UniqueID{ClassID: 1259, Oid: 3}
+		schema := toc.StatementWithType{ObjectType: "SCHEMA", Statement: "CREATE SCHEMA foo;"}
+		createType := toc.StatementWithType{ObjectType: "TYPE", Statement: "CREATE TYPE foo.t AS (i int);", ObjectID: typeID}
+		commentType := toc.StatementWithType{ObjectType: "TYPE", Statement: "COMMENT ON TYPE foo.t IS 'type';", ObjectID: typeID}
+		createFunc := toc.StatementWithType{ObjectType: "FUNCTION", Statement: "CREATE FUNCTION foo.f() RETURNS int AS 'SELECT 1' LANGUAGE sql;", ObjectID: funcID}
+		createTable := toc.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE foo.bar (a foo.t);", ObjectID: tableID, Dependencies: []toc.UniqueID{typeID}}
+		constraint := toc.StatementWithType{ObjectType: "CONSTRAINT", Statement: "ALTER TABLE ONLY foo.bar ADD CONSTRAINT c CHECK (true);"}
+		Describe("GroupStatementsByDependency", func() {
+			It("groups statements for the same object and links them to the objects they depend on", func() {
+				groups := restore.GroupStatementsByDependency([]toc.StatementWithType{createType, createFunc, commentType, createTable})
+
+				Expect(groups).To(Equal([]restore.StatementGroup{
+					{Statements: []toc.StatementWithType{createType, commentType}, Dependents: []int{2}},
+					{Statements: []toc.StatementWithType{createFunc}},
+					{Statements: []toc.StatementWithType{createTable}, NumDependencies: 1},
+				}))
+			})
+			It("ignores dependencies on objects that are not being restored", func() {
+				groups := restore.GroupStatementsByDependency([]toc.StatementWithType{createFunc, createTable})
+
+				Expect(groups[0].Dependents).To(BeEmpty())
+				Expect(groups[1].NumDependencies).To(Equal(0))
+			})
+			It("ignores dependencies on objects that come later in the list", func() {
+				createTypeOnTable := createType
+				createTypeOnTable.Dependencies = []toc.UniqueID{tableID}
+				groups := restore.GroupStatementsByDependency([]toc.StatementWithType{createTypeOnTable, createTable})
+
+				Expect(groups[0].NumDependencies).To(Equal(0))
+				Expect(groups[0].Dependents).To(Equal([]int{1}))
+				Expect(groups[1].NumDependencies).To(Equal(1))
+			})
+		})
+		Describe("ExecuteDependentStatements", func() {
+			expectExec := func(statement toc.StatementWithType) {
+				mock.ExpectExec(regexp.QuoteMeta(statement.Statement)).WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			It("executes statements without dependency information in order around the dependency-ordered statements", func() {
+				expectExec(schema)
+				expectExec(createType)
+				expectExec(commentType)
+				expectExec(createFunc)
+				expectExec(createTable)
+				expectExec(constraint)
+				progressBar := utils.NewProgressBar(6, "", utils.PB_NONE)
+				progressBar.Start()
+
+				restore.ExecuteDependentStatements([]toc.StatementWithType{schema, createType, commentType, createTable, createFunc, constraint}, progressBar)
+
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+		})
+	})
 })
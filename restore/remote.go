@@ -19,7 +19,8 @@ import (
 func VerifyBackupDirectoriesExistOnAllHosts() {
 	_, err := globalCluster.ExecuteLocalCommand(fmt.Sprintf("test -d %s", globalFPInfo.GetDirForContent(-1)))
 	gplog.FatalOnError(err, "Backup directory %s missing or inaccessible", globalFPInfo.GetDirForContent(-1))
-	if MustGetFlagString(options.PLUGIN_CONFIG) == "" || backupConfig.SingleDataFile {
+	// When resizing, the restore segments do not correspond to the backup segments
+	if (MustGetFlagString(options.PLUGIN_CONFIG) == "" || backupConfig.SingleDataFile) && !isResizeRestore() {
 		remoteOutput := globalCluster.GenerateAndExecuteCommand("Verifying backup directories exist", func(contentID int) string {
 			return fmt.Sprintf("test -d %s", globalFPInfo.GetDirForContent(contentID))
 		}, cluster.ON_SEGMENTS)
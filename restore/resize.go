@@ -0,0 +1,317 @@
+package restore
+
+/*
+ * This file contains functions related to restoring a backup to a cluster with
+ * a different number of segments than the cluster it was taken on.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+func getRestoreSegmentCount() int {
+	// The master is included in the cluster's content IDs as content -1
+	return len(globalCluster.ContentIDs) - 1
+}
+
+/*
+ * Backups taken before the segment count was recorded are assumed to have been
+ * taken on a cluster of the same size.
+ */
+func isResizeRestore() bool {
+	return backupConfig.SegmentCount != 0 && backupConfig.SegmentCount != getRestoreSegmentCount()
+}
+
+/*
+ * A table that gpexpand had not yet redistributed onto the new segments when
+ * the backup was taken has data files only on the segments of the cluster
+ * before the expansion.
+ */
+func getBackupSegmentCount(entry toc.MasterDataEntry) int {
+	if entry.SegmentCount != 0 {
+		return entry.SegmentCount
+	}
+	return backupConfig.SegmentCount
+}
+
+func isResizeRestoreForEntry(entry toc.MasterDataEntry) bool {
+	backupSegmentCount := getBackupSegmentCount(entry)
+	return backupSegmentCount != 0 && backupSegmentCount != getRestoreSegmentCount()
+}
+
+/*
+ * A backup taken while gpexpand was redistributing tables holds tables in both
+ * the pre-expansion and the expanded layout, so it can be restored without the
+ * --resize-cluster flag to a cluster of either size. The tables in the other
+ * layout are loaded in the same way as when resizing.
+ */
+func isRestoreToExpansionLayout() bool {
+	isExpansionBackup := false
+	isPreExpansionLayout := false
+	for _, entry := range globalTOC.DataEntries {
+		if entry.SegmentCount != 0 {
+			isExpansionBackup = true
+			isPreExpansionLayout = isPreExpansionLayout || entry.SegmentCount == getRestoreSegmentCount()
+		}
+	}
+	return isExpansionBackup && (isPreExpansionLayout || !isResizeRestore())
+}
+
+func ValidateSegmentCount() {
+	resizeCluster := MustGetFlagBool(options.RESIZE_CLUSTER)
+	if MustGetFlagString(options.RESIZE_MAPPING_FILE) != "" && !resizeCluster {
+		gplog.Fatal(errors.Errorf("The --resize-mapping-file flag must be used with the --resize-cluster flag."), "")
+	}
+	if !ShouldRestoreSection("data") {
+		return
+	}
+	if resizeCluster && backupConfig.SegmentCount == 0 {
+		gplog.Fatal(errors.Errorf("Backup does not record the number of segments it was taken on and cannot be restored with the --resize-cluster flag."), "")
+	}
+	if isRestoreToExpansionLayout() {
+		validateRestoreToExpansionLayout()
+		return
+	}
+	if !isResizeRestore() {
+		return
+	}
+	if !resizeCluster {
+		gplog.Fatal(errors.Errorf("Backup was taken on a cluster with %d segments, but the restore cluster has %d segments. Use the --resize-cluster flag to restore to a cluster with a different number of segments.", backupConfig.SegmentCount, getRestoreSegmentCount()), "")
+	}
+	if backupConfig.SingleDataFile {
+		gplog.Fatal(errors.Errorf("Cannot use the --resize-cluster flag when restoring backups with a single data file per segment."), "")
+	}
+	if backupConfig.Plugin != "" {
+		gplog.Fatal(errors.Errorf("Cannot use the --resize-cluster flag when restoring backups taken with a plugin."), "")
+	}
+	if MustGetFlagString(options.OUTPUT_SQL) != "" {
+		gplog.Fatal(errors.Errorf("Cannot write data restore statements when restoring to a cluster with a different number of segments. Use the --metadata-only flag with --output-sql."), "")
+	}
+}
+
+func validateRestoreToExpansionLayout() {
+	numOtherLayoutTables := 0
+	for _, entry := range globalTOC.DataEntries {
+		if isResizeRestoreForEntry(entry) {
+			numOtherLayoutTables++
+		}
+	}
+	if numOtherLayoutTables == 0 {
+		return
+	}
+	if backupConfig.Plugin != "" {
+		gplog.Fatal(errors.Errorf("Backup was taken during an expansion and %d tables in it were backed up in a different segment layout than the restore cluster, which cannot be restored from a backup taken with a plugin.", numOtherLayoutTables), "")
+	}
+	if MustGetFlagString(options.OUTPUT_SQL) != "" {
+		gplog.Fatal(errors.Errorf("Cannot write data restore statements for tables backed up in a different segment layout than the restore cluster. Use the --metadata-only flag with --output-sql."), "")
+	}
+	gplog.Info("Backup was taken during an expansion; %d tables backed up in a different segment layout will be redistributed onto the %d segments of the restore cluster", numOtherLayoutTables, getRestoreSegmentCount())
+}
+
+/*
+ * By default, the data files for backup content k are read by restore content
+ * k modulo the number of restore segments. The returned map is keyed by restore
+ * content ID and lists the backup content IDs whose files that segment reads.
+ */
+func GetDefaultResizeMapping(backupSegmentCount int, restoreSegmentCount int) map[int][]int {
+	mapping := make(map[int][]int)
+	for contentID := 0; contentID < backupSegmentCount; contentID++ {
+		restoreContentID := contentID % restoreSegmentCount
+		mapping[restoreContentID] = append(mapping[restoreContentID], contentID)
+	}
+	return mapping
+}
+
+/*
+ * A mapping file lets users choose which segment reads each backup segment's
+ * files, for example so that files are read on the host they were copied to.
+ * Every backup content ID must be mapped exactly once.
+ */
+func ReadResizeMappingFile(filename string, backupSegmentCount int, restoreSegmentCount int) map[int][]int {
+	lines, err := iohelper.ReadLinesFromFile(filename)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to read resize mapping file %s", filename))
+
+	mapping := make(map[int][]int)
+	isMapped := make(map[int]bool)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pair := strings.Split(line, ":")
+		if len(pair) != 2 {
+			gplog.Fatal(errors.Errorf(`Invalid line "%s" in resize mapping file %s. Lines must be of the form "<backup content ID>:<restore content ID>".`, line, filename), "")
+		}
+		backupContentID, backupErr := strconv.Atoi(strings.TrimSpace(pair[0]))
+		restoreContentID, restoreErr := strconv.Atoi(strings.TrimSpace(pair[1]))
+		if backupErr != nil || restoreErr != nil {
+			gplog.Fatal(errors.Errorf(`Invalid line "%s" in resize mapping file %s. Lines must be of the form "<backup content ID>:<restore content ID>".`, line, filename), "")
+		}
+		if backupContentID < 0 || backupContentID >= backupSegmentCount {
+			gplog.Fatal(errors.Errorf("Backup content ID %d in resize mapping file %s is out of range; the backup has %d segments.", backupContentID, filename, backupSegmentCount), "")
+		}
+		if restoreContentID < 0 || restoreContentID >= restoreSegmentCount {
+			gplog.Fatal(errors.Errorf("Restore content ID %d in resize mapping file %s is out of range; the restore cluster has %d segments.", restoreContentID, filename, restoreSegmentCount), "")
+		}
+		if isMapped[backupContentID] {
+			gplog.Fatal(errors.Errorf("Backup content ID %d is mapped more than once in resize mapping file %s.", backupContentID, filename), "")
+		}
+		isMapped[backupContentID] = true
+		mapping[restoreContentID] = append(mapping[restoreContentID], backupContentID)
+	}
+	for contentID := 0; contentID < backupSegmentCount; contentID++ {
+		if !isMapped[contentID] {
+			gplog.Fatal(errors.Errorf("Backup content ID %d is not mapped in resize mapping file %s.", contentID, filename), "")
+		}
+	}
+	return mapping
+}
+
+func InitializeResizeMapping() {
+	if !isResizeRestore() || !ShouldRestoreSection("data") {
+		return
+	}
+	backupSegmentCount := backupConfig.SegmentCount
+	restoreSegmentCount := getRestoreSegmentCount()
+	if mappingFile := MustGetFlagString(options.RESIZE_MAPPING_FILE); mappingFile != "" {
+		resizeMapping = ReadResizeMappingFile(mappingFile, backupSegmentCount, restoreSegmentCount)
+	} else {
+		resizeMapping = GetDefaultResizeMapping(backupSegmentCount, restoreSegmentCount)
+	}
+	gplog.Info("Restoring data from a backup of %d segments to a cluster of %d segments", backupSegmentCount, restoreSegmentCount)
+}
+
+/*
+ * The returned command runs on every segment of the restore cluster as the
+ * command of an external web table, so it uses the environment variables set
+ * for those commands instead of the placeholders substituted by COPY ... ON
+ * SEGMENT. Each segment reads the files of the backup segments mapped to it.
+ */
+func GetResizeReadCommand(fpInfo filepath.FilePathInfo, oid uint32, mapping map[int][]int) string {
+	restoreContentIDs := make([]int, 0, len(mapping))
+	for restoreContentID := range mapping {
+		restoreContentIDs = append(restoreContentIDs, restoreContentID)
+	}
+	sort.Ints(restoreContentIDs)
+
+	cases := make([]string, 0, len(restoreContentIDs)+1)
+	for _, restoreContentID := range restoreContentIDs {
+		backupContentIDs := make([]string, 0, len(mapping[restoreContentID]))
+		for _, backupContentID := range mapping[restoreContentID] {
+			backupContentIDs = append(backupContentIDs, strconv.Itoa(backupContentID))
+		}
+		cases = append(cases, fmt.Sprintf(`%d) ids="%s" ;;`, restoreContentID, strings.Join(backupContentIDs, " ")))
+	}
+	cases = append(cases, `*) ids="" ;;`)
+
+	filePath := fpInfo.GetTableBackupFilePathForCopyCommand(oid, utils.GetPipeThroughProgram().Extension, false)
+	filePath = strings.Replace(filePath, "<SEG_DATA_DIR>", "$GP_SEG_DATADIR", -1)
+	filePath = strings.Replace(filePath, "<SEGID>", "${id}", -1)
+
+	readCommand := utils.GetPipeThroughProgram().InputCommand
+	if encryptionKey != nil {
+		// The key file is in the data directory of the restore segment, and the whole pipeline reads the data file
+		readCommand = strings.Replace(readCommand, "<SEG_DATA_DIR>", "$GP_SEG_DATADIR", -1)
+		readCommand = strings.Replace(readCommand, "<SEGID>", "${GP_SEGMENT_ID}", -1)
+		readCommand = fmt.Sprintf("(%s)", readCommand)
+	}
+
+	return fmt.Sprintf("case $GP_SEGMENT_ID in %s esac; for id in $ids; do %s < %s || exit 1; done",
+		strings.Join(cases, " "), readCommand, filePath)
+}
+
+/*
+ * Rows read through an external web table are redistributed according to the
+ * distribution policy of the table they are inserted into, which is what lets
+ * a backup be restored to a cluster with a different number of segments.
+ */
+func GetCopyTableInWithResizeQueries(tableName string, tableAttributes string, oid uint32, readCommand string) (string, string, string) {
+	externalTableName := fmt.Sprintf("gpbackup_resize_%d", oid)
+	createQuery := fmt.Sprintf("CREATE EXTERNAL WEB TEMP TABLE %s (LIKE %s) EXECUTE '%s' ON ALL FORMAT 'csv' (DELIMITER '%s');", externalTableName, tableName, readCommand, tableDelim)
+	insertQuery := fmt.Sprintf("INSERT INTO %s%s SELECT * FROM %s;", tableName, tableAttributes, externalTableName)
+	dropQuery := fmt.Sprintf("DROP EXTERNAL TABLE %s;", externalTableName)
+	return createQuery, insertQuery, dropQuery
+}
+
+func CopyTableInWithResize(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, oid uint32, readCommand string, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	createQuery, insertQuery, dropQuery := GetCopyTableInWithResizeQueries(tableName, tableAttributes, oid, readCommand)
+	_, err := connectionPool.Exec(createQuery, whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error creating external table to load data into table %s", tableName))
+	}
+	result, err := connectionPool.Exec(insertQuery, whichConn)
+	_, dropErr := connectionPool.Exec(dropQuery, whichConn)
+	if err != nil {
+		return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+	}
+	if dropErr != nil {
+		gplog.Verbose("Error dropping external table used to load data into table %s: %s", tableName, dropErr.Error())
+	}
+	numRows, _ := result.RowsAffected()
+	return numRows, nil
+}
+
+func isReplicatedTable(tableName string, whichConn int) (bool, error) {
+	if connectionPool.Version.Before("6") {
+		return false, nil
+	}
+	query := fmt.Sprintf(`
+SELECT CASE
+	WHEN policytype = 'r' THEN 'true'
+	ELSE 'false'
+END AS string
+FROM gp_distribution_policy
+WHERE localoid = '%s'::regclass::oid`, utils.EscapeSingleQuotes(tableName))
+	result, err := dbconn.SelectString(connectionPool, query, whichConn)
+	if err != nil || result == "" {
+		return false, err
+	}
+	return strconv.ParseBool(result)
+}
+
+/*
+ * Every segment of a replicated table holds all of its rows, so only the files
+ * of the first backup segment are read, and there is no meaningful row count
+ * from the backup to check against. Tables whose distribution policy was
+ * changed by a table rule are loaded in the same way, with each segment
+ * reading its own files unless the cluster is also being resized.
+ */
+func restoreSingleTableDataWithResize(fpInfo *filepath.FilePathInfo, entry toc.MasterDataEntry, tableName string, whichConn int) error {
+	isReplicated, err := isReplicatedTable(tableName, whichConn)
+	if err != nil {
+		return err
+	}
+	wasReplicated := isReplicated
+	if backupReplicated, isRedistributed := getRedistributedTable(entry); isRedistributed {
+		wasReplicated = backupReplicated
+	}
+	mapping := resizeMapping
+	if backupSegmentCount := getBackupSegmentCount(entry); backupSegmentCount != backupConfig.SegmentCount {
+		mapping = GetDefaultResizeMapping(backupSegmentCount, getRestoreSegmentCount())
+	} else if mapping == nil {
+		segmentCount := getRestoreSegmentCount()
+		mapping = GetDefaultResizeMapping(segmentCount, segmentCount)
+	}
+	if wasReplicated {
+		mapping = map[int][]int{0: {0}}
+	}
+	readCommand := GetResizeReadCommand(*fpInfo, entry.Oid, mapping)
+	numRowsRestored, err := CopyTableInWithResize(connectionPool, tableName, entry.AttributeString, entry.Oid, readCommand, whichConn)
+	if err != nil || isReplicated || wasReplicated {
+		return err
+	}
+	return CheckRowsRestored(numRowsRestored, entry.RowsCopied, tableName)
+}
@@ -0,0 +1,199 @@
+package restore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/resize tests", func() {
+	var testCluster *cluster.Cluster
+	BeforeEach(func() {
+		testCluster = cluster.NewCluster([]cluster.SegConfig{
+			{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"},
+			{ContentID: 0, Hostname: "localhost", DataDir: "/data/gpseg0"},
+			{ContentID: 1, Hostname: "localhost", DataDir: "/data/gpseg1"},
+		})
+		restore.SetCluster(testCluster)
+	})
+	Describe("ValidateSegmentCount", func() {
+		BeforeEach(func() {
+			restore.SetTOC(&toc.TOC{})
+		})
+		It("passes when the backup was taken on a cluster of the same size", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 2})
+			restore.ValidateSegmentCount()
+		})
+		It("passes when the backup does not record its segment count", func() {
+			restore.SetBackupConfig(&history.BackupConfig{})
+			restore.ValidateSegmentCount()
+		})
+		It("panics when segment counts differ and --resize-cluster is not set", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4})
+			defer testhelper.ShouldPanicWithMessage("Backup was taken on a cluster with 4 segments, but the restore cluster has 2 segments. Use the --resize-cluster flag to restore to a cluster with a different number of segments.")
+			restore.ValidateSegmentCount()
+		})
+		It("passes when segment counts differ and --resize-cluster is set", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4})
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "true")
+			restore.ValidateSegmentCount()
+		})
+		It("passes when segment counts differ for a metadata-only restore", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4})
+			_ = cmdFlags.Set(options.METADATA_ONLY, "true")
+			restore.ValidateSegmentCount()
+		})
+		It("panics when resizing a backup that does not record its segment count", func() {
+			restore.SetBackupConfig(&history.BackupConfig{})
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "true")
+			defer testhelper.ShouldPanicWithMessage("Backup does not record the number of segments it was taken on and cannot be restored with the --resize-cluster flag.")
+			restore.ValidateSegmentCount()
+		})
+		It("panics when resizing a single data file backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4, SingleDataFile: true})
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "true")
+			defer testhelper.ShouldPanicWithMessage("Cannot use the --resize-cluster flag when restoring backups with a single data file per segment.")
+			restore.ValidateSegmentCount()
+		})
+		Describe("backups taken during an expansion", func() {
+			BeforeEach(func() {
+				restore.SetTOC(&toc.TOC{DataEntries: []toc.MasterDataEntry{
+					{Schema: "public", Name: "redistributed", Oid: 1},
+					{Schema: "public", Name: "not_redistributed", Oid: 2, SegmentCount: 2},
+				}})
+			})
+			It("passes without --resize-cluster when restoring to the pre-expansion layout", func() {
+				restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4})
+				restore.ValidateSegmentCount()
+			})
+			It("passes without --resize-cluster when restoring to the expanded layout", func() {
+				testCluster = cluster.NewCluster([]cluster.SegConfig{
+					{ContentID: -1, Hostname: "localhost", DataDir: "/data/gpseg-1"},
+					{ContentID: 0, Hostname: "localhost", DataDir: "/data/gpseg0"},
+					{ContentID: 1, Hostname: "localhost", DataDir: "/data/gpseg1"},
+					{ContentID: 2, Hostname: "localhost", DataDir: "/data/gpseg2"},
+					{ContentID: 3, Hostname: "localhost", DataDir: "/data/gpseg3"},
+				})
+				restore.SetCluster(testCluster)
+				restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4})
+				restore.ValidateSegmentCount()
+			})
+			It("panics without --resize-cluster when restoring to a cluster of another size", func() {
+				restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 5})
+				restore.SetTOC(&toc.TOC{DataEntries: []toc.MasterDataEntry{{Schema: "public", Name: "not_redistributed", Oid: 2, SegmentCount: 3}}})
+				defer testhelper.ShouldPanicWithMessage("Backup was taken on a cluster with 5 segments, but the restore cluster has 2 segments. Use the --resize-cluster flag to restore to a cluster with a different number of segments.")
+				restore.ValidateSegmentCount()
+			})
+			It("panics when tables in the other layout were backed up with a plugin", func() {
+				restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 4, Plugin: "/tmp/plugin.sh"})
+				defer testhelper.ShouldPanicWithMessage("Backup was taken during an expansion and 1 tables in it were backed up in a different segment layout than the restore cluster, which cannot be restored from a backup taken with a plugin.")
+				restore.ValidateSegmentCount()
+			})
+		})
+		It("panics when a mapping file is given without --resize-cluster", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 2})
+			_ = cmdFlags.Set(options.RESIZE_MAPPING_FILE, "/tmp/mapping")
+			defer testhelper.ShouldPanicWithMessage("The --resize-mapping-file flag must be used with the --resize-cluster flag.")
+			restore.ValidateSegmentCount()
+		})
+	})
+	Describe("GetDefaultResizeMapping", func() {
+		It("maps backup segments to restore segments round-robin when shrinking", func() {
+			Expect(restore.GetDefaultResizeMapping(5, 2)).To(Equal(map[int][]int{0: {0, 2, 4}, 1: {1, 3}}))
+		})
+		It("maps each backup segment to the restore segment with the same content ID when growing", func() {
+			Expect(restore.GetDefaultResizeMapping(2, 4)).To(Equal(map[int][]int{0: {0}, 1: {1}}))
+		})
+	})
+	Describe("ReadResizeMappingFile", func() {
+		var mappingFile string
+		writeMappingFile := func(contents string) {
+			file, err := ioutil.TempFile("", "resize_mapping")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = file.WriteString(contents)
+			Expect(err).ToNot(HaveOccurred())
+			_ = file.Close()
+			mappingFile = file.Name()
+		}
+		AfterEach(func() {
+			_ = os.Remove(mappingFile)
+		})
+		It("reads a mapping of backup content IDs to restore content IDs", func() {
+			writeMappingFile("0:1\n1:1\n\n2:0\n")
+
+			Expect(restore.ReadResizeMappingFile(mappingFile, 3, 2)).To(Equal(map[int][]int{0: {2}, 1: {0, 1}}))
+		})
+		It("panics when a line is malformed", func() {
+			writeMappingFile("0:1\n1\n")
+			defer testhelper.ShouldPanicWithMessage(`Invalid line "1" in resize mapping file`)
+			restore.ReadResizeMappingFile(mappingFile, 2, 2)
+		})
+		It("panics when a restore content ID is out of range", func() {
+			writeMappingFile("0:0\n1:2\n")
+			defer testhelper.ShouldPanicWithMessage("Restore content ID 2 in resize mapping file")
+			restore.ReadResizeMappingFile(mappingFile, 2, 2)
+		})
+		It("panics when a backup content ID is mapped twice", func() {
+			writeMappingFile("0:0\n0:1\n1:1\n")
+			defer testhelper.ShouldPanicWithMessage("Backup content ID 0 is mapped more than once")
+			restore.ReadResizeMappingFile(mappingFile, 2, 2)
+		})
+		It("panics when a backup content ID is not mapped", func() {
+			writeMappingFile("0:0\n")
+			defer testhelper.ShouldPanicWithMessage("Backup content ID 1 is not mapped")
+			restore.ReadResizeMappingFile(mappingFile, 2, 2)
+		})
+	})
+	Describe("GetResizeReadCommand", func() {
+		BeforeEach(func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -1", InputCommand: "gzip -d -c", Extension: ".gz"})
+		})
+		It("reads the files of each mapped backup segment from the segment data directory", func() {
+			fpInfo := filepath.NewFilePathInfo(testCluster, "", "20170101010101", "gpseg")
+			command := restore.GetResizeReadCommand(fpInfo, 3456, map[int][]int{0: {0, 2}, 1: {1}})
+
+			Expect(command).To(Equal(`case $GP_SEGMENT_ID in 0) ids="0 2" ;; 1) ids="1" ;; *) ids="" ;; esac; for id in $ids; do gzip -d -c < $GP_SEG_DATADIR/backups/20170101/20170101010101/gpbackup_${id}_20170101010101_3456.gz || exit 1; done`))
+		})
+		It("reads the files of each mapped backup segment from a user-specified backup directory", func() {
+			fpInfo := filepath.NewFilePathInfo(testCluster, "/backups", "20170101010101", "gpseg")
+			command := restore.GetResizeReadCommand(fpInfo, 3456, map[int][]int{0: {0}})
+
+			Expect(command).To(Equal(`case $GP_SEGMENT_ID in 0) ids="0" ;; *) ids="" ;; esac; for id in $ids; do gzip -d -c < /backups/gpseg${id}/backups/20170101/20170101010101/gpbackup_${id}_20170101010101_3456.gz || exit 1; done`))
+		})
+		It("decrypts the files of an encrypted backup with the key file of the restore segment", func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -1", InputCommand: "/usr/local/greenplum-db/bin/gpbackup_helper --decrypt --encryption-key-file <SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_key_1234 | gzip -d -c", Extension: ".gz"})
+			restore.SetEncryptionKey(&utils.EncryptionKey{})
+			defer restore.SetEncryptionKey(nil)
+			fpInfo := filepath.NewFilePathInfo(testCluster, "", "20170101010101", "gpseg")
+			command := restore.GetResizeReadCommand(fpInfo, 3456, map[int][]int{0: {0}})
+
+			Expect(command).To(Equal(`case $GP_SEGMENT_ID in 0) ids="0" ;; *) ids="" ;; esac; for id in $ids; do (/usr/local/greenplum-db/bin/gpbackup_helper --decrypt --encryption-key-file $GP_SEG_DATADIR/gpbackup_${GP_SEGMENT_ID}_20170101010101_key_1234 | gzip -d -c) < $GP_SEG_DATADIR/backups/20170101/20170101010101/gpbackup_${id}_20170101010101_3456.gz || exit 1; done`))
+		})
+	})
+	Describe("CopyTableInWithResize", func() {
+		It("loads data through an external web table and drops it afterwards", func() {
+			mock.ExpectExec(regexp.QuoteMeta(`CREATE EXTERNAL WEB TEMP TABLE gpbackup_resize_3456 (LIKE public.foo) EXECUTE 'cat' ON ALL FORMAT 'csv' (DELIMITER ',');`)).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO public.foo(i,j) SELECT * FROM gpbackup_resize_3456;`)).WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectExec(regexp.QuoteMeta(`DROP EXTERNAL TABLE gpbackup_resize_3456;`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			numRows, err := restore.CopyTableInWithResize(connectionPool, "public.foo", "(i,j)", 3456, "cat", 0)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numRows).To(Equal(int64(10)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
@@ -30,32 +30,51 @@ import (
 func initializeFlags(cmd *cobra.Command) {
 	SetFlagDefaults(cmd.Flags())
 
-	_ = cmd.MarkFlagRequired(options.TIMESTAMP)
-
 	cmdFlags = cmd.Flags()
 }
 func SetFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.String(options.BACKUP_DIR, "", "The absolute path of the directory in which the backup files to be restored are located")
+	flagSet.String(options.CLUSTER_TIMESTAMP, "", "The timestamp of a backup of several databases to be restored, in the format YYYYMMDDHHMMSS")
+	flagSet.String(options.CONFIG, "", "A YAML file setting the value of any flag that is not given on the command line or in a GPRESTORE_<FLAG> environment variable, with optional per-database profiles")
 	flagSet.Bool(options.CREATE_DB, false, "Create the database before metadata restore")
 	flagSet.Bool(options.DATA_ONLY, false, "Only restore data, do not restore metadata")
+	flagSet.StringArray(options.DBNAME, []string{}, "Restore only the specified database(s) of the backup given by --cluster-timestamp. --dbname can be specified multiple times.")
 	flagSet.Bool(options.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(options.ENCRYPTION_KEY_COMMAND, "", "A command whose output is the key with which the backup files were encrypted")
+	flagSet.String(options.ENCRYPTION_KEY_FILE, "", "A file containing the key with which the backup files were encrypted")
 	flagSet.StringArray(options.EXCLUDE_SCHEMA, []string{}, "Restore all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.String(options.EXCLUDE_SCHEMA_FILE, "", "A file containing a list of schemas that will not be restored")
+	flagSet.StringArray(options.EXCLUDE_SCHEMA_PATTERN, []string{}, "Restore all metadata except objects in schemas matching the specified glob or /regular expression/ pattern(s). --exclude-schema-pattern can be specified multiple times.")
+	flagSet.StringArray(options.EXCLUDE_OBJECT_TYPE, []string{}, "Restore all metadata except objects of the specified type(s), such as ROLE, TRIGGER, or COMMENT. --exclude-object-type can be specified multiple times.")
 	flagSet.StringArray(options.EXCLUDE_RELATION, []string{}, "Restore all metadata except the specified relation(s). --exclude-table can be specified multiple times.")
 	flagSet.String(options.EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will not be restored")
+	flagSet.StringArray(options.EXCLUDE_RELATION_PATTERN, []string{}, "Restore all metadata except relations matching the specified glob or /regular expression/ pattern(s), such as 'etl.stage_*'. --exclude-table-pattern can be specified multiple times.")
 	flagSet.Bool("help", false, "Help for gprestore")
 	flagSet.StringArray(options.INCLUDE_SCHEMA, []string{}, "Restore only the specified schema(s). --include-schema can be specified multiple times.")
 	flagSet.String(options.INCLUDE_SCHEMA_FILE, "", "A file containing a list of schemas that will be restored")
+	flagSet.StringArray(options.INCLUDE_SCHEMA_PATTERN, []string{}, "Restore only schemas matching the specified glob or /regular expression/ pattern(s). --include-schema-pattern can be specified multiple times.")
+	flagSet.StringArray(options.INCLUDE_OBJECT_TYPE, []string{}, "Restore only metadata for objects of the specified type(s), such as SCHEMA, TABLE, or VIEW. --include-object-type can be specified multiple times.")
 	flagSet.StringArray(options.INCLUDE_RELATION, []string{}, "Restore only the specified relation(s). --include-table can be specified multiple times.")
 	flagSet.String(options.INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will be restored")
-	flagSet.Bool(options.INCREMENTAL, false, "Only restore data for all heap tables and only AO tables that have been modified since the last backup")
+	flagSet.StringArray(options.INCLUDE_RELATION_PATTERN, []string{}, "Restore only relations matching the specified glob or /regular expression/ pattern(s), such as 'etl.stage_*'. --include-table-pattern can be specified multiple times.")
+	flagSet.Bool(options.INCREMENTAL, false, "Only restore data for tables that have been modified since the last backup")
 	flagSet.Bool(options.METADATA_ONLY, false, "Only restore metadata, do not restore data")
-	flagSet.Int(options.JOBS, 1, "Number of parallel connections to use when restoring table data and post-data")
+	flagSet.Bool(options.NO_OWNER, false, "Do not restore the owners of objects; restored objects are owned by the user running the restore")
+	flagSet.Bool(options.NO_PRIVILEGES, false, "Do not restore privileges (GRANT and REVOKE statements) or default privileges")
+	flagSet.Bool(options.NO_TABLESPACES, false, "Do not restore tablespaces; all objects are restored to the default tablespace")
+	flagSet.Int(options.JOBS, 1, "Number of parallel connections to use when restoring pre-data, table data, and post-data")
 	flagSet.Bool(options.ON_ERROR_CONTINUE, false, "Log errors and continue restore, instead of exiting on first error")
+	flagSet.String(options.OUTPUT_SQL, "", "Write the restore statements to the specified file instead of executing them against the database")
 	flagSet.String(options.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(options.QUIET, false, "Suppress non-warning, non-error log messages")
 	flagSet.String(options.REDIRECT_DB, "", "Restore to the specified database instead of the database that was backed up")
+	flagSet.Bool(options.RESIZE_CLUSTER, false, "Restore a backup taken on a cluster with a different number of segments, redistributing the data across the segments of this cluster")
+	flagSet.String(options.RESIZE_MAPPING_FILE, "", "A file mapping the content ID of each segment in the backup to the content ID of the segment that reads its data files, one \"<backup content ID>:<restore content ID>\" pair per line")
+	flagSet.String(options.ROLE_MAPPING, "", "A YAML file mapping the names of roles in the backup to the names of roles in the restore database; owners and privileges of roles that are not mapped are not restored")
+	flagSet.StringArray(options.SECTION, []string{}, "Restore only the specified section(s) of the backup: global, predata, data, postdata, or statistics. --section can be specified multiple times.")
+	flagSet.StringArray(options.TABLESPACE_MAPPING, []string{}, "Restore objects in a tablespace to an existing tablespace instead, in the format \"<backup tablespace>=<restore tablespace>\". --tablespace-mapping can be specified multiple times.")
+	flagSet.String(options.TABLE_RULES_FILE, "", "A YAML file of rules that change the storage options and distribution policies of matching tables as they are restored")
 	flagSet.Bool(options.WITH_GLOBALS, false, "Restore global metadata")
 	flagSet.String(options.TIMESTAMP, "", "The timestamp to be restored, in the format YYYYMMDDHHMMSS")
 	flagSet.Bool(options.VERBOSE, false, "Print verbose log messages")
@@ -73,18 +92,35 @@ func DoInit(cmd *cobra.Command) {
 	utils.InitializeSignalHandler(DoCleanup, "restore process", &wasTerminated)
 }
 
+/*
+ * Flags not given on the command line are set from the environment and the
+ * configuration file before the flags are validated, so that a --timestamp
+ * from either of them satisfies the check that --timestamp or
+ * --cluster-timestamp is given.
+ */
+func ApplyConfig(cmd *cobra.Command) error {
+	return options.ApplyConfig(cmd.Flags(), "gprestore", options.REDIRECT_DB)
+}
+
 /*
 * This function handles argument parsing and validation, e.g. checking that a passed filename exists.
 * It should only validate; initialization with any sort of side effects should go in DoInit or DoSetup.
  */
 func DoValidation(cmd *cobra.Command) {
+	effectiveConfig = options.GetEffectiveConfig(cmd.Flags())
 	ValidateFlagCombinations(cmd.Flags())
 	err := utils.ValidateFullPath(MustGetFlagString(options.BACKUP_DIR))
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(options.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
-	if !filepath.IsValidTimestamp(MustGetFlagString(options.TIMESTAMP)) {
-		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", MustGetFlagString(options.TIMESTAMP)), "")
+	err = utils.ValidateFullPath(MustGetFlagString(options.ENCRYPTION_KEY_FILE))
+	gplog.FatalOnError(err)
+	timestamp := MustGetFlagString(options.TIMESTAMP)
+	if IsClusterRestore() {
+		timestamp = MustGetFlagString(options.CLUSTER_TIMESTAMP)
+	}
+	if !filepath.IsValidTimestamp(timestamp) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", timestamp), "")
 	}
 }
 
@@ -113,9 +149,15 @@ func DoSetup() {
 	} else {
 		InitializeBackupConfig()
 	}
+	InitializeEncryption()
 
 	BackupConfigurationValidation()
-	metadataFilename := globalFPInfo.GetMetadataFilePath()
+	InitializeObjectDefinitions()
+	InitializeResizeMapping()
+	InitializeRoleMapping()
+	InitializeTablespaceMapping()
+	InitializeTableRules()
+	metadataFilename := decryptedFiles.MustGetPath(globalFPInfo.GetMetadataFilePath())
 	if !backupConfig.DataOnly {
 		gplog.Verbose("Metadata will be restored from %s", metadataFilename)
 	}
@@ -123,16 +165,40 @@ func DoSetup() {
 	if MustGetFlagString(options.REDIRECT_DB) != "" {
 		unquotedRestoreDatabase = MustGetFlagString(options.REDIRECT_DB)
 	}
-	ValidateDatabaseExistence(unquotedRestoreDatabase, MustGetFlagBool(options.CREATE_DB), backupConfig.IncludeTableFiltered || backupConfig.DataOnly)
-	if MustGetFlagBool(options.WITH_GLOBALS) {
+	outputSQLFilename := MustGetFlagString(options.OUTPUT_SQL)
+	var sessionSetupQuery string
+	var gucStatements []toc.StatementWithType
+	if outputSQLFilename == "" {
+		ValidateDatabaseExistence(unquotedRestoreDatabase, MustGetFlagBool(options.CREATE_DB), backupConfig.IncludeTableFiltered || backupConfig.DataOnly)
+	} else {
+		InitializeSQLOutputFile(outputSQLFilename)
+		sessionSetupQuery = GetSetupQuery()
+		gucStatements = GetRestoreMetadataStatements("global", metadataFilename, []string{"SESSION GUCS"}, []string{})
+		WriteSessionSettingsToOutputFile(sessionSetupQuery, gucStatements)
+	}
+	if ShouldRestoreSection("global") {
 		restoreGlobal(metadataFilename)
 	} else if MustGetFlagBool(options.CREATE_DB) {
 		createDatabase(metadataFilename)
 	}
+	if outputSQLFilename != "" && MustGetFlagBool(options.CREATE_DB) {
+		// Session settings do not persist across a reconnect, so they are written again
+		WriteConnectToOutputFile(utils.QuoteIdent(connectionPool, unquotedRestoreDatabase))
+		WriteSessionSettingsToOutputFile(sessionSetupQuery, gucStatements)
+	}
 	if connectionPool != nil {
 		connectionPool.Close()
 	}
-	InitializeConnectionPool(unquotedRestoreDatabase)
+	/*
+	 * When writing statements to a file, the restore database only needs to
+	 * exist for an incremental restore, which compares the backup against the
+	 * schemas and tables already in the database.
+	 */
+	if outputSQLFilename != "" && !MustGetFlagBool(options.INCREMENTAL) {
+		CreateConnectionPool("postgres")
+	} else {
+		InitializeConnectionPool(unquotedRestoreDatabase)
+	}
 
 	/*
 	 * We don't need to validate anything if we're creating the database; we
@@ -140,39 +206,46 @@ func DoSetup() {
 	 * For on-error-continue, we will see the same errors later when we try to run SQL,
 	 * but since they will not stop the restore, it is not necessary to log them twice.
 	 */
-	if !MustGetFlagBool(options.CREATE_DB) && !MustGetFlagBool(options.ON_ERROR_CONTINUE) && !MustGetFlagBool(options.INCREMENTAL) {
+	if !MustGetFlagBool(options.CREATE_DB) && !MustGetFlagBool(options.ON_ERROR_CONTINUE) && !MustGetFlagBool(options.INCREMENTAL) && outputSQLFilename == "" {
 		relationsToRestore := GenerateRestoreRelationList()
 		ValidateRelationsInRestoreDatabase(connectionPool, relationsToRestore)
 	}
 }
 
 func DoRestore() {
-	metadataFilename := globalFPInfo.GetMetadataFilePath()
-	isDataOnly := backupConfig.DataOnly || MustGetFlagBool(options.DATA_ONLY)
-	isMetadataOnly := backupConfig.MetadataOnly || MustGetFlagBool(options.METADATA_ONLY)
+	metadataFilename := decryptedFiles.MustGetPath(globalFPInfo.GetMetadataFilePath())
 
-	if !isDataOnly {
+	if ShouldRestoreSection("predata") {
 		restorePredata(metadataFilename)
 	}
 
-	if !isMetadataOnly {
-		if MustGetFlagString(options.PLUGIN_CONFIG) == "" {
+	if ShouldRestoreSection("data") {
+		if MustGetFlagString(options.PLUGIN_CONFIG) == "" && !isResizeRestore() && !isRestoreToExpansionLayout() {
 			backupFileCount := 2 // 1 for the actual data file, 1 for the segment TOC file
-			if !backupConfig.SingleDataFile {
+			if !backupConfig.SingleDataFile || len(globalTOC.DataEntries) == 0 {
+				// An incremental backup in which no tables changed has no data files
 				backupFileCount = len(globalTOC.DataEntries)
 			}
 			VerifyBackupFileCountOnSegments(backupFileCount)
 		}
 		restoreData()
+		if shouldRestoreLargeObjects() {
+			restoreLargeObjects()
+		}
 	}
 
-	if !isDataOnly {
+	if ShouldRestoreSection("postdata") {
 		restorePostdata(metadataFilename)
 	}
 
-	if MustGetFlagBool(options.WITH_STATS) && backupConfig.WithStatistics {
+	if ShouldRestoreSection("statistics") && backupConfig.WithStatistics {
 		restoreStatistics()
 	}
+
+	if isOutputToFile() {
+		CloseSQLOutputFile()
+		gplog.Info("Restore statements written to %s", MustGetFlagString(options.OUTPUT_SQL))
+	}
 }
 
 func createDatabase(metadataFilename string) {
@@ -278,12 +351,19 @@ func restorePredata(metadataFilename string) {
 	filters := NewFilters(inSchemas, exSchemas, inRelations, exRelations)
 	schemaStatements := GetRestoreMetadataStatementsFiltered("predata", metadataFilename, []string{"SCHEMA"}, []string{}, filters)
 	statements := GetRestoreMetadataStatementsFiltered("predata", metadataFilename, []string{}, []string{"SCHEMA"}, filters)
+	if !isOutputToFile() {
+		statements = RemapExistingLargeObjects(statements)
+	}
 
 	progressBar := utils.NewProgressBar(len(schemaStatements)+len(statements), "Pre-data objects restored: ", utils.PB_VERBOSE)
 	progressBar.Start()
 
 	RestoreSchemas(schemaStatements, progressBar)
-	ExecuteRestoreMetadataStatements(statements, "Pre-data objects", progressBar, utils.PB_VERBOSE, false)
+	if connectionPool.NumConns > 1 && !isOutputToFile() {
+		ExecuteDependentStatements(statements, progressBar)
+	} else {
+		ExecuteRestoreMetadataStatements(statements, "Pre-data objects", progressBar, utils.PB_VERBOSE, false)
+	}
 
 	progressBar.Finish()
 	if wasTerminated {
@@ -312,7 +392,7 @@ func restoreData() {
 	filteredDataEntries := make(map[string][]toc.MasterDataEntry)
 	for _, entry := range restorePlanEntries {
 		fpInfo := GetBackupFPInfoForTimestamp(entry.Timestamp)
-		tocfile := toc.NewTOC(fpInfo.GetTOCFilePath())
+		tocfile := toc.NewTOC(decryptedFiles.MustGetPath(fpInfo.GetTOCFilePath()))
 		restorePlanTableFQNs := entry.TableFQNs
 		filteredDataEntriesForTimestamp := tocfile.GetDataEntriesMatching(MustGetFlagStringArray(options.INCLUDE_SCHEMA),
 			MustGetFlagStringArray(options.EXCLUDE_SCHEMA), options.MustGetFlagStringArray(cmdFlags, options.INCLUDE_RELATION),
@@ -320,6 +400,12 @@ func restoreData() {
 		filteredDataEntries[entry.Timestamp] = filteredDataEntriesForTimestamp
 		totalTables += len(filteredDataEntriesForTimestamp)
 	}
+	if isOutputToFile() {
+		WriteDataStatementsToOutputFile(restorePlanEntries, filteredDataEntries, MustGetFlagBool(options.INCREMENTAL))
+		gplog.Info("Data restore statements written for %d tables", totalTables)
+		return
+	}
+
 	dataProgressBar := utils.NewProgressBar(totalTables, "Tables restored: ", utils.PB_INFO)
 	dataProgressBar.Start()
 
@@ -372,6 +458,7 @@ func restoreStatistics() {
 	}
 	statisticsFilename := globalFPInfo.GetStatisticsFilePath()
 	gplog.Info("Restoring query planner statistics from %s", statisticsFilename)
+	statisticsFilename = decryptedFiles.MustGetPath(statisticsFilename)
 
 	inSchemas := MustGetFlagStringArray(options.INCLUDE_SCHEMA)
 	exSchemas := MustGetFlagStringArray(options.EXCLUDE_SCHEMA)
@@ -430,7 +517,7 @@ func DoTeardown() {
 			return
 		}
 		reportFilename := globalFPInfo.GetRestoreReportFilePath(restoreStartTime)
-		report.WriteRestoreReportFile(reportFilename, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, errMsg)
+		report.WriteRestoreReportFile(reportFilename, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, effectiveConfig, errMsg)
 		report.EmailReport(globalCluster, globalFPInfo.Timestamp, reportFilename, "gprestore")
 		if pluginConfig != nil {
 			pluginConfig.CleanupPluginForRestore(globalCluster, globalFPInfo)
@@ -490,18 +577,19 @@ func DoCleanup(restoreFailed bool) {
 	}()
 
 	gplog.Verbose("Beginning cleanup")
-	if backupConfig != nil && backupConfig.SingleDataFile {
+	if backupConfig != nil && (backupConfig.SingleDataFile || encryptionKey != nil) {
 		fpInfoList := GetBackupFPInfoListFromRestorePlan()
 		for _, fpInfo := range fpInfoList {
-			if restoreFailed {
+			if backupConfig.SingleDataFile && restoreFailed {
 				utils.CleanUpSegmentHelperProcesses(globalCluster, fpInfo, "restore")
 			}
 			utils.CleanUpHelperFilesOnAllHosts(globalCluster, fpInfo)
-			if wasTerminated { // These should all end on their own in a successful restore
+			if backupConfig.SingleDataFile && wasTerminated { // These should all end on their own in a successful restore
 				utils.TerminateHangingCopySessions(connectionPool, fpInfo, "gprestore")
 			}
 		}
 	}
+	decryptedFiles.Cleanup()
 
 	if connectionPool != nil {
 		connectionPool.Close()
@@ -0,0 +1,207 @@
+package restore
+
+/*
+ * This file contains functions related to rewriting or removing the owners and
+ * privileges of objects when restoring to a cluster with different roles than
+ * the cluster the backup was taken on.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Matches a quoted or unquoted role or tablespace name as printed by gpbackup
+const identifierPattern = `("(?:[^"]|"")+"|[^\s",;()]+)`
+
+var (
+	ownerRegex          = regexp.MustCompile(`^(ALTER .+ OWNER TO )` + identifierPattern + `;$`)
+	forRoleRegex        = regexp.MustCompile(`^(ALTER DEFAULT PRIVILEGES FOR ROLE )` + identifierPattern + `( .*)$`)
+	granteeRegex        = regexp.MustCompile(`^(.* (?:TO|FROM) )` + identifierPattern + `((?: WITH GRANT OPTION)?;)$`)
+	roleMembershipRegex = regexp.MustCompile(`^GRANT ` + identifierPattern + ` TO ` + identifierPattern + `( WITH ADMIN OPTION)?(?: GRANTED BY ` + identifierPattern + `)?;$`)
+)
+
+/*
+ * A role mapping file is a YAML map from the names of roles in the backup to
+ * the names of the roles that should replace them, for example:
+ *
+ *   prod_etl: dev_etl
+ *   prod_reader: dev_reader
+ *
+ * Role names are not quoted in the file.
+ */
+func ReadRoleMappingFile(filename string) map[string]string {
+	contents, err := ioutil.ReadFile(filename)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to read role mapping file %s", filename))
+
+	mapping := make(map[string]string)
+	err = yaml.UnmarshalStrict(contents, &mapping)
+	if err != nil {
+		gplog.Fatal(errors.Errorf("Unable to parse role mapping file %s: %v", filename, err), "")
+	}
+	for oldRole, newRole := range mapping {
+		if oldRole == "" || newRole == "" {
+			gplog.Fatal(errors.Errorf(`Invalid mapping "%s: %s" in role mapping file %s. Role names cannot be empty.`, oldRole, newRole, filename), "")
+		}
+	}
+	return mapping
+}
+
+/*
+ * The new role names are quoted once here, rather than each time a statement
+ * is rewritten, as quoting requires a query.
+ */
+func InitializeRoleMapping() {
+	mappingFile := MustGetFlagString(options.ROLE_MAPPING)
+	if mappingFile == "" {
+		return
+	}
+	mapping := ReadRoleMappingFile(mappingFile)
+	roleMapping = make(map[string]string, len(mapping))
+	for oldRole, newRole := range mapping {
+		roleMapping[oldRole] = utils.QuoteIdent(connectionPool, newRole)
+	}
+	gplog.Info("Mapping %d roles using role mapping file %s", len(roleMapping), mappingFile)
+}
+
+func mapRole(role string) (string, bool) {
+	if role == "PUBLIC" {
+		return role, true
+	}
+	newRole, ok := roleMapping[utils.UnquoteIdent(role)]
+	return newRole, ok
+}
+
+/*
+ * Owner, privilege, default privilege, and role membership statements are
+ * removed if --no-owner or --no-privileges is set, as appropriate. If a role
+ * mapping is given, the roles in those statements are replaced with the
+ * mapped roles, and any line referring to a role that is not mapped is
+ * removed, as that role may not exist in the restore cluster. A statement is
+ * removed entirely if none of its lines remain.
+ */
+func ApplyRoleOptions(statements []toc.StatementWithType) []toc.StatementWithType {
+	noOwner := MustGetFlagBool(options.NO_OWNER)
+	noPrivileges := MustGetFlagBool(options.NO_PRIVILEGES)
+	if !noOwner && !noPrivileges && roleMapping == nil {
+		return statements
+	}
+	filteredStatements := make([]toc.StatementWithType, 0, len(statements))
+	for _, statement := range statements {
+		if statement.FromDefinition {
+			filteredStatements = append(filteredStatements, statement)
+			continue
+		}
+		isPrivileges := statement.ObjectType == "DEFAULT PRIVILEGES" || toc.GetMetadataAttributeType(statement) == "PRIVILEGES"
+		isOwner := statement.ObjectType == "LANGUAGE" || ownerRegex.MatchString(strings.TrimSpace(statement.Statement))
+		if isPrivileges && noPrivileges {
+			continue
+		}
+		var rewriteLine func(line string) (string, bool)
+		switch {
+		case isPrivileges:
+			rewriteLine = rewritePrivilegesLine
+		case statement.ObjectType == "ROLE GRANT":
+			rewriteLine = rewriteRoleMembershipLine
+		case isOwner:
+			rewriteLine = func(line string) (string, bool) {
+				return rewriteOwnerLine(line, noOwner)
+			}
+		default:
+			filteredStatements = append(filteredStatements, statement)
+			continue
+		}
+		if rewritten, ok := rewriteStatement(statement.Statement, rewriteLine); ok {
+			statement.Statement = rewritten
+			filteredStatements = append(filteredStatements, statement)
+		}
+	}
+	return filteredStatements
+}
+
+func rewriteStatement(statement string, rewriteLine func(line string) (string, bool)) (string, bool) {
+	lines := strings.Split(statement, "\n")
+	rewrittenLines := make([]string, 0, len(lines))
+	hasStatement := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			rewrittenLines = append(rewrittenLines, line)
+			continue
+		}
+		if rewritten, ok := rewriteLine(line); ok {
+			rewrittenLines = append(rewrittenLines, rewritten)
+			hasStatement = true
+		}
+	}
+	return strings.Join(rewrittenLines, "\n"), hasStatement
+}
+
+func rewriteOwnerLine(line string, noOwner bool) (string, bool) {
+	match := ownerRegex.FindStringSubmatch(line)
+	if match == nil {
+		return line, true
+	}
+	if noOwner {
+		return "", false
+	}
+	if roleMapping == nil {
+		return line, true
+	}
+	newRole, ok := mapRole(match[2])
+	if !ok {
+		return "", false
+	}
+	return match[1] + newRole + ";", true
+}
+
+func rewritePrivilegesLine(line string) (string, bool) {
+	if roleMapping == nil {
+		return line, true
+	}
+	if match := forRoleRegex.FindStringSubmatch(line); match != nil {
+		newRole, ok := mapRole(match[2])
+		if !ok {
+			return "", false
+		}
+		line = match[1] + newRole + match[3]
+	}
+	match := granteeRegex.FindStringSubmatch(line)
+	if match == nil {
+		return line, true
+	}
+	newRole, ok := mapRole(match[2])
+	if !ok {
+		return "", false
+	}
+	return match[1] + newRole + match[3], true
+}
+
+func rewriteRoleMembershipLine(line string) (string, bool) {
+	if roleMapping == nil {
+		return line, true
+	}
+	match := roleMembershipRegex.FindStringSubmatch(line)
+	if match == nil {
+		return line, true
+	}
+	role, roleOK := mapRole(match[1])
+	member, memberOK := mapRole(match[2])
+	if !roleOK || !memberOK {
+		return "", false
+	}
+	rewritten := fmt.Sprintf("GRANT %s TO %s%s", role, member, match[3])
+	// A grantor that is not mapped is dropped so the current user is recorded instead
+	if grantor, ok := mapRole(match[4]); match[4] != "" && ok {
+		rewritten += " GRANTED BY " + grantor
+	}
+	return rewritten + ";", true
+}
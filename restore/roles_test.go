@@ -0,0 +1,156 @@
+package restore_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/roles tests", func() {
+	Describe("ReadRoleMappingFile", func() {
+		var mappingFile string
+		writeMappingFile := func(contents string) {
+			file, err := ioutil.TempFile("", "role_mapping")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = file.WriteString(contents)
+			Expect(err).ToNot(HaveOccurred())
+			_ = file.Close()
+			mappingFile = file.Name()
+		}
+		AfterEach(func() {
+			_ = os.Remove(mappingFile)
+		})
+		It("reads a mapping of backup roles to restore roles", func() {
+			writeMappingFile("prod_etl: dev_etl\n\"Prod Reader\": dev_reader\n")
+
+			Expect(restore.ReadRoleMappingFile(mappingFile)).To(Equal(map[string]string{"prod_etl": "dev_etl", "Prod Reader": "dev_reader"}))
+		})
+		It("panics when the file is not a map of role names", func() {
+			writeMappingFile("- prod_etl\n- dev_etl\n")
+			defer testhelper.ShouldPanicWithMessage("Unable to parse role mapping file")
+			restore.ReadRoleMappingFile(mappingFile)
+		})
+		It("panics when a role is mapped to an empty name", func() {
+			writeMappingFile("prod_etl: \"\"\n")
+			defer testhelper.ShouldPanicWithMessage(`Invalid mapping "prod_etl: " in role mapping file`)
+			restore.ReadRoleMappingFile(mappingFile)
+		})
+	})
+	Describe("ApplyRoleOptions", func() {
+		ownerStatement := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nALTER TABLE public.foo OWNER TO prod_etl;\n"}
+		privilegesStatement := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: `
+
+REVOKE ALL ON TABLE public.foo FROM PUBLIC;
+REVOKE ALL ON TABLE public.foo FROM prod_etl;
+GRANT ALL ON TABLE public.foo TO prod_etl;
+GRANT SELECT ON TABLE public.foo TO "Prod Reader" WITH GRANT OPTION;
+GRANT SELECT ON TABLE public.foo TO auditor;
+`}
+		defaultPrivilegesStatement := toc.StatementWithType{Schema: "public", Name: "", ObjectType: "DEFAULT PRIVILEGES", Statement: `
+
+ALTER DEFAULT PRIVILEGES FOR ROLE prod_etl IN SCHEMA public REVOKE ALL ON TABLES FROM PUBLIC;
+ALTER DEFAULT PRIVILEGES FOR ROLE prod_etl IN SCHEMA public GRANT SELECT ON TABLES TO auditor;
+ALTER DEFAULT PRIVILEGES FOR ROLE prod_etl IN SCHEMA public GRANT SELECT ON TABLES TO "Prod Reader";
+`}
+		roleGrantStatement := toc.StatementWithType{Name: "prod_etl", ObjectType: "ROLE GRANT", Statement: "\nGRANT prod_etl TO \"Prod Reader\" WITH ADMIN OPTION GRANTED BY gpadmin;"}
+		otherStatement := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.foo (\n\ti integer\n) DISTRIBUTED BY (i);\n"}
+		statements := []toc.StatementWithType{ownerStatement, privilegesStatement, defaultPrivilegesStatement, roleGrantStatement, otherStatement}
+
+		AfterEach(func() {
+			restore.SetRoleMapping(nil)
+		})
+		It("returns statements unchanged when no role options are set", func() {
+			Expect(restore.ApplyRoleOptions(statements)).To(Equal(statements))
+		})
+		It("removes owner statements when --no-owner is set", func() {
+			_ = cmdFlags.Set(options.NO_OWNER, "true")
+
+			Expect(restore.ApplyRoleOptions(statements)).To(Equal([]toc.StatementWithType{privilegesStatement, defaultPrivilegesStatement, roleGrantStatement, otherStatement}))
+		})
+		It("removes the owners of procedural language support functions when --no-owner is set", func() {
+			_ = cmdFlags.Set(options.NO_OWNER, "true")
+			languageStatement := toc.StatementWithType{Name: "plpythonu", ObjectType: "LANGUAGE", Statement: "\n\nCREATE PROCEDURAL LANGUAGE plpythonu;\nALTER FUNCTION pg_catalog.plpython_call_handler() OWNER TO prod_etl;\n"}
+
+			result := restore.ApplyRoleOptions([]toc.StatementWithType{languageStatement})
+
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Statement).To(Equal("\n\nCREATE PROCEDURAL LANGUAGE plpythonu;\n"))
+		})
+		It("removes privilege and default privilege statements when --no-privileges is set", func() {
+			_ = cmdFlags.Set(options.NO_PRIVILEGES, "true")
+
+			Expect(restore.ApplyRoleOptions(statements)).To(Equal([]toc.StatementWithType{ownerStatement, roleGrantStatement, otherStatement}))
+		})
+		Context("with a role mapping", func() {
+			BeforeEach(func() {
+				restore.SetRoleMapping(map[string]string{"prod_etl": "dev_etl", "Prod Reader": "dev_reader", "gpadmin": "gpadmin"})
+			})
+			It("maps the owner of an object", func() {
+				result := restore.ApplyRoleOptions([]toc.StatementWithType{ownerStatement})
+
+				Expect(result).To(HaveLen(1))
+				Expect(result[0].Statement).To(Equal("\n\nALTER TABLE public.foo OWNER TO dev_etl;\n"))
+			})
+			It("removes the owner statement of an object owned by an unmapped role", func() {
+				restore.SetRoleMapping(map[string]string{"Prod Reader": "dev_reader"})
+
+				Expect(restore.ApplyRoleOptions([]toc.StatementWithType{ownerStatement})).To(BeEmpty())
+			})
+			It("maps grantees and removes grants to unmapped roles", func() {
+				result := restore.ApplyRoleOptions([]toc.StatementWithType{privilegesStatement})
+
+				Expect(result).To(HaveLen(1))
+				Expect(result[0].Statement).To(Equal(`
+
+REVOKE ALL ON TABLE public.foo FROM PUBLIC;
+REVOKE ALL ON TABLE public.foo FROM dev_etl;
+GRANT ALL ON TABLE public.foo TO dev_etl;
+GRANT SELECT ON TABLE public.foo TO dev_reader WITH GRANT OPTION;
+`))
+			})
+			It("maps the roles of default privileges and removes those granted to unmapped roles", func() {
+				result := restore.ApplyRoleOptions([]toc.StatementWithType{defaultPrivilegesStatement})
+
+				Expect(result).To(HaveLen(1))
+				Expect(result[0].Statement).To(Equal(`
+
+ALTER DEFAULT PRIVILEGES FOR ROLE dev_etl IN SCHEMA public REVOKE ALL ON TABLES FROM PUBLIC;
+ALTER DEFAULT PRIVILEGES FOR ROLE dev_etl IN SCHEMA public GRANT SELECT ON TABLES TO dev_reader;
+`))
+			})
+			It("removes default privileges for an unmapped role", func() {
+				restore.SetRoleMapping(map[string]string{"Prod Reader": "dev_reader"})
+
+				Expect(restore.ApplyRoleOptions([]toc.StatementWithType{defaultPrivilegesStatement})).To(BeEmpty())
+			})
+			It("maps role memberships", func() {
+				result := restore.ApplyRoleOptions([]toc.StatementWithType{roleGrantStatement})
+
+				Expect(result).To(HaveLen(1))
+				Expect(result[0].Statement).To(Equal("\nGRANT dev_etl TO dev_reader WITH ADMIN OPTION GRANTED BY gpadmin;"))
+			})
+			It("removes the grantor of a role membership when the grantor is not mapped", func() {
+				restore.SetRoleMapping(map[string]string{"prod_etl": "dev_etl", "Prod Reader": "dev_reader"})
+				result := restore.ApplyRoleOptions([]toc.StatementWithType{roleGrantStatement})
+
+				Expect(result).To(HaveLen(1))
+				Expect(result[0].Statement).To(Equal("\nGRANT dev_etl TO dev_reader WITH ADMIN OPTION;"))
+			})
+			It("removes role memberships of unmapped roles", func() {
+				restore.SetRoleMapping(map[string]string{"prod_etl": "dev_etl"})
+
+				Expect(restore.ApplyRoleOptions([]toc.StatementWithType{roleGrantStatement})).To(BeEmpty())
+			})
+			It("does not change other statements", func() {
+				Expect(restore.ApplyRoleOptions([]toc.StatementWithType{otherStatement})).To(Equal([]toc.StatementWithType{otherStatement}))
+			})
+		})
+	})
+})
@@ -0,0 +1,481 @@
+package restore
+
+/*
+ * This file contains structs and functions related to changing the storage
+ * options and distribution policies of tables as they are restored, according
+ * to the rules in a table rules file.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+ * A table rules file is a YAML list of rules, for example:
+ *
+ *   - table: "sales.fact_*"
+ *     storage: "appendoptimized=true, orientation=column, compresstype=zstd"
+ *     distributed-by: [customer_id]
+ *   - table: "dim_*"
+ *     distribution: replicated
+ *
+ * Tables are matched with the same patterns as --include-table-pattern, and
+ * each table is changed by the first rule that matches it. The partitions of
+ * a partition table use the partition storage options if they are given, and
+ * the storage options otherwise.
+ */
+type TableRule struct {
+	Table            string   `yaml:"table"`
+	Storage          string   `yaml:"storage"`
+	PartitionStorage string   `yaml:"partition-storage"`
+	DistributedBy    []string `yaml:"distributed-by"`
+	Distribution     string   `yaml:"distribution"`
+	isMatch          func(schema string, table string) bool
+}
+
+func (rule TableRule) DistPolicy() string {
+	switch {
+	case len(rule.DistributedBy) > 0:
+		return fmt.Sprintf("DISTRIBUTED BY (%s)", strings.Join(rule.DistributedBy, ", "))
+	case rule.Distribution != "":
+		return fmt.Sprintf("DISTRIBUTED %s", strings.ToUpper(rule.Distribution))
+	}
+	return ""
+}
+
+func ReadTableRulesFile(filename string) []TableRule {
+	contents, err := ioutil.ReadFile(filename)
+	gplog.FatalOnError(err, fmt.Sprintf("Unable to read table rules file %s", filename))
+
+	rules := make([]TableRule, 0)
+	err = yaml.UnmarshalStrict(contents, &rules)
+	if err != nil {
+		gplog.Fatal(errors.Errorf("Unable to parse table rules file %s: %v", filename, err), "")
+	}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Table == "" {
+			gplog.Fatal(errors.Errorf("Rule %d in table rules file %s does not specify a table pattern.", i+1, filename), "")
+		}
+		rule.isMatch, err = options.CompileRelationPattern(rule.Table)
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Invalid table pattern in rule %d in table rules file %s: %v", i+1, filename, err), "")
+		}
+		if rule.Storage == "" && rule.PartitionStorage == "" && rule.DistPolicy() == "" {
+			gplog.Fatal(errors.Errorf("Rule %d in table rules file %s does not change storage options or distribution.", i+1, filename), "")
+		}
+		if len(rule.DistributedBy) > 0 && rule.Distribution != "" {
+			gplog.Fatal(errors.Errorf("Rule %d in table rules file %s cannot specify both distributed-by and distribution.", i+1, filename), "")
+		}
+		if rule.Distribution != "" && !utils.Exists([]string{"randomly", "replicated"}, strings.ToLower(rule.Distribution)) {
+			gplog.Fatal(errors.Errorf(`Invalid distribution "%s" in rule %d in table rules file %s. Distribution must be "randomly" or "replicated".`, rule.Distribution, i+1, filename), "")
+		}
+	}
+	return rules
+}
+
+/*
+ * Changing the distribution policy of a table means that its data must be
+ * redistributed as it is restored, which is done in the same way as when
+ * restoring to a cluster with a different number of segments.
+ */
+func InitializeTableRules() {
+	filename := MustGetFlagString(options.TABLE_RULES_FILE)
+	if filename == "" {
+		return
+	}
+	tableRules = ReadTableRulesFile(filename)
+	redistributedTables = make(map[string]bool)
+	changesDistribution := false
+	for _, rule := range tableRules {
+		changesDistribution = changesDistribution || rule.DistPolicy() != ""
+	}
+	if changesDistribution && ShouldRestoreSection("data") {
+		if backupConfig.SingleDataFile {
+			gplog.Fatal(errors.Errorf("Cannot change the distribution of tables when restoring backups with a single data file per segment."), "")
+		}
+		if backupConfig.Plugin != "" {
+			gplog.Fatal(errors.Errorf("Cannot change the distribution of tables when restoring backups taken with a plugin."), "")
+		}
+		if MustGetFlagString(options.OUTPUT_SQL) != "" {
+			gplog.Fatal(errors.Errorf("Cannot write data restore statements when changing the distribution of tables. Use the --metadata-only flag with --output-sql."), "")
+		}
+		if backupConfig.DataOnly {
+			gplog.Fatal(errors.Errorf("Cannot change the distribution of tables when restoring a data-only backup, as it does not record the distribution of its tables."), "")
+		}
+		// The statements are read as they were backed up, before any rules are applied to them
+		metadataFile := iohelper.MustOpenFileForReading(decryptedFiles.MustGetPath(globalFPInfo.GetMetadataFilePath()))
+		statements := globalTOC.GetSQLStatementForObjectTypes("predata", metadataFile, []string{"TABLE"}, []string{}, []string{}, []string{}, []string{}, []string{})
+		redistributedTables = GetRedistributedTables(statements)
+	}
+	gplog.Info("Applying %d rules from table rules file %s", len(tableRules), filename)
+}
+
+/*
+ * The tables whose data must be redistributed are found from the CREATE TABLE
+ * statements in the backup and the rules alone, rather than as the statements
+ * are restored, so that they are also known when restoring only the data of a
+ * backup. The returned map records whether each table was replicated in the
+ * backup.
+ */
+func GetRedistributedTables(statements []toc.StatementWithType) map[string]bool {
+	tables := make(map[string]bool)
+	for _, statement := range statements {
+		if !isCreateTableStatement(statement) {
+			continue
+		}
+		rule := findTableRule(utils.UnquoteIdent(statement.Schema), utils.UnquoteIdent(statement.Name))
+		if rule == nil || rule.DistPolicy() == "" {
+			continue
+		}
+		fqn := utils.MakeFQN(statement.Schema, statement.Name)
+		createTable := mustParseCreateTableStatement(statement.Statement, fqn)
+		if rule.DistPolicy() != createTable.DistPolicy {
+			tables[fqn] = createTable.DistPolicy == "DISTRIBUTED REPLICATED"
+		}
+	}
+	return tables
+}
+
+func isCreateTableStatement(statement toc.StatementWithType) bool {
+	trimmed := strings.TrimSpace(statement.Statement)
+	return statement.ObjectType == "TABLE" && (strings.HasPrefix(trimmed, "CREATE TABLE ") || strings.HasPrefix(trimmed, "CREATE UNLOGGED TABLE "))
+}
+
+// A table rule the user asked for is never silently skipped
+func mustParseCreateTableStatement(statement string, fqn string) CreateTableStatement {
+	createTable, err := ParseCreateTableStatement(statement)
+	if err != nil {
+		gplog.Fatal(errors.Errorf("Unable to apply table rules to table %s: %v", fqn, err), "")
+	}
+	return createTable
+}
+
+func findTableRule(schema string, table string) *TableRule {
+	for i := range tableRules {
+		if tableRules[i].isMatch(schema, table) {
+			return &tableRules[i]
+		}
+	}
+	return nil
+}
+
+/*
+ * Rules are applied to the parsed CREATE TABLE statement, rather than by
+ * searching its text, so that options in column defaults or partition bounds
+ * are never changed by accident. External and foreign tables are not changed.
+ */
+func ApplyTableRules(statements []toc.StatementWithType) []toc.StatementWithType {
+	if len(tableRules) == 0 {
+		return statements
+	}
+	for i, statement := range statements {
+		if statement.FromDefinition || !isCreateTableStatement(statement) {
+			continue
+		}
+		rule := findTableRule(utils.UnquoteIdent(statement.Schema), utils.UnquoteIdent(statement.Name))
+		if rule == nil {
+			continue
+		}
+		fqn := utils.MakeFQN(statement.Schema, statement.Name)
+		createTable := mustParseCreateTableStatement(statement.Statement, fqn)
+		applyTableRule(rule, fqn, &createTable)
+		statements[i].Statement = createTable.String()
+	}
+	return statements
+}
+
+func applyTableRule(rule *TableRule, fqn string, createTable *CreateTableStatement) {
+	if rule.Storage != "" {
+		createTable.StorageOpts = rule.Storage
+	}
+	partitionStorage := rule.PartitionStorage
+	if partitionStorage == "" {
+		partitionStorage = rule.Storage
+	}
+	if partitionStorage != "" {
+		createTable.PartDef = ReplacePartitionStorage(createTable.PartDef, partitionStorage)
+		createTable.PartTemplateDef = ReplacePartitionStorage(createTable.PartTemplateDef, partitionStorage)
+	}
+	if distPolicy := rule.DistPolicy(); distPolicy != "" {
+		createTable.DistPolicy = distPolicy
+	}
+	gplog.Verbose("Applying table rule for pattern %s to table %s", rule.Table, fqn)
+}
+
+/*
+ * The data of a leaf partition that was backed up separately is redistributed
+ * if the distribution policy of its partition root was changed. The returned
+ * wasReplicated value reports whether the table was replicated in the backup.
+ */
+func getRedistributedTable(entry toc.MasterDataEntry) (wasReplicated bool, isRedistributed bool) {
+	tableName := entry.Name
+	if entry.PartitionRoot != "" {
+		tableName = entry.PartitionRoot
+	}
+	wasReplicated, isRedistributed = redistributedTables[utils.MakeFQN(entry.Schema, tableName)]
+	return wasReplicated, isRedistributed
+}
+
+/*
+ * CreateTableStatement holds the clauses of a CREATE TABLE statement printed
+ * by PrintRegularTableCreateStatement, so that they can be changed and the
+ * statement printed again in the same format.
+ */
+type CreateTableStatement struct {
+	Definition      string // Everything through the closing parenthesis of the column list
+	Inherits        string
+	StorageOpts     string
+	Tablespace      string
+	DistPolicy      string
+	PartDef         string
+	PartTemplateDef string
+	Remainder       string // Any statements following the CREATE TABLE and SET SUBPARTITION TEMPLATE statements
+}
+
+func ParseCreateTableStatement(statement string) (CreateTableStatement, error) {
+	createTable := CreateTableStatement{}
+	columnsStart := indexUnquoted(statement, 0, '(')
+	if columnsStart == -1 {
+		return createTable, errors.New("CREATE TABLE statement has no column list")
+	}
+	columnsEnd := skipParenthesized(statement, columnsStart)
+	if columnsEnd == -1 {
+		return createTable, errors.New("CREATE TABLE statement has an unterminated column list")
+	}
+	createTable.Definition = statement[:columnsEnd]
+	statementEnd := indexUnquoted(statement, columnsEnd, ';')
+	if statementEnd == -1 {
+		return createTable, errors.New("CREATE TABLE statement is not terminated")
+	}
+
+	pos := columnsEnd
+	for {
+		start, end := nextToken(statement, pos, statementEnd)
+		if start == end {
+			break
+		}
+		keyword := strings.ToUpper(statement[start:end])
+		argStart, argEnd := nextToken(statement, end, statementEnd)
+		argument := statement[argStart:argEnd]
+		switch keyword {
+		case "INHERITS", "WITH":
+			if !strings.HasPrefix(argument, "(") {
+				return createTable, errors.Errorf("expected a parenthesized list after %s", keyword)
+			}
+			if keyword == "INHERITS" {
+				createTable.Inherits = argument[1 : len(argument)-1]
+			} else {
+				createTable.StorageOpts = argument[1 : len(argument)-1]
+			}
+		case "TABLESPACE":
+			createTable.Tablespace = argument
+		case "DISTRIBUTED":
+			if strings.ToUpper(argument) == "BY" {
+				_, argEnd = nextToken(statement, argEnd, statementEnd)
+			}
+			createTable.DistPolicy = statement[start:argEnd]
+		case "PARTITION":
+			createTable.PartDef = strings.TrimSpace(statement[start:statementEnd])
+			argEnd = statementEnd
+		default:
+			return createTable, errors.Errorf("unexpected %s in CREATE TABLE statement", statement[start:end])
+		}
+		pos = argEnd
+	}
+
+	remainder := statement[statementEnd+1:]
+	if strings.HasPrefix(remainder, "\nALTER TABLE ") {
+		templateEnd := indexUnquoted(remainder, 1, ';')
+		if templateEnd != -1 && strings.Contains(remainder[:templateEnd], "SET SUBPARTITION TEMPLATE") {
+			createTable.PartTemplateDef = remainder[1:templateEnd]
+			remainder = remainder[templateEnd+1:]
+		}
+	}
+	createTable.Remainder = remainder
+	return createTable, nil
+}
+
+func (createTable CreateTableStatement) String() string {
+	var statement strings.Builder
+	statement.WriteString(createTable.Definition + " ")
+	if createTable.Inherits != "" {
+		statement.WriteString(fmt.Sprintf("INHERITS (%s) ", createTable.Inherits))
+	}
+	if createTable.StorageOpts != "" {
+		statement.WriteString(fmt.Sprintf("WITH (%s) ", createTable.StorageOpts))
+	}
+	if createTable.Tablespace != "" {
+		statement.WriteString(fmt.Sprintf("TABLESPACE %s ", createTable.Tablespace))
+	}
+	statement.WriteString(createTable.DistPolicy)
+	if createTable.PartDef != "" {
+		statement.WriteString(" " + createTable.PartDef)
+	}
+	statement.WriteString(";")
+	if createTable.PartTemplateDef != "" {
+		statement.WriteString("\n" + createTable.PartTemplateDef + ";")
+	}
+	statement.WriteString(createTable.Remainder)
+	return statement.String()
+}
+
+/*
+ * Each partition in a partition definition or subpartition template has its
+ * own storage options, which are replaced while keeping the name of the
+ * partition's table.
+ */
+func ReplacePartitionStorage(partitionDef string, storage string) string {
+	var replaced strings.Builder
+	pos := 0
+	for {
+		start, end := nextToken(partitionDef, pos, len(partitionDef))
+		if start == end {
+			break
+		}
+		token := partitionDef[start:end]
+		replaced.WriteString(partitionDef[pos:start])
+		pos = end
+		if strings.HasPrefix(token, "(") && strings.HasSuffix(token, ")") {
+			replaced.WriteString("(" + ReplacePartitionStorage(token[1:len(token)-1], storage) + ")")
+			continue
+		}
+		replaced.WriteString(token)
+		if strings.ToUpper(token) != "WITH" {
+			continue
+		}
+		optionsStart, optionsEnd := nextToken(partitionDef, end, len(partitionDef))
+		if !strings.HasPrefix(partitionDef[optionsStart:optionsEnd], "(") {
+			continue
+		}
+		partitionOptions := make([]string, 0)
+		for _, option := range splitUnquoted(partitionDef[optionsStart+1:optionsEnd-1], ',') {
+			option = strings.TrimSpace(option)
+			if strings.HasPrefix(strings.ToLower(option), "tablename") {
+				partitionOptions = append(partitionOptions, option)
+			}
+		}
+		partitionOptions = append(partitionOptions, storage)
+		replaced.WriteString(partitionDef[end:optionsStart])
+		replaced.WriteString(fmt.Sprintf("(%s)", strings.Join(partitionOptions, ", ")))
+		pos = optionsEnd
+	}
+	replaced.WriteString(partitionDef[pos:])
+	return replaced.String()
+}
+
+// Returns the index just past the quoted identifier or string literal starting at pos
+func skipQuoted(str string, pos int) int {
+	quote := str[pos]
+	for i := pos + 1; i < len(str); i++ {
+		if str[i] == quote {
+			if i+1 < len(str) && str[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(str)
+}
+
+// Returns the index just past the parenthesized list starting at pos, or -1 if it is not closed
+func skipParenthesized(str string, pos int) int {
+	depth := 0
+	for i := pos; i < len(str); {
+		switch str[i] {
+		case '\'', '"':
+			i = skipQuoted(str, i)
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return -1
+}
+
+// Returns the index of the first char at or after pos that is not quoted or parenthesized, or -1
+func indexUnquoted(str string, pos int, char byte) int {
+	for i := pos; i < len(str); {
+		switch {
+		case str[i] == char:
+			return i
+		case str[i] == '\'' || str[i] == '"':
+			i = skipQuoted(str, i)
+		case str[i] == '(':
+			end := skipParenthesized(str, i)
+			if end == -1 {
+				return -1
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+func splitUnquoted(str string, separator byte) []string {
+	parts := make([]string, 0)
+	for {
+		end := indexUnquoted(str, 0, separator)
+		if end == -1 {
+			return append(parts, str)
+		}
+		parts = append(parts, str[:end])
+		str = str[end+1:]
+	}
+}
+
+/*
+ * A token is a parenthesized list, or a word that may contain quoted parts,
+ * such as a qualified name. The returned start and end are equal if there are
+ * no more tokens before limit.
+ */
+func nextToken(str string, pos int, limit int) (int, int) {
+	for pos < limit && strings.ContainsRune(" \t\n", rune(str[pos])) {
+		pos++
+	}
+	if pos >= limit {
+		return limit, limit
+	}
+	if str[pos] == '(' {
+		end := skipParenthesized(str, pos)
+		if end == -1 || end > limit {
+			end = limit
+		}
+		return pos, end
+	}
+	end := pos
+	for end < limit && !strings.ContainsRune(" \t\n(),;", rune(str[end])) {
+		if str[end] == '\'' || str[end] == '"' {
+			end = skipQuoted(str, end)
+		} else {
+			end++
+		}
+	}
+	if end == pos {
+		// A lone separator is a token of its own
+		end++
+	}
+	if end > limit {
+		end = limit
+	}
+	return pos, end
+}
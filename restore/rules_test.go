@@ -0,0 +1,216 @@
+package restore_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/rules tests", func() {
+	partitionTable := `
+
+CREATE TABLE public.rank (
+	id integer,
+	gender character(1) DEFAULT 'M'::bpchar
+) WITH (appendonly=false) TABLESPACE fast_ssd DISTRIBUTED BY (id) PARTITION BY LIST(gender)
+	(
+	PARTITION girls VALUES('F') WITH (tablename='rank_1_prt_girls', appendonly=false ),
+	PARTITION boys VALUES('M') WITH (tablename='rank_1_prt_boys', appendonly=false ),
+	DEFAULT PARTITION other  WITH (tablename='rank_1_prt_other', appendonly=false )
+	);
+ALTER TABLE public.rank
+SET SUBPARTITION TEMPLATE
+          (
+          SUBPARTITION usa VALUES('usa') WITH (tablename='rank'),
+          DEFAULT SUBPARTITION other_regions  WITH (tablename='rank')
+          );
+ALTER TABLE ONLY public.rank ALTER COLUMN gender SET STATISTICS 50;
+`
+	Describe("ReadTableRulesFile", func() {
+		var rulesFile string
+		writeRulesFile := func(contents string) {
+			file, err := ioutil.TempFile("", "table_rules")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = file.WriteString(contents)
+			Expect(err).ToNot(HaveOccurred())
+			_ = file.Close()
+			rulesFile = file.Name()
+		}
+		AfterEach(func() {
+			_ = os.Remove(rulesFile)
+		})
+		It("reads a list of table rules", func() {
+			writeRulesFile(`
+- table: "sales.fact_*"
+  storage: "appendoptimized=true, orientation=column"
+  distributed-by: [customer_id, order_id]
+- table: dim_*
+  distribution: replicated
+`)
+
+			rules := restore.ReadTableRulesFile(rulesFile)
+
+			Expect(rules).To(HaveLen(2))
+			Expect(rules[0].Table).To(Equal("sales.fact_*"))
+			Expect(rules[0].Storage).To(Equal("appendoptimized=true, orientation=column"))
+			Expect(rules[0].DistPolicy()).To(Equal("DISTRIBUTED BY (customer_id, order_id)"))
+			Expect(rules[1].DistPolicy()).To(Equal("DISTRIBUTED REPLICATED"))
+		})
+		It("panics when a rule has no table pattern", func() {
+			writeRulesFile("- storage: \"appendoptimized=true\"\n")
+			defer testhelper.ShouldPanicWithMessage("Rule 1 in table rules file")
+			restore.ReadTableRulesFile(rulesFile)
+		})
+		It("panics when a rule does not change anything", func() {
+			writeRulesFile("- table: foo\n")
+			defer testhelper.ShouldPanicWithMessage("does not change storage options or distribution")
+			restore.ReadTableRulesFile(rulesFile)
+		})
+		It("panics when a rule specifies both a distribution key and a distribution", func() {
+			writeRulesFile("- table: foo\n  distributed-by: [i]\n  distribution: randomly\n")
+			defer testhelper.ShouldPanicWithMessage("cannot specify both distributed-by and distribution")
+			restore.ReadTableRulesFile(rulesFile)
+		})
+		It("panics when a rule has an invalid distribution", func() {
+			writeRulesFile("- table: foo\n  distribution: everywhere\n")
+			defer testhelper.ShouldPanicWithMessage(`Invalid distribution "everywhere"`)
+			restore.ReadTableRulesFile(rulesFile)
+		})
+		It("panics when a rule has an unknown field", func() {
+			writeRulesFile("- table: foo\n  compression: zstd\n")
+			defer testhelper.ShouldPanicWithMessage("Unable to parse table rules file")
+			restore.ReadTableRulesFile(rulesFile)
+		})
+	})
+	Describe("ParseCreateTableStatement", func() {
+		It("parses the clauses of a partition table", func() {
+			createTable, err := restore.ParseCreateTableStatement(partitionTable)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(createTable.Definition).To(Equal("\n\nCREATE TABLE public.rank (\n\tid integer,\n\tgender character(1) DEFAULT 'M'::bpchar\n)"))
+			Expect(createTable.StorageOpts).To(Equal("appendonly=false"))
+			Expect(createTable.Tablespace).To(Equal("fast_ssd"))
+			Expect(createTable.DistPolicy).To(Equal("DISTRIBUTED BY (id)"))
+			Expect(createTable.PartDef).To(HavePrefix("PARTITION BY LIST(gender)"))
+			Expect(createTable.PartTemplateDef).To(HavePrefix("ALTER TABLE public.rank\nSET SUBPARTITION TEMPLATE"))
+			Expect(createTable.Remainder).To(Equal("\nALTER TABLE ONLY public.rank ALTER COLUMN gender SET STATISTICS 50;\n"))
+		})
+		It("prints a parsed statement unchanged", func() {
+			statements := []string{
+				partitionTable,
+				"\n\nCREATE TABLE public.foo (\n) DISTRIBUTED RANDOMLY;\n",
+				"\n\nCREATE UNLOGGED TABLE public.\"Foo (Bar)\" (\n\t\"i;j\" integer DEFAULT 1\n) INHERITS (public.parent) WITH (fillfactor=70) DISTRIBUTED REPLICATED;\n",
+			}
+			for _, statement := range statements {
+				createTable, err := restore.ParseCreateTableStatement(statement)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createTable.String()).To(Equal(statement))
+			}
+		})
+		It("returns an error for an unexpected clause", func() {
+			_, err := restore.ParseCreateTableStatement("\n\nCREATE TABLE public.foo (\n) USING heap DISTRIBUTED RANDOMLY;\n")
+
+			Expect(err).To(MatchError("unexpected USING in CREATE TABLE statement"))
+		})
+	})
+	Describe("ReplacePartitionStorage", func() {
+		It("replaces the storage options of each partition and keeps its table name", func() {
+			partDef := "PARTITION BY RANGE(i) (START (1) END (3) EVERY (1) WITH (tablename='foo_1_prt_1', appendonly=false ), PARTITION p2 START (3) END (4))"
+
+			Expect(restore.ReplacePartitionStorage(partDef, "appendoptimized=true, compresstype=zstd")).To(Equal("PARTITION BY RANGE(i) (START (1) END (3) EVERY (1) WITH (tablename='foo_1_prt_1', appendoptimized=true, compresstype=zstd), PARTITION p2 START (3) END (4))"))
+		})
+	})
+	writeAndReadRules := func(contents string) []restore.TableRule {
+		file, err := ioutil.TempFile("", "table_rules")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.Remove(file.Name())
+		_, _ = file.WriteString(contents)
+		_ = file.Close()
+		return restore.ReadTableRulesFile(file.Name())
+	}
+	Describe("ApplyTableRules", func() {
+		otherTable := toc.StatementWithType{Schema: "public", Name: "other", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.other (\n\ti integer\n) DISTRIBUTED BY (i);\n"}
+		tableComment := toc.StatementWithType{Schema: "public", Name: "rank", ObjectType: "TABLE", Statement: "\n\nCOMMENT ON TABLE public.rank IS 'WITH (appendonly=false)';\n"}
+
+		AfterEach(func() {
+			restore.SetTableRules(nil)
+		})
+		It("returns statements unchanged when there are no rules", func() {
+			statements := []toc.StatementWithType{otherTable}
+
+			Expect(restore.ApplyTableRules(statements)).To(Equal([]toc.StatementWithType{otherTable}))
+		})
+		It("changes the storage options and distribution of matching tables", func() {
+			restore.SetTableRules(writeAndReadRules(`
+- table: public.r*
+  storage: "appendoptimized=true, orientation=column, compresstype=zstd"
+  partition-storage: "appendoptimized=true, compresstype=zstd"
+  distribution: randomly
+`))
+			rankTable := toc.StatementWithType{Schema: "public", Name: "rank", ObjectType: "TABLE", Statement: partitionTable}
+
+			result := restore.ApplyTableRules([]toc.StatementWithType{rankTable, tableComment, otherTable})
+
+			Expect(result[0].Statement).To(Equal(`
+
+CREATE TABLE public.rank (
+	id integer,
+	gender character(1) DEFAULT 'M'::bpchar
+) WITH (appendoptimized=true, orientation=column, compresstype=zstd) TABLESPACE fast_ssd DISTRIBUTED RANDOMLY PARTITION BY LIST(gender)
+	(
+	PARTITION girls VALUES('F') WITH (tablename='rank_1_prt_girls', appendoptimized=true, compresstype=zstd),
+	PARTITION boys VALUES('M') WITH (tablename='rank_1_prt_boys', appendoptimized=true, compresstype=zstd),
+	DEFAULT PARTITION other  WITH (tablename='rank_1_prt_other', appendoptimized=true, compresstype=zstd)
+	);
+ALTER TABLE public.rank
+SET SUBPARTITION TEMPLATE
+          (
+          SUBPARTITION usa VALUES('usa') WITH (tablename='rank', appendoptimized=true, compresstype=zstd),
+          DEFAULT SUBPARTITION other_regions  WITH (tablename='rank', appendoptimized=true, compresstype=zstd)
+          );
+ALTER TABLE ONLY public.rank ALTER COLUMN gender SET STATISTICS 50;
+`))
+			Expect(result[1]).To(Equal(tableComment))
+			Expect(result[2]).To(Equal(otherTable))
+		})
+	})
+	Describe("GetRedistributedTables", func() {
+		rankTable := toc.StatementWithType{Schema: "public", Name: "rank", ObjectType: "TABLE", Statement: partitionTable}
+		replicatedTable := toc.StatementWithType{Schema: "public", Name: "replicated", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.replicated (\n\ti integer\n) DISTRIBUTED REPLICATED;\n"}
+		randomTable := toc.StatementWithType{Schema: "public", Name: "random", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.random (\n\ti integer\n) DISTRIBUTED RANDOMLY;\n"}
+
+		AfterEach(func() {
+			restore.SetTableRules(nil)
+		})
+		It("returns the tables whose distribution is changed by a rule and whether they were replicated", func() {
+			restore.SetTableRules(writeAndReadRules(`
+- table: public.r*
+  distribution: randomly
+- table: public.other
+  storage: "appendoptimized=true"
+`))
+			otherTable := toc.StatementWithType{Schema: "public", Name: "other", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.other (\n\ti integer\n) DISTRIBUTED BY (i);\n"}
+
+			tables := restore.GetRedistributedTables([]toc.StatementWithType{rankTable, replicatedTable, randomTable, otherTable})
+
+			Expect(tables).To(Equal(map[string]bool{"public.rank": false, "public.replicated": true}))
+		})
+		It("panics when a table that a rule matches cannot be parsed", func() {
+			restore.SetTableRules(writeAndReadRules(`
+- table: public.bad
+  distribution: randomly
+`))
+			badTable := toc.StatementWithType{Schema: "public", Name: "bad", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.bad (\n\ti integer\n) USING heap DISTRIBUTED BY (i);\n"}
+
+			defer testhelper.ShouldPanicWithMessage("Unable to apply table rules to table public.bad: unexpected USING in CREATE TABLE statement")
+			restore.GetRedistributedTables([]toc.StatementWithType{badTable})
+		})
+	})
+})
@@ -0,0 +1,94 @@
+package restore
+
+/*
+ * This file contains functions related to choosing which sections of a backup
+ * are restored.
+ */
+
+import (
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+var validSections = []string{"global", "predata", "data", "postdata", "statistics"}
+
+func isSectionRestore() bool {
+	return len(MustGetFlagStringArray(options.SECTION)) > 0
+}
+
+/*
+ * When --section is not used, the sections to restore are determined by the
+ * --data-only, --metadata-only, --with-globals, and --with-stats flags and by
+ * the contents of the backup, as they were before --section existed.
+ */
+func ShouldRestoreSection(section string) bool {
+	if isSectionRestore() {
+		for _, requested := range MustGetFlagStringArray(options.SECTION) {
+			if requested == section {
+				return true
+			}
+		}
+		return false
+	}
+	switch section {
+	case "global":
+		return MustGetFlagBool(options.WITH_GLOBALS)
+	case "predata", "postdata":
+		return !backupConfig.DataOnly && !MustGetFlagBool(options.DATA_ONLY)
+	case "data":
+		return !backupConfig.MetadataOnly && !MustGetFlagBool(options.METADATA_ONLY)
+	case "statistics":
+		return MustGetFlagBool(options.WITH_STATS)
+	}
+	return false
+}
+
+func ValidateSectionFlags(flags *pflag.FlagSet) {
+	options.CheckExclusiveFlags(flags, options.SECTION, options.DATA_ONLY)
+	options.CheckExclusiveFlags(flags, options.SECTION, options.METADATA_ONLY)
+	options.CheckExclusiveFlags(flags, options.SECTION, options.WITH_GLOBALS)
+	options.CheckExclusiveFlags(flags, options.SECTION, options.WITH_STATS)
+
+	sections, _ := flags.GetStringArray(options.SECTION)
+	hasPredata := false
+	for _, section := range sections {
+		isValid := false
+		for _, validSection := range validSections {
+			if section == validSection {
+				isValid = true
+				break
+			}
+		}
+		if !isValid {
+			gplog.Fatal(errors.Errorf(`Invalid section "%s". Valid sections are: %s.`, section, strings.Join(validSections, ", ")), "")
+		}
+		hasPredata = hasPredata || section == "predata"
+	}
+	createDB, _ := flags.GetBool(options.CREATE_DB)
+	if len(sections) > 0 && createDB && !hasPredata {
+		gplog.Fatal(errors.Errorf("The --create-db flag can only be used with --section if the predata section is restored."), "")
+	}
+}
+
+/*
+ * Sections that were requested explicitly must be present in the backup;
+ * otherwise the restore would silently do nothing for them.
+ */
+func ValidateSectionsInBackup() {
+	if !isSectionRestore() {
+		return
+	}
+	if backupConfig.MetadataOnly && ShouldRestoreSection("data") {
+		gplog.Fatal(errors.Errorf("Cannot restore the data section of a metadata-only backup."), "")
+	}
+	if backupConfig.DataOnly && (ShouldRestoreSection("predata") || ShouldRestoreSection("postdata")) {
+		gplog.Fatal(errors.Errorf("Cannot restore the predata or postdata sections of a data-only backup."), "")
+	}
+	if !backupConfig.WithStatistics && ShouldRestoreSection("statistics") {
+		gplog.Fatal(errors.Errorf("Cannot restore the statistics section of a backup taken without the --with-stats flag."), "")
+	}
+}
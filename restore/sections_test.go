@@ -0,0 +1,110 @@
+package restore_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/sections tests", func() {
+	Describe("ShouldRestoreSection", func() {
+		BeforeEach(func() {
+			restore.SetBackupConfig(&history.BackupConfig{WithStatistics: true})
+		})
+		It("restores pre-data, data, and post-data by default", func() {
+			Expect(restore.ShouldRestoreSection("global")).To(BeFalse())
+			Expect(restore.ShouldRestoreSection("predata")).To(BeTrue())
+			Expect(restore.ShouldRestoreSection("data")).To(BeTrue())
+			Expect(restore.ShouldRestoreSection("postdata")).To(BeTrue())
+			Expect(restore.ShouldRestoreSection("statistics")).To(BeFalse())
+		})
+		It("restores only data for a data-only restore", func() {
+			_ = cmdFlags.Set(options.DATA_ONLY, "true")
+
+			Expect(restore.ShouldRestoreSection("predata")).To(BeFalse())
+			Expect(restore.ShouldRestoreSection("data")).To(BeTrue())
+			Expect(restore.ShouldRestoreSection("postdata")).To(BeFalse())
+		})
+		It("restores globals and statistics when requested with the legacy flags", func() {
+			_ = cmdFlags.Set(options.WITH_GLOBALS, "true")
+			_ = cmdFlags.Set(options.WITH_STATS, "true")
+
+			Expect(restore.ShouldRestoreSection("global")).To(BeTrue())
+			Expect(restore.ShouldRestoreSection("statistics")).To(BeTrue())
+		})
+		It("restores only the sections passed to --section", func() {
+			_ = cmdFlags.Set(options.SECTION, "postdata")
+			_ = cmdFlags.Set(options.SECTION, "statistics")
+
+			Expect(restore.ShouldRestoreSection("global")).To(BeFalse())
+			Expect(restore.ShouldRestoreSection("predata")).To(BeFalse())
+			Expect(restore.ShouldRestoreSection("data")).To(BeFalse())
+			Expect(restore.ShouldRestoreSection("postdata")).To(BeTrue())
+			Expect(restore.ShouldRestoreSection("statistics")).To(BeTrue())
+		})
+	})
+	Describe("ValidateSectionFlags", func() {
+		It("passes for valid sections", func() {
+			_ = cmdFlags.Set(options.SECTION, "global")
+			_ = cmdFlags.Set(options.SECTION, "predata")
+			restore.ValidateSectionFlags(cmdFlags)
+		})
+		It("panics for an invalid section", func() {
+			_ = cmdFlags.Set(options.SECTION, "indexes")
+			defer testhelper.ShouldPanicWithMessage(`Invalid section "indexes". Valid sections are: global, predata, data, postdata, statistics.`)
+			restore.ValidateSectionFlags(cmdFlags)
+		})
+		It("panics when used with --data-only", func() {
+			_ = cmdFlags.Set(options.SECTION, "data")
+			_ = cmdFlags.Set(options.DATA_ONLY, "true")
+			defer testhelper.ShouldPanicWithMessage("The following flags may not be specified together: section, data-only")
+			restore.ValidateSectionFlags(cmdFlags)
+		})
+		It("panics when used with --with-stats", func() {
+			_ = cmdFlags.Set(options.SECTION, "statistics")
+			_ = cmdFlags.Set(options.WITH_STATS, "true")
+			defer testhelper.ShouldPanicWithMessage("The following flags may not be specified together: section, with-stats")
+			restore.ValidateSectionFlags(cmdFlags)
+		})
+		It("panics when --create-db is used without the predata section", func() {
+			_ = cmdFlags.Set(options.SECTION, "data")
+			_ = cmdFlags.Set(options.CREATE_DB, "true")
+			defer testhelper.ShouldPanicWithMessage("The --create-db flag can only be used with --section if the predata section is restored.")
+			restore.ValidateSectionFlags(cmdFlags)
+		})
+		It("passes when --create-db is used with the predata section", func() {
+			_ = cmdFlags.Set(options.SECTION, "predata")
+			_ = cmdFlags.Set(options.CREATE_DB, "true")
+			restore.ValidateSectionFlags(cmdFlags)
+		})
+	})
+	Describe("ValidateSectionsInBackup", func() {
+		It("panics when restoring the data section of a metadata-only backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{MetadataOnly: true})
+			_ = cmdFlags.Set(options.SECTION, "data")
+			defer testhelper.ShouldPanicWithMessage("Cannot restore the data section of a metadata-only backup.")
+			restore.ValidateSectionsInBackup()
+		})
+		It("panics when restoring the postdata section of a data-only backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{DataOnly: true})
+			_ = cmdFlags.Set(options.SECTION, "postdata")
+			defer testhelper.ShouldPanicWithMessage("Cannot restore the predata or postdata sections of a data-only backup.")
+			restore.ValidateSectionsInBackup()
+		})
+		It("panics when restoring statistics from a backup without statistics", func() {
+			restore.SetBackupConfig(&history.BackupConfig{})
+			_ = cmdFlags.Set(options.SECTION, "statistics")
+			defer testhelper.ShouldPanicWithMessage("Cannot restore the statistics section of a backup taken without the --with-stats flag.")
+			restore.ValidateSectionsInBackup()
+		})
+		It("passes when the requested sections are in the backup", func() {
+			restore.SetBackupConfig(&history.BackupConfig{DataOnly: true})
+			_ = cmdFlags.Set(options.SECTION, "data")
+			restore.ValidateSectionsInBackup()
+		})
+	})
+})
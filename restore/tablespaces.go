@@ -0,0 +1,207 @@
+package restore
+
+/*
+ * This file contains functions related to restoring objects to different
+ * tablespaces than the ones they were stored in on the backup cluster, or to
+ * the default tablespace.
+ */
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+var alterIndexTablespaceRegex = regexp.MustCompile(`^(ALTER INDEX .+ SET TABLESPACE )` + identifierPattern + `;$`)
+
+/*
+ * Each mapping is of the form "<backup tablespace>=<restore tablespace>", with
+ * unquoted tablespace names.
+ */
+func ParseTablespaceMapping(mappings []string) map[string]string {
+	mapping := make(map[string]string, len(mappings))
+	for _, pair := range mappings {
+		separator := strings.Index(pair, "=")
+		if separator <= 0 || separator == len(pair)-1 {
+			gplog.Fatal(errors.Errorf(`Invalid tablespace mapping "%s". Tablespace mappings must be of the form "<backup tablespace>=<restore tablespace>".`, pair), "")
+		}
+		oldTablespace, newTablespace := pair[:separator], pair[separator+1:]
+		if _, ok := mapping[oldTablespace]; ok {
+			gplog.Fatal(errors.Errorf("Tablespace %s is mapped more than once.", oldTablespace), "")
+		}
+		mapping[oldTablespace] = newTablespace
+	}
+	return mapping
+}
+
+/*
+ * Mapped tablespaces are not created, so the tablespaces they are mapped to
+ * must already exist in the restore cluster.
+ */
+func InitializeTablespaceMapping() {
+	mappings := MustGetFlagStringArray(options.TABLESPACE_MAPPING)
+	if len(mappings) == 0 {
+		return
+	}
+	mapping := ParseTablespaceMapping(mappings)
+	existingTablespaces := dbconn.MustSelectStringSlice(connectionPool, "SELECT spcname AS string FROM pg_tablespace")
+	tablespaceMapping = make(map[string]string, len(mapping))
+	for oldTablespace, newTablespace := range mapping {
+		if !utils.Exists(existingTablespaces, newTablespace) {
+			gplog.Fatal(errors.Errorf("Tablespace %s, to which tablespace %s is mapped, does not exist in the restore cluster.", newTablespace, oldTablespace), "")
+		}
+		tablespaceMapping[oldTablespace] = utils.QuoteIdent(connectionPool, newTablespace)
+	}
+	gplog.Info("Mapping %d tablespaces to existing tablespaces", len(tablespaceMapping))
+}
+
+/*
+ * If --no-tablespaces is set, tablespaces are not created and TABLESPACE
+ * clauses are removed from database, table, index, and materialized view
+ * statements so that those objects are created in the default tablespace.
+ * Otherwise, mapped tablespaces are not created and references to them are
+ * replaced with the tablespaces they are mapped to.
+ */
+func ApplyTablespaceOptions(statements []toc.StatementWithType) []toc.StatementWithType {
+	noTablespaces := MustGetFlagBool(options.NO_TABLESPACES)
+	if !noTablespaces && tablespaceMapping == nil {
+		return statements
+	}
+	filteredStatements := make([]toc.StatementWithType, 0, len(statements))
+	for _, statement := range statements {
+		switch statement.ObjectType {
+		case "TABLESPACE":
+			if _, ok := tablespaceMapping[utils.UnquoteIdent(statement.Name)]; noTablespaces || ok {
+				continue
+			}
+		case "DATABASE", "TABLE", "INDEX", "MATERIALIZED VIEW":
+			if statement.FromDefinition || toc.GetMetadataAttributeType(statement) != "" {
+				break
+			}
+			rewritten, ok := rewriteTablespaceStatement(statement, noTablespaces)
+			if !ok {
+				continue
+			}
+			statement.Statement = rewritten
+		}
+		filteredStatements = append(filteredStatements, statement)
+	}
+	return filteredStatements
+}
+
+func mapTablespace(tablespace string) string {
+	if newTablespace, ok := tablespaceMapping[utils.UnquoteIdent(tablespace)]; ok {
+		return newTablespace
+	}
+	return tablespace
+}
+
+/*
+ * Only the TABLESPACE clauses that belong to the statement itself are changed,
+ * so column names, column defaults, and string literals that happen to contain
+ * "TABLESPACE" are left alone. An ALTER INDEX ... SET TABLESPACE statement is
+ * removed entirely when --no-tablespaces is set.
+ */
+func rewriteTablespaceStatement(statement toc.StatementWithType, noTablespaces bool) (string, bool) {
+	trimmed := strings.TrimSpace(statement.Statement)
+	switch {
+	case statement.ObjectType == "INDEX":
+		return rewriteStatement(statement.Statement, func(line string) (string, bool) {
+			match := alterIndexTablespaceRegex.FindStringSubmatch(line)
+			if match == nil {
+				return line, true
+			} else if noTablespaces {
+				return "", false
+			}
+			return match[1] + mapTablespace(match[2]) + ";", true
+		})
+	case isCreateTableStatement(statement):
+		createTable, err := ParseCreateTableStatement(statement.Statement)
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Unable to apply tablespace options to table %s: %v", utils.MakeFQN(statement.Schema, statement.Name), err), "")
+		}
+		if createTable.Tablespace != "" {
+			createTable.Tablespace = mapOrRemoveTablespace(createTable.Tablespace, noTablespaces)
+		}
+		createTable.PartDef = replaceTablespaceClauses(createTable.PartDef, noTablespaces)
+		createTable.PartTemplateDef = replaceTablespaceClauses(createTable.PartTemplateDef, noTablespaces)
+		return createTable.String(), true
+	case strings.HasPrefix(trimmed, "CREATE DATABASE "):
+		// The clauses follow CREATE DATABASE and the database name
+		clausesStart := skipTokens(statement.Statement, 0, 3)
+		return statement.Statement[:clausesStart] + replaceTablespaceClauses(statement.Statement[clausesStart:], noTablespaces), true
+	case strings.HasPrefix(trimmed, "CREATE MATERIALIZED VIEW "):
+		// The clauses follow CREATE MATERIALIZED VIEW and the view name, and precede the AS keyword of the view query
+		clausesStart := skipTokens(statement.Statement, 0, 4)
+		clausesEnd := clausesStart
+		for {
+			start, end := nextToken(statement.Statement, clausesEnd, len(statement.Statement))
+			if start == end || strings.ToUpper(statement.Statement[start:end]) == "AS" {
+				break
+			}
+			clausesEnd = end
+		}
+		return statement.Statement[:clausesStart] + replaceTablespaceClauses(statement.Statement[clausesStart:clausesEnd], noTablespaces) + statement.Statement[clausesEnd:], true
+	}
+	return statement.Statement, true
+}
+
+func mapOrRemoveTablespace(tablespace string, noTablespaces bool) string {
+	if noTablespaces {
+		return ""
+	}
+	return mapTablespace(tablespace)
+}
+
+// Returns the position just past the next count tokens of str, starting at pos
+func skipTokens(str string, pos int, count int) int {
+	for i := 0; i < count; i++ {
+		_, pos = nextToken(str, pos, len(str))
+	}
+	return pos
+}
+
+/*
+ * Replaces or removes the tablespace of each TABLESPACE clause in a list of
+ * clauses, such as the options of a CREATE DATABASE statement or a partition
+ * definition. Parenthesized lists are searched as well, since partition
+ * definitions contain the definitions of their subpartitions.
+ */
+func replaceTablespaceClauses(clauses string, noTablespaces bool) string {
+	var replaced strings.Builder
+	pos := 0
+	for {
+		start, end := nextToken(clauses, pos, len(clauses))
+		if start == end {
+			break
+		}
+		token := clauses[start:end]
+		if strings.HasPrefix(token, "(") && strings.HasSuffix(token, ")") {
+			replaced.WriteString(clauses[pos:start])
+			replaced.WriteString("(" + replaceTablespaceClauses(token[1:len(token)-1], noTablespaces) + ")")
+			pos = end
+			continue
+		}
+		if strings.ToUpper(token) == "TABLESPACE" {
+			nameStart, nameEnd := nextToken(clauses, end, len(clauses))
+			if nameStart != nameEnd && !strings.ContainsRune("(),;", rune(clauses[nameStart])) {
+				if !noTablespaces {
+					replaced.WriteString(clauses[pos:start])
+					replaced.WriteString("TABLESPACE " + mapTablespace(clauses[nameStart:nameEnd]))
+				}
+				pos = nameEnd
+				continue
+			}
+		}
+		replaced.WriteString(clauses[pos:end])
+		pos = end
+	}
+	replaced.WriteString(clauses[pos:])
+	return replaced.String()
+}
@@ -0,0 +1,120 @@
+package restore_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/tablespaces tests", func() {
+	Describe("ParseTablespaceMapping", func() {
+		It("parses mappings of backup tablespaces to restore tablespaces", func() {
+			Expect(restore.ParseTablespaceMapping([]string{"fast_ssd=pg_default", "Archive Space=archive"})).To(Equal(map[string]string{"fast_ssd": "pg_default", "Archive Space": "archive"}))
+		})
+		It("panics when a mapping does not contain a restore tablespace", func() {
+			defer testhelper.ShouldPanicWithMessage(`Invalid tablespace mapping "fast_ssd="`)
+			restore.ParseTablespaceMapping([]string{"fast_ssd="})
+		})
+		It("panics when a mapping does not contain a separator", func() {
+			defer testhelper.ShouldPanicWithMessage(`Invalid tablespace mapping "fast_ssd"`)
+			restore.ParseTablespaceMapping([]string{"fast_ssd"})
+		})
+		It("panics when a tablespace is mapped twice", func() {
+			defer testhelper.ShouldPanicWithMessage("Tablespace fast_ssd is mapped more than once.")
+			restore.ParseTablespaceMapping([]string{"fast_ssd=pg_default", "fast_ssd=archive"})
+		})
+	})
+	Describe("ApplyTablespaceOptions", func() {
+		createTablespace := toc.StatementWithType{Name: "fast_ssd", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE fast_ssd LOCATION '/data/ssd';"}
+		otherTablespace := toc.StatementWithType{Name: "archive", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE archive LOCATION '/data/archive';"}
+		createDatabase := toc.StatementWithType{Name: "testdb", ObjectType: "DATABASE", Statement: "\n\nCREATE DATABASE testdb TEMPLATE template0 TABLESPACE fast_ssd ENCODING 'UTF8';"}
+		createTable := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: `
+
+CREATE TABLE public.foo (
+	i integer
+) WITH (appendonly=true) TABLESPACE fast_ssd DISTRIBUTED BY (i) PARTITION BY RANGE(i) (PARTITION p1 START (1) END (2) TABLESPACE archive, PARTITION p2 START (2) END (3) TABLESPACE fast_ssd);
+`}
+		tableComment := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCOMMENT ON TABLE public.foo IS 'Moved off TABLESPACE fast_ssd';"}
+		createIndex := toc.StatementWithType{Schema: "public", Name: "foo_idx", ObjectType: "INDEX", Statement: "\n\nCREATE INDEX foo_idx ON public.foo USING btree (i);"}
+		alterIndex := toc.StatementWithType{Schema: "public", Name: "foo_idx", ObjectType: "INDEX", Statement: "\nALTER INDEX public.foo_idx SET TABLESPACE fast_ssd;"}
+		createMaterializedView := toc.StatementWithType{Schema: "public", Name: "foo_mv", ObjectType: "MATERIALIZED VIEW", Statement: "\n\nCREATE MATERIALIZED VIEW public.foo_mv TABLESPACE fast_ssd AS  SELECT foo.i\n   FROM public.foo\nWITH NO DATA;\n"}
+		statements := []toc.StatementWithType{createTablespace, otherTablespace, createDatabase, createTable, tableComment, createIndex, alterIndex, createMaterializedView}
+
+		AfterEach(func() {
+			restore.SetTablespaceMapping(nil)
+		})
+		It("returns statements unchanged when no tablespace options are set", func() {
+			Expect(restore.ApplyTablespaceOptions(statements)).To(Equal(statements))
+		})
+		It("removes tablespaces and tablespace clauses when --no-tablespaces is set", func() {
+			_ = cmdFlags.Set(options.NO_TABLESPACES, "true")
+
+			result := restore.ApplyTablespaceOptions(statements)
+
+			Expect(result).To(HaveLen(5))
+			Expect(result[0].Statement).To(Equal("\n\nCREATE DATABASE testdb TEMPLATE template0 ENCODING 'UTF8';"))
+			Expect(result[1].Statement).To(Equal(`
+
+CREATE TABLE public.foo (
+	i integer
+) WITH (appendonly=true) DISTRIBUTED BY (i) PARTITION BY RANGE(i) (PARTITION p1 START (1) END (2), PARTITION p2 START (2) END (3));
+`))
+			Expect(result[2]).To(Equal(tableComment))
+			Expect(result[3]).To(Equal(createIndex))
+			Expect(result[4].Statement).To(Equal("\n\nCREATE MATERIALIZED VIEW public.foo_mv AS  SELECT foo.i\n   FROM public.foo\nWITH NO DATA;\n"))
+		})
+		It("replaces mapped tablespaces and does not create them", func() {
+			restore.SetTablespaceMapping(map[string]string{"fast_ssd": "pg_default"})
+
+			result := restore.ApplyTablespaceOptions(statements)
+
+			Expect(result).To(HaveLen(7))
+			Expect(result[0]).To(Equal(otherTablespace))
+			Expect(result[1].Statement).To(Equal("\n\nCREATE DATABASE testdb TEMPLATE template0 TABLESPACE pg_default ENCODING 'UTF8';"))
+			Expect(result[2].Statement).To(Equal(`
+
+CREATE TABLE public.foo (
+	i integer
+) WITH (appendonly=true) TABLESPACE pg_default DISTRIBUTED BY (i) PARTITION BY RANGE(i) (PARTITION p1 START (1) END (2) TABLESPACE archive, PARTITION p2 START (2) END (3) TABLESPACE pg_default);
+`))
+			Expect(result[3]).To(Equal(tableComment))
+			Expect(result[4]).To(Equal(createIndex))
+			Expect(result[5].Statement).To(Equal("\nALTER INDEX public.foo_idx SET TABLESPACE pg_default;"))
+			Expect(result[6].Statement).To(Equal("\n\nCREATE MATERIALIZED VIEW public.foo_mv TABLESPACE pg_default AS  SELECT foo.i\n   FROM public.foo\nWITH NO DATA;\n"))
+		})
+		It("replaces quoted tablespace names", func() {
+			restore.SetTablespaceMapping(map[string]string{"Fast SSD": `"New SSD"`})
+			quotedTable := toc.StatementWithType{Schema: "public", Name: "bar", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.bar (\n\ti integer\n) TABLESPACE \"Fast SSD\" DISTRIBUTED RANDOMLY;\n"}
+
+			result := restore.ApplyTablespaceOptions([]toc.StatementWithType{quotedTable})
+
+			Expect(result[0].Statement).To(Equal("\n\nCREATE TABLE public.bar (\n\ti integer\n) TABLESPACE \"New SSD\" DISTRIBUTED RANDOMLY;\n"))
+		})
+		It("does not change columns or string literals that contain TABLESPACE", func() {
+			restore.SetTablespaceMapping(map[string]string{"fast_ssd": "pg_default"})
+			table := toc.StatementWithType{Schema: "public", Name: "bar", ObjectType: "TABLE", Statement: `
+
+CREATE TABLE public.bar (
+	tablespace text DEFAULT ' TABLESPACE fast_ssd',
+	location text
+) TABLESPACE fast_ssd DISTRIBUTED BY (tablespace) PARTITION BY LIST(tablespace) (PARTITION p1 VALUES ('TABLESPACE fast_ssd') TABLESPACE fast_ssd);
+`}
+			database := toc.StatementWithType{Name: "tablespace", ObjectType: "DATABASE", Statement: "\n\nCREATE DATABASE tablespace TEMPLATE template0 TABLESPACE fast_ssd;"}
+
+			result := restore.ApplyTablespaceOptions([]toc.StatementWithType{table, database})
+
+			Expect(result[0].Statement).To(Equal(`
+
+CREATE TABLE public.bar (
+	tablespace text DEFAULT ' TABLESPACE fast_ssd',
+	location text
+) TABLESPACE pg_default DISTRIBUTED BY (tablespace) PARTITION BY LIST(tablespace) (PARTITION p1 VALUES ('TABLESPACE fast_ssd') TABLESPACE pg_default);
+`))
+			Expect(result[1].Statement).To(Equal("\n\nCREATE DATABASE tablespace TEMPLATE template0 TABLESPACE pg_default;"))
+		})
+	})
+})
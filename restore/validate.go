@@ -2,6 +2,7 @@ package restore
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -24,6 +25,123 @@ func validateFilterListsInBackupSet() {
 	ValidateExcludeRelationsInBackupSet(MustGetFlagStringArray(options.EXCLUDE_RELATION))
 }
 
+/*
+ * Filter patterns are resolved against the schemas and relations in the TOC,
+ * and the matching names are added to the corresponding filter flags so that
+ * the rest of the restore treats them as if they had been passed individually.
+ * The TOC stores quoted names, so patterns are matched against the unquoted
+ * names, as they are during backup, and the quoted names are added.
+ */
+func ResolveFilterPatternsInBackupSet() {
+	includeSchemaPatterns := MustGetFlagStringArray(options.INCLUDE_SCHEMA_PATTERN)
+	excludeSchemaPatterns := MustGetFlagStringArray(options.EXCLUDE_SCHEMA_PATTERN)
+	if len(includeSchemaPatterns) > 0 || len(excludeSchemaPatterns) > 0 {
+		schemas := getSchemasInBackupSet()
+		includeSchemas, err := matchSchemaPatternsInBackupSet(includeSchemaPatterns, schemas)
+		gplog.FatalOnError(err)
+		excludeSchemas, err := matchSchemaPatternsInBackupSet(excludeSchemaPatterns, schemas)
+		gplog.FatalOnError(err)
+		options.AddFilterPatternMatches(cmdFlags, options.INCLUDE_SCHEMA_PATTERN, options.INCLUDE_SCHEMA, includeSchemas)
+		options.AddFilterPatternMatches(cmdFlags, options.EXCLUDE_SCHEMA_PATTERN, options.EXCLUDE_SCHEMA, excludeSchemas)
+	}
+
+	includeRelationPatterns := MustGetFlagStringArray(options.INCLUDE_RELATION_PATTERN)
+	excludeRelationPatterns := MustGetFlagStringArray(options.EXCLUDE_RELATION_PATTERN)
+	if len(includeRelationPatterns) > 0 || len(excludeRelationPatterns) > 0 {
+		relations := getRelationsInBackupSet()
+		includeRelations, err := matchRelationPatternsInBackupSet(includeRelationPatterns, relations)
+		gplog.FatalOnError(err)
+		excludeRelations, err := matchRelationPatternsInBackupSet(excludeRelationPatterns, relations)
+		gplog.FatalOnError(err)
+		options.AddFilterPatternMatches(cmdFlags, options.INCLUDE_RELATION_PATTERN, options.INCLUDE_RELATION, includeRelations)
+		options.AddFilterPatternMatches(cmdFlags, options.EXCLUDE_RELATION_PATTERN, options.EXCLUDE_RELATION, excludeRelations)
+	}
+}
+
+func matchSchemaPatternsInBackupSet(patterns []string, schemas []string) ([]string, error) {
+	unquotedSchemas := make([]string, 0, len(schemas))
+	quotedSchemas := make(map[string]string, len(schemas))
+	for _, schema := range schemas {
+		unquotedSchema := utils.UnquoteIdent(schema)
+		unquotedSchemas = append(unquotedSchemas, unquotedSchema)
+		quotedSchemas[unquotedSchema] = schema
+	}
+	unquotedMatches, err := options.MatchSchemaPatterns(patterns, unquotedSchemas)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0, len(unquotedMatches))
+	for _, match := range unquotedMatches {
+		matches = append(matches, quotedSchemas[match])
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func matchRelationPatternsInBackupSet(patterns []string, relations []options.FqnStruct) ([]string, error) {
+	unquotedRelations := make([]options.FqnStruct, 0, len(relations))
+	quotedFQNs := make(map[options.FqnStruct]string, len(relations))
+	for _, relation := range relations {
+		unquotedRelation := options.FqnStruct{SchemaName: utils.UnquoteIdent(relation.SchemaName), TableName: utils.UnquoteIdent(relation.TableName)}
+		unquotedRelations = append(unquotedRelations, unquotedRelation)
+		quotedFQNs[unquotedRelation] = utils.MakeFQN(relation.SchemaName, relation.TableName)
+	}
+	unquotedMatches, err := options.FilterRelationPatterns(patterns, unquotedRelations)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]string, 0, len(unquotedMatches))
+	for _, match := range unquotedMatches {
+		matches = append(matches, quotedFQNs[match])
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func getSchemasInBackupSet() []string {
+	schemas := make([]string, 0)
+	schemaSet := make(map[string]bool)
+	addSchema := func(schema string) {
+		if schema != "" && !schemaSet[schema] {
+			schemaSet[schema] = true
+			schemas = append(schemas, schema)
+		}
+	}
+	for _, entry := range globalTOC.PredataEntries {
+		if entry.ObjectType == "SCHEMA" {
+			addSchema(entry.Name)
+		} else {
+			addSchema(entry.Schema)
+		}
+	}
+	for _, entry := range globalTOC.DataEntries {
+		addSchema(entry.Schema)
+	}
+	return schemas
+}
+
+func getRelationsInBackupSet() []options.FqnStruct {
+	relations := make([]options.FqnStruct, 0)
+	relationSet := make(map[string]bool)
+	addRelation := func(schema string, name string) {
+		fqn := utils.MakeFQN(schema, name)
+		if !relationSet[fqn] {
+			relationSet[fqn] = true
+			relations = append(relations, options.FqnStruct{SchemaName: schema, TableName: name})
+		}
+	}
+	for _, entry := range globalTOC.PredataEntries {
+		switch entry.ObjectType {
+		case "TABLE", "VIEW", "MATERIALIZED VIEW", "SEQUENCE":
+			addRelation(entry.Schema, entry.Name)
+		}
+	}
+	for _, entry := range globalTOC.DataEntries {
+		addRelation(entry.Schema, entry.Name)
+	}
+	return relations
+}
+
 func ValidateIncludeSchemasInBackupSet(schemaList []string) {
 	if keys := getFilterSchemasInBackupSet(schemaList); len(keys) != 0 {
 		gplog.Fatal(errors.Errorf("Could not find the following schema(s) in the backup set: %s", strings.Join(keys, ", ")), "")
@@ -106,6 +224,12 @@ func ValidateRelationsInRestoreDatabase(connectionPool *dbconn.DBConn, relationL
 	if len(relationList) == 0 {
 		return
 	}
+	restoresPredata := ShouldRestoreSection("predata")
+	sectionsNeedingRelations := getSectionsNeedingRelations()
+	if !restoresPredata && len(sectionsNeedingRelations) == 0 {
+		// A restore of only global objects does not touch any relations
+		return
+	}
 	utils.ValidateFQNs(relationList)
 	quotedTablesStr := utils.SliceToQuotedString(relationList)
 	query := fmt.Sprintf(`
@@ -117,20 +241,25 @@ WHERE quote_ident(n.nspname) || '.' || quote_ident(c.relname) IN (%s)`, quotedTa
 	relationsInDB := dbconn.MustSelectStringSlice(connectionPool, query)
 
 	/*
-	 * For data-only we check that the relations we are planning to restore
-	 * are already defined in the database so we have somewhere to put the data.
+	 * For data-only, or when the predata section is not restored, we check that
+	 * the relations we are planning to restore are already defined in the
+	 * database so we have somewhere to put the data, indexes, or statistics.
 	 *
-	 * For non-data-only we check that the relations we are planning to restore
+	 * Otherwise we check that the relations we are planning to restore
 	 * are not already in the database so we don't get duplicate data.
 	 */
 	var errMsg string
-	if backupConfig.DataOnly || MustGetFlagBool(options.DATA_ONLY) {
+	if !restoresPredata {
 		if len(relationsInDB) < len(relationList) {
+			restoreDescription := "data-only restore"
+			if isSectionRestore() {
+				restoreDescription = describeSections(sectionsNeedingRelations)
+			}
 			dbRelationsSet := utils.NewSet(relationsInDB)
 			for _, restoreRelation := range relationList {
 				matches := dbRelationsSet.MatchesFilter(restoreRelation)
 				if !matches {
-					errMsg = fmt.Sprintf("Relation %s must exist for data-only restore", restoreRelation)
+					errMsg = fmt.Sprintf("Relation %s must exist for %s", restoreRelation, restoreDescription)
 				}
 			}
 		}
@@ -142,6 +271,24 @@ WHERE quote_ident(n.nspname) || '.' || quote_ident(c.relname) IN (%s)`, quotedTa
 	}
 }
 
+// These are the sections that restore into relations created by the predata section
+func getSectionsNeedingRelations() []string {
+	sections := make([]string, 0)
+	for _, section := range []string{"data", "postdata", "statistics"} {
+		if ShouldRestoreSection(section) {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+func describeSections(sections []string) string {
+	if len(sections) == 1 {
+		return fmt.Sprintf("restore of the %s section", sections[0])
+	}
+	return fmt.Sprintf("restore of the %s and %s sections", strings.Join(sections[:len(sections)-1], ", "), sections[len(sections)-1])
+}
+
 func ValidateIncludeRelationsInBackupSet(schemaList []string) {
 	if keys := getFilterRelationsInBackupSet(schemaList); len(keys) != 0 {
 		gplog.Fatal(errors.Errorf("Could not find the following relation(s) in the backup set: %s", strings.Join(keys, ", ")), "")
@@ -220,7 +367,7 @@ func ValidateBackupFlagCombinations() {
 	if backupConfig.SingleDataFile && MustGetFlagInt(options.JOBS) != 1 {
 		gplog.Fatal(errors.Errorf("Cannot use jobs flag when restoring backups with a single data file per segment."), "")
 	}
-	if (backupConfig.IncludeTableFiltered || backupConfig.DataOnly) && MustGetFlagBool(options.WITH_GLOBALS) {
+	if (backupConfig.IncludeTableFiltered || backupConfig.DataOnly) && ShouldRestoreSection("global") {
 		gplog.Fatal(errors.Errorf("Global metadata is not backed up in table-filtered or data-only backups."), "")
 	}
 	if backupConfig.MetadataOnly && MustGetFlagBool(options.DATA_ONLY) {
@@ -229,7 +376,25 @@ func ValidateBackupFlagCombinations() {
 	if backupConfig.DataOnly && MustGetFlagBool(options.METADATA_ONLY) {
 		gplog.Fatal(errors.Errorf("Cannot use metadata-only flag when restoring data-only backup"), "")
 	}
+	ValidateSectionsInBackup()
 	validateBackupFlagPluginCombinations()
+	validateBackupFlagOutputSQLCombinations()
+	ValidateSegmentCount()
+}
+
+func validateBackupFlagOutputSQLCombinations() {
+	if MustGetFlagString(options.OUTPUT_SQL) == "" || !ShouldRestoreSection("data") {
+		return
+	}
+	if backupConfig.SingleDataFile {
+		gplog.Fatal(errors.Errorf("Cannot write data restore statements for a backup taken with a single data file per segment. Use the --metadata-only flag with --output-sql."), "")
+	}
+	if backupConfig.EncryptionKeyID != "" {
+		gplog.Fatal(errors.Errorf("Cannot write data restore statements for an encrypted backup, as the key used to decrypt the data is removed when gprestore exits. Use the --metadata-only flag with --output-sql."), "")
+	}
+	if backupConfig.Plugin != "" {
+		gplog.Fatal(errors.Errorf("Cannot write data restore statements for a backup taken with a plugin. Use the --metadata-only flag with --output-sql."), "")
+	}
 }
 
 func validateBackupFlagPluginCombinations() {
@@ -244,9 +409,13 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	options.CheckExclusiveFlags(flags, options.DATA_ONLY, options.WITH_GLOBALS)
 	options.CheckExclusiveFlags(flags, options.DATA_ONLY, options.CREATE_DB)
 	options.CheckExclusiveFlags(flags, options.DEBUG, options.QUIET, options.VERBOSE)
-	options.CheckExclusiveFlags(flags, options.INCLUDE_SCHEMA, options.INCLUDE_RELATION, options.INCLUDE_RELATION_FILE)
-	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.INCLUDE_SCHEMA)
-	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_RELATION, options.INCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.INCLUDE_RELATION_FILE)
+	options.CheckExclusiveFlags(flags, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_PATTERN, options.INCLUDE_RELATION, options.INCLUDE_RELATION_FILE, options.INCLUDE_RELATION_PATTERN)
+	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_PATTERN, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_PATTERN)
+	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_PATTERN, options.EXCLUDE_RELATION, options.INCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.INCLUDE_RELATION_FILE, options.EXCLUDE_RELATION_PATTERN, options.INCLUDE_RELATION_PATTERN)
 	options.CheckExclusiveFlags(flags, options.METADATA_ONLY, options.DATA_ONLY)
+	options.CheckExclusiveFlags(flags, options.NO_TABLESPACES, options.TABLESPACE_MAPPING)
 	options.CheckExclusiveFlags(flags, options.PLUGIN_CONFIG, options.BACKUP_DIR)
+	options.CheckExclusiveFlags(flags, options.ENCRYPTION_KEY_FILE, options.ENCRYPTION_KEY_COMMAND)
+	ValidateSectionFlags(flags)
+	ValidateClusterRestoreFlags(flags)
 }
@@ -32,10 +32,10 @@ var _ = Describe("restore/validate tests", func() {
 			tocfile, backupfile = testutils.InitializeTestTOC(buffer, "predata")
 			backupfile.ByteCount = table1Len
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema1", Name: "table1", ObjectType: "TABLE"}, 0, backupfile.ByteCount)
-			tocfile.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
+			tocfile.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "", 0, 0)
 			backupfile.ByteCount += table2Len
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema2", Name: "table2", ObjectType: "TABLE"}, table1Len, backupfile.ByteCount)
-			tocfile.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "")
+			tocfile.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "", 0, 0)
 			backupfile.ByteCount += sequenceLen
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema", Name: "somesequence", ObjectType: "SEQUENCE"}, table1Len+table2Len, backupfile.ByteCount)
 			restore.SetTOC(tocfile)
@@ -70,13 +70,51 @@ var _ = Describe("restore/validate tests", func() {
 			testhelper.ExpectRegexp(logfile, "[WARNING]:-Could not find the following excluded schema(s) in the backup set: schema3")
 		})
 	})
+	Describe("ResolveFilterPatternsInBackupSet", func() {
+		BeforeEach(func() {
+			tocfile, _ = testutils.InitializeTestTOC(buffer, "predata")
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Name: "etl", ObjectType: "SCHEMA"}, 0, 0)
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "etl", Name: "stage_1", ObjectType: "TABLE"}, 0, 0)
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "etl", Name: "stage_view", ObjectType: "VIEW"}, 0, 0)
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "etl", Name: "stage_func", ObjectType: "FUNCTION"}, 0, 0)
+			tocfile.AddMasterDataEntry("public", "stage_2", 1, "(i)", 0, "", 0, 0)
+			restore.SetTOC(tocfile)
+		})
+		It("adds the relations in the backup that match table patterns to the table filters", func() {
+			_ = cmdFlags.Set(options.INCLUDE_RELATION_PATTERN, "stage_*")
+			restore.ResolveFilterPatternsInBackupSet()
+
+			Expect(restore.MustGetFlagStringArray(options.INCLUDE_RELATION)).To(Equal([]string{"etl.stage_1", "etl.stage_view", "public.stage_2"}))
+		})
+		It("adds the schemas in the backup that match schema patterns to the schema filters", func() {
+			_ = cmdFlags.Set(options.EXCLUDE_SCHEMA_PATTERN, "/e.l/")
+			restore.ResolveFilterPatternsInBackupSet()
+
+			Expect(restore.MustGetFlagStringArray(options.EXCLUDE_SCHEMA)).To(Equal([]string{"etl"}))
+		})
+		It("panics when an include pattern matches nothing in the backup", func() {
+			_ = cmdFlags.Set(options.INCLUDE_SCHEMA_PATTERN, "stage*")
+			defer testhelper.ShouldPanicWithMessage("No objects match the --include-schema-pattern pattern(s): stage*")
+			restore.ResolveFilterPatternsInBackupSet()
+		})
+		It("matches patterns against unquoted names and adds the quoted names to the filters", func() {
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Name: `"ETL"`, ObjectType: "SCHEMA"}, 0, 0)
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: `"ETL"`, Name: `"Stage 3"`, ObjectType: "TABLE"}, 0, 0)
+			_ = cmdFlags.Set(options.INCLUDE_RELATION_PATTERN, "ETL.Stage*")
+			_ = cmdFlags.Set(options.EXCLUDE_SCHEMA_PATTERN, "E*")
+			restore.ResolveFilterPatternsInBackupSet()
+
+			Expect(restore.MustGetFlagStringArray(options.INCLUDE_RELATION)).To(Equal([]string{`"ETL"."Stage 3"`}))
+			Expect(restore.MustGetFlagStringArray(options.EXCLUDE_SCHEMA)).To(Equal([]string{`"ETL"`}))
+		})
+	})
 	Describe("GenerateRestoreRelationList", func() {
 		BeforeEach(func() {
 			tocfile, _ = testutils.InitializeTestTOC(buffer, "metadata")
-			tocfile.AddMasterDataEntry("s1", "table1", 1, "(j)", 0, "")
-			tocfile.AddMasterDataEntry("s1", "table2", 2, "(j)", 0, "")
-			tocfile.AddMasterDataEntry("s2", "table1", 3, "(j)", 0, "")
-			tocfile.AddMasterDataEntry("s2", "table2", 4, "(j)", 0, "")
+			tocfile.AddMasterDataEntry("s1", "table1", 1, "(j)", 0, "", 0, 0)
+			tocfile.AddMasterDataEntry("s1", "table2", 2, "(j)", 0, "", 0, 0)
+			tocfile.AddMasterDataEntry("s2", "table1", 3, "(j)", 0, "", 0, 0)
+			tocfile.AddMasterDataEntry("s2", "table2", 4, "(j)", 0, "", 0, 0)
 			restore.SetTOC(tocfile)
 		})
 		It("returns all tables if no filtering is used", func() {
@@ -162,6 +200,30 @@ var _ = Describe("restore/validate tests", func() {
 				restore.ValidateRelationsInRestoreDatabase(connectionPool, filterList)
 			})
 		})
+		Context("section restore without the predata section", func() {
+			It("does not check relations for a restore of only the global section", func() {
+				_ = cmdFlags.Set(options.SECTION, "global")
+				filterList = []string{"public.table1"}
+				restore.ValidateRelationsInRestoreDatabase(connectionPool, filterList)
+				Expect(mock.ExpectationsWereMet()).To(Succeed())
+			})
+			It("panics naming the postdata section if a table is missing from the database", func() {
+				_ = cmdFlags.Set(options.SECTION, "postdata")
+				mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"string"}))
+				filterList = []string{"public.table1"}
+				defer testhelper.ShouldPanicWithMessage("Relation public.table1 must exist for restore of the postdata section")
+				restore.ValidateRelationsInRestoreDatabase(connectionPool, filterList)
+			})
+			It("panics naming every requested section that needs the relations", func() {
+				_ = cmdFlags.Set(options.SECTION, "data")
+				_ = cmdFlags.Set(options.SECTION, "postdata")
+				_ = cmdFlags.Set(options.SECTION, "statistics")
+				mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows([]string{"string"}))
+				filterList = []string{"public.table1"}
+				defer testhelper.ShouldPanicWithMessage("Relation public.table1 must exist for restore of the data, postdata and statistics sections")
+				restore.ValidateRelationsInRestoreDatabase(connectionPool, filterList)
+			})
+		})
 		Context("restore includes metadata", func() {
 			It("passes if table is not present in database", func() {
 				noTableRows := sqlmock.NewRows([]string{"string"})
@@ -193,10 +255,10 @@ var _ = Describe("restore/validate tests", func() {
 		BeforeEach(func() {
 			tocfile, backupfile = testutils.InitializeTestTOC(buffer, "predata")
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema1", Name: "table1", ObjectType: "TABLE"}, 0, backupfile.ByteCount)
-			tocfile.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
+			tocfile.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "", 0, 0)
 
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema2", Name: "table2", ObjectType: "TABLE"}, 0, backupfile.ByteCount)
-			tocfile.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "")
+			tocfile.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "", 0, 0)
 
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema1", Name: "somesequence", ObjectType: "SEQUENCE"}, 0, backupfile.ByteCount)
 			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema1", Name: "someview", ObjectType: "VIEW"}, 0, backupfile.ByteCount)
@@ -15,6 +15,7 @@ import (
 	"github.com/greenplum-db/gpbackup/report"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 )
 
 /*
@@ -69,6 +70,13 @@ func CreateConnectionPool(unquotedDBName string) {
 
 func InitializeConnectionPool(unquotedDBName string) {
 	CreateConnectionPool(unquotedDBName)
+	setupQuery := GetSetupQuery()
+	for i := 0; i < connectionPool.NumConns; i++ {
+		connectionPool.MustExec(setupQuery, i)
+	}
+}
+
+func GetSetupQuery() string {
 	setupQuery := `
 SET application_name TO 'gprestore';
 SET search_path TO pg_catalog;
@@ -105,10 +113,7 @@ SET default_with_oids = off;
 		}
 	}
 	setupQuery += SetMaxCsvLineLengthQuery(connectionPool)
-
-	for i := 0; i < connectionPool.NumConns; i++ {
-		connectionPool.MustExec(setupQuery, i)
-	}
+	return setupQuery
 }
 
 func SetMaxCsvLineLengthQuery(connectionPool *dbconn.DBConn) string {
@@ -135,17 +140,49 @@ func InitializeBackupConfig() {
 	report.EnsureDatabaseVersionCompatibility(backupConfig.DatabaseVersion, connectionPool.Version)
 }
 
+/*
+ * The key is checked against the id of the key recorded in the backup config
+ * before it is used, and is copied to the segments so that gpbackup_helper
+ * can decrypt the data files there.
+ */
+func InitializeEncryption() {
+	keyFile := MustGetFlagString(options.ENCRYPTION_KEY_FILE)
+	keyCommand := MustGetFlagString(options.ENCRYPTION_KEY_COMMAND)
+	if backupConfig.EncryptionKeyID == "" {
+		if keyFile != "" || keyCommand != "" {
+			gplog.Warn("Backup %s is not encrypted, so the encryption key is not used", globalFPInfo.Timestamp)
+		}
+		return
+	}
+	if keyFile == "" && keyCommand == "" {
+		gplog.Fatal(errors.Errorf("Backup %s is encrypted with key %s. Use --encryption-key-file or --encryption-key-command to provide the key.",
+			globalFPInfo.Timestamp, backupConfig.EncryptionKeyID), "")
+	}
+	var err error
+	encryptionKey, err = utils.ReadEncryptionKey(keyFile, keyCommand, backupConfig.EncryptionKeyID)
+	gplog.FatalOnError(err)
+	decryptedFiles = utils.NewDecryptedFiles(encryptionKey)
+	if ShouldRestoreSection("data") {
+		utils.VerifyHelperVersionOnSegments(version, globalCluster)
+		for _, fpInfo := range GetBackupFPInfoListFromRestorePlan() {
+			utils.WriteEncryptionKeyToSegments(encryptionKey, globalCluster, fpInfo)
+		}
+		utils.AddEncryptionToPipeThroughProgram(globalFPInfo.GetSegmentHelperFilePathForCopyCommand("key"))
+	}
+}
+
 func BackupConfigurationValidation() {
 	if !backupConfig.MetadataOnly {
 		gplog.Verbose("Gathering information on backup directories")
 		VerifyBackupDirectoriesExistOnAllHosts()
 	}
 
-	VerifyMetadataFilePaths(MustGetFlagBool(options.WITH_STATS))
+	VerifyMetadataFilePaths(ShouldRestoreSection("statistics"))
 
-	tocFilename := globalFPInfo.GetTOCFilePath()
+	tocFilename := decryptedFiles.MustGetPath(globalFPInfo.GetTOCFilePath())
 	globalTOC = toc.NewTOC(tocFilename)
 	globalTOC.InitializeMetadataEntryMap()
+	WarnIfBackupIsPartial(globalTOC, globalFPInfo.Timestamp)
 
 	// Legacy backups prior to the incremental feature would have no restoreplan yaml element
 	if isLegacyBackup := backupConfig.RestorePlan == nil; isLegacyBackup {
@@ -154,9 +191,17 @@ func BackupConfigurationValidation() {
 
 	ValidateBackupFlagCombinations()
 
+	ResolveFilterPatternsInBackupSet()
 	validateFilterListsInBackupSet()
 }
 
+func WarnIfBackupIsPartial(backupTOC *toc.TOC, backupTimestamp string) {
+	if len(backupTOC.SkippedTables) > 0 {
+		gplog.Warn("Backup %s is partial. The following tables could not be locked during the backup and were not backed up: %s",
+			backupTimestamp, strings.Join(backupTOC.SkippedTables, ", "))
+	}
+}
+
 func SetRestorePlanForLegacyBackup(toc *toc.TOC, backupTimestamp string, backupConfig *history.BackupConfig) {
 	tableFQNs := make([]string, 0, len(toc.DataEntries))
 	for _, entry := range toc.DataEntries {
@@ -189,7 +234,7 @@ func RecoverMetadataFilesUsingPlugin() {
 
 	metadataFiles := []string{globalFPInfo.GetConfigFilePath(), globalFPInfo.GetMetadataFilePath(),
 		globalFPInfo.GetBackupReportFilePath()}
-	if MustGetFlagBool(options.WITH_STATS) {
+	if ShouldRestoreSection("statistics") {
 		metadataFiles = append(metadataFiles, globalFPInfo.GetStatisticsFilePath())
 	}
 	for _, filename := range metadataFiles {
@@ -197,6 +242,9 @@ func RecoverMetadataFilesUsingPlugin() {
 	}
 
 	InitializeBackupConfig()
+	if backupConfig.StructuredMetadata {
+		pluginConfig.MustRestoreFile(globalFPInfo.GetStructuredMetadataFilePath())
+	}
 
 	var fpInfoList []filepath.FilePathInfo
 	if backupConfig.MetadataOnly {
@@ -252,7 +300,7 @@ func GetRestoreMetadataStatementsFiltered(section string, filename string, inclu
 		exRelations = filters.excludeRelations
 		fpInfoList := GetBackupFPInfoListFromRestorePlan()
 		for _, fpInfo := range fpInfoList {
-			tocFilename := fpInfo.GetTOCFilePath()
+			tocFilename := decryptedFiles.MustGetPath(fpInfo.GetTOCFilePath())
 			tocfile := toc.NewTOC(tocFilename)
 			inRelations = append(inRelations, toc.GetIncludedPartitionRoots(tocfile.DataEntries, inRelations)...)
 		}
@@ -270,11 +318,44 @@ func GetRestoreMetadataStatementsFiltered(section string, filename string, inclu
 		}
 	}
 	statements = globalTOC.GetSQLStatementForObjectTypes(section, metadataFile, includeObjectTypes, excludeObjectTypes, inSchemas, exSchemas, inRelations, exRelations)
+	statements = FilterStatementsByObjectType(statements)
+	statements = RenderStatementsFromDefinitions(statements)
+	statements = ApplyRoleOptions(statements)
+	statements = ApplyTablespaceOptions(statements)
+	statements = ApplyTableRules(statements)
 	return statements
 }
 
+/*
+ * Session GUCs are always restored, as they are needed to restore every other
+ * object correctly.
+ */
+func FilterStatementsByObjectType(statements []toc.StatementWithType) []toc.StatementWithType {
+	includedObjectTypes := MustGetFlagStringArray(options.INCLUDE_OBJECT_TYPE)
+	excludedObjectTypes := MustGetFlagStringArray(options.EXCLUDE_OBJECT_TYPE)
+	if len(includedObjectTypes) == 0 && len(excludedObjectTypes) == 0 {
+		return statements
+	}
+	filteredStatements := make([]toc.StatementWithType, 0, len(statements))
+	for _, statement := range statements {
+		if statement.ObjectType != "SESSION GUCS" {
+			if !options.ObjectTypeMatchesFilter(includedObjectTypes, excludedObjectTypes, statement.ObjectType) {
+				continue
+			}
+			attributeType := toc.GetMetadataAttributeType(statement)
+			if attributeType != "" && !options.ObjectTypeMatchesFilter(includedObjectTypes, excludedObjectTypes, attributeType) {
+				continue
+			}
+		}
+		filteredStatements = append(filteredStatements, statement)
+	}
+	return filteredStatements
+}
+
 func ExecuteRestoreMetadataStatements(statements []toc.StatementWithType, objectsTitle string, progressBar utils.ProgressBar, showProgressBar int, executeInParallel bool) {
-	if progressBar == nil {
+	if isOutputToFile() {
+		WriteStatementsToOutputFile(statements, progressBar)
+	} else if progressBar == nil {
 		ExecuteStatementsAndCreateProgressBar(statements, objectsTitle, showProgressBar, executeInParallel)
 	} else {
 		ExecuteStatements(statements, progressBar, executeInParallel)
@@ -307,13 +388,17 @@ func GetBackupFPInfoForTimestamp(timestamp string) filepath.FilePathInfo {
 func setGUCsForConnection(gucStatements []toc.StatementWithType, whichConn int) []toc.StatementWithType {
 	if gucStatements == nil {
 		objectTypes := []string{"SESSION GUCS"}
-		gucStatements = GetRestoreMetadataStatements("global", globalFPInfo.GetMetadataFilePath(), objectTypes, []string{})
+		gucStatements = GetRestoreMetadataStatements("global", decryptedFiles.MustGetPath(globalFPInfo.GetMetadataFilePath()), objectTypes, []string{})
 	}
 	ExecuteStatementsAndCreateProgressBar(gucStatements, "", utils.PB_NONE, false, whichConn)
 	return gucStatements
 }
 
 func RestoreSchemas(schemaStatements []toc.StatementWithType, progressBar utils.ProgressBar) {
+	if isOutputToFile() {
+		WriteStatementsToOutputFile(schemaStatements, progressBar)
+		return
+	}
 	numErrors := 0
 	for _, schema := range schemaStatements {
 		_, err := connectionPool.Exec(schema.Statement, 0)
@@ -366,7 +451,7 @@ func GetExistingSchemas()([]string, error){
 	return existingSchemas, err
 }
 
-func TruncateTablesBeforeRestore(entries []toc.MasterDataEntry) error {
+func GetTruncateTablesQuery(entries []toc.MasterDataEntry) string {
 	query := `TRUNCATE `
 	tableFQNs := make([]string, 0)
 	for _, entry := range entries {
@@ -375,6 +460,10 @@ func TruncateTablesBeforeRestore(entries []toc.MasterDataEntry) error {
 	}
 	query += strings.Join(tableFQNs, ",")
 	query += ";"
-	_, err := connectionPool.Exec(query)
+	return query
+}
+
+func TruncateTablesBeforeRestore(entries []toc.MasterDataEntry) error {
+	_, err := connectionPool.Exec(GetTruncateTablesQuery(entries))
 	return err
 }
@@ -99,6 +99,27 @@ var _ = Describe("wrapper tests", func() {
 			restore.RestoreSchemas(schemaArray, ignoredProgressBar)
 		})
 	})
+	Describe("FilterStatementsByObjectType", func() {
+		gucs := toc.StatementWithType{ObjectType: "SESSION GUCS", Statement: "\nSET client_encoding = 'UTF8';\n"}
+		role := toc.StatementWithType{Name: "role1", ObjectType: "ROLE", Statement: "\n\nCREATE ROLE role1;\n"}
+		table := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.foo (i int);\n"}
+		comment := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCOMMENT ON TABLE public.foo IS 'comment';\n"}
+		statements := []toc.StatementWithType{gucs, role, table, comment}
+		It("returns all statements when no object types are filtered", func() {
+			Expect(restore.FilterStatementsByObjectType(statements)).To(Equal(statements))
+		})
+		It("removes statements for excluded object types", func() {
+			_ = cmdFlags.Set(options.EXCLUDE_OBJECT_TYPE, "ROLE")
+			_ = cmdFlags.Set(options.EXCLUDE_OBJECT_TYPE, "COMMENT")
+
+			Expect(restore.FilterStatementsByObjectType(statements)).To(Equal([]toc.StatementWithType{gucs, table}))
+		})
+		It("keeps only statements for included object types, along with their comments and session GUCs", func() {
+			_ = cmdFlags.Set(options.INCLUDE_OBJECT_TYPE, "TABLE")
+
+			Expect(restore.FilterStatementsByObjectType(statements)).To(Equal([]toc.StatementWithType{gucs, table, comment}))
+		})
+	})
 	Describe("SetRestorePlanForLegacyBackup", func() {
 		legacyBackupConfig := history.BackupConfig{}
 		legacyBackupConfig.RestorePlan = nil
@@ -126,6 +147,18 @@ var _ = Describe("wrapper tests", func() {
 		})
 
 	})
+	Describe("WarnIfBackupIsPartial", func() {
+		It("warns about the tables that were skipped by the backup", func() {
+			restore.WarnIfBackupIsPartial(&toc.TOC{SkippedTables: []string{"public.foo", "public.bar"}}, "20170101010101")
+
+			Expect(string(logfile.Contents())).To(ContainSubstring("Backup 20170101010101 is partial. The following tables could not be locked during the backup and were not backed up: public.foo, public.bar"))
+		})
+		It("does not warn about a complete backup", func() {
+			restore.WarnIfBackupIsPartial(&toc.TOC{}, "20170101010101")
+
+			Expect(string(logfile.Contents())).ToNot(ContainSubstring("is partial"))
+		})
+	})
 	Describe("restore history tests", func() {
 		sampleConfigContents := `
 executablepath: /bin/echo
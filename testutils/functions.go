@@ -134,6 +134,7 @@ func DefaultMetadataMap(objType string, hasPrivileges bool, hasOwner bool, hasCo
 }
 
 var objNameToClassID = map[string]uint32{
+	"ACCESS METHOD":             2601,
 	"AGGREGATE":                 1255,
 	"CAST":                      2605,
 	"COLLATION":                 3456,
@@ -142,22 +143,28 @@ var objNameToClassID = map[string]uint32{
 	"DATABASE":                  1262,
 	"DOMAIN":                    1247,
 	"EVENT TRIGGER":             3466,
+	"EXTENDED STATISTICS":       3381,
 	"EXTENSION":                 3079,
 	"FOREIGN DATA WRAPPER":      2328,
 	"FOREIGN SERVER":            1417,
 	"FUNCTION":                  1255,
 	"INDEX":                     2610,
 	"LANGUAGE":                  2612,
+	"LARGE OBJECT":              2613,
 	"OPERATOR CLASS":            2616,
 	"OPERATOR FAMILY":           2753,
 	"OPERATOR":                  2617,
+	"POLICY":                    3256,
+	"PROCEDURE":                 1255,
 	"PROTOCOL":                  7175,
+	"PUBLICATION":               6104,
 	"RESOURCE GROUP":            6436,
 	"RESOURCE QUEUE":            6026,
 	"ROLE":                      1260,
 	"RULE":                      2618,
 	"SCHEMA":                    2615,
 	"SEQUENCE":                  1259,
+	"SUBSCRIPTION":              6100,
 	"TABLE":                     1259,
 	"TABLESPACE":                1213,
 	"TEXT SEARCH CONFIGURATION": 3602,
@@ -178,15 +185,15 @@ func ClassIDFromObjectName(objName string) uint32 {
 func DefaultACLForType(grantee string, objType string) backup.ACL {
 	return backup.ACL{
 		Grantee:    grantee,
-		Select:     objType == "PROTOCOL" || objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW",
+		Select:     objType == "PROTOCOL" || objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW" || objType == "LARGE OBJECT",
 		Insert:     objType == "PROTOCOL" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW",
-		Update:     objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW",
+		Update:     objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW" || objType == "LARGE OBJECT",
 		Delete:     objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW",
 		Truncate:   objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
 		References: objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW",
 		Trigger:    objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE" || objType == "MATERIALIZED VIEW",
 		Usage:      objType == "LANGUAGE" || objType == "SCHEMA" || objType == "SEQUENCE" || objType == "FOREIGN DATA WRAPPER" || objType == "FOREIGN SERVER",
-		Execute:    objType == "FUNCTION" || objType == "AGGREGATE",
+		Execute:    objType == "FUNCTION" || objType == "AGGREGATE" || objType == "PROCEDURE",
 		Create:     objType == "DATABASE" || objType == "SCHEMA" || objType == "TABLESPACE",
 		Temporary:  objType == "DATABASE",
 		Connect:    objType == "DATABASE",
@@ -0,0 +1,307 @@
+package toc
+
+/*
+ * This file contains structs and functions related to the structured metadata
+ * file, which gpbackup writes alongside the metadata file when run with
+ * --structured-metadata. It holds the attributes of each object in a form
+ * that gprestore can change and render as SQL, rather than changing the
+ * statements in the metadata file directly.
+ */
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"gopkg.in/yaml.v2"
+)
+
+type ObjectDefinitions struct {
+	Definitions []*ObjectDefinition
+	index       map[string]*ObjectDefinition
+}
+
+/*
+ * An object is identified by the same fields as the metadata entries of its
+ * statements. StatementType and Identifier are the type and name used for the
+ * object in its owner, privilege, comment, and security label statements.
+ */
+type ObjectDefinition struct {
+	Schema                string `yaml:",omitempty"`
+	Name                  string
+	ObjectType            string
+	ReferenceObject       string `yaml:",omitempty"`
+	StatementType         string
+	Identifier            string
+	CommentTarget         string              `yaml:",omitempty"`
+	Comment               string              `yaml:",omitempty"`
+	OwnerType             string              `yaml:",omitempty"`
+	Owner                 string              `yaml:",omitempty"`
+	PrivilegeType         string              `yaml:",omitempty"`
+	HasPrivileges         bool                `yaml:",omitempty"`
+	Grants                []Grant             `yaml:",omitempty"`
+	SecurityLabelProvider string              `yaml:",omitempty"`
+	SecurityLabel         string              `yaml:",omitempty"`
+	Table                 *TableDefinition    `yaml:",omitempty"`
+	Database              *DatabaseDefinition `yaml:",omitempty"`
+	ObjectID              UniqueID            `yaml:",omitempty"`
+	Dependencies          []UniqueID          `yaml:",omitempty"`
+}
+
+// Privileges is a comma-separated list of privileges, such as "SELECT,INSERT" or "ALL"
+type Grant struct {
+	Grantee         string
+	Privileges      string
+	WithGrantOption bool `yaml:",omitempty"`
+}
+
+type TableDefinition struct {
+	Unlogged        bool   `yaml:",omitempty"`
+	OfType          string `yaml:",omitempty"`
+	Columns         []ColumnDefinition
+	Inherits        []string `yaml:",omitempty"`
+	StorageOpts     string   `yaml:",omitempty"`
+	Tablespace      string   `yaml:",omitempty"`
+	DistPolicy      string
+	PartDef         string `yaml:",omitempty"`
+	PartTemplateDef string `yaml:",omitempty"`
+}
+
+/*
+ * The CREATE DATABASE statement and the configuration parameters of a database
+ * have separate definitions, under the object types of their metadata entries.
+ * Attributes that were not printed in the metadata file are left empty.
+ */
+type DatabaseDefinition struct {
+	Tablespace string   `yaml:",omitempty"`
+	Encoding   string   `yaml:",omitempty"`
+	Collate    string   `yaml:",omitempty"`
+	CType      string   `yaml:",omitempty"`
+	GUCs       []string `yaml:",omitempty"`
+}
+
+// A StatTarget of -1 means that the column uses the default statistics target
+type ColumnDefinition struct {
+	Name        string
+	Type        string `yaml:",omitempty"`
+	Collation   string `yaml:",omitempty"`
+	Default     string `yaml:",omitempty"`
+	NotNull     bool   `yaml:",omitempty"`
+	Encoding    string `yaml:",omitempty"`
+	StatTarget  int
+	StorageType string `yaml:",omitempty"`
+	Options     string `yaml:",omitempty"`
+}
+
+func NewObjectDefinitions() *ObjectDefinitions {
+	return &ObjectDefinitions{Definitions: make([]*ObjectDefinition, 0), index: make(map[string]*ObjectDefinition)}
+}
+
+func ReadObjectDefinitions(filename string) *ObjectDefinitions {
+	definitions := NewObjectDefinitions()
+	contents, err := ioutil.ReadFile(filename)
+	gplog.FatalOnError(err)
+	err = yaml.Unmarshal(contents, definitions)
+	gplog.FatalOnError(err)
+	for _, definition := range definitions.Definitions {
+		definitions.index[definitionKey(definition.ObjectType, definition.Schema, definition.Name, definition.ReferenceObject)] = definition
+	}
+	return definitions
+}
+
+func (definitions *ObjectDefinitions) WriteToFileAndMakeReadOnly(filename string) {
+	definitions.WriteToEncryptedFileAndMakeReadOnly(filename, nil)
+}
+
+func (definitions *ObjectDefinitions) WriteToEncryptedFileAndMakeReadOnly(filename string, key *utils.EncryptionKey) {
+	contents, err := yaml.Marshal(definitions)
+	gplog.FatalOnError(err)
+	err = utils.WriteToEncryptedFileAndMakeReadOnly(filename, contents, key)
+	gplog.FatalOnError(err)
+}
+
+func definitionKey(objectType string, schema string, name string, referenceObject string) string {
+	return fmt.Sprintf("%s|%s|%s|%s", objectType, schema, name, referenceObject)
+}
+
+/*
+ * Returns the definition of the object with the given metadata entry, adding
+ * an empty one if it does not exist yet.
+ */
+func (definitions *ObjectDefinitions) Get(entry MetadataEntry) *ObjectDefinition {
+	key := definitionKey(entry.ObjectType, entry.Schema, entry.Name, entry.ReferenceObject)
+	if definition, ok := definitions.index[key]; ok {
+		return definition
+	}
+	definition := &ObjectDefinition{Schema: entry.Schema, Name: entry.Name, ObjectType: entry.ObjectType, ReferenceObject: entry.ReferenceObject}
+	definitions.Definitions = append(definitions.Definitions, definition)
+	definitions.index[key] = definition
+	return definition
+}
+
+// Returns the definition of the object with the given metadata entry, if there is one
+func (definitions *ObjectDefinitions) Lookup(entry MetadataEntry) (*ObjectDefinition, bool) {
+	definition, ok := definitions.index[definitionKey(entry.ObjectType, entry.Schema, entry.Name, entry.ReferenceObject)]
+	return definition, ok
+}
+
+// Returns the definition of the object a statement belongs to, if there is one
+func (definitions *ObjectDefinitions) Find(statement StatementWithType) (ObjectDefinition, bool) {
+	definition, ok := definitions.index[definitionKey(statement.ObjectType, statement.Schema, statement.Name, statement.ReferenceObject)]
+	if !ok {
+		return ObjectDefinition{}, false
+	}
+	return *definition, true
+}
+
+/*
+ * The functions below render statements in the same format as the metadata
+ * file, so that a statement can be matched to the attribute it was printed
+ * from. They return an empty string if the object has no such attribute.
+ */
+
+func (definition ObjectDefinition) RenderComment() string {
+	if definition.Comment == "" {
+		return ""
+	}
+	tableStr := ""
+	if definition.CommentTarget != "" {
+		tableStr = fmt.Sprintf(" ON %s", definition.CommentTarget)
+	}
+	return fmt.Sprintf("COMMENT ON %s %s%s IS '%s';", definition.StatementType, definition.Identifier, tableStr, utils.EscapeSingleQuotes(definition.Comment))
+}
+
+func (definition ObjectDefinition) RenderOwner() string {
+	if definition.Owner == "" || definition.OwnerType == "" {
+		return ""
+	}
+	return fmt.Sprintf("ALTER %s %s OWNER TO %s;", definition.OwnerType, definition.Identifier, definition.Owner)
+}
+
+func (definition ObjectDefinition) RenderPrivileges() string {
+	if !definition.HasPrivileges {
+		return ""
+	}
+	target := fmt.Sprintf("%s%s", definition.PrivilegeType, definition.Identifier)
+	statements := []string{fmt.Sprintf("REVOKE ALL ON %s FROM PUBLIC;", target)}
+	if definition.Owner != "" {
+		statements = append(statements, fmt.Sprintf("REVOKE ALL ON %s FROM %s;", target, definition.Owner))
+	}
+	for _, grant := range definition.Grants {
+		grantOption := ""
+		if grant.WithGrantOption {
+			grantOption = " WITH GRANT OPTION"
+		}
+		statements = append(statements, fmt.Sprintf("GRANT %s ON %s TO %s%s;", grant.Privileges, target, grant.Grantee, grantOption))
+	}
+	return strings.Join(statements, "\n")
+}
+
+func (definition ObjectDefinition) RenderSecurityLabel() string {
+	if definition.SecurityLabel == "" {
+		return ""
+	}
+	return fmt.Sprintf("SECURITY LABEL FOR %s ON %s %s IS '%s';", definition.SecurityLabelProvider, definition.StatementType, definition.Identifier, utils.EscapeSingleQuotes(definition.SecurityLabel))
+}
+
+func (definition ObjectDefinition) RenderCreateTable() string {
+	table := definition.Table
+	if table == nil {
+		return ""
+	}
+	var statement strings.Builder
+	modifier := ""
+	if table.Unlogged {
+		modifier = "UNLOGGED "
+	}
+	typeStr := ""
+	if table.OfType != "" {
+		typeStr = fmt.Sprintf("OF %s ", table.OfType)
+	}
+	statement.WriteString(fmt.Sprintf("CREATE %sTABLE %s %s(\n", modifier, definition.Identifier, typeStr))
+	columns := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		line := fmt.Sprintf("\t%s %s", column.Name, column.Type)
+		if table.OfType != "" {
+			line = fmt.Sprintf("\t%s WITH OPTIONS", column.Name)
+		}
+		if column.Collation != "" {
+			line += fmt.Sprintf(" COLLATE %s", column.Collation)
+		}
+		if column.Default != "" {
+			line += fmt.Sprintf(" DEFAULT %s", column.Default)
+		}
+		if column.NotNull {
+			line += " NOT NULL"
+		}
+		if column.Encoding != "" {
+			line += fmt.Sprintf(" ENCODING (%s)", column.Encoding)
+		}
+		columns = append(columns, line)
+	}
+	if len(columns) > 0 {
+		statement.WriteString(strings.Join(columns, ",\n") + "\n")
+	}
+	statement.WriteString(") ")
+	if len(table.Inherits) != 0 {
+		statement.WriteString(fmt.Sprintf("INHERITS (%s) ", strings.Join(table.Inherits, ", ")))
+	}
+	if table.StorageOpts != "" {
+		statement.WriteString(fmt.Sprintf("WITH (%s) ", table.StorageOpts))
+	}
+	if table.Tablespace != "" {
+		statement.WriteString(fmt.Sprintf("TABLESPACE %s ", table.Tablespace))
+	}
+	statement.WriteString(table.DistPolicy)
+	if table.PartDef != "" {
+		statement.WriteString(" " + table.PartDef)
+	}
+	statement.WriteString(";\n")
+	if table.PartTemplateDef != "" {
+		statement.WriteString(table.PartTemplateDef + ";\n")
+	}
+	for _, column := range table.Columns {
+		if column.StatTarget > -1 {
+			statement.WriteString(fmt.Sprintf("\nALTER TABLE ONLY %s ALTER COLUMN %s SET STATISTICS %d;", definition.Identifier, column.Name, column.StatTarget))
+		}
+		if column.StorageType != "" {
+			statement.WriteString(fmt.Sprintf("\nALTER TABLE ONLY %s ALTER COLUMN %s SET STORAGE %s;", definition.Identifier, column.Name, column.StorageType))
+		}
+		if column.Options != "" {
+			statement.WriteString(fmt.Sprintf("\nALTER TABLE ONLY %s ALTER COLUMN %s SET (%s);", definition.Identifier, column.Name, column.Options))
+		}
+	}
+	return strings.TrimSpace(statement.String())
+}
+
+func (definition ObjectDefinition) RenderCreateDatabase() string {
+	database := definition.Database
+	if database == nil {
+		return ""
+	}
+	statement := fmt.Sprintf("CREATE DATABASE %s TEMPLATE template0", definition.Identifier)
+	if database.Tablespace != "" {
+		statement += fmt.Sprintf(" TABLESPACE %s", database.Tablespace)
+	}
+	if database.Encoding != "" {
+		statement += fmt.Sprintf(" ENCODING '%s'", database.Encoding)
+	}
+	if database.Collate != "" {
+		statement += fmt.Sprintf(" LC_COLLATE '%s'", database.Collate)
+	}
+	if database.CType != "" {
+		statement += fmt.Sprintf(" LC_CTYPE '%s'", database.CType)
+	}
+	return statement + ";"
+}
+
+// Each configuration parameter of a database is printed as its own statement
+func (definition ObjectDefinition) RenderDatabaseGUC(index int) string {
+	database := definition.Database
+	if database == nil || index < 0 || index >= len(database.GUCs) {
+		return ""
+	}
+	return fmt.Sprintf("ALTER DATABASE %s %s;", definition.Identifier, database.GUCs[index])
+}
@@ -0,0 +1,111 @@
+package toc_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/toc definitions tests", func() {
+	tableEntry := toc.MetadataEntry{Schema: "public", Name: "foo", ObjectType: "TABLE"}
+	var definition toc.ObjectDefinition
+	BeforeEach(func() {
+		definition = toc.ObjectDefinition{
+			Schema:        "public",
+			Name:          "foo",
+			ObjectType:    "TABLE",
+			StatementType: "TABLE",
+			Identifier:    "public.foo",
+			Comment:       "It's a table",
+			OwnerType:     "TABLE",
+			Owner:         "testrole",
+			PrivilegeType: "TABLE ",
+			HasPrivileges: true,
+			Grants:        []toc.Grant{{Grantee: "reader", Privileges: "SELECT"}, {Grantee: "PUBLIC", Privileges: "ALL", WithGrantOption: true}},
+			Table: &toc.TableDefinition{
+				Columns: []toc.ColumnDefinition{
+					{Name: "i", Type: "integer", NotNull: true, StatTarget: -1},
+					{Name: "j", Type: "text", Default: "'x'::text", StatTarget: 10, StorageType: "PLAIN"},
+				},
+				StorageOpts: "appendonly=true",
+				Tablespace:  "fast_ssd",
+				DistPolicy:  "DISTRIBUTED BY (i)",
+			},
+		}
+	})
+	Describe("Get and Find", func() {
+		It("returns the same definition for the same object", func() {
+			definitions := toc.NewObjectDefinitions()
+			definitions.Get(tableEntry).Owner = "testrole"
+
+			found, ok := definitions.Find(toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE"})
+
+			Expect(ok).To(BeTrue())
+			Expect(found.Owner).To(Equal("testrole"))
+			Expect(definitions.Definitions).To(HaveLen(1))
+		})
+		It("does not find a definition for a different object", func() {
+			definitions := toc.NewObjectDefinitions()
+			definitions.Get(tableEntry)
+
+			_, ok := definitions.Find(toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "INDEX"})
+
+			Expect(ok).To(BeFalse())
+		})
+	})
+	Describe("ReadObjectDefinitions", func() {
+		It("reads the definitions written by WriteToFileAndMakeReadOnly", func() {
+			dir, err := ioutil.TempDir("", "definitions")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+			filename := path.Join(dir, "metadata.yaml")
+			definitions := toc.NewObjectDefinitions()
+			*definitions.Get(tableEntry) = definition
+			definitions.WriteToFileAndMakeReadOnly(filename)
+
+			found, ok := toc.ReadObjectDefinitions(filename).Find(toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE"})
+
+			Expect(ok).To(BeTrue())
+			Expect(found).To(Equal(definition))
+		})
+	})
+	Describe("Render functions", func() {
+		It("renders the attributes of an object", func() {
+			Expect(definition.RenderComment()).To(Equal("COMMENT ON TABLE public.foo IS 'It''s a table';"))
+			Expect(definition.RenderOwner()).To(Equal("ALTER TABLE public.foo OWNER TO testrole;"))
+			Expect(definition.RenderPrivileges()).To(Equal(`REVOKE ALL ON TABLE public.foo FROM PUBLIC;
+REVOKE ALL ON TABLE public.foo FROM testrole;
+GRANT SELECT ON TABLE public.foo TO reader;
+GRANT ALL ON TABLE public.foo TO PUBLIC WITH GRANT OPTION;`))
+			Expect(definition.RenderSecurityLabel()).To(Equal(""))
+		})
+		It("renders a table definition", func() {
+			Expect(definition.RenderCreateTable()).To(Equal(`CREATE TABLE public.foo (
+	i integer NOT NULL,
+	j text DEFAULT 'x'::text
+) WITH (appendonly=true) TABLESPACE fast_ssd DISTRIBUTED BY (i);
+
+ALTER TABLE ONLY public.foo ALTER COLUMN j SET STATISTICS 10;
+ALTER TABLE ONLY public.foo ALTER COLUMN j SET STORAGE PLAIN;`))
+		})
+		It("renders a database definition", func() {
+			database := toc.ObjectDefinition{Name: "testdb", ObjectType: "DATABASE", Identifier: "testdb", Database: &toc.DatabaseDefinition{Tablespace: "fast_ssd", Encoding: "UTF8", Collate: "en_US.utf-8", CType: "en_US.utf-8"}}
+			gucs := toc.ObjectDefinition{Name: "testdb", ObjectType: "DATABASE GUC", Identifier: "testdb", Database: &toc.DatabaseDefinition{GUCs: []string{"SET search_path TO public", "SET work_mem TO '1GB'"}}}
+
+			Expect(database.RenderCreateDatabase()).To(Equal("CREATE DATABASE testdb TEMPLATE template0 TABLESPACE fast_ssd ENCODING 'UTF8' LC_COLLATE 'en_US.utf-8' LC_CTYPE 'en_US.utf-8';"))
+			Expect(gucs.RenderDatabaseGUC(1)).To(Equal("ALTER DATABASE testdb SET work_mem TO '1GB';"))
+			Expect(gucs.RenderDatabaseGUC(2)).To(Equal(""))
+			Expect(definition.RenderCreateDatabase()).To(Equal(""))
+		})
+		It("does not render an owner without an owner statement type", func() {
+			definition.OwnerType = ""
+
+			Expect(definition.RenderOwner()).To(Equal(""))
+		})
+	})
+})
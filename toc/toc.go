@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"regexp"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/utils"
@@ -18,7 +19,10 @@ type TOC struct {
 	PostdataEntries     []MetadataEntry
 	StatisticsEntries   []MetadataEntry
 	DataEntries         []MasterDataEntry
+	LargeObjectEntries  []LargeObjectDataEntry `yaml:",omitempty"`
 	IncrementalMetadata IncrementalEntries
+	SkippedTables       []string           `yaml:",omitempty"` // Tables left out of the backup because they could not be locked
+	ObjectDefinitions   *ObjectDefinitions `yaml:"-"`          // Only set when backing up with --structured-metadata
 }
 
 type SegmentTOC struct {
@@ -32,6 +36,18 @@ type MetadataEntry struct {
 	ReferenceObject string
 	StartByte       uint64
 	EndByte         uint64
+	ObjectID        UniqueID   `yaml:",omitempty"`
+	Dependencies    []UniqueID `yaml:",omitempty"`
+}
+
+/*
+ * Identifies the catalog object a metadata entry belongs to, so that gprestore
+ * can order statements for dependent objects without re-deriving the
+ * dependencies from the statements themselves.
+ */
+type UniqueID struct {
+	ClassID uint32
+	Oid     uint32
 }
 
 type MasterDataEntry struct {
@@ -41,6 +57,19 @@ type MasterDataEntry struct {
 	AttributeString string
 	RowsCopied      int64
 	PartitionRoot   string
+	SegmentSize     int64 `yaml:",omitempty"` // on-disk size in bytes on the segment where the table is largest
+	SegmentCount    int   `yaml:",omitempty"` // set when the table spanned fewer segments than the cluster, as during an expansion
+}
+
+/*
+ * The contents of all large objects are written one after another to a single
+ * data file on the coordinator, and each entry records where the contents of
+ * one large object start and end in that file.
+ */
+type LargeObjectDataEntry struct {
+	Oid       uint32
+	StartByte uint64
+	EndByte   uint64
 }
 
 type SegmentDataEntry struct {
@@ -48,8 +77,15 @@ type SegmentDataEntry struct {
 	EndByte   uint64
 }
 
+/*
+ * PartitionRoots maps each leaf partition to its root partition table. It is
+ * only recorded by backups without --leaf-partition-data, which back up the
+ * data of a partition table as a whole if any of its leaf partitions changed.
+ */
 type IncrementalEntries struct {
-	AO map[string]AOEntry
+	AO             map[string]AOEntry
+	Heap           map[string]HeapEntry `yaml:",omitempty"`
+	PartitionRoots map[string]string    `yaml:",omitempty"`
 }
 
 type AOEntry struct {
@@ -57,6 +93,19 @@ type AOEntry struct {
 	LastDDLTimestamp string
 }
 
+/*
+ * Modcount is the number of tuples inserted, updated, and deleted since
+ * statistics were last reset, and Size is the sum of the sizes of the table on
+ * all segments.
+ */
+type HeapEntry struct {
+	Modcount            int64
+	LastDDLTimestamp    string
+	StatsResetTimestamp string
+	Relfilenode         uint32
+	Size                int64
+}
+
 func NewTOC(filename string) *TOC {
 	toc := &TOC{}
 	contents, err := ioutil.ReadFile(filename)
@@ -76,9 +125,13 @@ func NewSegmentTOC(filename string) *SegmentTOC {
 }
 
 func (toc *TOC) WriteToFileAndMakeReadOnly(filename string) {
+	toc.WriteToEncryptedFileAndMakeReadOnly(filename, nil)
+}
+
+func (toc *TOC) WriteToEncryptedFileAndMakeReadOnly(filename string, key *utils.EncryptionKey) {
 	contents, err := yaml.Marshal(toc)
 	gplog.FatalOnError(err)
-	err = utils.WriteToFileAndMakeReadOnly(filename, contents)
+	err = utils.WriteToEncryptedFileAndMakeReadOnly(filename, contents, key)
 	gplog.FatalOnError(err)
 }
 
@@ -96,6 +149,9 @@ type StatementWithType struct {
 	ObjectType      string
 	ReferenceObject string
 	Statement       string
+	ObjectID        UniqueID
+	Dependencies    []UniqueID
+	FromDefinition  bool // Rendered from the structured metadata file rather than read from the metadata file
 }
 
 func GetIncludedPartitionRoots(tocDataEntries []MasterDataEntry, includeRelations []string) []string {
@@ -130,7 +186,7 @@ func (toc *TOC) GetSQLStatementForObjectTypes(section string, metadataFile io.Re
 			contents := make([]byte, entry.EndByte-entry.StartByte)
 			_, err := metadataFile.ReadAt(contents, int64(entry.StartByte))
 			gplog.FatalOnError(err)
-			statements = append(statements, StatementWithType{Schema: entry.Schema, Name: entry.Name, ObjectType: entry.ObjectType, ReferenceObject: entry.ReferenceObject, Statement: string(contents)})
+			statements = append(statements, StatementWithType{Schema: entry.Schema, Name: entry.Name, ObjectType: entry.ObjectType, ReferenceObject: entry.ReferenceObject, Statement: string(contents), ObjectID: entry.ObjectID, Dependencies: entry.Dependencies})
 		}
 	}
 	return statements
@@ -220,11 +276,16 @@ func (toc *TOC) GetDataEntriesMatching(includeSchemas []string, excludeSchemas [
 	return matchingEntries
 }
 
+/*
+ * Statements rendered from the structured metadata file already name the
+ * redirect database, so this only changes statements restored from the
+ * metadata file as-is, such as those of backups without structured metadata.
+ */
 func SubstituteRedirectDatabaseInStatements(statements []StatementWithType, oldQuotedName string, newQuotedName string) []StatementWithType {
 	shouldReplace := map[string]bool{"DATABASE GUC": true, "DATABASE": true, "DATABASE METADATA": true}
 	pattern := regexp.MustCompile(fmt.Sprintf("DATABASE %s(;| OWNER| SET| TO| FROM| IS| TEMPLATE)", regexp.QuoteMeta(oldQuotedName)))
 	for i := range statements {
-		if shouldReplace[statements[i].ObjectType] {
+		if shouldReplace[statements[i].ObjectType] && !statements[i].FromDefinition {
 			statements[i].Statement = pattern.ReplaceAllString(statements[i].Statement, fmt.Sprintf("DATABASE %s$1", newQuotedName))
 		}
 	}
@@ -242,6 +303,24 @@ func RemoveActiveRole(activeUser string, statements []StatementWithType) []State
 	return newStatements
 }
 
+/*
+ * Comments, privileges, and security labels are written as separate statements
+ * under the TOC entry of the object they belong to, so they can only be told
+ * apart from the statements that create the object by their contents.
+ */
+func GetMetadataAttributeType(statement StatementWithType) string {
+	trimmed := strings.TrimSpace(statement.Statement)
+	switch {
+	case strings.HasPrefix(trimmed, "COMMENT ON "):
+		return "COMMENT"
+	case strings.HasPrefix(trimmed, "SECURITY LABEL "):
+		return "SECURITY LABEL"
+	case statement.ObjectType != "ROLE GRANT" && (strings.HasPrefix(trimmed, "REVOKE ") || strings.HasPrefix(trimmed, "GRANT ")):
+		return "PRIVILEGES"
+	}
+	return ""
+}
+
 func (toc *TOC) InitializeMetadataEntryMap() {
 	toc.metadataEntryMap = make(map[string]*[]MetadataEntry, 4)
 	toc.metadataEntryMap["global"] = &toc.GlobalEntries
@@ -265,8 +344,32 @@ func (toc *TOC) AddMetadataEntry(section string, entry MetadataEntry, start, end
 	*toc.metadataEntryMap[section] = append(*toc.metadataEntryMap[section], entry)
 }
 
-func (toc *TOC) AddMasterDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string) {
-	toc.DataEntries = append(toc.DataEntries, MasterDataEntry{schema, name, oid, attributeString, rowsCopied, PartitionRoot})
+/*
+ * Records the object and dependencies for all entries added to the section
+ * since the entry at index start, which is how statements for an object's
+ * comments, owner, and privileges are tied to the object itself.
+ */
+func (toc *TOC) AddDependencyInfo(section string, start int, objectID UniqueID, dependencies []UniqueID) {
+	entries := *toc.metadataEntryMap[section]
+	for i := start; i < len(entries); i++ {
+		entries[i].ObjectID = objectID
+		entries[i].Dependencies = dependencies
+		if toc.ObjectDefinitions == nil {
+			continue
+		}
+		if definition, ok := toc.ObjectDefinitions.Lookup(entries[i]); ok {
+			definition.ObjectID = objectID
+			definition.Dependencies = dependencies
+		}
+	}
+}
+
+func (toc *TOC) AddMasterDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string, segmentSize int64, segmentCount int) {
+	toc.DataEntries = append(toc.DataEntries, MasterDataEntry{schema, name, oid, attributeString, rowsCopied, PartitionRoot, segmentSize, segmentCount})
+}
+
+func (toc *TOC) AddLargeObjectDataEntry(oid uint32, startByte uint64, endByte uint64) {
+	toc.LargeObjectEntries = append(toc.LargeObjectEntries, LargeObjectDataEntry{oid, startByte, endByte})
 }
 
 func (toc *SegmentTOC) AddSegmentDataEntry(oid uint, startByte uint64, endByte uint64) {
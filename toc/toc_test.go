@@ -195,11 +195,11 @@ var _ = Describe("utils/toc tests", func() {
 	})
 	Describe("GetDataEntriesMatching", func() {
 		BeforeEach(func() {
-			tocfile.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
-			tocfile.AddMasterDataEntry("schema2", "table2", 1, "(i)", 0, "")
-			tocfile.AddMasterDataEntry("schema3", "table3", 1, "(i)", 0, "")
-			tocfile.AddMasterDataEntry("schema3", "table3_partition1", 1, "(i)", 0, "table3")
-			tocfile.AddMasterDataEntry("schema3", "table3_partition2", 1, "(i)", 0, "table3")
+			tocfile.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema2", "table2", 1, "(i)", 0, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema3", "table3", 1, "(i)", 0, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema3", "table3_partition1", 1, "(i)", 0, "table3", 0, 0)
+			tocfile.AddMasterDataEntry("schema3", "table3_partition2", 1, "(i)", 0, "table3", 0, 0)
 		})
 		Context("Non-empty restore plan", func() {
 			restorePlanTableFQNs := []string{"schema1.table1", "schema2.table2", "schema3.table3", "schema3.table3_partition1", "schema3.table3_partition2"}
@@ -398,24 +398,73 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 			Expect(resultStatements).To(Equal([]toc.StatementWithType{user1, user2}))
 		})
 	})
+	Describe("GetMetadataAttributeType", func() {
+		It("returns the type of comment, privilege, and security label statements", func() {
+			Expect(toc.GetMetadataAttributeType(toc.StatementWithType{ObjectType: "TABLE", Statement: "\n\nCOMMENT ON TABLE public.foo IS 'comment';\n"})).To(Equal("COMMENT"))
+			Expect(toc.GetMetadataAttributeType(toc.StatementWithType{ObjectType: "TABLE", Statement: "\n\nREVOKE ALL ON TABLE public.foo FROM PUBLIC;\n"})).To(Equal("PRIVILEGES"))
+			Expect(toc.GetMetadataAttributeType(toc.StatementWithType{ObjectType: "TABLE", Statement: "\n\nSECURITY LABEL FOR dummy ON TABLE public.foo IS 'unclassified';\n"})).To(Equal("SECURITY LABEL"))
+		})
+		It("returns an empty string for other statements", func() {
+			Expect(toc.GetMetadataAttributeType(toc.StatementWithType{ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.foo (i int);\n"})).To(Equal(""))
+			Expect(toc.GetMetadataAttributeType(toc.StatementWithType{ObjectType: "ROLE GRANT", Statement: "\n\nGRANT role1 TO role2;\n"})).To(Equal(""))
+		})
+	})
+	Describe("AddDependencyInfo", func() {
+		It("records the object and its dependencies on every entry added since the given index", func() {
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema", Name: "type", ObjectType: "TYPE"}, 0, 10)
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE"}, 10, 20)
+			tocfile.AddMetadataEntry("predata", toc.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE"}, 20, 30)
+			objectID := toc.UniqueID{ClassID: 1259, Oid: 2}
+			dependencies := []toc.UniqueID{{ClassID: 1247, Oid: 1}}
+
+			tocfile.AddDependencyInfo("predata", 1, objectID, dependencies)
+
+			Expect(tocfile.PredataEntries[0].ObjectID).To(Equal(toc.UniqueID{}))
+			Expect(tocfile.PredataEntries[0].Dependencies).To(BeNil())
+			Expect(tocfile.PredataEntries[1].ObjectID).To(Equal(objectID))
+			Expect(tocfile.PredataEntries[1].Dependencies).To(Equal(dependencies))
+			Expect(tocfile.PredataEntries[2].ObjectID).To(Equal(objectID))
+			Expect(tocfile.PredataEntries[2].Dependencies).To(Equal(dependencies))
+		})
+		It("records the object and its dependencies on the definitions of those entries when writing structured metadata", func() {
+			tocfile.ObjectDefinitions = toc.NewObjectDefinitions()
+			typeEntry := toc.MetadataEntry{Schema: "schema", Name: "type", ObjectType: "TYPE"}
+			tableEntry := toc.MetadataEntry{Schema: "schema", Name: "table1", ObjectType: "TABLE"}
+			tocfile.AddMetadataEntry("predata", typeEntry, 0, 10)
+			tocfile.ObjectDefinitions.Get(typeEntry)
+			tocfile.AddMetadataEntry("predata", tableEntry, 10, 20)
+			tocfile.ObjectDefinitions.Get(tableEntry)
+			objectID := toc.UniqueID{ClassID: 1259, Oid: 2}
+			dependencies := []toc.UniqueID{{ClassID: 1247, Oid: 1}}
+
+			tocfile.AddDependencyInfo("predata", 1, objectID, dependencies)
+
+			typeDefinition, _ := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Schema: "schema", Name: "type", ObjectType: "TYPE"})
+			tableDefinition, _ := tocfile.ObjectDefinitions.Find(toc.StatementWithType{Schema: "schema", Name: "table1", ObjectType: "TABLE"})
+			Expect(typeDefinition.ObjectID).To(Equal(toc.UniqueID{}))
+			Expect(tableDefinition.ObjectID).To(Equal(objectID))
+			Expect(tableDefinition.Dependencies).To(Equal(dependencies))
+			Expect(tocfile.ObjectDefinitions.Definitions).To(HaveLen(2))
+		})
+	})
 	Describe("GetIncludedPartitionRoots", func() {
 		It("does not return anything if relations are not leaf partitions", func() {
-			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
+			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", 0, 0)
 			roots := toc.GetIncludedPartitionRoots(tocfile.DataEntries, []string{"schema0.name0", "schema1.name1"})
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns root parition of leaf partitions", func() {
-			tocfile.AddMasterDataEntry("schema0", "name0", 2, "attribute0", 1, "root0")
-			tocfile.AddMasterDataEntry("schema1", "name1", 3, "attribute0", 1, "root1")
+			tocfile.AddMasterDataEntry("schema0", "name0", 2, "attribute0", 1, "root0", 0, 0)
+			tocfile.AddMasterDataEntry("schema1", "name1", 3, "attribute0", 1, "root1", 0, 0)
 			roots := toc.GetIncludedPartitionRoots(tocfile.DataEntries, []string{"schema0.name0", "schema1.name1"})
 			Expect(roots).To(ConsistOf("schema0.root0", "schema1.root1"))
 		})
 		It("only returns root partitions of leaf partitions", func() {
-			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			tocfile.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2", 0, 0)
+			tocfile.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3", 0, 0)
 			roots := toc.GetIncludedPartitionRoots(tocfile.DataEntries, []string{"schema2.name2", "schema3.name3"})
 			Expect(roots).To(ConsistOf("schema2.root2", "schema3.root3"))
 		})
@@ -424,18 +473,18 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns nothing if relation is not part of TOC data entries", func() {
-			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			tocfile.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2", 0, 0)
+			tocfile.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3", 0, 0)
 			roots := toc.GetIncludedPartitionRoots(tocfile.DataEntries, []string{"schema4.name4", "schema5.name5"})
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns empty if no relations are passed in", func() {
-			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			tocfile.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			tocfile.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			tocfile.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "", 0, 0)
+			tocfile.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2", 0, 0)
+			tocfile.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3", 0, 0)
 			roots := toc.GetIncludedPartitionRoots(tocfile.DataEntries, []string{})
 			Expect(roots).To(BeEmpty())
 		})
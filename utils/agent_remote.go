@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
 	path "path/filepath"
@@ -64,6 +65,41 @@ func WriteOidListToSegments(oidList []string, c *cluster.Cluster, fpInfo filepat
 	c.CheckClusterError(remoteOutput, errMsg, errFunc, false)
 }
 
+/*
+ * The key is copied instead of being written by a remote command so that it
+ * does not appear in the command line of any process.
+ */
+func WriteEncryptionKeyToSegments(key *EncryptionKey, c *cluster.Cluster, fpInfo filepath.FilePathInfo) {
+	localKeyFile, err := operating.System.TempFile("", "gpbackup-key")
+	gplog.FatalOnError(err, "Cannot open temporary file to write encryption key")
+	defer func() {
+		err = operating.System.Remove(localKeyFile.Name())
+		if err != nil {
+			gplog.Warn("Cannot remove temporary encryption key file: %s, Err: %s", localKeyFile.Name(), err.Error())
+		}
+	}()
+	_, err = localKeyFile.WriteString(hex.EncodeToString(key.Key))
+	if err == nil {
+		err = localKeyFile.Close()
+	}
+	gplog.FatalOnError(err, localKeyFile.Name())
+
+	generateScpCmd := func(contentID int) string {
+		sourceFile := localKeyFile.Name()
+		hostname := c.GetHostForContent(contentID)
+		dest := fpInfo.GetSegmentHelperFilePath(contentID, "key")
+
+		return fmt.Sprintf(`scp -p %s %s:%s`, sourceFile, hostname, dest)
+	}
+	remoteOutput := c.GenerateAndExecuteCommand("Scp encryption key file to segments", generateScpCmd, cluster.ON_MASTER_TO_SEGMENTS)
+
+	errMsg := "Failed to scp encryption key file"
+	errFunc := func(contentID int) string {
+		return "Failed to run scp"
+	}
+	c.CheckClusterError(remoteOutput, errMsg, errFunc, false)
+}
+
 func WriteOidsToFile(filename string, oidList []string) {
 	oidFp, err := iohelper.OpenFileForWriting(filename)
 	gplog.FatalOnError(err, filename)
@@ -111,7 +147,7 @@ func VerifyHelperVersionOnSegments(version string, c *cluster.Cluster) {
 	}
 }
 
-func StartGpbackupHelpers(c *cluster.Cluster, fpInfo filepath.FilePathInfo, operation string, pluginConfigFile string, compressStr string, onErrorContinue bool) {
+func StartGpbackupHelpers(c *cluster.Cluster, fpInfo filepath.FilePathInfo, operation string, pluginConfigFile string, compressStr string, onErrorContinue bool, encrypted bool) {
 	gphomePath := operating.System.Getenv("GPHOME")
 	pluginStr := ""
 	if pluginConfigFile != "" {
@@ -128,7 +164,11 @@ func StartGpbackupHelpers(c *cluster.Cluster, fpInfo filepath.FilePathInfo, oper
 		scriptFile := fpInfo.GetSegmentHelperFilePath(contentID, "script")
 		pipeFile := fpInfo.GetSegmentPipeFilePath(contentID)
 		backupFile := fpInfo.GetTableBackupFilePath(contentID, 0, GetPipeThroughProgram().Extension, true)
-		helperCmdStr := fmt.Sprintf("gpbackup_helper %s --toc-file %s --oid-file %s --pipe-file %s --data-file %s --content %d%s%s%s", operation, tocFile, oidFile, pipeFile, backupFile, contentID, pluginStr, compressStr, onErrorContinueStr)
+		encryptionStr := ""
+		if encrypted {
+			encryptionStr = fmt.Sprintf(" --encryption-key-file %s", fpInfo.GetSegmentHelperFilePath(contentID, "key"))
+		}
+		helperCmdStr := fmt.Sprintf("gpbackup_helper %s --toc-file %s --oid-file %s --pipe-file %s --data-file %s --content %d%s%s%s%s", operation, tocFile, oidFile, pipeFile, backupFile, contentID, pluginStr, compressStr, onErrorContinueStr, encryptionStr)
 		// we run these commands in sequence to ensure that any failure is critical; the last command ensures the agent process was successfully started
 		return fmt.Sprintf(`cat << HEREDOC > %[1]s && chmod +x %[1]s && ( nohup %[1]s &> /dev/null &)
 #!/bin/bash
@@ -145,11 +185,12 @@ HEREDOC
 }
 
 func CleanUpHelperFilesOnAllHosts(c *cluster.Cluster, fpInfo filepath.FilePathInfo) {
-	remoteOutput := c.GenerateAndExecuteCommand("Removing oid list and helper script files from segment data directories", func(contentID int) string {
+	remoteOutput := c.GenerateAndExecuteCommand("Removing oid list, encryption key, and helper script files from segment data directories", func(contentID int) string {
 		errorFile := fmt.Sprintf("%s_error", fpInfo.GetSegmentPipeFilePath(contentID))
 		oidFile := fpInfo.GetSegmentHelperFilePath(contentID, "oid")
 		scriptFile := fpInfo.GetSegmentHelperFilePath(contentID, "script")
-		return fmt.Sprintf("rm -f %s && rm -f %s && rm -f %s", errorFile, oidFile, scriptFile)
+		keyFile := fpInfo.GetSegmentHelperFilePath(contentID, "key")
+		return fmt.Sprintf("rm -f %s && rm -f %s && rm -f %s && rm -f %s", errorFile, oidFile, scriptFile, keyFile)
 	}, cluster.ON_SEGMENTS)
 	errMsg := fmt.Sprintf("Unable to remove segment helper file(s). See %s for a complete list of segments with errors and remove manually.",
 		gplog.GetLogFilePath())
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
@@ -75,6 +76,19 @@ var _ = Describe("agent remote", func() {
 			Expect(string(logfile.Contents())).To(ContainSubstring(`[DEBUG]:-Command was: scp fake_master fake_host`))
 		})
 	})
+	Describe("WriteEncryptionKeyToSegments()", func() {
+		It("generates the correct scp commands to copy the key file to segments", func() {
+			key, _ := utils.ParseEncryptionKey(strings.Repeat("0123456789abcdef", 4))
+
+			utils.WriteEncryptionKeyToSegments(key, testCluster, fpInfo)
+
+			Expect(testExecutor.NumExecutions).To(Equal(1))
+			cc := testExecutor.ClusterCommands[0]
+			Expect(len(cc)).To(Equal(2))
+			Expect(cc[0][2]).To(MatchRegexp("scp -p .*/gpbackup-key.* localhost:/data/gpseg0/gpbackup_0_11112233445566_key_.*"))
+			Expect(cc[1][2]).To(MatchRegexp("scp -p .*/gpbackup-key.* remotehost1:/data/gpseg1/gpbackup_1_11112233445566_key_.*"))
+		})
+	})
 	Describe("WriteOidsToFile()", func() {
 		It("writes oid list, delimited by newline characters", func() {
 			utils.WriteOidsToFile("myFilename", oidList)
@@ -123,10 +137,18 @@ var _ = Describe("agent remote", func() {
 	})
 	Describe("StartGpbackupHelpers()", func() {
 		It("Correctly propagates --on-error-continue flag to gpbackup_helper", func() {
-			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", true)
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", true, false)
 
 			cc := testExecutor.ClusterCommands[0]
 			Expect(cc[0][4]).To(ContainSubstring(" --on-error-continue"))
+			Expect(cc[0][4]).ToNot(ContainSubstring(" --encryption-key-file"))
+		})
+		It("passes the encryption key file on each segment to gpbackup_helper", func() {
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "", "", false, true)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc[0][4]).To(ContainSubstring(fmt.Sprintf(" --encryption-key-file /data/gpseg0/gpbackup_0_11112233445566_key_%d", fpInfo.PID)))
+			Expect(cc[1][4]).To(ContainSubstring(fmt.Sprintf(" --encryption-key-file /data/gpseg1/gpbackup_1_11112233445566_key_%d", fpInfo.PID)))
 		})
 	})
 	Describe("CheckAgentErrorsOnSegments", func() {
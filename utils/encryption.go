@@ -0,0 +1,401 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	path "path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Encrypted files start with a header consisting of a magic string, the id
+ * of the key the file was encrypted with, and a random nonce prefix. The
+ * header is followed by chunks of at most encryptionChunkSize bytes of
+ * plaintext, each sealed with AES-256-GCM and preceded by the length of its
+ * ciphertext. The nonce of each chunk is the nonce prefix followed by the
+ * number of the chunk, and the last chunk is authenticated as the last one,
+ * so reordered, dropped, or truncated chunks are detected when decrypting.
+ */
+const (
+	encryptionMagic       = "GPBKENC1"
+	encryptionKeyIDLength = 16
+	encryptionNonceLength = 12
+	encryptionPrefixSize  = 8
+	encryptionChunkSize   = 64 * 1024
+	encryptionKeyIDEnvVar = "GPBACKUP_ENCRYPTION_KEY_ID"
+)
+
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+/*
+ * Keys are 32 bytes written as 64 hexadecimal characters. The id of a key is
+ * derived from the key, so that the id recorded in the backup config can be
+ * used to check that the right key was given without revealing the key.
+ */
+func ParseEncryptionKey(contents string) (*EncryptionKey, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(contents))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("Encryption key must be 64 hexadecimal characters")
+	}
+	checksum := sha256.Sum256(key)
+	return &EncryptionKey{ID: hex.EncodeToString(checksum[:])[:encryptionKeyIDLength], Key: key}, nil
+}
+
+/*
+ * The key is read from keyFile if it is given, otherwise from the output of
+ * keyCommand. The id of the key expected, if any, is passed to keyCommand in
+ * the GPBACKUP_ENCRYPTION_KEY_ID environment variable so that it can look up
+ * the key a backup was taken with.
+ */
+func ReadEncryptionKey(keyFile string, keyCommand string, keyID string) (*EncryptionKey, error) {
+	var contents []byte
+	var err error
+	if keyFile != "" {
+		contents, err = operating.System.ReadFile(keyFile)
+		if err != nil {
+			return nil, errors.Errorf("Unable to read encryption key file %s: %v", keyFile, err)
+		}
+	} else {
+		cmd := exec.Command("bash", "-c", keyCommand)
+		cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", encryptionKeyIDEnvVar, keyID))
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		contents, err = cmd.Output()
+		if err != nil {
+			return nil, errors.Errorf("Encryption key command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+	key, err := ParseEncryptionKey(string(contents))
+	if err != nil {
+		return nil, err
+	}
+	if keyID != "" && key.ID != keyID {
+		return nil, errors.Errorf("Backup was encrypted with key %s, but key %s was given", keyID, key.ID)
+	}
+	return key, nil
+}
+
+func newEncryptionCipher(key *EncryptionKey) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func getChunkNonce(prefix []byte, chunkNum uint32) []byte {
+	nonce := make([]byte, encryptionNonceLength)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encryptionPrefixSize:], chunkNum)
+	return nonce
+}
+
+func getChunkAdditionalData(isFinal bool) []byte {
+	if isFinal {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+type EncryptWriter struct {
+	writer      io.Writer
+	aead        cipher.AEAD
+	prefix      []byte
+	buffer      []byte
+	chunkNum    uint32
+	wroteHeader bool
+	closed      bool
+}
+
+/*
+ * Closing an EncryptWriter writes the last chunk but does not close the
+ * underlying writer. Data written to an EncryptWriter that is not closed
+ * cannot be decrypted.
+ */
+func NewEncryptWriter(writer io.Writer, key *EncryptionKey) (*EncryptWriter, error) {
+	aead, err := newEncryptionCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, encryptionPrefixSize)
+	_, err = rand.Read(prefix)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte(encryptionMagic), key.ID...)
+	header = append(header, prefix...)
+	return &EncryptWriter{
+		writer: writer,
+		aead:   aead,
+		prefix: prefix,
+		buffer: header,
+	}, nil
+}
+
+func (w *EncryptWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("Write to closed encrypted file")
+	}
+	if !w.wroteHeader {
+		_, err := w.writer.Write(w.buffer)
+		if err != nil {
+			return 0, err
+		}
+		w.buffer = make([]byte, 0, encryptionChunkSize)
+		w.wroteHeader = true
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, so that the last chunk is always sealed by Close
+		if len(w.buffer) == encryptionChunkSize {
+			err := w.sealChunk(false)
+			if err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buffer[len(w.buffer):encryptionChunkSize], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *EncryptWriter) sealChunk(isFinal bool) error {
+	if w.chunkNum == ^uint32(0) {
+		return errors.New("Encrypted file is too large")
+	}
+	ciphertext := w.aead.Seal(nil, getChunkNonce(w.prefix, w.chunkNum), w.buffer, getChunkAdditionalData(isFinal))
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(ciphertext)))
+	_, err := w.writer.Write(append(length, ciphertext...))
+	if err != nil {
+		return err
+	}
+	w.chunkNum++
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func (w *EncryptWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	if !w.wroteHeader {
+		_, err := w.Write(nil)
+		if err != nil {
+			return err
+		}
+	}
+	w.closed = true
+	return w.sealChunk(true)
+}
+
+type DecryptReader struct {
+	reader    io.Reader
+	aead      cipher.AEAD
+	prefix    []byte
+	plaintext []byte
+	chunkNum  uint32
+	readFinal bool
+}
+
+func NewDecryptReader(reader io.Reader, key *EncryptionKey) (*DecryptReader, error) {
+	header := make([]byte, len(encryptionMagic)+encryptionKeyIDLength+encryptionPrefixSize)
+	_, err := io.ReadFull(reader, header)
+	if err != nil || string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New("Data is not encrypted or its header is corrupt")
+	}
+	keyID := string(header[len(encryptionMagic) : len(encryptionMagic)+encryptionKeyIDLength])
+	if keyID != key.ID {
+		return nil, errors.Errorf("Data was encrypted with key %s, but key %s was given", keyID, key.ID)
+	}
+	aead, err := newEncryptionCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptReader{
+		reader: reader,
+		aead:   aead,
+		prefix: header[len(encryptionMagic)+encryptionKeyIDLength:],
+	}, nil
+}
+
+func (r *DecryptReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.readFinal {
+			return 0, io.EOF
+		}
+		err := r.openChunk()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+func (r *DecryptReader) openChunk() error {
+	length := make([]byte, 4)
+	_, err := io.ReadFull(r.reader, length)
+	if err != nil {
+		return errors.New("Encrypted data is truncated")
+	}
+	ciphertextLength := binary.BigEndian.Uint32(length)
+	if ciphertextLength > uint32(encryptionChunkSize+r.aead.Overhead()) {
+		return errors.New("Encrypted data is corrupt")
+	}
+	ciphertext := make([]byte, ciphertextLength)
+	_, err = io.ReadFull(r.reader, ciphertext)
+	if err != nil {
+		return errors.New("Encrypted data is truncated")
+	}
+	nonce := getChunkNonce(r.prefix, r.chunkNum)
+	r.plaintext, err = r.aead.Open(nil, nonce, ciphertext, getChunkAdditionalData(false))
+	if err != nil {
+		r.plaintext, err = r.aead.Open(nil, nonce, ciphertext, getChunkAdditionalData(true))
+		if err != nil {
+			return errors.New("Encrypted data is corrupt")
+		}
+		r.readFinal = true
+		n, _ := r.reader.Read(make([]byte, 1))
+		if n > 0 {
+			return errors.New("Encrypted data has trailing data after its last chunk")
+		}
+	}
+	r.chunkNum++
+	return nil
+}
+
+// The contents are written unencrypted if key is nil
+func WriteToEncryptedFileAndMakeReadOnly(filename string, contents []byte, key *EncryptionKey) error {
+	if key != nil {
+		var buffer bytes.Buffer
+		encryptWriter, err := NewEncryptWriter(&buffer, key)
+		if err != nil {
+			return err
+		}
+		_, err = encryptWriter.Write(contents)
+		if err != nil {
+			return err
+		}
+		err = encryptWriter.Close()
+		if err != nil {
+			return err
+		}
+		contents = buffer.Bytes()
+	}
+	return WriteToFileAndMakeReadOnly(filename, contents)
+}
+
+func DecryptFile(sourceFilename string, destFilename string, key *EncryptionKey) error {
+	source, err := os.Open(sourceFilename)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	dest, err := os.OpenFile(destFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	decryptReader, err := NewDecryptReader(source, key)
+	if err == nil {
+		_, err = io.Copy(dest, decryptReader)
+	}
+	closeErr := dest.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(destFilename)
+		return errors.Wrapf(err, "Unable to decrypt file %s", sourceFilename)
+	}
+	return nil
+}
+
+/*
+ * DecryptedFiles decrypts encrypted metadata files of a backup into a private
+ * temporary directory the first time they are read, and returns the path of
+ * the decrypted copy. A nil DecryptedFiles is used for unencrypted backups,
+ * whose files are read directly.
+ */
+type DecryptedFiles struct {
+	Key   *EncryptionKey
+	dir   string
+	paths map[string]string
+	mutex sync.Mutex
+}
+
+func NewDecryptedFiles(key *EncryptionKey) *DecryptedFiles {
+	return &DecryptedFiles{Key: key, paths: make(map[string]string)}
+}
+
+func (files *DecryptedFiles) MustGetPath(filename string) string {
+	decryptedFilename, err := files.GetPath(filename)
+	gplog.FatalOnError(err)
+	return decryptedFilename
+}
+
+func (files *DecryptedFiles) GetPath(filename string) (string, error) {
+	if files == nil {
+		return filename, nil
+	}
+	files.mutex.Lock()
+	defer files.mutex.Unlock()
+	if decryptedFilename, ok := files.paths[filename]; ok {
+		return decryptedFilename, nil
+	}
+	if files.dir == "" {
+		dir, err := ioutil.TempDir("", "gpbackup-decrypted")
+		if err != nil {
+			return "", err
+		}
+		files.dir = dir
+	}
+	decryptedFilename := path.Join(files.dir, path.Base(filename))
+	err := DecryptFile(filename, decryptedFilename, files.Key)
+	if err != nil {
+		return "", err
+	}
+	files.paths[filename] = decryptedFilename
+	return decryptedFilename, nil
+}
+
+func (files *DecryptedFiles) Cleanup() {
+	if files == nil || files.dir == "" {
+		return
+	}
+	err := os.RemoveAll(files.dir)
+	if err != nil {
+		gplog.Warn("Unable to remove decrypted files in %s: %v", files.dir, err)
+	}
+}
+
+/*
+ * Data written by COPY is encrypted and decrypted by gpbackup_helper, which
+ * reads the key from keyFile on each segment.
+ */
+func AddEncryptionToPipeThroughProgram(keyFile string) {
+	helperPath := fmt.Sprintf("%s/bin/gpbackup_helper", operating.System.Getenv("GPHOME"))
+	pipeThroughProgram.OutputCommand = fmt.Sprintf("%s | %s --encrypt --encryption-key-file %s", pipeThroughProgram.OutputCommand, helperPath, keyFile)
+	pipeThroughProgram.InputCommand = fmt.Sprintf("%s --decrypt --encryption-key-file %s | %s", helperPath, keyFile, pipeThroughProgram.InputCommand)
+}
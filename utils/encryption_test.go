@@ -0,0 +1,178 @@
+package utils_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/encryption tests", func() {
+	keyString := strings.Repeat("0123456789abcdef", 4)
+	otherKeyString := strings.Repeat("fedcba9876543210", 4)
+	var key, otherKey *utils.EncryptionKey
+	BeforeEach(func() {
+		key, _ = utils.ParseEncryptionKey(keyString)
+		otherKey, _ = utils.ParseEncryptionKey(otherKeyString)
+	})
+	encrypt := func(plaintext []byte) []byte {
+		var buffer bytes.Buffer
+		writer, err := utils.NewEncryptWriter(&buffer, key)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = writer.Write(plaintext)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		return buffer.Bytes()
+	}
+	decrypt := func(ciphertext []byte, decryptKey *utils.EncryptionKey) ([]byte, error) {
+		reader, err := utils.NewDecryptReader(bytes.NewReader(ciphertext), decryptKey)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(reader)
+	}
+
+	Describe("ParseEncryptionKey", func() {
+		It("parses a key and derives its id", func() {
+			Expect(key.Key).To(HaveLen(32))
+			Expect(key.ID).To(HaveLen(16))
+			Expect(otherKey.ID).ToNot(Equal(key.ID))
+		})
+		It("ignores surrounding whitespace", func() {
+			parsedKey, err := utils.ParseEncryptionKey(keyString + "\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsedKey).To(Equal(key))
+		})
+		It("returns an error for a key of the wrong length", func() {
+			_, err := utils.ParseEncryptionKey("0123456789abcdef")
+			Expect(err).To(MatchError("Encryption key must be 64 hexadecimal characters"))
+		})
+		It("returns an error for a key that is not hexadecimal", func() {
+			_, err := utils.ParseEncryptionKey(strings.Repeat("x", 64))
+			Expect(err).To(MatchError("Encryption key must be 64 hexadecimal characters"))
+		})
+	})
+	Describe("ReadEncryptionKey", func() {
+		AfterEach(func() {
+			operating.InitializeSystemFunctions()
+		})
+		It("reads a key from a key file", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) { return []byte(keyString), nil }
+
+			readKey, err := utils.ReadEncryptionKey("/tmp/key", "", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readKey).To(Equal(key))
+		})
+		It("reads a key from the output of a key command that is given the expected key id", func() {
+			readKey, err := utils.ReadEncryptionKey("", `[ "$GPBACKUP_ENCRYPTION_KEY_ID" = "`+key.ID+`" ] && echo `+keyString, key.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(readKey).To(Equal(key))
+		})
+		It("returns an error if the key does not have the expected id", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) { return []byte(otherKeyString), nil }
+
+			_, err := utils.ReadEncryptionKey("/tmp/key", "", key.ID)
+			Expect(err).To(MatchError("Backup was encrypted with key " + key.ID + ", but key " + otherKey.ID + " was given"))
+		})
+		It("returns an error if the key command fails", func() {
+			_, err := utils.ReadEncryptionKey("", "echo 'no key' >&2; exit 1", "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no key"))
+		})
+	})
+	Describe("NewEncryptWriter and NewDecryptReader", func() {
+		It("decrypts encrypted data spanning several chunks", func() {
+			plaintext := bytes.Repeat([]byte("0123456789"), 20000)
+			ciphertext := encrypt(plaintext)
+
+			Expect(bytes.Contains(ciphertext, []byte("0123456789"))).To(BeFalse())
+			Expect(decrypt(ciphertext, key)).To(Equal(plaintext))
+		})
+		It("decrypts encrypted empty data", func() {
+			Expect(decrypt(encrypt([]byte{}), key)).To(BeEmpty())
+		})
+		It("returns an error when decrypting with a different key", func() {
+			_, err := decrypt(encrypt([]byte("data")), otherKey)
+			Expect(err).To(MatchError("Data was encrypted with key " + key.ID + ", but key " + otherKey.ID + " was given"))
+		})
+		It("returns an error when the data is not encrypted", func() {
+			_, err := decrypt([]byte("plain data that is not encrypted"), key)
+			Expect(err).To(MatchError("Data is not encrypted or its header is corrupt"))
+		})
+		It("returns an error when whole chunks are missing from the end of the data", func() {
+			plaintext := bytes.Repeat([]byte("0123456789"), 20000)
+			ciphertext := encrypt(plaintext)
+			lastChunkLength := (len(plaintext)%(64*1024) + 16) + 4
+
+			_, err := decrypt(ciphertext[:len(ciphertext)-lastChunkLength], key)
+			Expect(err).To(MatchError("Encrypted data is truncated"))
+		})
+		It("returns an error when the data is modified", func() {
+			ciphertext := encrypt([]byte("data"))
+			ciphertext[len(ciphertext)-1] ^= 1
+
+			_, err := decrypt(ciphertext, key)
+			Expect(err).To(MatchError("Encrypted data is corrupt"))
+		})
+	})
+	Describe("NewEncryptedFileWithByteCountFromFile and DecryptedFiles", func() {
+		var tempDir string
+		BeforeEach(func() {
+			tempDir, _ = ioutil.TempDir("", "gpbackup-encryption-test")
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(tempDir)
+		})
+		It("encrypts the contents of a file as they are written, counting the bytes written before encryption", func() {
+			filename := tempDir + "/gpbackup_20170101010101_metadata.sql"
+			file := utils.NewEncryptedFileWithByteCountFromFile(filename, key)
+			file.MustPrintf("CREATE ROLE %s;", "foo")
+			Expect(file.ByteCount).To(Equal(uint64(16)))
+			file.Close()
+
+			contents, _ := ioutil.ReadFile(filename)
+			Expect(string(contents)).ToNot(ContainSubstring("CREATE ROLE"))
+			decryptedFiles := utils.NewDecryptedFiles(key)
+			defer decryptedFiles.Cleanup()
+			Expect(ioutil.ReadFile(decryptedFiles.MustGetPath(filename))).To(Equal([]byte("CREATE ROLE foo;")))
+		})
+		It("writes a read-only file encrypted only when given a key", func() {
+			filename := tempDir + "/gpbackup_20170101010101_toc.yaml"
+			plainFilename := tempDir + "/gpbackup_20170101010102_toc.yaml"
+
+			Expect(utils.WriteToEncryptedFileAndMakeReadOnly(filename, []byte("globalentries: []"), key)).To(Succeed())
+			Expect(utils.WriteToEncryptedFileAndMakeReadOnly(plainFilename, []byte("globalentries: []"), nil)).To(Succeed())
+			info, _ := os.Stat(filename)
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0444)))
+			Expect(ioutil.ReadFile(plainFilename)).To(Equal([]byte("globalentries: []")))
+			contents, _ := ioutil.ReadFile(filename)
+			Expect(decrypt(contents, key)).To(Equal([]byte("globalentries: []")))
+		})
+		It("returns the file itself when the backup is not encrypted", func() {
+			var decryptedFiles *utils.DecryptedFiles
+			Expect(decryptedFiles.MustGetPath("/tmp/file")).To(Equal("/tmp/file"))
+		})
+	})
+	Describe("AddEncryptionToPipeThroughProgram", func() {
+		It("adds encryption after compression and decryption before decompression", func() {
+			originalProgram := utils.GetPipeThroughProgram()
+			defer utils.SetPipeThroughProgram(originalProgram)
+			operating.System.Getenv = func(key string) string { return "/usr/local/greenplum-db" }
+			defer operating.InitializeSystemFunctions()
+			utils.InitializePipeThroughParameters(true, 1)
+
+			utils.AddEncryptionToPipeThroughProgram("/data/gpseg0/key")
+
+			program := utils.GetPipeThroughProgram()
+			Expect(program.OutputCommand).To(Equal("gzip -c -1 | /usr/local/greenplum-db/bin/gpbackup_helper --encrypt --encryption-key-file /data/gpseg0/key"))
+			Expect(program.InputCommand).To(Equal("/usr/local/greenplum-db/bin/gpbackup_helper --decrypt --encryption-key-file /data/gpseg0/key | gzip -d -c"))
+			Expect(program.Extension).To(Equal(".gz"))
+		})
+	})
+})
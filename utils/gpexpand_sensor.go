@@ -11,7 +11,7 @@ import (
 )
 
 const (
-	BackupPreventedByGpexpandMessage GpexpandFailureMessage = `Greenplum expansion currently in process, please re-run gpbackup when the expansion has completed`
+	BackupPreventedByGpexpandMessage GpexpandFailureMessage = `Greenplum expansion is currently adding new segments, please re-run gpbackup when the new segments have been added or the expansion has completed`
 
 	RestorePreventedByGpexpandMessage GpexpandFailureMessage = `Greenplum expansion currently in process.  Once expansion is complete, it will be possible to restart gprestore, but please note existing backup sets taken with a different cluster configuration may no longer be compatible with the newly expanded cluster configuration`
 
@@ -29,18 +29,37 @@ type GpexpandSensor struct {
 
 type GpexpandFailureMessage string
 
+/*
+ * In phase 1, gpexpand adds the new segments to the cluster and the catalog is
+ * changing. In phase 2, the new segments are part of the cluster and gpexpand
+ * redistributes the tables onto them one at a time, so tables that have not
+ * been redistributed yet still span only the segments of the old cluster.
+ */
+type GpexpandPhase int
+
+const (
+	GpexpandNotRunning GpexpandPhase = iota
+	GpexpandAddingSegments
+	GpexpandRedistributingTables
+)
+
 func CheckGpexpandRunning(errMsg GpexpandFailureMessage) {
+	if GetGpexpandPhase() != GpexpandNotRunning {
+		gplog.Fatal(errors.New(string(errMsg)), "")
+	}
+}
+
+func GetGpexpandPhase() GpexpandPhase {
 	postgresConn := dbconn.NewDBConnFromEnvironment("postgres")
 	postgresConn.MustConnect(1)
 	defer postgresConn.Close()
-	if postgresConn.Version.AtLeast("6") {
-		gpexpandSensor := NewGpexpandSensor(vfs.OS(), postgresConn)
-		isGpexpandRunning, err := gpexpandSensor.IsGpexpandRunning()
-		gplog.FatalOnError(err)
-		if isGpexpandRunning {
-			gplog.Fatal(errors.New(string(errMsg)), "")
-		}
+	if postgresConn.Version.Before("6") {
+		return GpexpandNotRunning
 	}
+	gpexpandSensor := NewGpexpandSensor(vfs.OS(), postgresConn)
+	phase, err := gpexpandSensor.GetGpexpandPhase()
+	gplog.FatalOnError(err)
+	return phase
 }
 
 func NewGpexpandSensor(myfs vfs.Filesystem, conn *dbconn.DBConn) GpexpandSensor {
@@ -51,20 +70,25 @@ func NewGpexpandSensor(myfs vfs.Filesystem, conn *dbconn.DBConn) GpexpandSensor
 }
 
 func (sensor GpexpandSensor) IsGpexpandRunning() (bool, error) {
+	phase, err := sensor.GetGpexpandPhase()
+	return phase != GpexpandNotRunning, err
+}
+
+func (sensor GpexpandSensor) GetGpexpandPhase() (GpexpandPhase, error) {
 	err := validateConnection(sensor.postgresConn)
 	if err != nil {
-		return false, err
+		return GpexpandNotRunning, err
 	}
 	masterDataDir, err := dbconn.SelectString(sensor.postgresConn, MasterDataDirQuery)
 	if err != nil {
-		return false, err
+		return GpexpandNotRunning, err
 	}
 
 	_, err = sensor.fs.Stat(filepath.Join(masterDataDir, GpexpandStatusFilename))
 	// error has 3 possible states:
 	if err == nil {
 		// file exists, so gpexpand is running
-		return true, nil
+		return GpexpandAddingSegments, nil
 	}
 	if os.IsNotExist(err) {
 		// file not present means gpexpand is not in "phase 1".
@@ -73,31 +97,31 @@ func (sensor GpexpandSensor) IsGpexpandRunning() (bool, error) {
 		var tableName string
 		tableName, err = dbconn.SelectString(sensor.postgresConn, GpexpandStatusTableExistsQuery)
 		if err != nil {
-			return false, err
+			return GpexpandNotRunning, err
 		}
 		if len(tableName) <= 0 {
 			// table does not exist
-			return false, nil
+			return GpexpandNotRunning, nil
 		}
 
 		var status string
 		status, err = dbconn.SelectString(sensor.postgresConn, GpexpandTemporaryTableStatusQuery)
 		if err != nil {
-			return false, err
+			return GpexpandNotRunning, err
 		}
 
 		// gpexpand should indicate being finished with either of 3 possible status messages:
 		if status == "EXPANSION STOPPED" || // error case
 			status == "EXPANSION COMPLETE" || // success case
 			status == "SETUP DONE" { // only one phase completed case
-			return false, nil
+			return GpexpandNotRunning, nil
 		}
 
-		return true, nil
+		return GpexpandRedistributingTables, nil
 	}
 
 	// Stat command returned a "real" error
-	return false, err
+	return GpexpandNotRunning, err
 }
 
 func validateConnection(conn *dbconn.DBConn) error {
@@ -107,6 +107,31 @@ var _ = Describe("gpexpand_sensor", func() {
 				Expect(result).To(BeFalse())
 			})
 		})
+		Describe("GetGpexpandPhase", func() {
+			It("returns that gpexpand is adding segments when its status file exists", func() {
+				mock.ExpectQuery(utils.MasterDataDirQuery).WillReturnRows(mddPathRow)
+				Expect(vfs.MkdirAll(memoryfs, sampleMasterDataDir, 0755)).To(Succeed())
+				path := filepath.Join(sampleMasterDataDir, utils.GpexpandStatusFilename)
+				Expect(vfs.WriteFile(memoryfs, path, []byte{0}, 0400)).To(Succeed())
+				gpexpandSensor := utils.NewGpexpandSensor(memoryfs, connectionPool)
+
+				phase, err := gpexpandSensor.GetGpexpandPhase()
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(phase).To(Equal(utils.GpexpandAddingSegments))
+			})
+			It("returns that gpexpand is redistributing tables when its status table shows it has not finished", func() {
+				mock.ExpectQuery(utils.MasterDataDirQuery).WillReturnRows(mddPathRow)
+				mock.ExpectQuery(regexp.QuoteMeta(utils.GpexpandStatusTableExistsQuery)).WillReturnRows(tableExistsRow)
+				mock.ExpectQuery(utils.GpexpandTemporaryTableStatusQuery).WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("EXPANSION STARTED"))
+				gpexpandSensor := utils.NewGpexpandSensor(memoryfs, connectionPool)
+
+				phase, err := gpexpandSensor.GetGpexpandPhase()
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(phase).To(Equal(utils.GpexpandRedistributingTables))
+			})
+		})
 		Describe("sad paths", func() {
 			It("returns an error when MDD query fails", func() {
 				mock.ExpectQuery(utils.MasterDataDirQuery).WillReturnError(errors.New("query error"))
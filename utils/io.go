@@ -89,23 +89,44 @@ func MustPrintBytes(file io.Writer, bytes []byte) uint64 {
  */
 
 type FileWithByteCount struct {
-	Filename  string
-	Writer    io.Writer
-	File      *os.File
-	ByteCount uint64
+	Filename      string
+	Writer        io.Writer
+	File          *os.File
+	ByteCount     uint64
+	encryptWriter *EncryptWriter
 }
 
 func NewFileWithByteCount(writer io.Writer) *FileWithByteCount {
-	return &FileWithByteCount{"", writer, nil, 0}
+	return &FileWithByteCount{Writer: writer}
 }
 
 func NewFileWithByteCountFromFile(filename string) *FileWithByteCount {
 	file, err := OpenFileForWrite(filename)
 	gplog.FatalOnError(err)
-	return &FileWithByteCount{filename, file, file, 0}
+	return &FileWithByteCount{Filename: filename, Writer: file, File: file}
+}
+
+/*
+ * If key is not nil, the contents of the file are encrypted as they are
+ * written. ByteCount still counts the bytes written before encryption, which
+ * are the offsets of the contents in the decrypted file.
+ */
+func NewEncryptedFileWithByteCountFromFile(filename string, key *EncryptionKey) *FileWithByteCount {
+	file := NewFileWithByteCountFromFile(filename)
+	if key != nil {
+		encryptWriter, err := NewEncryptWriter(file.File, key)
+		gplog.FatalOnError(err)
+		file.Writer = encryptWriter
+		file.encryptWriter = encryptWriter
+	}
+	return file
 }
 
 func (file *FileWithByteCount) Close() {
+	if file.encryptWriter != nil {
+		err := file.encryptWriter.Close()
+		gplog.FatalOnError(err)
+	}
 	if file.File != nil {
 		err := file.File.Sync()
 		gplog.FatalOnError(err)
@@ -136,6 +157,12 @@ func (file *FileWithByteCount) MustPrint(s string) {
 	file.ByteCount += uint64(bytesWritten)
 }
 
+func (file *FileWithByteCount) MustWrite(bytes []byte) {
+	bytesWritten, err := file.Writer.Write(bytes)
+	gplog.FatalOnError(err, "Unable to write to file")
+	file.ByteCount += uint64(bytesWritten)
+}
+
 func CopyFile(src, dest string) error {
 	info, err := os.Stat(src)
 	if err == nil {
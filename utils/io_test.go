@@ -88,6 +88,7 @@ var _ = Describe("utils/io tests", func() {
 		It("closes the FileWithByteCount and makes it read-only if it has a filename", func() {
 			_ = os.Remove("testfile")
 			file = utils.NewFileWithByteCountFromFile("testfile")
+			defer os.Remove("testfile")
 			file.Close()
 			defer testhelper.ShouldPanicWithMessage("write testfile: file already closed: Unable to write to file")
 			file.MustPrintf("message")
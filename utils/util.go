@@ -10,7 +10,9 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"runtime/debug"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -23,6 +25,9 @@ import (
 const MINIMUM_GPDB4_VERSION = "4.3.17"
 const MINIMUM_GPDB5_VERSION = "5.1.0"
 
+// Large object contents are backed up and restored 1MB at a time
+const LargeObjectChunkSize = 1024 * 1024
+
 /*
  * General helper functions
  */
@@ -107,6 +112,37 @@ func InitializeSignalHandler(cleanupFunc func(bool), procDesc string, termFlag *
 	}()
 }
 
+/*
+ * This is deferred by commands that write no report of their own. It recovers
+ * the value passed to it from a panic in the main goroutine, then cleans up
+ * and exits with the error code set by gplog. If the process was terminated,
+ * it waits for the cleanup done by the signal handler instead.
+ */
+func TeardownAndExit(recovered interface{}, cleanupFunc func(bool), cleanupGroup *sync.WaitGroup, termFlag *bool, successMsg string) {
+	defer func() {
+		cleanupFunc(false)
+
+		errorCode := gplog.GetErrorCode()
+		if errorCode == 0 {
+			gplog.Info(successMsg)
+		}
+		os.Exit(errorCode)
+	}()
+
+	if recovered != nil {
+		// gplog's Fatal will cause a panic with error code 2
+		if gplog.GetErrorCode() != 2 {
+			gplog.Error(fmt.Sprintf("%v: %s", recovered, debug.Stack()))
+			gplog.SetErrorCode(2)
+		} else {
+			fmt.Println(recovered)
+		}
+	}
+	if *termFlag {
+		cleanupGroup.Wait()
+	}
+}
+
 // TODO: Uniquely identify COPY commands in the multiple data file case to allow terminating sessions
 func TerminateHangingCopySessions(connectionPool *dbconn.DBConn, fpInfo filepath.FilePathInfo, appName string) {
 	copyFileName := fpInfo.GetSegmentPipePathForCopyCommand()